@@ -5,9 +5,12 @@ import (
 	"duh/internal/domain/service"
 	"duh/internal/infrastructure/filesystem/common"
 	"duh/internal/infrastructure/filesystem/file_db"
+	"duh/internal/infrastructure/filesystem/fs_cache_adapter"
 	"duh/internal/infrastructure/filesystem/fs_function_adapter"
 	"duh/internal/infrastructure/filesystem/fs_user_repository"
 	"duh/internal/infrastructure/filesystem/tomll"
+	"duh/internal/infrastructure/shelll"
+	"duh/internal/infrastructure/systemm"
 	"duh/internal/interfaces/cli/command"
 	"duh/internal/interfaces/cli/handler"
 
@@ -18,32 +21,52 @@ func InitializeCLI() *cobra.Command {
 	// Initialize infrastructure
 	pathProvider := &common.BasePathProvider{}
 	fileHandler := &tomll.TomlFileHandler{}
-	dbAdapter := file_db.NewFileDbAdapter(pathProvider, &common.GitConfigPathProvider{}, fileHandler)
-	userRepository := fs_user_repository.NewFsUserRepository(fileHandler, pathProvider)
+	environmentAdapter := systemm.NewEnvironmentAdapter()
+	// Selects the active profile, for the adapters storing the packages of each profile
+	profileService := service.NewProfileService(environmentAdapter)
+	dbAdapter := file_db.NewFileDbAdapter(pathProvider, &common.GitConfigPathProvider{}, fileHandler, profileService)
+	userRepository := fs_user_repository.NewFsUserRepository(fileHandler, pathProvider, profileService)
 	functionRepository := fs_function_adapter.NewFSFunctionsRepository(pathProvider, userRepository)
 	initDbService := file_db.NewInitDbService(pathProvider, fileHandler)
+	shellAdapter := shelll.NewShellAdapter()
+	cacheAdapter := fs_cache_adapter.NewFSCacheAdapter(pathProvider, &common.GitConfigPathProvider{}, fileHandler, environmentAdapter)
 
 	// Initialize domain services
 	aliasService := service.NewAliasService(dbAdapter)
 	packageService := service.NewPackageService(dbAdapter)
+	conflictService := service.NewConflictService(dbAdapter, functionRepository)
+	conditionService := service.NewConditionService(environmentAdapter)
+	lockService := service.NewLockService(dbAdapter, userRepository)
+	importService := service.NewImportService(dbAdapter, functionRepository)
+	lintService := service.NewLintService(dbAdapter, functionRepository)
 
 	// Initialize use cases
 	aliasUsecase := usecase.NewAliasUsecase(aliasService)
-	exportsUsecase := usecase.NewExportsUsecase(dbAdapter)
-	functionsUsecase := usecase.NewFunctionsUsecase(functionRepository)
-	injectUsecase := usecase.NewInjectUsecase(dbAdapter, functionRepository)
-	packageUsecase := usecase.NewPackageUsecase(packageService)
+	exportsService := service.NewExportsService(dbAdapter)
+	exportsUsecase := usecase.NewExportsUsecase(dbAdapter, exportsService)
+	functionsUsecase := usecase.NewFunctionsUsecase(functionRepository, conditionService)
+	injectUsecase := usecase.NewInjectUsecase(dbAdapter, functionRepository, shellAdapter, cacheAdapter, conditionService, userRepository)
+	packageUsecase := usecase.NewPackageUsecase(packageService, injectUsecase)
 	selfUsecase := usecase.NewSelfUsecase(dbAdapter)
 	initFilesystemDBUsecase := usecase.NewInitFilesystemDBUsecase(pathProvider, initDbService)
+	doctorUsecase := usecase.NewDoctorUsecase(conflictService)
+	profileUsecase := usecase.NewProfileUsecase(userRepository, environmentAdapter)
+	lockUsecase := usecase.NewLockUsecase(lockService)
+	importUsecase := usecase.NewImportUsecase(importService)
+	lintUsecase := usecase.NewLintUsecase(lintService)
 
 	// Initialize handlers
 	initFileDBHandler := handler.NewInitFileDBHandler(initFilesystemDBUsecase)
 	aliasHandler := handler.NewAliasHandler(aliasUsecase)
 	exportsHandler := handler.NewExportsHandler(exportsUsecase)
-	functionsHandler := handler.NewFunctionsHandler(functionsUsecase)
+	functionsHandler := handler.NewFunctionsHandler(functionsUsecase, lintUsecase)
 	injectHandler := handler.NewInjectHandler(injectUsecase)
 	packageHandler := handler.NewPackageHandler(packageUsecase)
 	selfHandler := handler.NewSelfHandler(selfUsecase)
+	doctorHandler := handler.NewDoctorHandler(doctorUsecase)
+	profileHandler := handler.NewProfileHandler(profileUsecase)
+	lockHandler := handler.NewLockHandler(lockUsecase)
+	importHandler := handler.NewImportHandler(importUsecase)
 
 	// Build and return root command
 	return command.BuildRootCli(
@@ -54,5 +77,9 @@ func InitializeCLI() *cobra.Command {
 		injectHandler,
 		packageHandler,
 		selfHandler,
+		doctorHandler,
+		profileHandler,
+		lockHandler,
+		importHandler,
 	)
 }
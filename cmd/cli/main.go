@@ -9,7 +9,8 @@ import (
 func main() {
 	cli := context.InitializeCLI()
 	if err := cli.Execute(); err != nil {
-		fmt.Println(err)
+		// Errors go to stderr, so the structured output of a failing command stays parseable
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 }
@@ -7,6 +7,7 @@ require (
 	github.com/go-git/go-git/v5 v5.16.4
 	github.com/pelletier/go-toml v1.9.5
 	github.com/stretchr/testify v1.11.1
+	golang.org/x/crypto v0.37.0
 	mvdan.cc/sh/v3 v3.12.0
 )
 
@@ -28,7 +29,6 @@ require (
 	github.com/skeema/knownhosts v1.3.1 // indirect
 	github.com/spf13/pflag v1.0.9 // indirect
 	github.com/xanzy/ssh-agent v0.3.3 // indirect
-	golang.org/x/crypto v0.37.0 // indirect
 	golang.org/x/net v0.39.0 // indirect
 	golang.org/x/sys v0.33.0 // indirect
 	gopkg.in/warnings.v0 v0.1.2 // indirect
@@ -38,5 +38,5 @@ require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/spf13/cobra v1.10.1
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	gopkg.in/yaml.v3 v3.0.1
 )
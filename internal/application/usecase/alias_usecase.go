@@ -1,6 +1,7 @@
 package usecase
 
 import (
+	"duh/internal/domain/entity"
 	"duh/internal/domain/service"
 )
 
@@ -29,7 +30,7 @@ func (a *AliasUsecase) UnsetAlias(aliasName string) error {
 	return a.aliasService.UnsetAlias(aliasName)
 }
 
-func (a *AliasUsecase) ListAliases() (map[string]string, error) {
+func (a *AliasUsecase) ListAliases() ([]entity.MergedAlias, error) {
 	// Delegate to domain service for business logic
 	return a.aliasService.GetMergedAliases()
 }
@@ -0,0 +1,21 @@
+package usecase
+
+import (
+	"duh/internal/domain/entity"
+	"duh/internal/domain/service"
+)
+
+type DoctorUsecase struct {
+	conflictService *service.ConflictService
+}
+
+func NewDoctorUsecase(conflictService *service.ConflictService) *DoctorUsecase {
+	return &DoctorUsecase{
+		conflictService: conflictService,
+	}
+}
+
+func (d *DoctorUsecase) GetConflicts() ([]entity.Conflict, error) {
+	// Delegate to domain service for business logic
+	return d.conflictService.FindConflicts()
+}
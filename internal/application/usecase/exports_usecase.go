@@ -1,26 +1,56 @@
 package usecase
 
 import (
+	"duh/internal/domain/entity"
 	"duh/internal/domain/port"
-	"maps"
+	"duh/internal/domain/service"
+	"errors"
 )
 
 type ExportsUsecase struct {
-	dbPort port.DbPort
+	dbPort         port.DbPort
+	exportsService *service.ExportsService
 }
 
-func NewExportsUsecase(dbPort port.DbPort) *ExportsUsecase {
+type SetExportOptions struct {
+	// Inject the value as is, without expanding variables
+	Literal bool
+	// Add the value to the start of a colon-separated list like PATH, instead of replacing it
+	Prepend bool
+	// Add the value to the end of a colon-separated list like PATH, instead of replacing it
+	Append bool
+}
+
+func NewExportsUsecase(dbPort port.DbPort, exportsService *service.ExportsService) *ExportsUsecase {
 	return &ExportsUsecase{
-		dbPort: dbPort,
+		dbPort:         dbPort,
+		exportsService: exportsService,
 	}
 }
 
-func (e *ExportsUsecase) SetExport(exportName, value string) error {
+func (e *ExportsUsecase) SetExport(exportName, value string, options SetExportOptions) error {
+	if options.Prepend && options.Append {
+		return errors.New("a value can't be both prepended and appended")
+	}
+	if options.Literal && (options.Prepend || options.Append) {
+		return errors.New("list entries are always expanded, they can't be literal")
+	}
 	repo, err := e.dbPort.GetDefaultPackage()
 	if err != nil {
 		return err
 	}
-	repo.Exports[exportName] = value
+	if repo.Exports == nil {
+		repo.Exports = map[string]entity.Export{}
+	}
+
+	switch {
+	case options.Prepend:
+		repo.Exports[exportName] = repo.Exports[exportName].WithPrepended(value)
+	case options.Append:
+		repo.Exports[exportName] = repo.Exports[exportName].WithAppended(value)
+	default:
+		repo.Exports[exportName] = entity.Export{Value: value, Literal: options.Literal}
+	}
 	return e.dbPort.UpsertPackage(*repo)
 }
 
@@ -34,15 +64,8 @@ func (e *ExportsUsecase) UnsetExport(exportName string) error {
 	return e.dbPort.UpsertPackage(*repo)
 }
 
-func (e *ExportsUsecase) ListExports() (map[string]string, error) {
-	repos, err := e.dbPort.GetEnabledPackages()
-	if err != nil {
-		return nil, err
-	}
-
-	allExports := make(map[string]string)
-	for _, repo := range repos {
-		maps.Copy(allExports, repo.Exports)
-	}
-	return allExports, nil
+// ListExports returns the exports of every enabled package, as the shell ends up with them
+func (e *ExportsUsecase) ListExports() ([]entity.MergedExport, error) {
+	// Delegate to domain service
+	return e.exportsService.GetMergedExports()
 }
@@ -0,0 +1,69 @@
+package usecase
+
+import (
+	"duh/internal/domain/entity"
+	"duh/internal/domain/port"
+	"duh/internal/domain/service"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SetExport_PathList(t *testing.T) {
+	dbPort := &port.MockDbAdapter{
+		DefaultRepo: entity.Package{Name: "local", Exports: map[string]entity.Export{}},
+	}
+	usecase := NewExportsUsecase(dbPort, service.NewExportsService(dbPort))
+
+	assert.NoError(t, usecase.SetExport("PATH", "$HOME/bin", SetExportOptions{Prepend: true}))
+	assert.NoError(t, usecase.SetExport("PATH", "$HOME/.local/bin", SetExportOptions{Prepend: true}))
+	// Prepending an entry again moves it to the start, without duplicating it
+	assert.NoError(t, usecase.SetExport("PATH", "$HOME/bin", SetExportOptions{Prepend: true}))
+	assert.NoError(t, usecase.SetExport("PATH", "/opt/bin", SetExportOptions{Append: true}))
+	assert.NoError(t, usecase.SetExport("PATH", "/opt/bin", SetExportOptions{Append: true}))
+
+	assert.Equal(t, entity.Export{
+		Prepend: []string{"$HOME/bin", "$HOME/.local/bin"},
+		Append:  []string{"/opt/bin"},
+	}, dbPort.Packages[0].Exports["PATH"])
+
+	assert.NoError(t, usecase.SetExport("TOKEN", "a$b", SetExportOptions{Literal: true}))
+	assert.Equal(t, entity.Export{Value: "a$b", Literal: true}, dbPort.Packages[0].Exports["TOKEN"])
+
+	assert.Error(t, usecase.SetExport("PATH", "/x", SetExportOptions{Prepend: true, Append: true}))
+	assert.Error(t, usecase.SetExport("PATH", "/x", SetExportOptions{Prepend: true, Literal: true}))
+}
+
+func Test_ListExports_MergesPathLists(t *testing.T) {
+	dbPort := &port.MockDbAdapter{
+		Packages: []entity.Package{
+			{Name: "team", DbFilePath: "/pkgs/team/db.toml", Exports: map[string]entity.Export{
+				"PATH":   {Prepend: []string{"/team/bin"}, Append: []string{"/team/tail"}},
+				"EDITOR": {Value: "nano"},
+			}},
+			{Name: "local", DbFilePath: "/pkgs/local/db.toml", Exports: map[string]entity.Export{
+				"PATH":   {Prepend: []string{"/local/bin"}, Append: []string{"/local/tail"}},
+				"EDITOR": {Value: "vim"},
+			}},
+		},
+		Enabled: []string{"team", "local"},
+	}
+
+	exports, err := NewExportsUsecase(dbPort, service.NewExportsService(dbPort)).ListExports()
+	assert.NoError(t, err)
+	assert.Len(t, exports, 2)
+	// The value comes from the last package only
+	assert.Equal(t, entity.MergedExport{
+		Name:    "EDITOR",
+		Export:  entity.Export{Value: "vim"},
+		Sources: []entity.Source{{Package: "local", File: "/pkgs/local/db.toml"}},
+	}, exports[0])
+	// List entries add up, from both packages
+	assert.Equal(t, "PATH", exports[1].Name)
+	assert.Equal(t, entity.Export{
+		Prepend: []string{"/local/bin", "/team/bin"},
+		Append:  []string{"/team/tail", "/local/tail"},
+	}, exports[1].Export)
+	assert.Equal(t, []entity.Source{{Package: "team", File: "/pkgs/team/db.toml"}, {Package: "local", File: "/pkgs/local/db.toml"}}, exports[1].Sources)
+	assert.Equal(t, "[prepend: /local/bin, /team/bin] [append: /team/tail, /local/tail]", exports[1].Export.String())
+}
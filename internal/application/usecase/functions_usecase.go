@@ -3,16 +3,19 @@ package usecase
 import (
 	"duh/internal/domain/entity"
 	"duh/internal/domain/port"
+	"duh/internal/domain/service"
 	"fmt"
 )
 
 type FunctionsUsecase struct {
-	functionPort port.FunctionPort
+	functionPort     port.FunctionPort
+	conditionService *service.ConditionService
 }
 
-func NewFunctionsUsecase(functionPort port.FunctionPort) *FunctionsUsecase {
+func NewFunctionsUsecase(functionPort port.FunctionPort, conditionService *service.ConditionService) *FunctionsUsecase {
 	return &FunctionsUsecase{
-		functionPort: functionPort,
+		functionPort:     functionPort,
+		conditionService: conditionService,
 	}
 }
 
@@ -24,6 +27,15 @@ func (f *FunctionsUsecase) GetAllFunctions() ([]entity.Script, error) {
 	return f.functionPort.GetAllScripts()
 }
 
+// TopLevelCodeWarning returns the warning about an invalid top_level_code preference, empty when it is valid
+func (f *FunctionsUsecase) TopLevelCodeWarning() string {
+	preference, err := f.functionPort.GetTopLevelCodePreference()
+	if err != nil {
+		return ""
+	}
+	return preference.Warning
+}
+
 func (f *FunctionsUsecase) GetInternalFunctions() ([]entity.Script, error) {
 	return f.functionPort.GetInternalScripts()
 }
@@ -48,6 +60,12 @@ func (f *FunctionsUsecase) GetScriptByFunctionName(functionName string) (*entity
 	return nil, nil
 }
 
+// MissingRequirements returns the commands a function requires with @requires that are not in the PATH
+func (f *FunctionsUsecase) MissingRequirements(function entity.Function) []string {
+	// Delegate to domain service
+	return f.conditionService.MissingCommands(function.Requires)
+}
+
 // Returns a path to a newly created script with the given name
 func (f *FunctionsUsecase) CreateScriptByName(scriptName string) (string, error) {
 	scripts, err := f.functionPort.GetAllScripts()
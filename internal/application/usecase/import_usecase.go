@@ -0,0 +1,21 @@
+package usecase
+
+import (
+	"duh/internal/domain/entity"
+	"duh/internal/domain/service"
+)
+
+type ImportUsecase struct {
+	importService *service.ImportService
+}
+
+func NewImportUsecase(importService *service.ImportService) *ImportUsecase {
+	return &ImportUsecase{
+		importService: importService,
+	}
+}
+
+func (i *ImportUsecase) ImportDotfile(path string, packageName string, dryRun bool) (entity.DotfileImport, error) {
+	// Delegate to domain service
+	return i.importService.ImportDotfile(path, packageName, dryRun)
+}
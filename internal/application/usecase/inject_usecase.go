@@ -2,49 +2,319 @@ package usecase
 
 import (
 	"duh/internal/domain/entity"
+	"duh/internal/domain/errorss"
 	"duh/internal/domain/port"
-	"duh/internal/domain/utils"
+	"duh/internal/domain/service"
+	"duh/internal/domain/utils/version"
 	"fmt"
+	"maps"
+	"slices"
+	"strconv"
 	"strings"
 )
 
 type InjectUsecase struct {
-	dbPort       port.DbPort
-	functionPort port.FunctionPort
+	dbPort           port.DbPort
+	functionPort     port.FunctionPort
+	shellPort        port.ShellPort
+	cachePort        port.CachePort
+	conditionService *service.ConditionService
+	profilePort      port.ProfilePort
 }
 
-func NewInjectUsecase(dbPort port.DbPort, functionPort port.FunctionPort) *InjectUsecase {
+type InjectOptions struct {
+	// Shell syntax to render, empty to detect it from the environment
+	Shell string
+	// Annotate each line with the package it comes from
+	Explain bool
+	// Rebuild the injection even if the cached one is still valid
+	NoCache bool
+}
+
+func NewInjectUsecase(
+	dbPort port.DbPort,
+	functionPort port.FunctionPort,
+	shellPort port.ShellPort,
+	cachePort port.CachePort,
+	conditionService *service.ConditionService,
+	profilePort port.ProfilePort,
+) *InjectUsecase {
 	return &InjectUsecase{
-		dbPort:       dbPort,
-		functionPort: functionPort,
+		dbPort:           dbPort,
+		functionPort:     functionPort,
+		shellPort:        shellPort,
+		cachePort:        cachePort,
+		conditionService: conditionService,
+		profilePort:      profilePort,
 	}
 }
 
-func (i *InjectUsecase) GetInjectionString() (string, error) {
-	enabledRepos, err := i.dbPort.GetEnabledPackages()
+// GetInjectionString renders everything duh injects, using the syntax of the requested shell.
+//
+// Packages are emitted in the order of activation (activated_repos in user preferences),
+// and keys are sorted inside each package, so the output is stable between runs.
+// When several packages define the same alias or export, the last package wins,
+// as its definition is evaluated last by the shell.
+// Entries and scripts whose condition does not match the current environment are skipped.
+//
+// The rendered injection is cached, and served again as long as no package file,
+// user preference or duh version changed. Use options.NoCache to force a rebuild.
+func (i *InjectUsecase) GetInjectionString(options InjectOptions) (string, error) {
+	renderer, err := i.shellPort.GetRenderer(options.Shell)
 	if err != nil {
 		return "", err
 	}
-	injectionLines := []string{"alias duh_reload='eval \"$(duh inject --quiet)\"'"}
-	for _, repo := range enabledRepos {
-		for key, value := range repo.Aliases {
-			escapedValue := utils.EscapeShellString(value)
-			injectionLines = append(injectionLines, fmt.Sprintf("alias %s=\"%s\"", key, escapedValue))
-		}
-		for key, value := range repo.Exports {
-			injectionLines = append(injectionLines, fmt.Sprintf("export %s=\"%s\"", key, value))
+
+	cacheName := "inject-" + renderer.Name()
+	if options.Explain {
+		cacheName += "-explain"
+	}
+	cacheKey := ""
+	if fingerprint, err := i.cachePort.InjectionFingerprint(); err == nil {
+		cacheKey = version.GetVersion() + "-" + fingerprint
+	}
+	if cacheKey != "" && !options.NoCache {
+		// The files are unchanged, the entry is still valid unless a command named by a condition was installed or removed
+		if cached, ok := i.cachePort.Get(cacheName, cacheKey); ok && i.conditionService.CommandPresenceUnchanged(cached.Commands) {
+			return cached.Content, nil
 		}
 	}
-	injectionString := strings.Join(injectionLines, "\n")
 
+	injectionString, commands, err := i.renderInjection(renderer, options)
+	if err != nil {
+		return "", err
+	}
+	if cacheKey != "" {
+		// A cache failure must never prevent the shell from being set up
+		_ = i.cachePort.Set(cacheName, entity.CacheEntry{Key: cacheKey, Commands: commands, Content: injectionString})
+	}
+	return injectionString, nil
+}
+
+// renderInjection renders the injection, and tells whether each command named by an if_command condition was found
+func (i *InjectUsecase) renderInjection(renderer port.ShellRenderer, options InjectOptions) (string, map[string]bool, error) {
+	loadedRepos, err := i.dbPort.GetEnabledPackages()
+	if err != nil {
+		return "", nil, err
+	}
 	activatedScripts, _ := i.getActivatedScripts()
+	conditions := []entity.Condition{}
+	for _, repo := range loadedRepos {
+		conditions = append(conditions, slices.Collect(maps.Values(repo.AliasConditions))...)
+		conditions = append(conditions, slices.Collect(maps.Values(repo.ExportConditions))...)
+	}
 	for _, script := range activatedScripts {
-		injectionString = fmt.Sprintf("%s\n%s", injectionString, script.DataToInject)
+		conditions = append(conditions, script.Condition)
+	}
+	commands := i.conditionService.CommandPresence(conditions)
+	injectionLines, enabledRepos := i.renderPackages(renderer, loadedRepos, activatedScripts, renderOptions{explain: options.Explain, lazyLoading: true})
+	header := []string{renderer.ReloadAlias()}
+	// A profile that cannot be used is reported at shell start, where the user sees it
+	if active, err := i.profilePort.GetActiveProfile(); err == nil && active.Warning != "" {
+		header = append(header, renderer.Warning(active.Warning))
 	}
+	if preference, err := i.functionPort.GetTopLevelCodePreference(); err == nil && preference.Warning != "" {
+		header = append(header, renderer.Warning(preference.Warning))
+	}
+	injectionString := strings.Join(append(header, injectionLines...), "\n")
 
 	bonus, _ := i.dbPort.BonusInjection(enabledRepos)
 	injectionString = fmt.Sprintf("%s\n%s", injectionString, bonus)
-	return injectionString, nil
+	return injectionString, commands, nil
+}
+
+// ExportPackages renders packages as a standalone script, to be sourced on machines without duh.
+//
+// Packages are rendered in the given order, exactly as GetInjectionString renders them when they are the only
+// enabled ones, with the functions of duh core they may rely on. The duh_reload alias is left out, as it needs duh,
+// and the git configuration of the packages is set through the GIT_CONFIG_* environment variables
+// instead of an include in ~/.gitconfig. Conditions are evaluated against the current environment.
+func (i *InjectUsecase) ExportPackages(packageNames []string, shell string) (string, error) {
+	renderer, err := i.shellPort.GetRenderer(shell)
+	if err != nil {
+		return "", err
+	}
+	allPackages, err := i.dbPort.GetAllPackages()
+	if err != nil {
+		return "", err
+	}
+	packages := []entity.Package{}
+	for _, name := range packageNames {
+		index := slices.IndexFunc(allPackages, func(p entity.Package) bool { return p.Name == name })
+		if index < 0 {
+			return "", &errorss.NotFoundError{Resource: "package", ID: name}
+		}
+		packages = append(packages, allPackages[index])
+	}
+
+	// Scripts of the exported packages only, in the order of the packages
+	scripts, err := i.functionPort.GetInternalScripts()
+	if err != nil {
+		return "", err
+	}
+	allScripts, err := i.functionPort.GetAllScripts()
+	if err != nil {
+		return "", err
+	}
+	for _, name := range packageNames {
+		for _, script := range allScripts {
+			if script.Package == name {
+				scripts = append(scripts, script)
+			}
+		}
+	}
+
+	quotedNames := []string{}
+	for _, name := range packageNames {
+		quotedNames = append(quotedNames, fmt.Sprintf("'%s'", name))
+	}
+	header := renderer.Comment(fmt.Sprintf("Exported by duh from %s, source this file from your shell configuration", strings.Join(quotedNames, ", ")))
+	// Lazy stubs source the scripts from this machine, the export must hold the scripts themselves
+	body, renderedPackages := i.renderPackages(renderer, packages, scripts, renderOptions{})
+	lines := append([]string{header}, body...)
+	lines = append(lines, gitConfigLines(renderer, renderedPackages)...)
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+type renderOptions struct {
+	// Annotate each line with the package it comes from
+	explain bool
+	// Render the scripts of lazy packages and scripts as stubs loading them on first call
+	lazyLoading bool
+}
+
+// renderPackages renders the aliases and exports of the packages, then the scripts, skipping the entries
+// whose condition is not met. It also returns the packages without their skipped entries.
+func (i *InjectUsecase) renderPackages(renderer port.ShellRenderer, loadedRepos []entity.Package, scripts []entity.Script, options renderOptions) ([]string, []entity.Package) {
+	explain := options.explain
+	enabledRepos := []entity.Package{}
+	skippedEntries := map[string][]string{}
+	for _, repo := range loadedRepos {
+		activeRepo, skipped := i.applyConditions(repo, renderer.Name())
+		enabledRepos = append(enabledRepos, activeRepo)
+		skippedEntries[repo.Name] = skipped
+	}
+
+	aliasOwners := lastDefinedBy(enabledRepos, func(p entity.Package) map[string]string { return p.Aliases })
+	exportOwners := lastDefinedBy(enabledRepos, entity.Package.ValueExports)
+
+	injectionLines := []string{}
+	for index, repo := range enabledRepos {
+		if explain {
+			injectionLines = append(injectionLines, renderer.Comment(fmt.Sprintf("package '%s'", repo.Name)))
+			for _, skipped := range skippedEntries[repo.Name] {
+				injectionLines = append(injectionLines, renderer.Comment(skipped))
+			}
+		}
+		for _, key := range slices.Sorted(maps.Keys(repo.Aliases)) {
+			line := renderer.Alias(key, repo.Aliases[key])
+			if explain {
+				line = explainLine(renderer, line, repo.Name, aliasOwners[key])
+			}
+			injectionLines = append(injectionLines, line)
+		}
+		for _, key := range slices.Sorted(maps.Keys(repo.Exports)) {
+			line := renderer.Export(key, repo.Exports[key])
+			if explain {
+				line = explainExport(renderer, line, enabledRepos, index, key, exportOwners[key])
+			}
+			injectionLines = append(injectionLines, line)
+		}
+	}
+
+	for _, script := range scripts {
+		if reason := i.conditionService.UnmetReason(script.Condition, renderer.Name()); reason != "" {
+			if explain {
+				injectionLines = append(injectionLines, renderer.Comment(fmt.Sprintf("skipped %s: %s", explainScript(script), reason)))
+			}
+			continue
+		}
+		if script.TopLevelPolicy == entity.TopLevelCodeRefuse {
+			if explain {
+				injectionLines = append(injectionLines, renderer.Comment(fmt.Sprintf("refused %s, it has code outside functions:", explainScript(script))))
+				injectionLines = append(injectionLines, topLevelCodeComments(renderer, script)...)
+			}
+			continue
+		}
+		if script.TopLevelPolicy == entity.TopLevelCodeWarn {
+			injectionLines = append(injectionLines, renderer.Warning(fmt.Sprintf("%s runs code outside functions at startup, %s",
+				explainScript(script), topLevelCodeLines(script))))
+		}
+		lazy := options.lazyLoading && loadsLazily(script, loadedRepos)
+		if explain {
+			comment := explainScript(script)
+			if lazy {
+				comment += ", loaded on first call"
+			}
+			if script.TopLevelPolicy == entity.TopLevelCodeStrip {
+				comment += ", code outside functions stripped:"
+			}
+			injectionLines = append(injectionLines, renderer.Comment(comment))
+			if script.TopLevelPolicy == entity.TopLevelCodeStrip {
+				injectionLines = append(injectionLines, topLevelCodeComments(renderer, script)...)
+				if len(script.DroppedFunctions) > 0 {
+					injectionLines = append(injectionLines, renderer.Comment(fmt.Sprintf("  functions dropped with this code: %s", strings.Join(script.DroppedFunctions, ", "))))
+				}
+			}
+		}
+		if lazy {
+			injectionLines = append(injectionLines, renderer.LazyScript(script))
+		} else {
+			injectionLines = append(injectionLines, renderer.Script(script))
+		}
+		for _, function := range script.Functions {
+			if completion := renderer.Completion(function); completion != "" {
+				injectionLines = append(injectionLines, completion)
+			}
+		}
+	}
+	return injectionLines, enabledRepos
+}
+
+// loadsLazily tells if a script is injected as stubs loading it on first call.
+// The directive of the script wins over the setting of its package. Scripts without functions,
+// or without a file to load like the ones of duh core, are always injected as is.
+// So are stripped scripts, sourcing their file would run the code stripped from them.
+func loadsLazily(script entity.Script, packages []entity.Package) bool {
+	if len(script.Functions) == 0 || script.Package == "" || script.TopLevelPolicy == entity.TopLevelCodeStrip {
+		return false
+	}
+	if script.Lazy != nil {
+		return *script.Lazy
+	}
+	for _, pkg := range packages {
+		if pkg.Name == script.Package {
+			return pkg.LazyFunctions
+		}
+	}
+	return false
+}
+
+// gitConfigLines sets the git configuration of the packages through the environment,
+// with the GIT_CONFIG_COUNT, GIT_CONFIG_KEY_<n> and GIT_CONFIG_VALUE_<n> variables read by git 2.31 and later.
+// Includes are left out: git only follows them from files, and their paths are files of this machine.
+func gitConfigLines(renderer port.ShellRenderer, packages []entity.Package) []string {
+	lines := []string{}
+	entries := []entity.GitConfigEntry{}
+	for _, pkg := range packages {
+		for _, entry := range pkg.GitConfig {
+			if isGitInclude(entry.Key) {
+				lines = append(lines, renderer.Comment(fmt.Sprintf("skipped %s = %s from '%s': git only follows includes from files", entry.Key, entry.Value, pkg.Name)))
+				continue
+			}
+			entries = append(entries, entry)
+		}
+	}
+	if len(entries) == 0 {
+		return lines
+	}
+	return append(lines, renderer.GitConfig(entries))
+}
+
+// isGitInclude tells if a git configuration key is include.path or includeIf.<condition>.path
+func isGitInclude(key string) bool {
+	section, rest, _ := strings.Cut(strings.ToLower(key), ".")
+	return (section == "include" && rest == "path") || (section == "includeif" && strings.HasSuffix(rest, ".path"))
 }
 
 func (i *InjectUsecase) getActivatedScripts() ([]entity.Script, error) {
@@ -59,3 +329,89 @@ func (i *InjectUsecase) getActivatedScripts() ([]entity.Script, error) {
 	scripts = append(scripts, scriptsRepos...)
 	return scripts, nil
 }
+
+// applyConditions returns the package without the aliases and exports whose condition is not met,
+// and a description of each skipped entry
+func (i *InjectUsecase) applyConditions(pkg entity.Package, shell string) (entity.Package, []string) {
+	skipped := []string{}
+	aliases := map[string]string{}
+	for _, name := range slices.Sorted(maps.Keys(pkg.Aliases)) {
+		if reason := i.conditionService.UnmetReason(pkg.AliasConditions[name], shell); reason != "" {
+			skipped = append(skipped, fmt.Sprintf("skipped alias '%s': %s", name, reason))
+			continue
+		}
+		aliases[name] = pkg.Aliases[name]
+	}
+	exports := map[string]entity.Export{}
+	for _, name := range slices.Sorted(maps.Keys(pkg.Exports)) {
+		if reason := i.conditionService.UnmetReason(pkg.ExportConditions[name], shell); reason != "" {
+			skipped = append(skipped, fmt.Sprintf("skipped export '%s': %s", name, reason))
+			continue
+		}
+		exports[name] = pkg.Exports[name]
+	}
+	pkg.Aliases = aliases
+	pkg.Exports = exports
+	return pkg, skipped
+}
+
+// lastDefinedBy returns, for each key, the name of the last package defining it
+func lastDefinedBy[V any](packages []entity.Package, entries func(entity.Package) map[string]V) map[string]string {
+	owners := map[string]string{}
+	for _, pkg := range packages {
+		for key := range entries(pkg) {
+			owners[key] = pkg.Name
+		}
+	}
+	return owners
+}
+
+func explainLine(renderer port.ShellRenderer, line string, packageName string, owner string) string {
+	if owner != packageName {
+		return fmt.Sprintf("%s %s", line, renderer.Comment(fmt.Sprintf("from '%s', overridden by '%s'", packageName, owner)))
+	}
+	return fmt.Sprintf("%s %s", line, renderer.Comment(fmt.Sprintf("from '%s'", packageName)))
+}
+
+// explainExport explains the export of packages[index].
+// Entries added to a list like PATH are only lost if a later package replaces the whole value.
+func explainExport(renderer port.ShellRenderer, line string, packages []entity.Package, index int, name string, owner string) string {
+	packageName := packages[index].Name
+	if packages[index].Exports[name].SetsValue() {
+		return explainLine(renderer, line, packageName, owner)
+	}
+	for _, later := range packages[index+1:] {
+		if later.Name == owner {
+			return fmt.Sprintf("%s %s", line, renderer.Comment(fmt.Sprintf("from '%s', overridden by '%s'", packageName, owner)))
+		}
+	}
+	return fmt.Sprintf("%s %s", line, renderer.Comment(fmt.Sprintf("from '%s', extends the list", packageName)))
+}
+
+// topLevelCodeComments lists the statements outside the functions of a script, one comment line each
+func topLevelCodeComments(renderer port.ShellRenderer, script entity.Script) []string {
+	comments := []string{}
+	for _, statement := range script.TopLevelCode {
+		comments = append(comments, renderer.Comment(fmt.Sprintf("  line %d: %s", statement.Line, statement.Details)))
+	}
+	return comments
+}
+
+// topLevelCodeLines tells where the statements outside the functions of a script are, like "lines 2, 5"
+func topLevelCodeLines(script entity.Script) string {
+	lines := []string{}
+	for _, statement := range script.TopLevelCode {
+		lines = append(lines, strconv.Itoa(statement.Line))
+	}
+	if len(lines) == 1 {
+		return "line " + lines[0]
+	}
+	return "lines " + strings.Join(lines, ", ")
+}
+
+func explainScript(script entity.Script) string {
+	if script.Package == "" {
+		return fmt.Sprintf("script '%s' from %s", script.Name, service.CorePackageName)
+	}
+	return fmt.Sprintf("script '%s' from '%s' (%s)", script.Name, script.Package, script.PathToFile)
+}
@@ -0,0 +1,425 @@
+package usecase
+
+import (
+	"duh/internal/domain/entity"
+	"duh/internal/domain/errorss"
+	"duh/internal/domain/port"
+	"duh/internal/domain/service"
+	"duh/internal/infrastructure/shelll"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestInjectUsecase() *InjectUsecase {
+	dbPort := &port.MockDbAdapter{
+		Packages: []entity.Package{
+			{
+				Name:    "team",
+				Aliases: map[string]string{"gs": "git status -sb", "k": "kubectl"},
+				Exports: map[string]entity.Export{"EDITOR": {Value: "nano"}},
+			},
+			{
+				Name:    "local",
+				Aliases: map[string]string{"gs": "git status", "ll": "ls -la"},
+				Exports: map[string]entity.Export{"EDITOR": {Value: "vim"}, "BROWSER": {Value: "firefox"}},
+			},
+		},
+		Enabled: []string{"team", "local"},
+	}
+	functionPort := &port.DummyFunctionRepository{
+		ActivatedScripts: []entity.Script{
+			{Name: "deploy", Package: "team", PathToFile: "/pkgs/team/functions/deploy.sh", DataToInject: "deploy() { :; }"},
+		},
+	}
+	return NewInjectUsecase(dbPort, functionPort, shelll.NewShellAdapter(), &port.MockCachePort{Fingerprint: "v1"}, service.NewConditionService(&port.MockEnvironmentPort{}), &port.MockProfilePort{})
+}
+
+func Test_GetInjectionString_Ordered(t *testing.T) {
+	usecase := newTestInjectUsecase()
+
+	injection, err := usecase.GetInjectionString(InjectOptions{Shell: "bash"})
+	assert.NoError(t, err)
+
+	lines := strings.Split(injection, "\n")
+	assert.Equal(t, []string{
+		`alias gs="git status -sb"`,
+		`alias k="kubectl"`,
+		`export EDITOR="nano"`,
+		`alias gs="git status"`,
+		`alias ll="ls -la"`,
+		`export BROWSER="firefox"`,
+		`export EDITOR="vim"`,
+		`deploy() { :; }`,
+	}, lines[1:9])
+
+	// Rendering twice must give the exact same output
+	again, err := usecase.GetInjectionString(InjectOptions{Shell: "bash"})
+	assert.NoError(t, err)
+	assert.Equal(t, injection, again)
+}
+
+func Test_GetInjectionString_Explain(t *testing.T) {
+	usecase := newTestInjectUsecase()
+
+	injection, err := usecase.GetInjectionString(InjectOptions{Shell: "bash", Explain: true})
+	assert.NoError(t, err)
+
+	assert.Contains(t, injection, "# package 'team'")
+	assert.Contains(t, injection, `alias gs="git status -sb" # from 'team', overridden by 'local'`)
+	assert.Contains(t, injection, `alias gs="git status" # from 'local'`)
+	assert.Contains(t, injection, `export EDITOR="vim" # from 'local'`)
+	assert.Contains(t, injection, "# script 'deploy' from 'team' (/pkgs/team/functions/deploy.sh)")
+}
+
+func Test_GetInjectionString_PathLists(t *testing.T) {
+	dbPort := &port.MockDbAdapter{
+		Packages: []entity.Package{
+			{Name: "team", Exports: map[string]entity.Export{"PATH": {Prepend: []string{"/team/bin"}}, "GOPATH": {Prepend: []string{"/team/go"}}}},
+			{Name: "local", Exports: map[string]entity.Export{"PATH": {Append: []string{"/local/bin"}}, "GOPATH": {Value: "$HOME/go"}}},
+		},
+		Enabled: []string{"team", "local"},
+	}
+	usecase := NewInjectUsecase(dbPort, &port.DummyFunctionRepository{}, shelll.NewShellAdapter(), &port.MockCachePort{Fingerprint: "v1"}, service.NewConditionService(&port.MockEnvironmentPort{}), &port.MockProfilePort{})
+
+	injection, err := usecase.GetInjectionString(InjectOptions{Shell: "bash", Explain: true})
+	assert.NoError(t, err)
+
+	assert.Contains(t, injection, `PATH="/team/bin${PATH:+:${PATH}}" ;; esac`)
+	assert.Contains(t, injection, `PATH="${PATH:+${PATH}:}/local/bin" ;; esac`)
+	assert.Contains(t, injection, "export PATH # from 'team', extends the list")
+	assert.Contains(t, injection, "export PATH # from 'local', extends the list")
+	assert.Contains(t, injection, "export GOPATH # from 'team', overridden by 'local'")
+	assert.Contains(t, injection, `export GOPATH="$HOME/go" # from 'local'`)
+}
+
+func Test_GetInjectionString_ProfileWarning(t *testing.T) {
+	profilePort := &port.MockProfilePort{Active: entity.ActiveProfile{
+		Name:    entity.DefaultProfileName,
+		Reason:  entity.ProfileSelectedByDefault,
+		Warning: "profile 'typo' from DUH_PROFILE does not exist, using the default profile",
+	}}
+	usecase := NewInjectUsecase(&port.MockDbAdapter{}, &port.DummyFunctionRepository{}, shelll.NewShellAdapter(), &port.MockCachePort{}, service.NewConditionService(&port.MockEnvironmentPort{}), profilePort)
+
+	injection, err := usecase.GetInjectionString(InjectOptions{Shell: "bash"})
+	assert.NoError(t, err)
+	assert.Contains(t, injection, `echo 'duh: profile '\''typo'\'' from DUH_PROFILE does not exist, using the default profile' >&2`)
+}
+
+func Test_GetInjectionString_TopLevelCodeWarning(t *testing.T) {
+	functionPort := &port.DummyFunctionRepository{TopLevelCode: entity.TopLevelCodePreference{
+		Policy:  entity.TopLevelCodeAllow,
+		Warning: "invalid top_level_code 'block' in user preferences, expected one of: allow, warn, strip, refuse, using allow",
+	}}
+	usecase := NewInjectUsecase(&port.MockDbAdapter{}, functionPort, shelll.NewShellAdapter(), &port.MockCachePort{}, service.NewConditionService(&port.MockEnvironmentPort{}), &port.MockProfilePort{})
+
+	injection, err := usecase.GetInjectionString(InjectOptions{Shell: "bash"})
+	assert.NoError(t, err)
+	// The warning is printed when the shell starts, not by the adapter reading the preference
+	assert.Contains(t, injection, `echo 'duh: invalid top_level_code '\''block'\'' in user preferences, expected one of: allow, warn, strip, refuse, using allow' >&2`)
+}
+
+func Test_GetInjectionString_Conditions(t *testing.T) {
+	dbPort := &port.MockDbAdapter{
+		Packages: []entity.Package{{
+			Name:             "team",
+			Aliases:          map[string]string{"copy": "pbcopy", "k": "kubectl", "ll": "ls -la"},
+			AliasConditions:  map[string]entity.Condition{"copy": {OS: "darwin"}, "k": {IfCommand: "kubectl"}},
+			Exports:          map[string]entity.Export{"BROWSER": {Value: "firefox"}, "ZDOTDIR": {Value: "$HOME/.zsh"}},
+			ExportConditions: map[string]entity.Condition{"BROWSER": {Hostname: "laptop-*"}, "ZDOTDIR": {Shell: "zsh"}},
+		}},
+		Enabled: []string{"team"},
+	}
+	functionPort := &port.DummyFunctionRepository{
+		ActivatedScripts: []entity.Script{
+			{Name: "brew", Package: "team", PathToFile: "/pkgs/team/functions/brew.sh", DataToInject: "brewup() { :; }", Condition: entity.Condition{OS: "darwin"}},
+			{Name: "kube", Package: "team", PathToFile: "/pkgs/team/functions/kube.sh", DataToInject: "kctx() { :; }", Condition: entity.Condition{IfCommand: "kubectl"}},
+		},
+	}
+	environmentPort := &port.MockEnvironmentPort{OperatingSystem: "linux", Host: "laptop-01", Commands: []string{"kubectl"}}
+	usecase := NewInjectUsecase(dbPort, functionPort, shelll.NewShellAdapter(), &port.MockCachePort{Fingerprint: "v1"}, service.NewConditionService(environmentPort), &port.MockProfilePort{})
+
+	injection, err := usecase.GetInjectionString(InjectOptions{Shell: "bash"})
+	assert.NoError(t, err)
+	assert.Contains(t, injection, `alias k="kubectl"`)
+	assert.Contains(t, injection, `alias ll="ls -la"`)
+	assert.Contains(t, injection, `export BROWSER="firefox"`)
+	assert.Contains(t, injection, "kctx() { :; }")
+	assert.NotContains(t, injection, "pbcopy")
+	assert.NotContains(t, injection, "ZDOTDIR")
+	assert.NotContains(t, injection, "brewup")
+
+	injection, err = usecase.GetInjectionString(InjectOptions{Shell: "zsh", Explain: true})
+	assert.NoError(t, err)
+	assert.Contains(t, injection, "# skipped alias 'copy': os is 'linux', not 'darwin'")
+	assert.Contains(t, injection, `export ZDOTDIR="$HOME/.zsh" # from 'team'`)
+	assert.Contains(t, injection, "# skipped script 'brew' from 'team' (/pkgs/team/functions/brew.sh): os is 'linux', not 'darwin'")
+}
+
+func Test_GetInjectionString_Lazy(t *testing.T) {
+	eager := false
+	lazy := true
+	dbPort := &port.MockDbAdapter{
+		Packages: []entity.Package{{Name: "team", LazyFunctions: true}, {Name: "local"}},
+		Enabled:  []string{"team", "local"},
+	}
+	functionPort := &port.DummyFunctionRepository{
+		ActivatedScripts: []entity.Script{
+			{Name: "deploy", Package: "team", PathToFile: "/pkgs/team/functions/deploy.sh", DataToInject: "deploy() { :; }", Functions: []entity.Function{{Name: "deploy"}}},
+			{Name: "prompt", Package: "team", PathToFile: "/pkgs/team/functions/prompt.sh", DataToInject: "prompt() { :; }", Functions: []entity.Function{{Name: "prompt"}}, Lazy: &eager},
+			{Name: "mkcd", Package: "local", PathToFile: "/pkgs/local/functions/mkcd.sh", DataToInject: "mkcd() { :; }", Functions: []entity.Function{{Name: "mkcd"}}, Lazy: &lazy},
+			{Name: "setup", Package: "local", PathToFile: "/pkgs/local/functions/setup.sh", DataToInject: "setup() { :; }", Functions: []entity.Function{{Name: "setup"}}},
+		},
+	}
+	functionPort.Scripts = functionPort.ActivatedScripts
+	usecase := NewInjectUsecase(dbPort, functionPort, shelll.NewShellAdapter(), &port.MockCachePort{Fingerprint: "v1"}, service.NewConditionService(&port.MockEnvironmentPort{}), &port.MockProfilePort{})
+
+	injection, err := usecase.GetInjectionString(InjectOptions{Shell: "bash", Explain: true})
+	assert.NoError(t, err)
+
+	// The package setting applies unless the script directive says otherwise
+	assert.Contains(t, injection, `deploy() { unset -f deploy; . '/pkgs/team/functions/deploy.sh'; deploy "$@"; }`)
+	assert.Contains(t, injection, "# script 'deploy' from 'team' (/pkgs/team/functions/deploy.sh), loaded on first call")
+	assert.Contains(t, injection, "prompt() { :; }")
+	assert.Contains(t, injection, `mkcd() { unset -f mkcd; . '/pkgs/local/functions/mkcd.sh'; mkcd "$@"; }`)
+	assert.Contains(t, injection, "setup() { :; }")
+	assert.NotContains(t, injection, "deploy() { :; }")
+
+	// A standalone export cannot source the scripts of this machine
+	export, err := usecase.ExportPackages([]string{"team"}, "bash")
+	assert.NoError(t, err)
+	assert.Contains(t, export, "deploy() { :; }")
+}
+
+func Test_GetInjectionString_Completion(t *testing.T) {
+	environments := []entity.FunctionArg{{Name: "<staging|production>", Description: "Environment to deploy"}}
+	dbPort := &port.MockDbAdapter{
+		Packages: []entity.Package{{Name: "team", LazyFunctions: true}},
+		Enabled:  []string{"team"},
+	}
+	functionPort := &port.DummyFunctionRepository{
+		ActivatedScripts: []entity.Script{
+			{Name: "deploy", Package: "team", PathToFile: "/pkgs/team/functions/deploy.sh", DataToInject: "deploy() { :; }", Functions: []entity.Function{
+				{Name: "deploy", Args: environments},
+				{Name: "mkcd", Args: []entity.FunctionArg{{Name: "dir"}}},
+			}},
+			{Name: "bash_only", Package: "team", PathToFile: "/pkgs/team/functions/bash_only.sh", DataToInject: "rollback() { :; }",
+				Functions: []entity.Function{{Name: "rollback", Args: environments}}, Condition: entity.Condition{Shell: "bash"}},
+		},
+	}
+	usecase := NewInjectUsecase(dbPort, functionPort, shelll.NewShellAdapter(), &port.MockCachePort{Fingerprint: "v1"}, service.NewConditionService(&port.MockEnvironmentPort{}), &port.MockProfilePort{})
+
+	// Completions are registered for the lazy stubs too
+	injection, err := usecase.GetInjectionString(InjectOptions{Shell: "bash"})
+	assert.NoError(t, err)
+	assert.Contains(t, injection, "complete -o default -W 'staging production' deploy")
+	assert.Contains(t, injection, "complete -o default -W 'staging production' rollback")
+	assert.NotContains(t, injection, "complete -o default -W 'staging production' mkcd")
+
+	// Functions of skipped scripts get no completion
+	injection, err = usecase.GetInjectionString(InjectOptions{Shell: "zsh"})
+	assert.NoError(t, err)
+	assert.Contains(t, injection, "compdef _duh_complete_deploy deploy")
+	assert.NotContains(t, injection, "rollback")
+}
+
+func Test_GetInjectionString_TopLevelCode(t *testing.T) {
+	topLevelCode := []entity.Warning{{Line: 2, Details: "cd /tmp"}, {Line: 5, Details: "set -e"}}
+	dbPort := &port.MockDbAdapter{
+		Packages: []entity.Package{{Name: "team", LazyFunctions: true}},
+		Enabled:  []string{"team"},
+	}
+	functionPort := &port.DummyFunctionRepository{
+		ActivatedScripts: []entity.Script{
+			{Name: "up", Package: "team", PathToFile: "/pkgs/team/functions/up.sh", DataToInject: "cd /tmp\nup() { :; }", Functions: []entity.Function{{Name: "up"}},
+				TopLevelCode: topLevelCode, TopLevelPolicy: entity.TopLevelCodeWarn},
+			{Name: "down", Package: "team", PathToFile: "/pkgs/team/functions/down.sh", DataToInject: "down() { :; }", Functions: []entity.Function{{Name: "down"}},
+				TopLevelCode: topLevelCode, TopLevelPolicy: entity.TopLevelCodeStrip, DroppedFunctions: []string{"pick"}},
+			{Name: "install", Package: "team", PathToFile: "/pkgs/team/functions/install.sh", Functions: []entity.Function{{Name: "install"}},
+				TopLevelCode: topLevelCode, TopLevelPolicy: entity.TopLevelCodeRefuse},
+		},
+	}
+	usecase := NewInjectUsecase(dbPort, functionPort, shelll.NewShellAdapter(), &port.MockCachePort{Fingerprint: "v1"}, service.NewConditionService(&port.MockEnvironmentPort{}), &port.MockProfilePort{})
+
+	injection, err := usecase.GetInjectionString(InjectOptions{Shell: "bash"})
+	assert.NoError(t, err)
+	assert.Contains(t, injection, "echo 'duh: script '\\''up'\\'' from '\\''team'\\'' (/pkgs/team/functions/up.sh) runs code outside functions at startup, lines 2, 5' >&2")
+	// Stripped scripts are not loaded lazily, sourcing their file would run the stripped code
+	assert.Contains(t, injection, "\ndown() { :; }\n")
+	assert.NotContains(t, injection, "install")
+
+	injection, err = usecase.GetInjectionString(InjectOptions{Shell: "bash", Explain: true})
+	assert.NoError(t, err)
+	assert.Contains(t, injection, strings.Join([]string{
+		"# script 'down' from 'team' (/pkgs/team/functions/down.sh), code outside functions stripped:",
+		"#   line 2: cd /tmp",
+		"#   line 5: set -e",
+		"#   functions dropped with this code: pick",
+		"down() { :; }",
+		"# refused script 'install' from 'team' (/pkgs/team/functions/install.sh), it has code outside functions:",
+		"#   line 2: cd /tmp",
+	}, "\n"))
+}
+
+func Test_ExportPackages(t *testing.T) {
+	dbPort := &port.MockDbAdapter{
+		Packages: []entity.Package{
+			{
+				Name:      "team",
+				Aliases:   map[string]string{"gs": "git status -sb", "k": "kubectl"},
+				Exports:   map[string]entity.Export{"PATH": {Prepend: []string{"/team/bin"}}},
+				GitConfig: []entity.GitConfigEntry{{Key: "alias.st", Value: "status -sb"}},
+			},
+			{
+				Name:    "local",
+				Aliases: map[string]string{"gs": "git status"},
+				GitConfig: []entity.GitConfigEntry{
+					{Key: "include.path", Value: "work.gitconfig"},
+					{Key: "includeIf.gitdir:~/work/.path", Value: "/home/me/work.gitconfig"},
+					{Key: "user.email", Value: "me@example.com"},
+				},
+			},
+			{Name: "other", Aliases: map[string]string{"o": "open"}},
+		},
+		Enabled: []string{"team"},
+	}
+	teamScript := entity.Script{Name: "deploy", Package: "team", DataToInject: "deploy() { :; }"}
+	functionPort := &port.DummyFunctionRepository{
+		InternalScripts:  []entity.Script{{Name: "require", DataToInject: "require() { :; }"}},
+		ActivatedScripts: []entity.Script{teamScript},
+		Scripts: []entity.Script{
+			{Name: "open", Package: "other", DataToInject: "open() { :; }"},
+			{Name: "greet", Package: "local", DataToInject: "greet() { :; }"},
+			teamScript,
+		},
+	}
+	usecase := NewInjectUsecase(dbPort, functionPort, shelll.NewShellAdapter(), &port.MockCachePort{}, service.NewConditionService(&port.MockEnvironmentPort{}), &port.MockProfilePort{})
+
+	// A package alone is rendered as the injection renders it, without duh_reload and with its gitconfig
+	injection, err := usecase.GetInjectionString(InjectOptions{Shell: "bash"})
+	assert.NoError(t, err)
+	exported, err := usecase.ExportPackages([]string{"team"}, "bash")
+	assert.NoError(t, err)
+	injectionLines := strings.Split(injection, "\n")
+	exportedLines := strings.Split(exported, "\n")
+	assert.Equal(t, "# Exported by duh from 'team', source this file from your shell configuration", exportedLines[0])
+	assert.Equal(t, injectionLines[1:len(injectionLines)-1], exportedLines[1:len(injectionLines)-1])
+	assert.Equal(t, []string{
+		"GIT_CONFIG_COUNT=${GIT_CONFIG_COUNT:-0}",
+		`export "GIT_CONFIG_KEY_${GIT_CONFIG_COUNT}="'alias.st'`,
+		`export "GIT_CONFIG_VALUE_${GIT_CONFIG_COUNT}="'status -sb'`,
+		"GIT_CONFIG_COUNT=$((GIT_CONFIG_COUNT + 1))",
+		"export GIT_CONFIG_COUNT",
+		"",
+	}, exportedLines[len(injectionLines)-1:])
+
+	// A bundle keeps the given order, the last package wins
+	exported, err = usecase.ExportPackages([]string{"team", "local"}, "bash")
+	assert.NoError(t, err)
+	assert.Less(t, strings.Index(exported, `alias gs="git status -sb"`), strings.Index(exported, `alias gs="git status"`))
+	assert.Less(t, strings.Index(exported, "deploy() { :; }"), strings.Index(exported, "greet() { :; }"))
+	assert.Contains(t, exported, `export "GIT_CONFIG_KEY_${GIT_CONFIG_COUNT}="'user.email'`)
+	// Includes point to files git must read itself
+	assert.Contains(t, exported, "# skipped include.path = work.gitconfig from 'local': git only follows includes from files")
+	assert.NotContains(t, exported, `'include.path'`)
+	assert.NotContains(t, exported, `'includeIf.gitdir:~/work/.path'`)
+	assert.NotContains(t, exported, "open")
+
+	_, err = usecase.ExportPackages([]string{"team", "unknown"}, "bash")
+	var notFoundErr *errorss.NotFoundError
+	assert.ErrorAs(t, err, &notFoundErr)
+
+	// Scripts that cannot be read fail the export instead of leaving the functions out
+	functionPort.AllScriptsErr = errors.New("unreadable functions directory")
+	_, err = usecase.ExportPackages([]string{"team"}, "bash")
+	assert.EqualError(t, err, "unreadable functions directory")
+}
+
+func Test_GetInjectionString_UnsupportedShell(t *testing.T) {
+	usecase := newTestInjectUsecase()
+
+	_, err := usecase.GetInjectionString(InjectOptions{Shell: "powershell"})
+	assert.Error(t, err)
+}
+
+func Test_GetInjectionString_Cache(t *testing.T) {
+	dbPort := &port.MockDbAdapter{
+		Packages: []entity.Package{{Name: "local", Aliases: map[string]string{"ll": "ls -la"}}},
+		Enabled:  []string{"local"},
+	}
+	cachePort := &port.MockCachePort{Fingerprint: "v1"}
+	usecase := NewInjectUsecase(dbPort, &port.DummyFunctionRepository{}, shelll.NewShellAdapter(), cachePort, service.NewConditionService(&port.MockEnvironmentPort{}), &port.MockProfilePort{})
+
+	injection, err := usecase.GetInjectionString(InjectOptions{Shell: "bash"})
+	assert.NoError(t, err)
+	assert.Contains(t, injection, `alias ll="ls -la"`)
+	assert.Contains(t, cachePort.Entries, "inject-bash")
+
+	// Same fingerprint: the cached injection is served
+	dbPort.Packages[0].Aliases["ll"] = "ls -lah"
+	injection, err = usecase.GetInjectionString(InjectOptions{Shell: "bash"})
+	assert.NoError(t, err)
+	assert.Contains(t, injection, `alias ll="ls -la"`)
+
+	// --no-cache forces a rebuild
+	injection, err = usecase.GetInjectionString(InjectOptions{Shell: "bash", NoCache: true})
+	assert.NoError(t, err)
+	assert.Contains(t, injection, `alias ll="ls -lah"`)
+
+	// A changed fingerprint invalidates the cache
+	dbPort.Packages[0].Aliases["ll"] = "ls -l"
+	cachePort.Fingerprint = "v2"
+	injection, err = usecase.GetInjectionString(InjectOptions{Shell: "bash"})
+	assert.NoError(t, err)
+	assert.Contains(t, injection, `alias ll="ls -l"`)
+
+	// Each shell has its own cache entry
+	injection, err = usecase.GetInjectionString(InjectOptions{Shell: "fish"})
+	assert.NoError(t, err)
+	assert.Contains(t, injection, `alias ll "ls -l"`)
+	assert.Contains(t, cachePort.Entries, "inject-fish")
+}
+
+func Test_GetInjectionString_CacheConditionCommands(t *testing.T) {
+	dbPort := &port.MockDbAdapter{
+		Packages: []entity.Package{{
+			Name:            "local",
+			Aliases:         map[string]string{"ls": "eza"},
+			AliasConditions: map[string]entity.Condition{"ls": {IfCommand: "eza"}},
+		}},
+		Enabled: []string{"local"},
+	}
+	functionPort := &port.DummyFunctionRepository{ActivatedScripts: []entity.Script{
+		{Name: "copy", Package: "local", DataToInject: "copy() { pbcopy; }", Condition: entity.Condition{IfCommand: "pbcopy"}},
+	}}
+	environmentPort := &port.MockEnvironmentPort{}
+	cachePort := &port.MockCachePort{Fingerprint: "v1"}
+	usecase := NewInjectUsecase(dbPort, functionPort, shelll.NewShellAdapter(), cachePort, service.NewConditionService(environmentPort), &port.MockProfilePort{})
+
+	injection, err := usecase.GetInjectionString(InjectOptions{Shell: "bash"})
+	assert.NoError(t, err)
+	assert.NotContains(t, injection, "eza")
+	// The commands named by conditions are stored with the entry
+	assert.Equal(t, map[string]bool{"eza": false, "pbcopy": false}, cachePort.Entries["inject-bash"].Commands)
+
+	// Installing a command named by an alias condition invalidates the entry, with the same fingerprint
+	environmentPort.Commands = []string{"eza"}
+	injection, err = usecase.GetInjectionString(InjectOptions{Shell: "bash"})
+	assert.NoError(t, err)
+	assert.Contains(t, injection, `alias ls="eza"`)
+
+	// So does a command named by a script directive
+	environmentPort.Commands = []string{"eza", "pbcopy"}
+	injection, err = usecase.GetInjectionString(InjectOptions{Shell: "bash"})
+	assert.NoError(t, err)
+	assert.Contains(t, injection, "copy() { pbcopy; }")
+
+	// Commands no condition names do not
+	environmentPort.Commands = []string{"eza", "pbcopy", "jq"}
+	dbPort.Packages[0].Aliases["ls"] = "eza -l"
+	injection, err = usecase.GetInjectionString(InjectOptions{Shell: "bash"})
+	assert.NoError(t, err)
+	assert.Contains(t, injection, `alias ls="eza"`)
+}
@@ -0,0 +1,21 @@
+package usecase
+
+import (
+	"duh/internal/domain/entity"
+	"duh/internal/domain/service"
+)
+
+type LintUsecase struct {
+	lintService *service.LintService
+}
+
+func NewLintUsecase(lintService *service.LintService) *LintUsecase {
+	return &LintUsecase{
+		lintService: lintService,
+	}
+}
+
+func (l *LintUsecase) LintScripts(packageName string) ([]entity.LintFinding, error) {
+	// Delegate to domain service
+	return l.lintService.LintScripts(packageName)
+}
@@ -0,0 +1,26 @@
+package usecase
+
+import (
+	"duh/internal/domain/entity"
+	"duh/internal/domain/service"
+)
+
+type LockUsecase struct {
+	lockService *service.LockService
+}
+
+func NewLockUsecase(lockService *service.LockService) *LockUsecase {
+	return &LockUsecase{
+		lockService: lockService,
+	}
+}
+
+func (l *LockUsecase) Lock() (entity.Lock, error) {
+	// Delegate to domain service
+	return l.lockService.Lock()
+}
+
+func (l *LockUsecase) Sync(locked bool, force bool) (entity.SyncResults, error) {
+	// Delegate to domain service
+	return l.lockService.Sync(locked, force)
+}
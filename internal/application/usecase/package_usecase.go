@@ -3,15 +3,18 @@ package usecase
 import (
 	"duh/internal/domain/entity"
 	"duh/internal/domain/service"
+	"strings"
 )
 
 type PackageUsecase struct {
 	packageService *service.PackageService
+	injectUsecase  *InjectUsecase
 }
 
-func NewPackageUsecase(packageService *service.PackageService) *PackageUsecase {
+func NewPackageUsecase(packageService *service.PackageService, injectUsecase *InjectUsecase) *PackageUsecase {
 	return &PackageUsecase{
 		packageService: packageService,
+		injectUsecase:  injectUsecase,
 	}
 }
 
@@ -53,19 +56,124 @@ func (p *PackageUsecase) RenamePackage(oldName, newName string) error {
 	return p.packageService.RenamePackage(oldName, newName)
 }
 
-func (p *PackageUsecase) AddPackage(url string, name *string) error {
+// AddPackage returns the names of the dependencies added along with the package
+func (p *PackageUsecase) AddPackage(url string, name *string) ([]string, error) {
 	// Application layer: orchestrate add then enable
 	return p.packageService.AddAndEnablePackage(url, name)
 }
 
+func (p *PackageUsecase) GetPackageInfo(packageName string) (*entity.PackageInfo, error) {
+	// Delegate to domain service
+	return p.packageService.GetPackageInfo(packageName)
+}
+
+func (p *PackageUsecase) ListPackagesInfo() ([]entity.PackageInfo, error) {
+	// Delegate to domain service
+	return p.packageService.ListPackagesInfo()
+}
+
 func (p *PackageUsecase) CreatePackage(name string) error {
 	// Delegate to domain service
 	return p.packageService.CreatePackage(name)
 }
 
-func (p *PackageUsecase) UpdatePackages(strategy string) (entity.PackageUpdateResults, error) {
+func (p *PackageUsecase) UpdatePackages(options entity.UpdateOptions) (entity.PackageUpdateResults, error) {
+	// Delegate to domain service
+	return p.packageService.UpdatePackages(options)
+}
+
+func (p *PackageUsecase) PreviewUpdates() (entity.UpdatePreview, error) {
+	// Delegate to domain service
+	return p.packageService.PreviewUpdates()
+}
+
+func (p *PackageUsecase) GetPackageHistory(packageName string, limit int) ([]entity.PackageHistoryEntry, error) {
+	// Delegate to domain service
+	return p.packageService.GetPackageHistory(packageName, limit)
+}
+
+// RollbackPackage restores the files of a package as they were at ref, and diffs the injection of the package,
+// rendered for the given shell before and after the rollback
+func (p *PackageUsecase) RollbackPackage(packageName string, ref string, shell string) (entity.Rollback, error) {
+	// A package broken by an update may not render, the rollback must still be possible
+	before, _ := p.renderPackage(packageName, shell)
+	rollback, err := p.packageService.RollbackPackage(packageName, ref)
+	if err != nil {
+		return entity.Rollback{}, err
+	}
+	after, _ := p.renderPackage(packageName, shell)
+	rollback.InjectionDiff = diffLines(before, after)
+	return rollback, nil
+}
+
+// renderPackage renders the injection of a single package, as duh package export does without its header
+func (p *PackageUsecase) renderPackage(packageName string, shell string) ([]string, error) {
+	exported, err := p.injectUsecase.ExportPackages([]string{packageName}, shell)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(strings.TrimSuffix(exported, "\n"), "\n")
+	return lines[1:], nil
+}
+
+// diffLines lists the lines removed from before and added in after, prefixed with - and +,
+// keeping the lines both have in common out, from their longest common subsequence
+func diffLines(before []string, after []string) []string {
+	common := make([][]int, len(before)+1)
+	for i := range common {
+		common[i] = make([]int, len(after)+1)
+	}
+	for i := len(before) - 1; i >= 0; i-- {
+		for j := len(after) - 1; j >= 0; j-- {
+			if before[i] == after[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+	diff := []string{}
+	i, j := 0, 0
+	for i < len(before) && j < len(after) {
+		switch {
+		case before[i] == after[j]:
+			i++
+			j++
+		case common[i+1][j] >= common[i][j+1]:
+			diff = append(diff, "- "+before[i])
+			i++
+		default:
+			diff = append(diff, "+ "+after[j])
+			j++
+		}
+	}
+	for ; i < len(before); i++ {
+		diff = append(diff, "- "+before[i])
+	}
+	for ; j < len(after); j++ {
+		diff = append(diff, "+ "+after[j])
+	}
+	return diff
+}
+
+func (p *PackageUsecase) GetPackagesStatus(fetch bool) ([]entity.PackageStatus, error) {
+	// Delegate to domain service
+	return p.packageService.GetPackagesStatus(fetch)
+}
+
+func (p *PackageUsecase) PinPackage(packageName string, ref string) error {
+	// Delegate to domain service
+	return p.packageService.PinPackage(packageName, ref)
+}
+
+func (p *PackageUsecase) UnpinPackage(packageName string) error {
+	// Delegate to domain service
+	return p.packageService.UnpinPackage(packageName)
+}
+
+func (p *PackageUsecase) SetLazyFunctions(packageName string, lazy bool) error {
 	// Delegate to domain service
-	return p.packageService.UpdatePackages(strategy)
+	return p.packageService.SetLazyFunctions(packageName, lazy)
 }
 
 func (p *PackageUsecase) EditPackage(packageName string) error {
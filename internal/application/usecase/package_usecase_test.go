@@ -0,0 +1,42 @@
+package usecase
+
+import (
+	"duh/internal/domain/entity"
+	"duh/internal/domain/port"
+	"duh/internal/domain/service"
+	"duh/internal/infrastructure/shelll"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RollbackPackage_InjectionDiff(t *testing.T) {
+	dbPort := &port.MockDbAdapter{
+		Packages: []entity.Package{{Name: "team", Aliases: map[string]string{"gs": "git status -sb", "k": "kubectl"}}},
+		Statuses: map[string]entity.PackageStatus{
+			"team": {Git: true, Branch: "main", Commit: "bbb0000000000000"},
+		},
+		Commits: map[string][]entity.PackageCommit{
+			"team": {{Hash: "bbb0000000000000"}, {Hash: "aaa0000000000000"}},
+		},
+		Contents: map[string]map[string]entity.PackageContent{
+			"team": {
+				"aaa0000000000000": {Aliases: map[string]string{"gs": "git status", "k": "kubectl"}},
+				"bbb0000000000000": {Aliases: map[string]string{"gs": "git status -sb", "k": "kubectl"}},
+			},
+		},
+	}
+	injectUsecase := NewInjectUsecase(dbPort, &port.DummyFunctionRepository{}, shelll.NewShellAdapter(), &port.MockCachePort{}, service.NewConditionService(&port.MockEnvironmentPort{}), &port.MockProfilePort{})
+	usecase := NewPackageUsecase(service.NewPackageService(dbPort), injectUsecase)
+
+	rollback, err := usecase.RollbackPackage("team", "", "bash")
+	assert.NoError(t, err)
+	// The rendered lines of the package are diffed, the unchanged ones are left out
+	assert.Equal(t, []string{`- alias gs="git status -sb"`, `+ alias gs="git status"`}, rollback.InjectionDiff)
+}
+
+func Test_DiffLines(t *testing.T) {
+	assert.Empty(t, diffLines([]string{"a", "b"}, []string{"a", "b"}))
+	assert.Equal(t, []string{"+ a", "+ b"}, diffLines(nil, []string{"a", "b"}))
+	assert.Equal(t, []string{"- b", "+ c", "+ d"}, diffLines([]string{"a", "b", "e"}, []string{"a", "c", "d", "e"}))
+}
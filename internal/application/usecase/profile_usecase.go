@@ -0,0 +1,55 @@
+package usecase
+
+import (
+	"duh/internal/domain/entity"
+	"duh/internal/domain/port"
+	"fmt"
+)
+
+type ProfileUsecase struct {
+	profilePort     port.ProfilePort
+	environmentPort port.EnvironmentPort
+}
+
+func NewProfileUsecase(profilePort port.ProfilePort, environmentPort port.EnvironmentPort) *ProfileUsecase {
+	return &ProfileUsecase{
+		profilePort:     profilePort,
+		environmentPort: environmentPort,
+	}
+}
+
+func (p *ProfileUsecase) ListProfiles() ([]entity.Profile, entity.ActiveProfile, error) {
+	profiles, err := p.profilePort.ListProfiles()
+	if err != nil {
+		return nil, entity.ActiveProfile{}, err
+	}
+	active, err := p.profilePort.GetActiveProfile()
+	if err != nil {
+		return nil, entity.ActiveProfile{}, err
+	}
+	return profiles, active, nil
+}
+
+func (p *ProfileUsecase) GetActiveProfile() (entity.ActiveProfile, error) {
+	return p.profilePort.GetActiveProfile()
+}
+
+func (p *ProfileUsecase) CreateProfile(name string, hostnames []string) error {
+	if name == "" {
+		return fmt.Errorf("profile name can't be empty")
+	}
+	return p.profilePort.CreateProfile(name, hostnames)
+}
+
+// UseProfile selects the profile on this machine, and returns the profile actually active afterwards,
+// which differs when DUH_PROFILE is set
+func (p *ProfileUsecase) UseProfile(name string) (entity.ActiveProfile, error) {
+	hostname, err := p.environmentPort.Hostname()
+	if err != nil {
+		return entity.ActiveProfile{}, err
+	}
+	if err := p.profilePort.UseProfile(name, hostname); err != nil {
+		return entity.ActiveProfile{}, err
+	}
+	return p.profilePort.GetActiveProfile()
+}
@@ -5,4 +5,7 @@ const (
 	PackageFunctionsDirName = "functions"
 	PackageDbFileName       = "db"
 	DuhConfigFileName       = "user_preferences"
+	LockFileName            = "duh_lock"
+	CacheDirName            = "cache"
+	PackageGitconfigName    = "gitconfig"
 )
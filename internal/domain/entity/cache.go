@@ -0,0 +1,10 @@
+package entity
+
+// CacheEntry is a cached content, with what it was computed from
+type CacheEntry struct {
+	// Fingerprint of the files the content was computed from
+	Key string
+	// Commands named by if_command conditions when the content was computed, with whether each was found
+	Commands map[string]bool
+	Content  string
+}
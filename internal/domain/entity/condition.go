@@ -0,0 +1,18 @@
+package entity
+
+// Condition restricts an entry to some environments, every field set must match.
+// An empty condition always matches.
+type Condition struct {
+	// Operating system, as reported by Go (linux, darwin, windows...)
+	OS string
+	// Hostname pattern, supporting * and ? wildcards (build-*)
+	Hostname string
+	// Shell the injection is rendered for (bash, zsh, fish, sh)
+	Shell string
+	// Command that must be available in the PATH
+	IfCommand string
+}
+
+func (c Condition) IsEmpty() bool {
+	return c == Condition{}
+}
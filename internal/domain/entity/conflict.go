@@ -0,0 +1,20 @@
+package entity
+
+const (
+	ConflictAlias    = "alias"
+	ConflictExport   = "export"
+	ConflictFunction = "function"
+	ConflictGitAlias = "git alias"
+	// An alias and a function share the same name
+	ConflictAliasFunction = "alias/function"
+)
+
+// Conflict is a name defined more than once across enabled packages
+type Conflict struct {
+	Kind string
+	Name string
+	// Packages defining the name, in injection order
+	Packages []string
+	// Package whose definition is used by the shell
+	Winner string
+}
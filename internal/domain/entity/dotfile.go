@@ -0,0 +1,30 @@
+package entity
+
+// Dotfile is what duh understands of an existing shell configuration file, like .bashrc or .zshrc
+type Dotfile struct {
+	Aliases map[string]string
+	Exports map[string]Export
+	// One script per function declared in the file, named after the function
+	Functions []Script
+	// Statements which are neither an alias, an export nor a function declaration
+	Unclassified []Warning
+}
+
+// DotfileImport is the outcome of importing a dotfile into a package
+type DotfileImport struct {
+	Package string
+	// Aliases and exports added to the package
+	Aliases map[string]string
+	Exports map[string]Export
+	// Scripts added to the functions directory of the package
+	Scripts []Script
+	// Definitions left out because the package already defines them differently
+	Skipped []string
+	// Statements of the dotfile which were not imported
+	Unclassified []Warning
+}
+
+// IsEmpty tells if the import brings nothing to the package
+func (d DotfileImport) IsEmpty() bool {
+	return len(d.Aliases) == 0 && len(d.Exports) == 0 && len(d.Scripts) == 0
+}
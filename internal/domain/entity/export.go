@@ -0,0 +1,83 @@
+package entity
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Export is an environment variable set by a package
+type Export struct {
+	// Value of the variable, shell variables in it are expanded unless Literal is set
+	Value string
+	// Inject Value as is, without any expansion
+	Literal bool
+	// Entries added to the start of a colon-separated list like PATH, when not already in it
+	Prepend []string
+	// Entries added to the end of a colon-separated list like PATH, when not already in it
+	Append []string
+}
+
+// IsPathList tells if the export edits a colon-separated list
+func (e Export) IsPathList() bool {
+	return len(e.Prepend) > 0 || len(e.Append) > 0
+}
+
+// SetsValue tells if the export replaces the current value of the variable.
+// Exports only prepending or appending entries keep it, so several packages can extend the same list.
+func (e Export) SetsValue() bool {
+	return e.Value != "" || !e.IsPathList()
+}
+
+// Then returns the export resulting from applying next after e
+func (e Export) Then(next Export) Export {
+	if next.SetsValue() {
+		return next
+	}
+	return Export{
+		Value:   e.Value,
+		Literal: e.Literal,
+		// next is evaluated last, so its entries end up at the very start of the list
+		Prepend: appendMissing(slices.Clone(next.Prepend), e.Prepend...),
+		Append:  appendMissing(slices.Clone(e.Append), next.Append...),
+	}
+}
+
+// WithPrepended returns the export with entry moved or added to the start of its prepended entries
+func (e Export) WithPrepended(entry string) Export {
+	e.Prepend = appendMissing([]string{entry}, e.Prepend...)
+	return e
+}
+
+// WithAppended returns the export with entry added to the end of its appended entries, if missing
+func (e Export) WithAppended(entry string) Export {
+	e.Append = appendMissing(slices.Clone(e.Append), entry)
+	return e
+}
+
+func (e Export) String() string {
+	parts := []string{}
+	if e.Value != "" || !e.IsPathList() {
+		value := e.Value
+		if e.Literal {
+			value += " (literal)"
+		}
+		parts = append(parts, value)
+	}
+	if len(e.Prepend) > 0 {
+		parts = append(parts, fmt.Sprintf("[prepend: %s]", strings.Join(e.Prepend, ", ")))
+	}
+	if len(e.Append) > 0 {
+		parts = append(parts, fmt.Sprintf("[append: %s]", strings.Join(e.Append, ", ")))
+	}
+	return strings.Join(parts, " ")
+}
+
+func appendMissing(list []string, entries ...string) []string {
+	for _, entry := range entries {
+		if !slices.Contains(list, entry) {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
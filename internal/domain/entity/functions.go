@@ -1,11 +1,48 @@
 package entity
 
+import "strings"
+
+// Policies for the code of a script outside its functions, which runs in every shell at startup
+const (
+	// Injected as is
+	TopLevelCodeAllow = "allow"
+	// Injected as is, with a warning printed when the shell starts
+	TopLevelCodeWarn = "warn"
+	// Only the function declarations of the script are injected
+	TopLevelCodeStrip = "strip"
+	// The script is not injected at all
+	TopLevelCodeRefuse = "refuse"
+)
+
+// TopLevelCodePolicies lists the values of the top_level_code preference
+var TopLevelCodePolicies = []string{TopLevelCodeAllow, TopLevelCodeWarn, TopLevelCodeStrip, TopLevelCodeRefuse}
+
+// TopLevelCodePreference is the top_level_code policy applied to the scripts loaded
+type TopLevelCodePreference struct {
+	Policy string
+	// Set when the preference is invalid and Policy fell back to allow
+	Warning string
+}
+
 type Script struct {
 	Name         string
+	Package      string // empty for internal scripts embedded in the binary
 	PathToFile   string
 	Functions    []Function
 	DataToInject string
 	Warnings     []Warning
+	// Read from the "# duh:" directive at the top of the script
+	Condition Condition
+	// Read from the "# duh: lazy" directive, nil when the script follows the setting of its package
+	Lazy *bool
+	// Statements outside the functions of the script, each with its line
+	TopLevelCode []Warning
+	// Policy applied to TopLevelCode when the script was loaded, empty when it has none.
+	// With strip, DataToInject only holds the function declarations, with refuse it is empty.
+	TopLevelPolicy string
+	// Functions declared inside the code stripped by the strip policy, like in an if block,
+	// which are left out of Functions as they are not injected
+	DroppedFunctions []string
 }
 
 // Warnings detected while loading the script
@@ -18,4 +55,82 @@ type Function struct {
 	Name string
 	// Extracted from comments, each line as a separate string
 	Documentation []string
+	// Parsed from Documentation: the @description tag, or the lines before the first tag
+	Description string
+	// Parsed from the @arg tags
+	Args []FunctionArg
+	// Parsed from the @example tags, an example can span several lines
+	Examples []string
+	// Commands the function needs in the PATH, parsed from the @requires tags
+	Requires []string
+	// Definition of the function, as written in the script
+	Source string
+	// Line of the script declaring the function
+	Line int
+}
+
+// FunctionArg is an argument of a function, documented with "@arg name description"
+type FunctionArg struct {
+	Name        string
+	Description string
+}
+
+// Summary is the first line of the description of the function
+func (f Function) Summary() string {
+	summary, _, _ := strings.Cut(f.Description, "\n")
+	return summary
+}
+
+// Usage is the synopsis of the function, built from its arguments like "mkcd <dir>"
+func (f Function) Usage() string {
+	parts := []string{f.Name}
+	for _, arg := range f.Args {
+		if strings.HasPrefix(arg.Name, "[") || strings.HasPrefix(arg.Name, "<") {
+			parts = append(parts, arg.Name)
+		} else {
+			parts = append(parts, "<"+arg.Name+">")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// Choices are the values an argument accepts, listed in its name like "<staging|production>".
+// Nil when the argument accepts any value.
+func (a FunctionArg) Choices() []string {
+	name := a.Label()
+	if !strings.Contains(name, "|") {
+		return nil
+	}
+	choices := []string{}
+	for _, choice := range strings.Split(name, "|") {
+		if choice = strings.TrimSpace(choice); choice != "" {
+			choices = append(choices, choice)
+		}
+	}
+	return choices
+}
+
+// Optional tells if the argument can be left out, when its name is written like "[depth]"
+func (a FunctionArg) Optional() bool {
+	return strings.HasPrefix(a.Name, "[")
+}
+
+// Repeated tells if the argument takes every remaining word, when its name ends with "..." like "[file...]"
+func (a FunctionArg) Repeated() bool {
+	return strings.HasSuffix(a.Name, "...") || strings.HasSuffix(strings.Trim(a.Name, "[]<>"), "...")
+}
+
+// Label is the name of the argument without the brackets and dots marking it optional or repeated
+func (a FunctionArg) Label() string {
+	return strings.TrimSuffix(strings.Trim(strings.TrimSuffix(a.Name, "..."), "[]<>"), "...")
+}
+
+// HasCompletion tells if one of the arguments of the function lists its values, so the shell can complete them
+func (f Function) HasCompletion() bool {
+	for _, arg := range f.Args {
+		if len(arg.Choices()) > 0 {
+			return true
+		}
+	}
+	return false
 }
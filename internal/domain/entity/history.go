@@ -0,0 +1,41 @@
+package entity
+
+import "time"
+
+// PackageCommit is a commit of the history of a package
+type PackageCommit struct {
+	Hash    string
+	Author  string
+	Date    time.Time
+	Subject string
+}
+
+// PackageHistoryEntry is a commit of a package, with the entries it added, removed or changed
+type PackageHistoryEntry struct {
+	Commit  PackageCommit
+	Changes []EntryChange
+}
+
+// Rollback is the result of duh package rollback
+type Rollback struct {
+	Package string
+	// Commit checked out before the rollback
+	From string
+	// Commit whose files were restored
+	To string
+	// Entries added, removed or changed by the rollback, listed in its commit message
+	Changes []EntryChange
+	// Lines of the injection of the package removed and added by the rollback, prefixed with - and +
+	InjectionDiff []string
+}
+
+// Length of the abbreviated commit hashes shown to the user
+const ShortCommitLength = 12
+
+// ShortCommit abbreviates a commit hash to ShortCommitLength characters
+func ShortCommit(commit string) string {
+	if len(commit) > ShortCommitLength {
+		return commit[:ShortCommitLength]
+	}
+	return commit
+}
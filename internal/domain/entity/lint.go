@@ -0,0 +1,38 @@
+package entity
+
+import "fmt"
+
+// Severities of lint findings, from the most to the least serious
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// Rules checked by duh functions lint
+const (
+	LintUnquotedExpansion    = "unquoted-expansion"
+	LintBashOnlySyntax       = "bash-only-syntax"
+	LintShadowedCommand      = "shadowed-command"
+	LintShadowedAlias        = "shadowed-alias"
+	LintMissingDocumentation = "missing-documentation"
+	LintExitInFunction       = "exit-in-function"
+	LintCodeOutsideFunction  = "code-outside-function"
+)
+
+// LintFinding is an issue found in a function script
+type LintFinding struct {
+	// Package of the script
+	Package  string
+	File     string
+	Line     int
+	Column   int
+	Severity string
+	Rule     string
+	Message  string
+}
+
+// String formats the finding like compilers do, so editors and CI tools can link to it
+func (f LintFinding) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s [%s]", f.File, f.Line, f.Column, f.Severity, f.Message, f.Rule)
+}
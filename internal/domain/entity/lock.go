@@ -0,0 +1,32 @@
+package entity
+
+// Lock records the state of every package, so it can be reproduced on another machine
+type Lock struct {
+	// Enabled packages come first, in their activation order
+	Packages []LockedPackage
+}
+
+type LockedPackage struct {
+	Name string
+	// Url of the origin remote, empty for packages created locally
+	Url string
+	// Commit checked out in the package, empty for packages which are not git repositories
+	Commit  string
+	Enabled bool
+	// Ref the package is pinned to, empty when it follows its remote
+	Pin string
+}
+
+// PackageRevision is the remote and commit a package is checked out from
+type PackageRevision struct {
+	Url    string
+	Commit string
+}
+
+type SyncResults struct {
+	Cloned []string
+	// Packages moved to the commit recorded in the lockfile
+	Reset                []string
+	LocalChangesDetected []string
+	OtherErrors          []error
+}
@@ -0,0 +1,32 @@
+package entity
+
+// Manifest describes a package, read from the [manifest] section of its db file
+type Manifest struct {
+	Description string
+	// Semantic version of the package, e.g. 1.2.0
+	Version string
+	Authors []string
+	// Oldest duh version able to use the package, empty when any version works
+	MinDuhVersion string
+	// Git URLs of the packages this one relies on, added and enabled before it
+	Dependencies []string
+}
+
+// PackageInfo is everything known about an installed package
+type PackageInfo struct {
+	Package Package
+	Enabled bool
+	Default bool
+	// Ref the package is pinned to, empty when it follows its remote
+	Pin string
+	// Status of each dependency of the package manifest, in declaration order
+	Dependencies []DependencyStatus
+	// Why the manifest is invalid, like a version which is not a semantic version, nil when it is valid
+	ManifestError error
+}
+
+type DependencyStatus struct {
+	Url string
+	// Name of the installed package cloned from Url, empty when it is missing
+	InstalledAs string
+}
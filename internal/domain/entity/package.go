@@ -1,19 +1,88 @@
 package entity
 
 const (
-	UpdateSafe  = "safe"
-	UpdateForce = "force"
-	UpdateKeep  = "keep"
+	UpdateSafe   = "safe"
+	UpdateForce  = "force"
+	UpdateKeep   = "keep"
+	UpdateRebase = "rebase"
 )
 
 type Package struct {
-	Name                 string
-	Aliases              map[string]string
-	Exports              map[string]string
+	Name    string
+	Aliases map[string]string
+	Exports map[string]Export
+	// File the aliases and exports are read from
+	DbFilePath           string
 	GitConfigIncludePath string
+	// Read from the [alias] section of the package gitconfig file
+	GitAliases map[string]string
+	// Every entry of the package gitconfig file, in order
+	GitConfig []GitConfigEntry
+	// Conditions of the aliases and exports only injected in some environments, by name
+	AliasConditions  map[string]Condition
+	ExportConditions map[string]Condition
+	Manifest         Manifest
+	// Inject a stub per function, loading its script on first call, instead of the scripts themselves
+	LazyFunctions bool
+}
+
+// Source is where the aliases and exports of the package are defined
+func (p Package) Source() Source {
+	return Source{Package: p.Name, File: p.DbFilePath}
+}
+
+// GitAliasSource is where the git aliases of the package are defined
+func (p Package) GitAliasSource() Source {
+	return Source{Package: p.Name, File: p.GitConfigIncludePath}
+}
+
+// ValueExports returns the exports replacing the value of their variable,
+// leaving out the ones only extending a list like PATH
+func (p Package) ValueExports() map[string]Export {
+	exports := map[string]Export{}
+	for name, export := range p.Exports {
+		if export.SetsValue() {
+			exports[name] = export
+		}
+	}
+	return exports
+}
+
+// Source is the package an item is defined in, and the file it is read from
+type Source struct {
+	Package string
+	File    string
+}
+
+// MergedAlias is an alias as the shell ends up with it, from the last package defining it
+type MergedAlias struct {
+	Name   string
+	Value  string
+	Source Source
+}
+
+// MergedExport is an export as the shell ends up with it: values are overridden by the last package,
+// while list entries add up, so an export can come from several packages
+type MergedExport struct {
+	Name   string
+	Export Export
+	// Every package contributing to the export, in injection order
+	Sources []Source
+}
+
+// GitConfigEntry is a git configuration variable, Key being written as section[.subsection].name
+type GitConfigEntry struct {
+	Key   string
+	Value string
 }
 
 type PackageUpdateResults struct {
 	LocalChangesDetected []string
 	OtherErrors          []error
+	// Pins moved to a newer tag, only with duh package update --upgrade
+	UpgradedPins []PinUpgrade
+	Updated      []PackageUpdate
+	UpToDate     []string
+	// Packages without git repository or remote, nothing to update from
+	Skipped []string
 }
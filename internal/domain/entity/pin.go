@@ -0,0 +1,29 @@
+package entity
+
+import "strings"
+
+// PinUpgrade is a pin moved to a newer tag by duh package update --upgrade
+type PinUpgrade struct {
+	Package string
+	From    string
+	To      string
+}
+
+// PinSeparator separates the url of a package source from the ref it is pinned to, whatever the ref,
+// e.g. https://host/repo#release/1.2
+const PinSeparator = "#"
+
+// SplitPinnedUrl splits a package source like https://host/repo#release/1.2 or https://host/repo@v1.2.0
+// into its url and ref. The ref is empty when the source has none.
+// Everything after the first PinSeparator is the ref. Without it, the ref follows an @ placed after the last / or :
+// of the url, so the @ of ssh urls like git@host:repo is not a ref separator, but such refs cannot contain / or :.
+func SplitPinnedUrl(source string) (string, string) {
+	if url, ref, found := strings.Cut(source, PinSeparator); found {
+		return url, ref
+	}
+	at := strings.LastIndex(source, "@")
+	if at <= strings.LastIndexAny(source, "/:") {
+		return source, ""
+	}
+	return source[:at], source[at+1:]
+}
@@ -0,0 +1,167 @@
+package entity
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// PackageContent is what a package injects at a given commit
+type PackageContent struct {
+	Aliases    map[string]string
+	Exports    map[string]Export
+	GitAliases map[string]string
+	// Source of each function, by name
+	Functions map[string]string
+}
+
+// Diff lists the entries changed from c to next, grouped by kind and sorted by name
+func (c PackageContent) Diff(next PackageContent) []EntryChange {
+	changes := []EntryChange{}
+	changes = append(changes, diffEntries(ConflictAlias, c.Aliases, next.Aliases, identity)...)
+	changes = append(changes, diffEntries(ConflictExport, c.Exports, next.Exports, Export.String)...)
+	// Functions can span many lines, only telling they changed is more readable
+	changes = append(changes, diffEntries(ConflictFunction, c.Functions, next.Functions, hidden)...)
+	changes = append(changes, diffEntries(ConflictGitAlias, c.GitAliases, next.GitAliases, identity)...)
+	return changes
+}
+
+func diffEntries[V any](kind string, current map[string]V, next map[string]V, describe func(V) string) []EntryChange {
+	names := slices.Collect(maps.Keys(current))
+	for name := range next {
+		if _, ok := current[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	changes := []EntryChange{}
+	for _, name := range names {
+		before, inCurrent := current[name]
+		after, inNext := next[name]
+		switch {
+		case !inCurrent:
+			changes = append(changes, EntryChange{Kind: kind, Name: name, Action: ChangeAdded, To: describe(after)})
+		case !inNext:
+			changes = append(changes, EntryChange{Kind: kind, Name: name, Action: ChangeRemoved, From: describe(before)})
+		case !reflect.DeepEqual(before, after):
+			changes = append(changes, EntryChange{Kind: kind, Name: name, Action: ChangeChanged, From: describe(before), To: describe(after)})
+		}
+	}
+	return changes
+}
+
+func identity(value string) string {
+	return value
+}
+
+func hidden(string) string {
+	return ""
+}
+
+// IncomingUpdate is a package fetched from its remote but not pulled yet
+type IncomingUpdate struct {
+	Package string
+	// Commit checked out, and commit an update would move the package to,
+	// the same as From when the remote brings nothing new
+	From string
+	To   string
+	// Content where the incoming changes start, the merge base of both commits, and content of To.
+	// Only read when the remote brings something new.
+	Current  PackageContent
+	Incoming PackageContent
+}
+
+// EntryChange is an alias, export, function or git alias modified by an update
+type EntryChange struct {
+	// One of ConflictAlias, ConflictExport, ConflictFunction or ConflictGitAlias
+	Kind string
+	Name string
+	// One of ChangeAdded, ChangeRemoved or ChangeChanged
+	Action string
+	// Values before and after the update, empty for functions
+	From string
+	To   string
+}
+
+func (c EntryChange) String() string {
+	switch {
+	case c.Kind == ConflictFunction:
+		return fmt.Sprintf("%s `%s` %s", c.Kind, c.Name, c.Action)
+	case c.Action == ChangeAdded:
+		return fmt.Sprintf("%s `%s` added: %s", c.Kind, c.Name, c.To)
+	case c.Action == ChangeRemoved:
+		return fmt.Sprintf("%s `%s` removed, was %s", c.Kind, c.Name, c.From)
+	default:
+		return fmt.Sprintf("%s `%s` changed from %s to %s", c.Kind, c.Name, c.From, c.To)
+	}
+}
+
+// CommitMessage summarizes changes as a commit message, a subject line followed by one line per change.
+// It is empty when there is no change.
+func CommitMessage(changes []EntryChange) string {
+	if len(changes) == 0 {
+		return ""
+	}
+	subject := ""
+	if len(changes) == 1 {
+		verbs := map[string]string{ChangeAdded: "Add", ChangeRemoved: "Remove", ChangeChanged: "Change"}
+		subject = fmt.Sprintf("%s %s `%s`", verbs[changes[0].Action], changes[0].Kind, changes[0].Name)
+	} else {
+		counts := map[string]int{}
+		kinds := []string{}
+		for _, change := range changes {
+			if counts[change.Kind] == 0 {
+				kinds = append(kinds, change.Kind)
+			}
+			counts[change.Kind]++
+		}
+		parts := []string{}
+		for _, kind := range kinds {
+			parts = append(parts, pluralize(counts[kind], kind))
+		}
+		subject = "Update " + strings.Join(parts, ", ")
+	}
+
+	lines := []string{subject, ""}
+	for _, change := range changes {
+		lines = append(lines, "- "+change.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func pluralize(count int, kind string) string {
+	if count == 1 {
+		return fmt.Sprintf("1 %s", kind)
+	}
+	if strings.HasSuffix(kind, "s") {
+		return fmt.Sprintf("%d %ses", count, kind)
+	}
+	return fmt.Sprintf("%d %ss", count, kind)
+}
+
+// PackagePreview lists the changes pulling a package would bring
+type PackagePreview struct {
+	Package string
+	From    string
+	To      string
+	Changes []EntryChange
+}
+
+// UpdatePreview is the result of duh package update --dry-run
+type UpdatePreview struct {
+	// Packages with incoming commits
+	Packages []PackagePreview
+	UpToDate []string
+	// Packages without git repository or remote, nothing to update from
+	Skipped []string
+	Errors  []error
+}
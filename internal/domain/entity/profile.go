@@ -0,0 +1,32 @@
+package entity
+
+// Profile used when no other profile is selected, it stores its packages in the [repositories] section
+const DefaultProfileName = "default"
+
+// Environment variable selecting the active profile, taking precedence over everything else
+const ProfileEnvVar = "DUH_PROFILE"
+
+// How the active profile was selected
+const (
+	ProfileSelectedByEnv      = "selected by " + ProfileEnvVar
+	ProfileSelectedByUse      = "selected with duh profile use"
+	ProfileSelectedByHostname = "selected by hostname rule"
+	ProfileSelectedByDefault  = "no other profile selected"
+)
+
+// Profile is a named set of enabled packages, so synced machines can use different packages
+type Profile struct {
+	Name            string
+	EnabledPackages []string
+	DefaultPackage  string
+	// Hostname patterns selecting the profile automatically
+	Hostnames []string
+}
+
+type ActiveProfile struct {
+	Name string
+	// One of the ProfileSelectedBy constants
+	Reason string
+	// Why the profile asked for is not the one used, empty when there is no problem
+	Warning string
+}
@@ -0,0 +1,41 @@
+package entity
+
+import "time"
+
+// PackageStatus is the git state of a package, as shown by duh package status
+type PackageStatus struct {
+	Name    string
+	Enabled bool
+	// Ref the package is pinned to, empty when it follows its remote
+	Pin string
+	// The package is a git repository
+	Git bool
+	// Url of the origin remote, empty for local-only packages
+	Remote string
+	// Checked out branch, empty when HEAD is detached
+	Branch string
+	// Checked out commit, empty for a repository without commit
+	Commit string
+	// Commits not pushed to the remote
+	Ahead int
+	// Commits of the remote not pulled yet
+	Behind int
+	// Files with uncommitted changes, relative to the package directory
+	Modified []string
+	// Last time the remote was fetched, zero when it never was
+	LastFetched time.Time
+	// Why the remote could not be fetched, Ahead and Behind are then from the last fetch
+	FetchError error
+	// Why the state of the package could not be read, only Name, Enabled and Pin are then set
+	Error error
+}
+
+// LocalOnly tells if the package has no remote to push to or pull from
+func (s PackageStatus) LocalOnly() bool {
+	return s.Remote == ""
+}
+
+// Clean tells if the package has neither uncommitted changes nor unpushed commits
+func (s PackageStatus) Clean() bool {
+	return len(s.Modified) == 0 && s.Ahead == 0
+}
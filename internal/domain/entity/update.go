@@ -0,0 +1,60 @@
+package entity
+
+// Number of packages updated at the same time when no limit is given
+const DefaultUpdateJobs = 4
+
+// Outcome of the update of a single package
+const (
+	UpdateStatusUpdated      = "updated"
+	UpdateStatusUpToDate     = "up to date"
+	UpdateStatusSkipped      = "skipped"
+	UpdateStatusLocalChanges = "local changes"
+	UpdateStatusFailed       = "failed"
+)
+
+type UpdateOptions struct {
+	// How local changes are handled, one of UpdateSafe, UpdateKeep or UpdateForce
+	Strategy string
+	// Move pins on a release tag to the newest compatible release before updating
+	Upgrade bool
+	// Maximum number of packages updated at the same time, DefaultUpdateJobs when not positive
+	Jobs int
+	// Called when a package starts and finishes its update, never concurrently
+	OnProgress func(UpdateProgress)
+	// Picks the side to keep for each conflict of the rebase strategy, never concurrently
+	// Without it, or when it fails, the update of the package is aborted
+	Resolve func(MergeConflict) (string, error)
+}
+
+// UpdateProgress reports a package starting or finishing its update
+type UpdateProgress struct {
+	Package string
+	// Empty when the update starts, one of the UpdateStatus values once it is done
+	Status string
+	// Commits of an update, or the error of a failure
+	Details string
+}
+
+// PackageUpdate is a package moved to a new commit, From and To being abbreviated hashes
+type PackageUpdate struct {
+	Package string
+	From    string
+	To      string
+}
+
+// Sides of a merge conflict
+const (
+	KeepLocal  = "local"
+	KeepRemote = "remote"
+)
+
+// MergeConflict is a key of a package file changed differently on the local and remote sides of an update
+type MergeConflict struct {
+	Package string
+	File    string
+	// Dotted path of the key, like aliases.gs
+	Key string
+	// Values on each side, written as in the file, empty when the side removed the key
+	Local  string
+	Remote string
+}
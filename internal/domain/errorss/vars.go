@@ -3,4 +3,5 @@ package errorss
 var (
 	ErrCouldNotGetPath = &InfrastructureError{Message: "could not get path"}
 	ErrFSDbInitFailed  = &InfrastructureError{Message: "filesystem database initialization failed"}
+	ErrLocalChanges    = &BusinessRuleError{Rule: "no_local_changes", Message: "local changes exist"}
 )
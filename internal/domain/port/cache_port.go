@@ -0,0 +1,40 @@
+package port
+
+import "duh/internal/domain/entity"
+
+type CachePort interface {
+	// Returns a fingerprint of every file the injection is computed from
+	// It changes as soon as a package or the user preferences change
+	InjectionFingerprint() (string, error)
+
+	// Returns the entry cached under the given name, only if it was stored with the same key
+	Get(name string, key string) (entity.CacheEntry, bool)
+
+	// Store the entry under the given name
+	Set(name string, entry entity.CacheEntry) error
+}
+
+type MockCachePort struct {
+	Fingerprint string
+	Entries     map[string]entity.CacheEntry
+}
+
+func (m *MockCachePort) InjectionFingerprint() (string, error) {
+	return m.Fingerprint, nil
+}
+
+func (m *MockCachePort) Get(name string, key string) (entity.CacheEntry, bool) {
+	entry, ok := m.Entries[name]
+	if !ok || entry.Key != key {
+		return entity.CacheEntry{}, false
+	}
+	return entry, true
+}
+
+func (m *MockCachePort) Set(name string, entry entity.CacheEntry) error {
+	if m.Entries == nil {
+		m.Entries = map[string]entity.CacheEntry{}
+	}
+	m.Entries[name] = entry
+	return nil
+}
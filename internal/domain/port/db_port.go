@@ -2,12 +2,16 @@ package port
 
 import (
 	"duh/internal/domain/entity"
+	"duh/internal/domain/errorss"
 	"fmt"
+	"slices"
+	"strings"
 )
 
 type DbPort interface {
 
-	/// Get all enabled repositories
+	/// Get all enabled repositories, in their activation order
+	/// When several packages define the same key, the last one wins
 	GetEnabledPackages() ([]entity.Package, error)
 
 	/// Get the default package
@@ -38,17 +42,69 @@ type DbPort interface {
 	// the string returned is the name of the added package
 	AddPackage(url string, name *string) (string, error)
 
+	// Find the installed package cloned from the given git url
+	// Returns its name, and false when no installed package comes from this url
+	FindPackageByUrl(url string) (string, bool, error)
+
 	// Create a new package with the given name
 	// By default it will be enabled
 	// Also returns the path to the created package
 	CreatePackage(name string) (string, error)
 
-	// Update repositories according to the specified strategy
+	// Update repositories according to options.Strategy, several at the same time up to options.Jobs
+	// Pinned packages are moved to the commit of their pin instead of the remote HEAD
 	// Strategies:
 	// - entity.UpdateSafe: Do not pull if local changes exist, return ErrChangesExist if changes are present
 	// - entity.UpdateKeep: Commit local changes before pulling
 	// - entity.UpdateForce: Discard local changes and reset to remote state
-	UpdatePackages(strategy string) (entity.PackageUpdateResults, error)
+	// - entity.UpdateRebase: Replay local changes on top of the remote, options.Resolve picking the side
+	//   to keep for db.toml keys changed on both sides
+	UpdatePackages(options entity.UpdateOptions) (entity.PackageUpdateResults, error)
+
+	// Fetch the remote of a package, without pulling, and read its content before and after the update
+	// Returns nil for a package which is not a git repository or has no remote
+	FetchIncomingUpdate(repoName string) (*entity.IncomingUpdate, error)
+
+	// Check out a tag, branch or commit in a package, and keep it on this ref during updates
+	PinPackage(repoName string, ref string) error
+
+	// Move a pinned package back to its default branch, following the remote again
+	UnpinPackage(repoName string) error
+
+	// Get the ref of each pinned package, by package name
+	GetPins() (map[string]string, error)
+
+	// Get the origin url and checked out commit of a package
+	// Both are empty for a package which is not a git repository
+	GetPackageRevision(repoName string) (entity.PackageRevision, error)
+
+	// Get the remote, branch, uncommitted changes and commits ahead and behind the remote of a package
+	// The remote is fetched first when fetch is set; Name, Enabled and Pin are left empty
+	GetPackageStatus(repoName string, fetch bool) (entity.PackageStatus, error)
+	// Same as GetPackageStatus for several packages, read concurrently, in the order of the names
+	// A package whose status cannot be read has its Error set, the others are still read
+	GetPackagesStatus(repoNames []string, fetch bool) ([]entity.PackageStatus, error)
+
+	// List the commits of a package, newest first, following first parents, at most limit of them
+	// Returns an empty list for a package which is not a git repository
+	ListPackageCommits(repoName string, limit int) ([]entity.PackageCommit, error)
+
+	// Resolve a tag, branch or (abbreviated) commit of a package to a full commit hash
+	ResolvePackageRef(repoName string, ref string) (string, error)
+
+	// Read the aliases, exports, git aliases and functions of a package at a commit
+	GetPackageContent(repoName string, commit string) (entity.PackageContent, error)
+
+	// Commit the files of a package as they were at a commit, on top of its current history
+	// Returns errorss.ErrLocalChanges when the package has local changes
+	RestorePackageCommit(repoName string, commit string, message string) error
+
+	// Move a package to a commit, fetching it from the remote if needed
+	// Returns errorss.ErrLocalChanges when the package has local changes, unless force is set
+	CheckoutPackageCommit(repoName string, commit string, force bool) error
+
+	// List the tags of a package repository, fetched from its remote
+	ListPackageTags(repoName string) ([]string, error)
 
 	// Edit a package's configuration file using the system's default editor
 	EditPackage(repoName string) error
@@ -72,12 +128,35 @@ type MockDbAdapter struct {
 	DefaultRepo entity.Package
 	Packages    []entity.Package
 	Enabled     []string
+	// Packages served by AddPackage, by url
+	RemotePackages map[string]entity.Package
+	// Url each package was added from, by package name
+	Origins map[string]string
+	Pins    map[string]string
+	// Tags of each package repository, by package name
+	Tags map[string][]string
+	// Revision of each package, by package name
+	Revisions map[string]entity.PackageRevision
+	// Packages with local changes, which cannot be checked out without force
+	LocalChanges []string
+	// Updates fetched from the remote of each package, by package name
+	Incoming map[string]*entity.IncomingUpdate
+	// Git state of each package, by package name
+	Statuses map[string]entity.PackageStatus
+	// History of each package, newest commit first, by package name
+	Commits map[string][]entity.PackageCommit
+	// Content of each package at a commit, by package name then commit
+	Contents map[string]map[string]entity.PackageContent
+	// Commit restored in each package, by package name
+	Restored map[string]string
+	// Message of the commit restoring each package, by package name
+	RestoreMessages map[string]string
 }
 
 func (m *MockDbAdapter) GetEnabledPackages() ([]entity.Package, error) {
 	enabledPackages := []entity.Package{}
-	for _, repo := range m.Packages {
-		for _, enabledName := range m.Enabled {
+	for _, enabledName := range m.Enabled {
+		for _, repo := range m.Packages {
 			if repo.Name == enabledName {
 				enabledPackages = append(enabledPackages, repo)
 			}
@@ -113,6 +192,7 @@ func (m *MockDbAdapter) DeletePackage(repoName string) error {
 		}
 	}
 	m.Packages = newPackages
+	delete(m.Pins, repoName)
 	return nil
 }
 
@@ -179,6 +259,18 @@ func (m *MockDbAdapter) AddPackage(url string, name *string) (string, error) {
 	if m.Enabled == nil {
 		m.Enabled = []string{}
 	}
+	if remote, ok := m.RemotePackages[url]; ok {
+		if name == nil {
+			name = &remote.Name
+		}
+		remote.Name = *name
+		if m.Origins == nil {
+			m.Origins = map[string]string{}
+		}
+		m.Origins[*name] = url
+		m.Packages = append(m.Packages, remote)
+		return *name, nil
+	}
 	if name == nil {
 		generatedName := "repo" + fmt.Sprint(len(m.Packages)+1)
 		name = &generatedName
@@ -188,6 +280,15 @@ func (m *MockDbAdapter) AddPackage(url string, name *string) (string, error) {
 	return "test/" + *name, nil
 }
 
+func (m *MockDbAdapter) FindPackageByUrl(url string) (string, bool, error) {
+	for name, origin := range m.Origins {
+		if origin == url {
+			return name, true, nil
+		}
+	}
+	return "", false, nil
+}
+
 func (m *MockDbAdapter) CheckInit() (bool, error) {
 	return true, nil
 }
@@ -208,11 +309,109 @@ func (m *MockDbAdapter) CreatePackage(name string) (string, error) {
 	return "test/" + name, nil
 }
 
-func (m *MockDbAdapter) UpdatePackages(strategy string) (entity.PackageUpdateResults, error) {
+func (m *MockDbAdapter) UpdatePackages(options entity.UpdateOptions) (entity.PackageUpdateResults, error) {
 	// Mock implementation does nothing
 	return entity.PackageUpdateResults{}, nil
 }
 
+func (m *MockDbAdapter) FetchIncomingUpdate(repoName string) (*entity.IncomingUpdate, error) {
+	return m.Incoming[repoName], nil
+}
+
+func (m *MockDbAdapter) PinPackage(repoName string, ref string) error {
+	if m.Pins == nil {
+		m.Pins = map[string]string{}
+	}
+	m.Pins[repoName] = ref
+	return nil
+}
+
+func (m *MockDbAdapter) UnpinPackage(repoName string) error {
+	delete(m.Pins, repoName)
+	return nil
+}
+
+func (m *MockDbAdapter) GetPins() (map[string]string, error) {
+	pins := map[string]string{}
+	for name, ref := range m.Pins {
+		pins[name] = ref
+	}
+	return pins, nil
+}
+
+func (m *MockDbAdapter) GetPackageRevision(repoName string) (entity.PackageRevision, error) {
+	return m.Revisions[repoName], nil
+}
+
+func (m *MockDbAdapter) GetPackageStatus(repoName string, fetch bool) (entity.PackageStatus, error) {
+	return m.Statuses[repoName], nil
+}
+
+func (m *MockDbAdapter) GetPackagesStatus(repoNames []string, fetch bool) ([]entity.PackageStatus, error) {
+	statuses := []entity.PackageStatus{}
+	for _, name := range repoNames {
+		statuses = append(statuses, m.Statuses[name])
+	}
+	return statuses, nil
+}
+
+func (m *MockDbAdapter) ListPackageCommits(repoName string, limit int) ([]entity.PackageCommit, error) {
+	commits := m.Commits[repoName]
+	return commits[:min(limit, len(commits))], nil
+}
+
+func (m *MockDbAdapter) ResolvePackageRef(repoName string, ref string) (string, error) {
+	for _, commit := range m.Commits[repoName] {
+		if strings.HasPrefix(commit.Hash, ref) {
+			return commit.Hash, nil
+		}
+	}
+	return "", fmt.Errorf("ref '%s' not found", ref)
+}
+
+func (m *MockDbAdapter) GetPackageContent(repoName string, commit string) (entity.PackageContent, error) {
+	return m.Contents[repoName][commit], nil
+}
+
+func (m *MockDbAdapter) RestorePackageCommit(repoName string, commit string, message string) error {
+	if slices.Contains(m.LocalChanges, repoName) {
+		return errorss.ErrLocalChanges
+	}
+	if m.Restored == nil {
+		m.Restored = map[string]string{}
+		m.RestoreMessages = map[string]string{}
+	}
+	m.Restored[repoName] = commit
+	m.RestoreMessages[repoName] = message
+	// The package now holds the aliases and exports of the restored commit
+	if content, ok := m.Contents[repoName][commit]; ok {
+		for index := range m.Packages {
+			if m.Packages[index].Name == repoName {
+				m.Packages[index].Aliases = content.Aliases
+				m.Packages[index].Exports = content.Exports
+			}
+		}
+	}
+	return nil
+}
+
+func (m *MockDbAdapter) CheckoutPackageCommit(repoName string, commit string, force bool) error {
+	if !force && slices.Contains(m.LocalChanges, repoName) {
+		return errorss.ErrLocalChanges
+	}
+	if m.Revisions == nil {
+		m.Revisions = map[string]entity.PackageRevision{}
+	}
+	revision := m.Revisions[repoName]
+	revision.Commit = commit
+	m.Revisions[repoName] = revision
+	return nil
+}
+
+func (m *MockDbAdapter) ListPackageTags(repoName string) ([]string, error) {
+	return m.Tags[repoName], nil
+}
+
 func (m *MockDbAdapter) EditPackage(repoName string) error {
 	// Mock implementation does nothing
 	return nil
@@ -0,0 +1,44 @@
+package port
+
+// EnvironmentPort describes the machine duh is running on, to evaluate entry conditions
+type EnvironmentPort interface {
+	// Operating system, as reported by Go (linux, darwin, windows...)
+	OS() string
+
+	// Hostname of the machine
+	Hostname() (string, error)
+
+	// Tells if a command is available in the PATH
+	HasCommand(name string) bool
+
+	// Value of an environment variable, empty when it is not set
+	Getenv(name string) string
+}
+
+type MockEnvironmentPort struct {
+	OperatingSystem string
+	Host            string
+	Commands        []string
+	Env             map[string]string
+}
+
+func (m *MockEnvironmentPort) Getenv(name string) string {
+	return m.Env[name]
+}
+
+func (m *MockEnvironmentPort) OS() string {
+	return m.OperatingSystem
+}
+
+func (m *MockEnvironmentPort) Hostname() (string, error) {
+	return m.Host, nil
+}
+
+func (m *MockEnvironmentPort) HasCommand(name string) bool {
+	for _, command := range m.Commands {
+		if command == name {
+			return true
+		}
+	}
+	return false
+}
@@ -1,6 +1,9 @@
 package port
 
-import "duh/internal/domain/entity"
+import (
+	"duh/internal/domain/entity"
+	"fmt"
+)
 
 type FunctionPort interface {
 	// Returns the scripts from activated repositories
@@ -13,15 +16,38 @@ type FunctionPort interface {
 	// Returns internal scripts embedded in the binary
 	GetInternalScripts() ([]entity.Script, error)
 
+	// Returns the top_level_code policy applied to the scripts of the packages
+	GetTopLevelCodePreference() (entity.TopLevelCodePreference, error)
+
 	// Creates a script by its name
 	CreateScriptByName(scriptName string) (string, error)
+
+	// Writes script.DataToInject to <script.Name>.sh in the functions directory of a package
+	// Returns the path of the script, and an error if it already exists
+	WriteScript(repoName string, script entity.Script) (string, error)
+
+	// Reads the aliases, exports and functions of a shell configuration file like .bashrc
+	ParseDotfile(path string) (entity.Dotfile, error)
+
+	// Runs the static checks of a script file, the ones only needing the script itself
+	LintScript(script entity.Script) ([]entity.LintFinding, error)
 }
 
 type DummyFunctionRepository struct {
 	Scripts          []entity.Script
 	ActivatedScripts []entity.Script
 	InternalScripts  []entity.Script
-	err              error
+	// Dotfiles served by ParseDotfile, by path
+	Dotfiles map[string]entity.Dotfile
+	// Content of the scripts written by WriteScript, by path
+	Written map[string]string
+	// Findings returned by LintScript, by path
+	Findings map[string][]entity.LintFinding
+	// Returned by GetTopLevelCodePreference
+	TopLevelCode entity.TopLevelCodePreference
+	// Error returned by GetAllScripts, when set
+	AllScriptsErr error
+	err           error
 }
 
 func (d *DummyFunctionRepository) GetActivatedScripts() ([]entity.Script, error) {
@@ -29,6 +55,9 @@ func (d *DummyFunctionRepository) GetActivatedScripts() ([]entity.Script, error)
 }
 
 func (d *DummyFunctionRepository) GetAllScripts() ([]entity.Script, error) {
+	if d.AllScriptsErr != nil {
+		return nil, d.AllScriptsErr
+	}
 	return d.Scripts, d.err
 }
 
@@ -36,9 +65,37 @@ func (d *DummyFunctionRepository) GetInternalScripts() ([]entity.Script, error)
 	return d.InternalScripts, d.err
 }
 
-func (d *DummyFunctionRepository) CreateScriptByName(scriptName string) (*entity.Script, error) {
-	script := &entity.Script{
-		Name: scriptName,
+func (d *DummyFunctionRepository) GetTopLevelCodePreference() (entity.TopLevelCodePreference, error) {
+	return d.TopLevelCode, d.err
+}
+
+func (d *DummyFunctionRepository) CreateScriptByName(scriptName string) (string, error) {
+	return "test/functions/" + scriptName + ".sh", d.err
+}
+
+func (d *DummyFunctionRepository) WriteScript(repoName string, script entity.Script) (string, error) {
+	path := repoName + "/functions/" + script.Name + ".sh"
+	if d.Written == nil {
+		d.Written = map[string]string{}
+	}
+	d.Written[path] = script.DataToInject
+	return path, d.err
+}
+
+func (d *DummyFunctionRepository) ParseDotfile(path string) (entity.Dotfile, error) {
+	dotfile, ok := d.Dotfiles[path]
+	if !ok {
+		return entity.Dotfile{}, fmt.Errorf("open %s: no such file or directory", path)
+	}
+	return dotfile, d.err
+}
+
+func (d *DummyFunctionRepository) LintScript(script entity.Script) ([]entity.LintFinding, error) {
+	findings := []entity.LintFinding{}
+	for _, finding := range d.Findings[script.PathToFile] {
+		finding.Package = script.Package
+		finding.File = script.PathToFile
+		findings = append(findings, finding)
 	}
-	return script, d.err
+	return findings, d.err
 }
@@ -0,0 +1,30 @@
+package port
+
+import (
+	"duh/internal/domain/entity"
+	"duh/internal/domain/errorss"
+)
+
+type LockPort interface {
+	// Read the lockfile, returns a NotFoundError when there is none
+	ReadLock() (*entity.Lock, error)
+
+	// Write the lockfile, replacing the previous one
+	WriteLock(lock entity.Lock) error
+}
+
+type MockLockPort struct {
+	Lock *entity.Lock
+}
+
+func (m *MockLockPort) ReadLock() (*entity.Lock, error) {
+	if m.Lock == nil {
+		return nil, &errorss.NotFoundError{Resource: "lockfile", ID: "mock"}
+	}
+	return m.Lock, nil
+}
+
+func (m *MockLockPort) WriteLock(lock entity.Lock) error {
+	m.Lock = &lock
+	return nil
+}
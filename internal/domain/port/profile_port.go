@@ -0,0 +1,54 @@
+package port
+
+import "duh/internal/domain/entity"
+
+type ProfilePort interface {
+	// List every profile, the default profile first
+	ListProfiles() ([]entity.Profile, error)
+
+	// Returns the profile used on this machine, and how it was selected, as resolved by the ProfileResolver
+	GetActiveProfile() (entity.ActiveProfile, error)
+
+	// Create a profile starting with the packages of the active profile
+	CreateProfile(name string, hostnames []string) error
+
+	// Select the profile to use on the machine with this hostname
+	UseProfile(name string, hostname string) error
+}
+
+// ProfileResolver selects the active profile, for the adapters storing the packages of each profile
+type ProfileResolver interface {
+	// Returns the profile used on this machine among profiles, given the profile chosen with duh profile use
+	// on each hostname. Resolution order: DUH_PROFILE, duh profile use, hostname rules, default profile
+	ResolveActiveProfile(profiles []entity.Profile, hostProfiles map[string]string) entity.ActiveProfile
+}
+
+type MockProfilePort struct {
+	Profiles     []entity.Profile
+	Active       entity.ActiveProfile
+	HostProfiles map[string]string
+}
+
+func (m *MockProfilePort) ListProfiles() ([]entity.Profile, error) {
+	return m.Profiles, nil
+}
+
+func (m *MockProfilePort) GetActiveProfile() (entity.ActiveProfile, error) {
+	if m.Active.Name == "" {
+		return entity.ActiveProfile{Name: entity.DefaultProfileName, Reason: entity.ProfileSelectedByDefault}, nil
+	}
+	return m.Active, nil
+}
+
+func (m *MockProfilePort) CreateProfile(name string, hostnames []string) error {
+	m.Profiles = append(m.Profiles, entity.Profile{Name: name, Hostnames: hostnames})
+	return nil
+}
+
+func (m *MockProfilePort) UseProfile(name string, hostname string) error {
+	if m.HostProfiles == nil {
+		m.HostProfiles = map[string]string{}
+	}
+	m.HostProfiles[hostname] = name
+	return nil
+}
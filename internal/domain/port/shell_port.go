@@ -0,0 +1,48 @@
+package port
+
+import "duh/internal/domain/entity"
+
+// ShellRenderer renders duh entries with the syntax of a specific shell
+type ShellRenderer interface {
+	// Name of the shell handled by this renderer (bash, zsh, fish, sh)
+	Name() string
+
+	// Render an alias definition
+	Alias(name, value string) string
+
+	// Render an exported environment variable, possibly on several lines
+	Export(name string, export entity.Export) string
+
+	// Render the duh_reload alias, re-evaluating duh inject in the current shell
+	ReloadAlias() string
+
+	// Render a function script, or a comment explaining why it is not injected
+	Script(script entity.Script) string
+
+	// Render a stub per function of a script, sourcing script.PathToFile on first call
+	// and calling the real function, or a comment explaining why it is not injected
+	LazyScript(script entity.Script) string
+
+	// Render a comment line
+	Comment(text string) string
+
+	// Render the tab completion of a function, from the values listed by its documented arguments.
+	// Empty when the function lists none, or when the shell has no programmable completion.
+	Completion(function entity.Function) string
+
+	// Render git configuration entries as GIT_CONFIG_KEY_<n> and GIT_CONFIG_VALUE_<n> environment variables,
+	// numbered after the entries already set by GIT_CONFIG_COUNT
+	GitConfig(entries []entity.GitConfigEntry) string
+
+	// Render a command printing a warning on the standard error, when the injection is evaluated
+	Warning(text string) string
+}
+
+type ShellPort interface {
+	// Returns the renderer of the given shell
+	// An empty shell name means the shell is detected from the environment
+	GetRenderer(shellName string) (ShellRenderer, error)
+
+	// List the names of the supported shells
+	SupportedShells() []string
+}
@@ -1,9 +1,11 @@
 package service
 
 import (
+	"duh/internal/domain/entity"
 	"duh/internal/domain/errorss"
 	"duh/internal/domain/port"
 	"maps"
+	"slices"
 )
 
 type AliasService struct {
@@ -47,22 +49,26 @@ func (a *AliasService) UnsetAlias(aliasName string) error {
 	return a.dbPort.UpsertPackage(*repo)
 }
 
-// GetMergedAliases aggregates aliases from all enabled repositories
-func (a *AliasService) GetMergedAliases() (map[string]string, error) {
+// GetMergedAliases aggregates aliases from all enabled repositories, sorted by name
+func (a *AliasService) GetMergedAliases() ([]entity.MergedAlias, error) {
 	repos, err := a.dbPort.GetEnabledPackages()
 	if err != nil {
 		return nil, err
 	}
 
 	// Business logic: merge aliases with priority (later repos override earlier ones)
-	entries := make(map[string]string)
+	entries := make(map[string]entity.MergedAlias)
 	for _, repo := range repos {
-		if repo.Aliases != nil {
-			maps.Copy(entries, repo.Aliases)
+		for name, value := range repo.Aliases {
+			entries[name] = entity.MergedAlias{Name: name, Value: value, Source: repo.Source()}
 		}
 	}
 
-	return entries, nil
+	merged := []entity.MergedAlias{}
+	for _, name := range slices.Sorted(maps.Keys(entries)) {
+		merged = append(merged, entries[name])
+	}
+	return merged, nil
 }
 
 // ValidateAliasName checks if an alias name is valid according to business rules
@@ -0,0 +1,28 @@
+package service
+
+import (
+	"duh/internal/domain/entity"
+	"duh/internal/domain/port"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_GetMergedAliases(t *testing.T) {
+	dbPort := &port.MockDbAdapter{
+		Packages: []entity.Package{
+			{Name: "team", DbFilePath: "/pkgs/team/db.toml", Aliases: map[string]string{"gs": "git status -sb", "deploy": "make deploy"}},
+			{Name: "local", DbFilePath: "/pkgs/local/db.toml", Aliases: map[string]string{"gs": "git status"}},
+			{Name: "disabled", DbFilePath: "/pkgs/disabled/db.toml", Aliases: map[string]string{"gs": "git stash"}},
+		},
+		Enabled: []string{"team", "local"},
+	}
+
+	aliases, err := NewAliasService(dbPort).GetMergedAliases()
+	assert.NoError(t, err)
+	// Sorted by name, each from the last package defining it
+	assert.Equal(t, []entity.MergedAlias{
+		{Name: "deploy", Value: "make deploy", Source: entity.Source{Package: "team", File: "/pkgs/team/db.toml"}},
+		{Name: "gs", Value: "git status", Source: entity.Source{Package: "local", File: "/pkgs/local/db.toml"}},
+	}, aliases)
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"duh/internal/domain/entity"
+	"duh/internal/domain/port"
+	"fmt"
+	"path"
+)
+
+// Friendly names accepted for the os condition
+var osAliases = map[string]string{
+	"macos": "darwin",
+	"osx":   "darwin",
+}
+
+type ConditionService struct {
+	environmentPort port.EnvironmentPort
+}
+
+func NewConditionService(environmentPort port.EnvironmentPort) *ConditionService {
+	return &ConditionService{
+		environmentPort: environmentPort,
+	}
+}
+
+// UnmetReason tells why a condition does not match the current environment, when rendering for the given shell.
+// It returns an empty string when the condition is met.
+func (c *ConditionService) UnmetReason(condition entity.Condition, shell string) string {
+	if condition.OS != "" {
+		expected := condition.OS
+		if alias, ok := osAliases[expected]; ok {
+			expected = alias
+		}
+		if current := c.environmentPort.OS(); current != expected {
+			return fmt.Sprintf("os is '%s', not '%s'", current, condition.OS)
+		}
+	}
+	if condition.Hostname != "" {
+		hostname, err := c.environmentPort.Hostname()
+		if err != nil {
+			return fmt.Sprintf("could not read hostname: %v", err)
+		}
+		if matched, _ := path.Match(condition.Hostname, hostname); !matched {
+			return fmt.Sprintf("hostname '%s' does not match '%s'", hostname, condition.Hostname)
+		}
+	}
+	if condition.Shell != "" && condition.Shell != shell {
+		return fmt.Sprintf("shell is '%s', not '%s'", shell, condition.Shell)
+	}
+	if condition.IfCommand != "" && !c.environmentPort.HasCommand(condition.IfCommand) {
+		return fmt.Sprintf("command '%s' not found", condition.IfCommand)
+	}
+	return ""
+}
+
+// MissingCommands returns the commands not available in the PATH, in the given order
+func (c *ConditionService) MissingCommands(commands []string) []string {
+	missing := []string{}
+	for _, command := range commands {
+		if !c.environmentPort.HasCommand(command) {
+			missing = append(missing, command)
+		}
+	}
+	return missing
+}
+
+// CommandPresence tells, for each command named by an if_command condition, whether it is in the PATH
+func (c *ConditionService) CommandPresence(conditions []entity.Condition) map[string]bool {
+	presence := map[string]bool{}
+	for _, condition := range conditions {
+		if condition.IfCommand != "" {
+			presence[condition.IfCommand] = c.environmentPort.HasCommand(condition.IfCommand)
+		}
+	}
+	return presence
+}
+
+// CommandPresenceUnchanged tells if each command is still found, or still missing, as recorded by CommandPresence
+func (c *ConditionService) CommandPresenceUnchanged(presence map[string]bool) bool {
+	for command, found := range presence {
+		if c.environmentPort.HasCommand(command) != found {
+			return false
+		}
+	}
+	return true
+}
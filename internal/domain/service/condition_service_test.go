@@ -0,0 +1,47 @@
+package service
+
+import (
+	"duh/internal/domain/entity"
+	"duh/internal/domain/port"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_UnmetReason(t *testing.T) {
+	conditionService := NewConditionService(&port.MockEnvironmentPort{
+		OperatingSystem: "darwin",
+		Host:            "build-42",
+		Commands:        []string{"kubectl"},
+	})
+
+	tests := []struct {
+		name      string
+		condition entity.Condition
+		expected  string
+	}{
+		{"empty condition", entity.Condition{}, ""},
+		{"matching os", entity.Condition{OS: "darwin"}, ""},
+		{"matching os alias", entity.Condition{OS: "macos"}, ""},
+		{"other os", entity.Condition{OS: "linux"}, "os is 'darwin', not 'linux'"},
+		{"matching hostname pattern", entity.Condition{Hostname: "build-*"}, ""},
+		{"other hostname", entity.Condition{Hostname: "laptop"}, "hostname 'build-42' does not match 'laptop'"},
+		{"matching shell", entity.Condition{Shell: "zsh"}, ""},
+		{"other shell", entity.Condition{Shell: "fish"}, "shell is 'zsh', not 'fish'"},
+		{"available command", entity.Condition{IfCommand: "kubectl"}, ""},
+		{"missing command", entity.Condition{IfCommand: "helm"}, "command 'helm' not found"},
+		{"every field must match", entity.Condition{OS: "darwin", IfCommand: "helm"}, "command 'helm' not found"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, conditionService.UnmetReason(test.condition, "zsh"))
+		})
+	}
+}
+
+func Test_MissingCommands(t *testing.T) {
+	conditionService := NewConditionService(&port.MockEnvironmentPort{Commands: []string{"git", "curl"}})
+
+	assert.Equal(t, []string{"fzf", "jq"}, conditionService.MissingCommands([]string{"fzf", "git", "jq", "curl"}))
+	assert.Empty(t, conditionService.MissingCommands(nil))
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"duh/internal/domain/entity"
+	"duh/internal/domain/port"
+	"maps"
+	"slices"
+)
+
+// Name used for the scripts embedded in the duh binary
+const CorePackageName = "duh core"
+
+type ConflictService struct {
+	dbPort       port.DbPort
+	functionPort port.FunctionPort
+}
+
+func NewConflictService(dbPort port.DbPort, functionPort port.FunctionPort) *ConflictService {
+	return &ConflictService{
+		dbPort:       dbPort,
+		functionPort: functionPort,
+	}
+}
+
+// definition is a name declared by a package
+type definition struct {
+	name        string
+	packageName string
+}
+
+// FindConflicts lists every alias, export, function and git alias defined by more than one
+// enabled package, as well as aliases sharing their name with a function.
+//
+// Business rule: packages are injected in activation order, so the last definition wins.
+// Git includes are added in the same order, so the same rule applies to git aliases.
+// An alias always shadows a function with the same name in an interactive shell.
+// Exports only prepending or appending entries to a list like PATH extend each other, so they never conflict.
+func (c *ConflictService) FindConflicts() ([]entity.Conflict, error) {
+	packages, err := c.dbPort.GetEnabledPackages()
+	if err != nil {
+		return nil, err
+	}
+	scripts, err := c.functionPort.GetInternalScripts()
+	if err != nil {
+		return nil, err
+	}
+	activatedScripts, err := c.functionPort.GetActivatedScripts()
+	if err != nil {
+		return nil, err
+	}
+	scripts = append(scripts, activatedScripts...)
+
+	aliases := definitionsOf(packages, func(p entity.Package) map[string]string { return p.Aliases })
+	exports := definitionsOf(packages, entity.Package.ValueExports)
+	gitAliases := definitionsOf(packages, func(p entity.Package) map[string]string { return p.GitAliases })
+	functions := []definition{}
+	for _, script := range scripts {
+		packageName := script.Package
+		if packageName == "" {
+			packageName = CorePackageName
+		}
+		for _, fn := range script.Functions {
+			functions = append(functions, definition{name: fn.Name, packageName: packageName})
+		}
+	}
+
+	conflicts := []entity.Conflict{}
+	conflicts = append(conflicts, findDuplicates(entity.ConflictAlias, aliases)...)
+	conflicts = append(conflicts, findDuplicates(entity.ConflictExport, exports)...)
+	conflicts = append(conflicts, findDuplicates(entity.ConflictFunction, functions)...)
+	conflicts = append(conflicts, findDuplicates(entity.ConflictGitAlias, gitAliases)...)
+	conflicts = append(conflicts, findAliasesShadowingFunctions(aliases, functions)...)
+	return conflicts, nil
+}
+
+func definitionsOf[V any](packages []entity.Package, entries func(entity.Package) map[string]V) []definition {
+	definitions := []definition{}
+	for _, pkg := range packages {
+		for _, key := range slices.Sorted(maps.Keys(entries(pkg))) {
+			definitions = append(definitions, definition{name: key, packageName: pkg.Name})
+		}
+	}
+	return definitions
+}
+
+// findDuplicates groups definitions by name, keeping their order, and reports the names defined by more than one package.
+// A package defining a name twice only overrides itself.
+func findDuplicates(kind string, definitions []definition) []entity.Conflict {
+	packagesByName := map[string][]string{}
+	for _, def := range definitions {
+		packagesByName[def.name] = append(packagesByName[def.name], def.packageName)
+	}
+
+	conflicts := []entity.Conflict{}
+	for _, name := range slices.Sorted(maps.Keys(packagesByName)) {
+		packages := uniquePackages(packagesByName[name])
+		if len(packages) < 2 {
+			continue
+		}
+		conflicts = append(conflicts, entity.Conflict{
+			Kind:     kind,
+			Name:     name,
+			Packages: packages,
+			Winner:   packages[len(packages)-1],
+		})
+	}
+	return conflicts
+}
+
+func findAliasesShadowingFunctions(aliases []definition, functions []definition) []entity.Conflict {
+	functionPackages := map[string][]string{}
+	for _, fn := range functions {
+		functionPackages[fn.name] = append(functionPackages[fn.name], fn.packageName)
+	}
+	aliasPackages := map[string][]string{}
+	for _, alias := range aliases {
+		if _, ok := functionPackages[alias.name]; ok {
+			aliasPackages[alias.name] = append(aliasPackages[alias.name], alias.packageName)
+		}
+	}
+
+	conflicts := []entity.Conflict{}
+	for _, name := range slices.Sorted(maps.Keys(aliasPackages)) {
+		winners := aliasPackages[name]
+		conflicts = append(conflicts, entity.Conflict{
+			Kind:     entity.ConflictAliasFunction,
+			Name:     name,
+			Packages: uniquePackages(append(slices.Clone(functionPackages[name]), winners...)),
+			Winner:   winners[len(winners)-1],
+		})
+	}
+	return conflicts
+}
+
+// uniquePackages removes the repeated package names, as a package may define a name more than once,
+// e.g. both as an alias and a function. A package is kept at its last definition, so the last package still wins.
+func uniquePackages(packages []string) []string {
+	unique := []string{}
+	for _, packageName := range packages {
+		unique = slices.DeleteFunc(unique, func(name string) bool { return name == packageName })
+		unique = append(unique, packageName)
+	}
+	return unique
+}
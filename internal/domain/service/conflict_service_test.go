@@ -0,0 +1,88 @@
+package service
+
+import (
+	"duh/internal/domain/entity"
+	"duh/internal/domain/port"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FindConflicts(t *testing.T) {
+	dbPort := &port.MockDbAdapter{
+		Packages: []entity.Package{
+			{
+				Name:       "team",
+				Aliases:    map[string]string{"gs": "git status -sb", "deploy": "make deploy"},
+				Exports:    map[string]entity.Export{"EDITOR": {Value: "nano"}, "PATH": {Prepend: []string{"/team/bin"}}},
+				GitAliases: map[string]string{"st": "status"},
+			},
+			{
+				Name:       "local",
+				Aliases:    map[string]string{"gs": "git status"},
+				Exports:    map[string]entity.Export{"EDITOR": {Value: "vim"}, "PAGER": {Value: "less"}, "PATH": {Append: []string{"/local/bin"}}},
+				GitAliases: map[string]string{"st": "status -sb"},
+			},
+			{
+				Name:    "disabled",
+				Aliases: map[string]string{"gs": "git stash"},
+			},
+		},
+		Enabled: []string{"team", "local"},
+	}
+	functionPort := &port.DummyFunctionRepository{
+		InternalScripts: []entity.Script{
+			{Name: "require", Functions: []entity.Function{{Name: "require"}}},
+		},
+		ActivatedScripts: []entity.Script{
+			{Name: "deploy", Package: "team", Functions: []entity.Function{{Name: "deploy"}}},
+			{Name: "utils", Package: "local", Functions: []entity.Function{{Name: "require"}}},
+		},
+	}
+
+	conflicts, err := NewConflictService(dbPort, functionPort).FindConflicts()
+	assert.NoError(t, err)
+	assert.Equal(t, []entity.Conflict{
+		{Kind: entity.ConflictAlias, Name: "gs", Packages: []string{"team", "local"}, Winner: "local"},
+		{Kind: entity.ConflictExport, Name: "EDITOR", Packages: []string{"team", "local"}, Winner: "local"},
+		{Kind: entity.ConflictFunction, Name: "require", Packages: []string{CorePackageName, "local"}, Winner: "local"},
+		{Kind: entity.ConflictGitAlias, Name: "st", Packages: []string{"team", "local"}, Winner: "local"},
+		{Kind: entity.ConflictAliasFunction, Name: "deploy", Packages: []string{"team"}, Winner: "team"},
+	}, conflicts)
+}
+
+func Test_FindConflicts_NameDefinedTwiceInOnePackage(t *testing.T) {
+	dbPort := &port.MockDbAdapter{
+		Packages: []entity.Package{{Name: "team"}, {Name: "local"}},
+		Enabled:  []string{"team", "local"},
+	}
+	functionPort := &port.DummyFunctionRepository{
+		ActivatedScripts: []entity.Script{
+			{Name: "build", Package: "team", Functions: []entity.Function{{Name: "foo"}, {Name: "bar"}}},
+			{Name: "helpers", Package: "team", Functions: []entity.Function{{Name: "foo"}}},
+			{Name: "utils", Package: "local", Functions: []entity.Function{{Name: "bar"}}},
+			{Name: "override", Package: "team", Functions: []entity.Function{{Name: "bar"}}},
+		},
+	}
+
+	conflicts, err := NewConflictService(dbPort, functionPort).FindConflicts()
+	assert.NoError(t, err)
+	// foo is only defined by team, and team defines bar again after local, so team wins
+	assert.Equal(t, []entity.Conflict{
+		{Kind: entity.ConflictFunction, Name: "bar", Packages: []string{"local", "team"}, Winner: "team"},
+	}, conflicts)
+}
+
+func Test_FindConflicts_NoConflicts(t *testing.T) {
+	dbPort := &port.MockDbAdapter{
+		Packages: []entity.Package{
+			{Name: "team", Aliases: map[string]string{"k": "kubectl"}},
+			{Name: "local", Aliases: map[string]string{"ll": "ls -la"}},
+		},
+		Enabled: []string{"team", "local"},
+	}
+
+	conflicts, err := NewConflictService(dbPort, &port.DummyFunctionRepository{}).FindConflicts()
+	assert.NoError(t, err)
+	assert.Empty(t, conflicts)
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"duh/internal/domain/entity"
+	"duh/internal/domain/port"
+	"maps"
+	"slices"
+)
+
+type ExportsService struct {
+	dbPort port.DbPort
+}
+
+func NewExportsService(dbPort port.DbPort) *ExportsService {
+	return &ExportsService{
+		dbPort: dbPort,
+	}
+}
+
+// GetMergedExports aggregates exports from all enabled packages, sorted by name:
+// values are overridden by the last package, while list entries add up
+func (e *ExportsService) GetMergedExports() ([]entity.MergedExport, error) {
+	repos, err := e.dbPort.GetEnabledPackages()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make(map[string]entity.MergedExport)
+	for _, repo := range repos {
+		for name, export := range repo.Exports {
+			entry := entries[name]
+			entry.Name = name
+			entry.Export = entry.Export.Then(export)
+			// A new value hides the packages the previous one came from
+			if export.SetsValue() {
+				entry.Sources = nil
+			}
+			entry.Sources = append(entry.Sources, repo.Source())
+			entries[name] = entry
+		}
+	}
+
+	merged := []entity.MergedExport{}
+	for _, name := range slices.Sorted(maps.Keys(entries)) {
+		merged = append(merged, entries[name])
+	}
+	return merged, nil
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"duh/internal/domain/entity"
+	"duh/internal/domain/errorss"
+	"duh/internal/domain/port"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+)
+
+type ImportService struct {
+	dbPort       port.DbPort
+	functionPort port.FunctionPort
+}
+
+func NewImportService(dbPort port.DbPort, functionPort port.FunctionPort) *ImportService {
+	return &ImportService{
+		dbPort:       dbPort,
+		functionPort: functionPort,
+	}
+}
+
+// ImportDotfile adds the aliases, exports and functions of a shell configuration file to a package,
+// the default one when packageName is empty. Each function gets its own script in the package.
+//
+// Business rule: the package keeps its own definitions, the ones it already defines differently
+// are skipped and reported, so importing the same file twice changes nothing.
+// Exports extending a list like PATH on both sides are combined instead.
+// With dryRun, the outcome is computed but nothing is written.
+func (i *ImportService) ImportDotfile(path string, packageName string, dryRun bool) (entity.DotfileImport, error) {
+	pkg, err := i.targetPackage(packageName)
+	if err != nil {
+		return entity.DotfileImport{}, err
+	}
+	dotfile, err := i.functionPort.ParseDotfile(path)
+	if err != nil {
+		return entity.DotfileImport{}, err
+	}
+
+	result := entity.DotfileImport{
+		Package:      pkg.Name,
+		Aliases:      map[string]string{},
+		Exports:      map[string]entity.Export{},
+		Scripts:      []entity.Script{},
+		Skipped:      []string{},
+		Unclassified: dotfile.Unclassified,
+	}
+	if pkg.Aliases == nil {
+		pkg.Aliases = map[string]string{}
+	}
+	if pkg.Exports == nil {
+		pkg.Exports = map[string]entity.Export{}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(dotfile.Aliases)) {
+		value := dotfile.Aliases[name]
+		existing, defined := pkg.Aliases[name]
+		switch {
+		case defined && existing == value:
+			continue
+		case defined:
+			result.Skipped = append(result.Skipped, fmt.Sprintf("alias '%s' already defined as '%s'", name, existing))
+		default:
+			pkg.Aliases[name] = value
+			result.Aliases[name] = value
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(dotfile.Exports)) {
+		export := dotfile.Exports[name]
+		existing, defined := pkg.Exports[name]
+		extendsList := !existing.SetsValue() && !export.SetsValue()
+		if defined && extendsList {
+			export = existing.Then(export)
+		}
+		switch {
+		case defined && reflect.DeepEqual(existing, export):
+			continue
+		case defined && !extendsList:
+			result.Skipped = append(result.Skipped, fmt.Sprintf("export '%s' already defined as '%s'", name, existing))
+		default:
+			pkg.Exports[name] = export
+			result.Exports[name] = export
+		}
+	}
+
+	definedIn, err := i.packageFunctions(pkg.Name)
+	if err != nil {
+		return entity.DotfileImport{}, err
+	}
+	for _, script := range dotfile.Functions {
+		if existing, defined := definedIn[script.Name]; defined {
+			if existing.DataToInject != script.DataToInject {
+				result.Skipped = append(result.Skipped, fmt.Sprintf("function '%s' already defined in %s", script.Name, existing.PathToFile))
+			}
+			continue
+		}
+		result.Scripts = append(result.Scripts, script)
+	}
+
+	if dryRun {
+		return result, nil
+	}
+	if len(result.Aliases) > 0 || len(result.Exports) > 0 {
+		if err := i.dbPort.UpsertPackage(*pkg); err != nil {
+			return entity.DotfileImport{}, err
+		}
+	}
+	for index, script := range result.Scripts {
+		scriptPath, err := i.functionPort.WriteScript(pkg.Name, script)
+		if err != nil {
+			return entity.DotfileImport{}, err
+		}
+		result.Scripts[index].PathToFile = scriptPath
+		result.Scripts[index].Package = pkg.Name
+	}
+	return result, nil
+}
+
+func (i *ImportService) targetPackage(packageName string) (*entity.Package, error) {
+	if packageName == "" {
+		return i.dbPort.GetDefaultPackage()
+	}
+	packages, err := i.dbPort.GetAllPackages()
+	if err != nil {
+		return nil, err
+	}
+	for _, pkg := range packages {
+		if pkg.Name == packageName {
+			return &pkg, nil
+		}
+	}
+	return nil, &errorss.NotFoundError{
+		Resource: "package",
+		ID:       packageName,
+	}
+}
+
+// packageFunctions returns the script defining each function and script name of a package.
+// A script name is reserved even without a function of the same name, as imported scripts are named after their function.
+func (i *ImportService) packageFunctions(packageName string) (map[string]entity.Script, error) {
+	scripts, err := i.functionPort.GetAllScripts()
+	if err != nil {
+		return nil, err
+	}
+	definedIn := map[string]entity.Script{}
+	for _, script := range scripts {
+		if script.Package != packageName {
+			continue
+		}
+		definedIn[script.Name] = script
+		for _, function := range script.Functions {
+			definedIn[function.Name] = script
+		}
+	}
+	return definedIn, nil
+}
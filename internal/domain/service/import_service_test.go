@@ -0,0 +1,100 @@
+package service
+
+import (
+	"duh/internal/domain/entity"
+	"duh/internal/domain/errorss"
+	"duh/internal/domain/port"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func dotfileFixture() map[string]entity.Dotfile {
+	return map[string]entity.Dotfile{
+		"/home/me/.bashrc": {
+			Aliases: map[string]string{"ll": "ls -la", "gs": "git status", "k": "kubectl"},
+			Exports: map[string]entity.Export{
+				"EDITOR": {Value: "vim"},
+				"PAGER":  {Value: "less"},
+				"PATH":   {Prepend: []string{"$HOME/bin"}},
+			},
+			Functions: []entity.Script{
+				{Name: "mkcd", DataToInject: "mkcd() { mkdir -p \"$1\" && cd \"$1\"; }\n", Functions: []entity.Function{{Name: "mkcd"}}},
+				{Name: "deploy", DataToInject: "deploy() { make deploy; }\n", Functions: []entity.Function{{Name: "deploy"}}},
+				{Name: "k8s", DataToInject: "k8s() { kubectl \"$@\"; }\n", Functions: []entity.Function{{Name: "k8s"}}},
+			},
+			Unclassified: []entity.Warning{{Line: 3, Details: "HISTSIZE=1000"}},
+		},
+	}
+}
+
+func Test_ImportDotfile(t *testing.T) {
+	local := entity.Package{
+		Name:    "local",
+		Aliases: map[string]string{"gs": "git status -sb", "k": "kubectl"},
+		Exports: map[string]entity.Export{"EDITOR": {Value: "nano"}, "PATH": {Append: []string{"/opt/bin"}}},
+	}
+	dbPort := &port.MockDbAdapter{Packages: []entity.Package{local}, DefaultRepo: local}
+	functionPort := &port.DummyFunctionRepository{
+		Dotfiles: dotfileFixture(),
+		Scripts: []entity.Script{
+			{Name: "tools", Package: "local", PathToFile: "local/functions/tools.sh", Functions: []entity.Function{{Name: "deploy"}}},
+			{Name: "mkcd", Package: "other", PathToFile: "other/functions/mkcd.sh", Functions: []entity.Function{{Name: "mkcd"}}},
+			// Imported before, with the same content
+			{Name: "k8s", Package: "local", PathToFile: "local/functions/k8s.sh", DataToInject: "k8s() { kubectl \"$@\"; }\n", Functions: []entity.Function{{Name: "k8s"}}},
+		},
+	}
+
+	result, err := NewImportService(dbPort, functionPort).ImportDotfile("/home/me/.bashrc", "", false)
+	assert.NoError(t, err)
+	assert.Equal(t, "local", result.Package)
+	assert.Equal(t, map[string]string{"ll": "ls -la"}, result.Aliases)
+	assert.Equal(t, map[string]entity.Export{
+		"PAGER": {Value: "less"},
+		"PATH":  {Prepend: []string{"$HOME/bin"}, Append: []string{"/opt/bin"}},
+	}, result.Exports)
+	assert.Equal(t, []string{
+		"alias 'gs' already defined as 'git status -sb'",
+		"export 'EDITOR' already defined as 'nano'",
+		"function 'deploy' already defined in local/functions/tools.sh",
+	}, result.Skipped)
+	assert.Equal(t, []entity.Warning{{Line: 3, Details: "HISTSIZE=1000"}}, result.Unclassified)
+
+	assert.Len(t, result.Scripts, 1)
+	assert.Equal(t, "local/functions/mkcd.sh", result.Scripts[0].PathToFile)
+	assert.Equal(t, map[string]string{"local/functions/mkcd.sh": "mkcd() { mkdir -p \"$1\" && cd \"$1\"; }\n"}, functionPort.Written)
+
+	imported := dbPort.Packages[0]
+	assert.Equal(t, map[string]string{"gs": "git status -sb", "k": "kubectl", "ll": "ls -la"}, imported.Aliases)
+	assert.Equal(t, entity.Export{Value: "nano"}, imported.Exports["EDITOR"])
+	assert.Equal(t, entity.Export{Prepend: []string{"$HOME/bin"}, Append: []string{"/opt/bin"}}, imported.Exports["PATH"])
+}
+
+func Test_ImportDotfile_DryRun(t *testing.T) {
+	dbPort := &port.MockDbAdapter{Packages: []entity.Package{{Name: "local"}, {Name: "work"}}}
+	functionPort := &port.DummyFunctionRepository{Dotfiles: dotfileFixture()}
+
+	result, err := NewImportService(dbPort, functionPort).ImportDotfile("/home/me/.bashrc", "work", true)
+	assert.NoError(t, err)
+	assert.Equal(t, "work", result.Package)
+	assert.Len(t, result.Aliases, 3)
+	assert.Len(t, result.Exports, 3)
+	assert.Len(t, result.Scripts, 3)
+	assert.Empty(t, result.Skipped)
+
+	// Nothing was written
+	assert.Nil(t, dbPort.Packages[1].Aliases)
+	assert.Empty(t, functionPort.Written)
+}
+
+func Test_ImportDotfile_Errors(t *testing.T) {
+	dbPort := &port.MockDbAdapter{Packages: []entity.Package{{Name: "local"}}, DefaultRepo: entity.Package{Name: "local"}}
+	functionPort := &port.DummyFunctionRepository{Dotfiles: dotfileFixture()}
+
+	_, err := NewImportService(dbPort, functionPort).ImportDotfile("/home/me/.bashrc", "unknown", false)
+	var notFoundErr *errorss.NotFoundError
+	assert.ErrorAs(t, err, &notFoundErr)
+
+	_, err = NewImportService(dbPort, functionPort).ImportDotfile("/home/me/.missing", "", false)
+	assert.Error(t, err)
+}
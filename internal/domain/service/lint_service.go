@@ -0,0 +1,115 @@
+package service
+
+import (
+	"cmp"
+	"duh/internal/domain/entity"
+	"duh/internal/domain/errorss"
+	"duh/internal/domain/port"
+	"fmt"
+	"slices"
+)
+
+// Commands found on most systems, a function named after one of them replaces it in the shell
+var commonCommands = []string{
+	"awk", "cat", "cd", "chmod", "chown", "cp", "curl", "cut", "date", "diff", "echo", "env", "export",
+	"find", "git", "grep", "head", "kill", "less", "ln", "ls", "make", "man", "mkdir", "mv", "printf",
+	"ps", "pwd", "read", "rm", "rmdir", "sed", "sort", "ssh", "sudo", "tail", "tar", "test", "time",
+	"touch", "tr", "type", "uniq", "vi", "vim", "wc", "which", "xargs",
+}
+
+type LintService struct {
+	dbPort       port.DbPort
+	functionPort port.FunctionPort
+}
+
+func NewLintService(dbPort port.DbPort, functionPort port.FunctionPort) *LintService {
+	return &LintService{
+		dbPort:       dbPort,
+		functionPort: functionPort,
+	}
+}
+
+// LintScripts checks the function scripts of every package, or of a single one when packageName is not empty.
+// Findings are sorted by file and position.
+//
+// Business rule: on top of the checks of each script, a function should not replace a common command,
+// nor share its name with an alias of another enabled package, which shadows it in an interactive shell.
+func (l *LintService) LintScripts(packageName string) ([]entity.LintFinding, error) {
+	if packageName != "" {
+		if err := l.validatePackageExists(packageName); err != nil {
+			return nil, err
+		}
+	}
+	scripts, err := l.functionPort.GetAllScripts()
+	if err != nil {
+		return nil, err
+	}
+	enabledPackages, err := l.dbPort.GetEnabledPackages()
+	if err != nil {
+		return nil, err
+	}
+
+	findings := []entity.LintFinding{}
+	for _, script := range scripts {
+		if packageName != "" && script.Package != packageName {
+			continue
+		}
+		scriptFindings, err := l.functionPort.LintScript(script)
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, scriptFindings...)
+		findings = append(findings, shadowingFindings(script, enabledPackages)...)
+	}
+
+	slices.SortStableFunc(findings, func(a, b entity.LintFinding) int {
+		return cmp.Or(cmp.Compare(a.File, b.File), cmp.Compare(a.Line, b.Line), cmp.Compare(a.Column, b.Column))
+	})
+	return findings, nil
+}
+
+// shadowingFindings reports the functions of a script replacing a common command, or hidden by an alias of another package
+func shadowingFindings(script entity.Script, packages []entity.Package) []entity.LintFinding {
+	findings := []entity.LintFinding{}
+	for _, function := range script.Functions {
+		finding := entity.LintFinding{
+			Package:  script.Package,
+			File:     script.PathToFile,
+			Line:     function.Line,
+			Column:   1,
+			Severity: entity.SeverityWarning,
+		}
+		if slices.Contains(commonCommands, function.Name) {
+			finding.Rule = entity.LintShadowedCommand
+			finding.Message = fmt.Sprintf("function '%s' replaces the %s command, use 'command %s' inside it to call the original", function.Name, function.Name, function.Name)
+			findings = append(findings, finding)
+		}
+		for _, pkg := range packages {
+			if pkg.Name == script.Package {
+				continue
+			}
+			if value, ok := pkg.Aliases[function.Name]; ok {
+				finding.Rule = entity.LintShadowedAlias
+				finding.Message = fmt.Sprintf("function '%s' is hidden by the alias '%s' of package '%s' in interactive shells", function.Name, value, pkg.Name)
+				findings = append(findings, finding)
+			}
+		}
+	}
+	return findings
+}
+
+func (l *LintService) validatePackageExists(packageName string) error {
+	packages, err := l.dbPort.GetAllPackages()
+	if err != nil {
+		return err
+	}
+	for _, pkg := range packages {
+		if pkg.Name == packageName {
+			return nil
+		}
+	}
+	return &errorss.NotFoundError{
+		Resource: "package",
+		ID:       packageName,
+	}
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"duh/internal/domain/entity"
+	"duh/internal/domain/errorss"
+	"duh/internal/domain/port"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLintService() *LintService {
+	dbPort := &port.MockDbAdapter{
+		Packages: []entity.Package{
+			{Name: "team", Aliases: map[string]string{"deploy": "make deploy"}},
+			{Name: "local", Aliases: map[string]string{"mkcd": "mkdir -p"}},
+			{Name: "disabled", Aliases: map[string]string{"up": "docker compose up"}},
+		},
+		Enabled: []string{"team", "local"},
+	}
+	functionPort := &port.DummyFunctionRepository{
+		Scripts: []entity.Script{
+			{Name: "tools", Package: "local", PathToFile: "local/functions/tools.sh", Functions: []entity.Function{
+				{Name: "mkcd", Line: 3}, {Name: "deploy", Line: 8}, {Name: "up", Line: 12}, {Name: "ls", Line: 15},
+			}},
+			{Name: "ci", Package: "team", PathToFile: "team/functions/ci.sh", Functions: []entity.Function{{Name: "ci", Line: 2}}},
+		},
+		Findings: map[string][]entity.LintFinding{
+			"local/functions/tools.sh": {
+				{Line: 9, Column: 5, Severity: entity.SeverityError, Rule: entity.LintExitInFunction},
+				{Line: 1, Column: 1, Severity: entity.SeverityWarning, Rule: entity.LintCodeOutsideFunction},
+			},
+			"team/functions/ci.sh": {{Line: 2, Column: 1, Severity: entity.SeverityInfo, Rule: entity.LintMissingDocumentation}},
+		},
+	}
+	return NewLintService(dbPort, functionPort)
+}
+
+func Test_LintScripts(t *testing.T) {
+	findings, err := newTestLintService().LintScripts("")
+	assert.NoError(t, err)
+
+	rules := []string{}
+	for _, finding := range findings {
+		rules = append(rules, finding.File+" "+finding.Rule)
+	}
+	// Sorted by file and position, the aliases of the package itself or of disabled packages do not count
+	assert.Equal(t, []string{
+		"local/functions/tools.sh code-outside-function",
+		"local/functions/tools.sh shadowed-alias",
+		"local/functions/tools.sh exit-in-function",
+		"local/functions/tools.sh shadowed-command",
+		"team/functions/ci.sh missing-documentation",
+	}, rules)
+	assert.Equal(t, entity.LintFinding{
+		Package:  "local",
+		File:     "local/functions/tools.sh",
+		Line:     8,
+		Column:   1,
+		Severity: entity.SeverityWarning,
+		Rule:     entity.LintShadowedAlias,
+		Message:  "function 'deploy' is hidden by the alias 'make deploy' of package 'team' in interactive shells",
+	}, findings[1])
+}
+
+func Test_LintScripts_Package(t *testing.T) {
+	service := newTestLintService()
+
+	findings, err := service.LintScripts("team")
+	assert.NoError(t, err)
+	assert.Len(t, findings, 1)
+	assert.Equal(t, "team", findings[0].Package)
+
+	_, err = service.LintScripts("unknown")
+	var notFoundErr *errorss.NotFoundError
+	assert.ErrorAs(t, err, &notFoundErr)
+}
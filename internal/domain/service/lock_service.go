@@ -0,0 +1,189 @@
+package service
+
+import (
+	"duh/internal/domain/entity"
+	"duh/internal/domain/errorss"
+	"duh/internal/domain/port"
+	"fmt"
+	"slices"
+)
+
+type LockService struct {
+	dbPort   port.DbPort
+	lockPort port.LockPort
+}
+
+func NewLockService(dbPort port.DbPort, lockPort port.LockPort) *LockService {
+	return &LockService{
+		dbPort:   dbPort,
+		lockPort: lockPort,
+	}
+}
+
+// Lock records the url, commit, enabled state and pin of every package in the lockfile.
+// Enabled packages are recorded first, in their activation order, so syncing keeps their precedence.
+func (l *LockService) Lock() (entity.Lock, error) {
+	packages, err := l.dbPort.GetAllPackages()
+	if err != nil {
+		return entity.Lock{}, err
+	}
+	enabledPackages, err := l.dbPort.GetEnabledPackages()
+	if err != nil {
+		return entity.Lock{}, err
+	}
+	pins, err := l.dbPort.GetPins()
+	if err != nil {
+		return entity.Lock{}, err
+	}
+
+	names := []string{}
+	for _, pkg := range enabledPackages {
+		names = append(names, pkg.Name)
+	}
+	enabledCount := len(names)
+	disabledNames := []string{}
+	for _, pkg := range packages {
+		if !slices.Contains(names, pkg.Name) {
+			disabledNames = append(disabledNames, pkg.Name)
+		}
+	}
+	slices.Sort(disabledNames)
+	names = append(names, disabledNames...)
+
+	lock := entity.Lock{Packages: []entity.LockedPackage{}}
+	for index, name := range names {
+		revision, err := l.dbPort.GetPackageRevision(name)
+		if err != nil {
+			return entity.Lock{}, fmt.Errorf("failed to read the revision of package '%s': %w", name, err)
+		}
+		lock.Packages = append(lock.Packages, entity.LockedPackage{
+			Name:    name,
+			Url:     revision.Url,
+			Commit:  revision.Commit,
+			Enabled: index < enabledCount,
+			Pin:     pins[name],
+		})
+	}
+	return lock, l.lockPort.WriteLock(lock)
+}
+
+// Sync brings the packages in line with the lockfile: missing packages are cloned,
+// pins and enabled packages are restored, and packages missing from the lockfile are disabled.
+// With locked, every package is also moved to the exact commit recorded in the lockfile,
+// packages with local changes being left alone unless force is set.
+func (l *LockService) Sync(locked bool, force bool) (entity.SyncResults, error) {
+	results := entity.SyncResults{
+		Cloned:               []string{},
+		Reset:                []string{},
+		LocalChangesDetected: []string{},
+		OtherErrors:          []error{},
+	}
+	lock, err := l.lockPort.ReadLock()
+	if err != nil {
+		return results, err
+	}
+	packages, err := l.dbPort.GetAllPackages()
+	if err != nil {
+		return results, err
+	}
+	installed := []string{}
+	for _, pkg := range packages {
+		installed = append(installed, pkg.Name)
+	}
+	pins, err := l.dbPort.GetPins()
+	if err != nil {
+		return results, err
+	}
+
+	available := []string{}
+	for _, lockedPackage := range lock.Packages {
+		name := lockedPackage.Name
+		if !slices.Contains(installed, name) {
+			if lockedPackage.Url == "" {
+				results.OtherErrors = append(results.OtherErrors, fmt.Errorf("package '%s' was created locally, it cannot be cloned", name))
+				continue
+			}
+			if _, err := l.dbPort.AddPackage(lockedPackage.Url, &name); err != nil {
+				results.OtherErrors = append(results.OtherErrors, fmt.Errorf("failed to clone package '%s': %w", name, err))
+				continue
+			}
+			results.Cloned = append(results.Cloned, name)
+		}
+		available = append(available, name)
+
+		if err := l.restorePin(lockedPackage, pins[name]); err != nil {
+			results.OtherErrors = append(results.OtherErrors, err)
+			continue
+		}
+		if !locked || lockedPackage.Commit == "" {
+			continue
+		}
+		revision, err := l.dbPort.GetPackageRevision(name)
+		if err != nil {
+			results.OtherErrors = append(results.OtherErrors, err)
+			continue
+		}
+		if revision.Commit == lockedPackage.Commit {
+			continue
+		}
+		err = l.dbPort.CheckoutPackageCommit(name, lockedPackage.Commit, force)
+		if err == errorss.ErrLocalChanges {
+			results.LocalChangesDetected = append(results.LocalChangesDetected, name)
+		} else if err != nil {
+			results.OtherErrors = append(results.OtherErrors, fmt.Errorf("failed to reset package '%s': %w", name, err))
+		} else if !slices.Contains(results.Cloned, name) {
+			results.Reset = append(results.Reset, name)
+		}
+	}
+
+	return results, l.restoreEnabledPackages(lock, available)
+}
+
+// restorePin pins the package like in the lockfile, current being its pin on this machine
+func (l *LockService) restorePin(lockedPackage entity.LockedPackage, current string) error {
+	if lockedPackage.Pin == current {
+		return nil
+	}
+	var err error
+	if lockedPackage.Pin == "" {
+		err = l.dbPort.UnpinPackage(lockedPackage.Name)
+	} else {
+		err = l.dbPort.PinPackage(lockedPackage.Name, lockedPackage.Pin)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to restore the pin of package '%s': %w", lockedPackage.Name, err)
+	}
+	return nil
+}
+
+// restoreEnabledPackages enables the available packages enabled in the lockfile, in their order, and only them
+func (l *LockService) restoreEnabledPackages(lock *entity.Lock, available []string) error {
+	expected := []string{}
+	for _, lockedPackage := range lock.Packages {
+		if lockedPackage.Enabled && slices.Contains(available, lockedPackage.Name) {
+			expected = append(expected, lockedPackage.Name)
+		}
+	}
+	enabledPackages, err := l.dbPort.GetEnabledPackages()
+	if err != nil {
+		return err
+	}
+	current := []string{}
+	for _, pkg := range enabledPackages {
+		current = append(current, pkg.Name)
+	}
+	if slices.Equal(current, expected) {
+		return nil
+	}
+	for _, name := range current {
+		if err := l.dbPort.DisablePackage(name); err != nil {
+			return err
+		}
+	}
+	for _, name := range expected {
+		if err := l.dbPort.EnablePackage(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
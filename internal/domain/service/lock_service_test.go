@@ -0,0 +1,90 @@
+package service
+
+import (
+	"duh/internal/domain/entity"
+	"duh/internal/domain/errorss"
+	"duh/internal/domain/port"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Lock(t *testing.T) {
+	dbPort := &port.MockDbAdapter{
+		Packages: []entity.Package{{Name: "zzz"}, {Name: "local"}, {Name: "team"}, {Name: "games"}},
+		Enabled:  []string{"team", "local"},
+		Pins:     map[string]string{"team": "v1.2.0"},
+		Revisions: map[string]entity.PackageRevision{
+			"team":  {Url: "https://git.example.com/team", Commit: "aaa111"},
+			"games": {Url: "https://git.example.com/games", Commit: "bbb222"},
+		},
+	}
+	lockPort := &port.MockLockPort{}
+
+	lock, err := NewLockService(dbPort, lockPort).Lock()
+	assert.NoError(t, err)
+	expected := entity.Lock{Packages: []entity.LockedPackage{
+		{Name: "team", Url: "https://git.example.com/team", Commit: "aaa111", Enabled: true, Pin: "v1.2.0"},
+		{Name: "local", Enabled: true},
+		{Name: "games", Url: "https://git.example.com/games", Commit: "bbb222"},
+		{Name: "zzz"},
+	}}
+	assert.Equal(t, expected, lock)
+	assert.Equal(t, expected, *lockPort.Lock)
+}
+
+func Test_Sync(t *testing.T) {
+	lockPort := &port.MockLockPort{Lock: &entity.Lock{Packages: []entity.LockedPackage{
+		{Name: "base", Url: "https://git.example.com/base", Commit: "ccc333", Enabled: true},
+		{Name: "team", Url: "https://git.example.com/team", Commit: "aaa111", Enabled: true, Pin: "v1.2.0"},
+		{Name: "local", Enabled: true},
+		{Name: "games", Url: "https://git.example.com/games", Commit: "bbb222"},
+		{Name: "notes", Enabled: true},
+	}}}
+	newDbPort := func() *port.MockDbAdapter {
+		return &port.MockDbAdapter{
+			Packages: []entity.Package{{Name: "local"}, {Name: "team"}, {Name: "games"}, {Name: "extra"}},
+			Enabled:  []string{"local", "extra", "games"},
+			Revisions: map[string]entity.PackageRevision{
+				"team":  {Url: "https://git.example.com/team", Commit: "ddd444"},
+				"games": {Url: "https://git.example.com/games", Commit: "eee555"},
+			},
+			RemotePackages: map[string]entity.Package{"https://git.example.com/base": {Name: "base"}},
+			LocalChanges:   []string{"games"},
+		}
+	}
+
+	dbPort := newDbPort()
+	results, err := NewLockService(dbPort, lockPort).Sync(false, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"base"}, results.Cloned)
+	assert.Empty(t, results.Reset)
+	assert.Len(t, results.OtherErrors, 1)
+	assert.ErrorContains(t, results.OtherErrors[0], "package 'notes' was created locally")
+	// Without --locked, commits are left as they are
+	assert.Equal(t, "ddd444", dbPort.Revisions["team"].Commit)
+	assert.Equal(t, map[string]string{"team": "v1.2.0"}, dbPort.Pins)
+	assert.Equal(t, []string{"base", "team", "local"}, dbPort.Enabled)
+
+	dbPort = newDbPort()
+	results, err = NewLockService(dbPort, lockPort).Sync(true, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"team"}, results.Reset)
+	assert.Equal(t, []string{"games"}, results.LocalChangesDetected)
+	assert.Equal(t, "ccc333", dbPort.Revisions["base"].Commit)
+	assert.Equal(t, "aaa111", dbPort.Revisions["team"].Commit)
+	assert.Equal(t, "eee555", dbPort.Revisions["games"].Commit)
+
+	dbPort = newDbPort()
+	results, err = NewLockService(dbPort, lockPort).Sync(true, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"team", "games"}, results.Reset)
+	assert.Empty(t, results.LocalChangesDetected)
+	assert.Equal(t, "bbb222", dbPort.Revisions["games"].Commit)
+}
+
+func Test_Sync_NoLockfile(t *testing.T) {
+	_, err := NewLockService(&port.MockDbAdapter{}, &port.MockLockPort{}).Sync(true, false)
+	var notFoundErr *errorss.NotFoundError
+	assert.ErrorAs(t, err, &notFoundErr)
+}
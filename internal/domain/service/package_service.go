@@ -4,6 +4,11 @@ import (
 	"duh/internal/domain/entity"
 	"duh/internal/domain/errorss"
 	"duh/internal/domain/port"
+	"duh/internal/domain/utils/version"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
 )
 
 type PackageService struct {
@@ -134,12 +139,309 @@ func (p *PackageService) RenamePackage(oldName, newName string) error {
 	return p.dbPort.RenamePackage(oldName, newName)
 }
 
-func (p *PackageService) AddAndEnablePackage(url string, name *string) error {
-	pkg, err := p.dbPort.AddPackage(url, name)
+// AddAndEnablePackage clones a package and the dependencies listed in its manifest.
+// It returns the names of the dependencies added along the way.
+//
+// Business rules:
+// - a url ending with #<ref>, or @<ref> for refs without / or :, pins the package to this tag, branch or commit
+// - a package requiring a newer duh version is removed again and rejected
+// - dependencies are enabled before the package relying on them, so the package can override them
+// - a dependency already installed from the same url is enabled instead of being cloned again,
+// which also stops dependency cycles
+func (p *PackageService) AddAndEnablePackage(url string, name *string) ([]string, error) {
+	_, addedDependencies, err := p.addPackage(url, name)
+	return addedDependencies, err
+}
+
+// addPackage adds and enables a package after its dependencies,
+// returning its name and the names of the dependencies added for it.
+// Business rule: a failed add leaves nothing behind, the package and the dependencies added for it are removed,
+// so the add can simply be run again.
+func (p *PackageService) addPackage(source string, name *string) (string, []string, error) {
+	url, ref := entity.SplitPinnedUrl(source)
+	packageName, err := p.dbPort.AddPackage(url, name)
+	if err != nil {
+		return "", nil, err
+	}
+	addedDependencies, err := p.setUpPackage(packageName, ref)
+	if err != nil {
+		p.rollbackPackages(append(addedDependencies, packageName))
+		return "", nil, err
+	}
+	return packageName, addedDependencies, nil
+}
+
+// setUpPackage pins, checks and enables a freshly added package after its dependencies,
+// returning the names of the dependencies added for it, even when it fails
+func (p *PackageService) setUpPackage(packageName string, ref string) ([]string, error) {
+	addedDependencies := []string{}
+	if ref != "" {
+		if err := p.dbPort.PinPackage(packageName, ref); err != nil {
+			return addedDependencies, err
+		}
+	}
+	pkg, err := p.getPackage(packageName)
+	if err != nil {
+		return addedDependencies, err
+	}
+	if err := validateManifest(packageName, pkg.Manifest); err != nil {
+		return addedDependencies, err
+	}
+	if err := checkMinDuhVersion(pkg.Manifest.MinDuhVersion); err != nil {
+		return addedDependencies, err
+	}
+
+	for _, dependencyUrl := range pkg.Manifest.Dependencies {
+		installedName, found, err := p.findPackageBySource(dependencyUrl)
+		if err != nil {
+			return addedDependencies, err
+		}
+		if found {
+			if err := p.dbPort.EnablePackage(installedName); err != nil {
+				return addedDependencies, err
+			}
+			continue
+		}
+		// A dependency failing to be added has already removed itself and its own dependencies
+		dependencyName, added, err := p.addPackage(dependencyUrl, nil)
+		if err != nil {
+			return addedDependencies, fmt.Errorf("dependency '%s' of '%s': %w", dependencyUrl, packageName, err)
+		}
+		addedDependencies = append(addedDependencies, added...)
+		addedDependencies = append(addedDependencies, dependencyName)
+	}
+	return addedDependencies, p.dbPort.EnablePackage(packageName)
+}
+
+// rollbackPackages disables and deletes packages added by a failed add, ignoring errors
+// so the error of the add is the one reported
+func (p *PackageService) rollbackPackages(packageNames []string) {
+	for _, packageName := range packageNames {
+		_ = p.dbPort.DisablePackage(packageName)
+		_ = p.dbPort.DeletePackage(packageName)
+	}
+}
+
+// GetPackageInfo gathers the manifest, status and dependencies of a package
+func (p *PackageService) GetPackageInfo(packageName string) (*entity.PackageInfo, error) {
+	pkg, err := p.getPackage(packageName)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := p.packagesInfo([]entity.Package{*pkg})
+	if err != nil {
+		return nil, err
+	}
+	return &infos[0], nil
+}
+
+// ListPackagesInfo gathers the manifest, status and dependencies of every package
+func (p *PackageService) ListPackagesInfo() ([]entity.PackageInfo, error) {
+	packages, err := p.dbPort.GetAllPackages()
+	if err != nil {
+		return nil, err
+	}
+	return p.packagesInfo(packages)
+}
+
+func (p *PackageService) packagesInfo(packages []entity.Package) ([]entity.PackageInfo, error) {
+	enabledPackages, err := p.dbPort.GetEnabledPackages()
+	if err != nil {
+		return nil, err
+	}
+	defaultPackage, err := p.dbPort.GetDefaultPackage()
+	if err != nil {
+		return nil, err
+	}
+	pins, err := p.dbPort.GetPins()
+	if err != nil {
+		return nil, err
+	}
+
+	infos := []entity.PackageInfo{}
+	for _, pkg := range packages {
+		info := entity.PackageInfo{
+			Package:       pkg,
+			Pin:           pins[pkg.Name],
+			Enabled:       slices.ContainsFunc(enabledPackages, func(enabled entity.Package) bool { return enabled.Name == pkg.Name }),
+			Default:       defaultPackage != nil && defaultPackage.Name == pkg.Name,
+			Dependencies:  []entity.DependencyStatus{},
+			ManifestError: validateManifest(pkg.Name, pkg.Manifest),
+		}
+		for _, dependencyUrl := range pkg.Manifest.Dependencies {
+			installedName, _, err := p.findPackageBySource(dependencyUrl)
+			if err != nil {
+				return nil, err
+			}
+			info.Dependencies = append(info.Dependencies, entity.DependencyStatus{Url: dependencyUrl, InstalledAs: installedName})
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// findPackageBySource finds the package installed from a url, ignoring the ref it may be pinned to
+func (p *PackageService) findPackageBySource(source string) (string, bool, error) {
+	url, _ := entity.SplitPinnedUrl(source)
+	return p.dbPort.FindPackageByUrl(url)
+}
+
+func (p *PackageService) PinPackage(packageName string, ref string) error {
+	if err := p.validatePackageExists(packageName); err != nil {
+		return err
+	}
+	return p.dbPort.PinPackage(packageName, ref)
+}
+
+func (p *PackageService) UnpinPackage(packageName string) error {
+	if err := p.validatePackageExists(packageName); err != nil {
+		return err
+	}
+	pins, err := p.dbPort.GetPins()
+	if err != nil {
+		return err
+	}
+	if _, ok := pins[packageName]; !ok {
+		return &errorss.BusinessRuleError{
+			Rule:    "package_not_pinned",
+			Message: fmt.Sprintf("package '%s' is not pinned", packageName),
+		}
+	}
+	return p.dbPort.UnpinPackage(packageName)
+}
+
+// SetLazyFunctions chooses whether the functions of a package are loaded on their first call instead of at shell startup.
+// Scripts with a "# duh: lazy" directive keep their own setting.
+func (p *PackageService) SetLazyFunctions(packageName string, lazy bool) error {
+	pkg, err := p.getPackage(packageName)
+	if err != nil {
+		return err
+	}
+	pkg.LazyFunctions = lazy
+	return p.dbPort.UpsertPackage(*pkg)
+}
+
+// upgradePins moves every pin on a release tag, like v1.2.0, to the newest release tag with the same major version.
+// Business rule: a new major version may break the shells using the package, it has to be pinned explicitly.
+// Pins on a branch, a commit or any other tag are left as is.
+func (p *PackageService) upgradePins() ([]entity.PinUpgrade, []error) {
+	pins, err := p.dbPort.GetPins()
+	if err != nil {
+		return nil, []error{err}
+	}
+	upgrades := []entity.PinUpgrade{}
+	errs := []error{}
+	for _, packageName := range slices.Sorted(maps.Keys(pins)) {
+		current := pins[packageName]
+		if !version.IsRelease(current) {
+			continue
+		}
+		tags, err := p.dbPort.ListPackageTags(packageName)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to list the tags of package '%s': %w", packageName, err))
+			continue
+		}
+		newest, err := newestCompatibleTag(current, tags)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to upgrade package '%s': %w", packageName, err))
+			continue
+		}
+		if newest == current {
+			continue
+		}
+		if err := p.dbPort.PinPackage(packageName, newest); err != nil {
+			errs = append(errs, fmt.Errorf("failed to upgrade package '%s' to '%s': %w", packageName, newest, err))
+			continue
+		}
+		upgrades = append(upgrades, entity.PinUpgrade{Package: packageName, From: current, To: newest})
+	}
+	return upgrades, errs
+}
+
+// newestCompatibleTag returns the newest release tag sharing the major version and naming style of current,
+// or current itself when there is none
+func newestCompatibleTag(current string, tags []string) (string, error) {
+	currentMajor, err := version.Major(current)
+	if err != nil {
+		return "", err
+	}
+	newest := current
+	for _, tag := range tags {
+		if !version.IsRelease(tag) || strings.HasPrefix(tag, "v") != strings.HasPrefix(current, "v") {
+			continue
+		}
+		major, err := version.Major(tag)
+		if err != nil {
+			return "", err
+		}
+		if major != currentMajor {
+			continue
+		}
+		comparison, err := version.Compare(tag, newest)
+		if err != nil {
+			return "", err
+		}
+		if comparison > 0 {
+			newest = tag
+		}
+	}
+	return newest, nil
+}
+
+func (p *PackageService) getPackage(packageName string) (*entity.Package, error) {
+	packages, err := p.dbPort.GetAllPackages()
+	if err != nil {
+		return nil, err
+	}
+	for _, pkg := range packages {
+		if pkg.Name == packageName {
+			return &pkg, nil
+		}
+	}
+	return nil, &errorss.NotFoundError{
+		Resource: "package",
+		ID:       packageName,
+	}
+}
+
+// validateManifest checks the versions of a package manifest are semantic versions
+func validateManifest(packageName string, manifest entity.Manifest) error {
+	versions := []struct{ field, value string }{
+		{"manifest.version", manifest.Version},
+		{"manifest.min_duh_version", manifest.MinDuhVersion},
+	}
+	for _, field := range versions {
+		if field.value == "" {
+			continue
+		}
+		if err := version.Validate(field.value); err != nil {
+			return &errorss.ValidationError{
+				Field:   field.field,
+				Message: fmt.Sprintf("package '%s' has an invalid %s: %v, expected a semantic version like 1.2.0", packageName, field.field, err),
+			}
+		}
+	}
+	return nil
+}
+
+// checkMinDuhVersion rejects packages requiring a newer duh than the running one.
+// Development builds have no version to compare, they accept every package.
+func checkMinDuhVersion(minDuhVersion string) error {
+	current := version.GetVersion()
+	if minDuhVersion == "" || current == version.DevVersion {
+		return nil
+	}
+	comparison, err := version.Compare(current, minDuhVersion)
 	if err != nil {
 		return err
 	}
-	return p.dbPort.EnablePackage(pkg)
+	if comparison < 0 {
+		return &errorss.BusinessRuleError{
+			Rule:    "min_duh_version",
+			Message: fmt.Sprintf("package requires duh %s or newer, current version is %s", minDuhVersion, current),
+		}
+	}
+	return nil
 }
 
 func (p *PackageService) CreatePackage(name string) error {
@@ -147,12 +449,211 @@ func (p *PackageService) CreatePackage(name string) error {
 	return err
 }
 
-func (p *PackageService) UpdatePackages(strategy string) (entity.PackageUpdateResults, error) {
-	results, err := p.dbPort.UpdatePackages(strategy)
-	return entity.PackageUpdateResults{
-		LocalChangesDetected: results.LocalChangesDetected,
-		OtherErrors:          results.OtherErrors,
-	}, err
+// UpdatePackages pulls every package, pinned packages staying on their pin.
+// With options.Upgrade, pins on a release tag are first moved to the newest compatible release.
+func (p *PackageService) UpdatePackages(options entity.UpdateOptions) (entity.PackageUpdateResults, error) {
+	upgradedPins := []entity.PinUpgrade{}
+	upgradeErrors := []error{}
+	if options.Upgrade {
+		upgradedPins, upgradeErrors = p.upgradePins()
+	}
+	results, err := p.dbPort.UpdatePackages(options)
+	results.OtherErrors = append(upgradeErrors, results.OtherErrors...)
+	results.UpgradedPins = upgradedPins
+	return results, err
+}
+
+// PreviewUpdates fetches every package and lists the aliases, exports, functions and git aliases
+// pulling it would add, remove or change, without updating anything.
+// Pinned packages are compared with the commit of their pin.
+func (p *PackageService) PreviewUpdates() (entity.UpdatePreview, error) {
+	packages, err := p.dbPort.GetAllPackages()
+	if err != nil {
+		return entity.UpdatePreview{}, err
+	}
+	preview := entity.UpdatePreview{
+		Packages: []entity.PackagePreview{},
+		UpToDate: []string{},
+		Skipped:  []string{},
+		Errors:   []error{},
+	}
+	for _, pkg := range packages {
+		update, err := p.dbPort.FetchIncomingUpdate(pkg.Name)
+		switch {
+		case err != nil:
+			preview.Errors = append(preview.Errors, fmt.Errorf("failed to fetch package '%s': %w", pkg.Name, err))
+		case update == nil:
+			preview.Skipped = append(preview.Skipped, pkg.Name)
+		case update.From == update.To:
+			preview.UpToDate = append(preview.UpToDate, pkg.Name)
+		default:
+			preview.Packages = append(preview.Packages, entity.PackagePreview{
+				Package: pkg.Name,
+				From:    update.From,
+				To:      update.To,
+				Changes: update.Current.Diff(update.Incoming),
+			})
+		}
+	}
+	return preview, nil
+}
+
+// GetPackagesStatus reads the git state of every package, enabled packages first in activation order.
+// With fetch set, the remote of each package is fetched first, so commits behind are up to date.
+// Packages are read concurrently, the remotes being fetched at the same time.
+func (p *PackageService) GetPackagesStatus(fetch bool) ([]entity.PackageStatus, error) {
+	grouped, err := p.GetPackagesGroupedByStatus()
+	if err != nil {
+		return nil, err
+	}
+	enabledPackages, err := p.dbPort.GetEnabledPackages()
+	if err != nil {
+		return nil, err
+	}
+	pins, err := p.dbPort.GetPins()
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, pkg := range enabledPackages {
+		names = append(names, pkg.Name)
+	}
+	names = append(names, grouped["disabled"]...)
+
+	statuses, err := p.dbPort.GetPackagesStatus(names, fetch)
+	if err != nil {
+		return nil, err
+	}
+	for index, name := range names {
+		statuses[index].Name = name
+		statuses[index].Enabled = index < len(enabledPackages)
+		statuses[index].Pin = pins[name]
+	}
+	return statuses, nil
+}
+
+// GetPackageHistory lists the last commits of a package, newest first, with the aliases, exports,
+// functions and git aliases each commit added, removed or changed
+func (p *PackageService) GetPackageHistory(packageName string, limit int) ([]entity.PackageHistoryEntry, error) {
+	if err := p.validatePackageExists(packageName); err != nil {
+		return nil, err
+	}
+	// One more commit is read, to compare the oldest commit shown with its parent
+	commits, err := p.dbPort.ListPackageCommits(packageName, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	contents := make([]entity.PackageContent, len(commits))
+	for index, commit := range commits {
+		if contents[index], err = p.dbPort.GetPackageContent(packageName, commit.Hash); err != nil {
+			return nil, fmt.Errorf("failed to read commit '%s': %w", commit.Hash, err)
+		}
+	}
+
+	history := []entity.PackageHistoryEntry{}
+	for index := 0; index < min(limit, len(commits)); index++ {
+		// The first commit of the package is compared with an empty package
+		parent := entity.PackageContent{}
+		if index+1 < len(commits) {
+			parent = contents[index+1]
+		}
+		history = append(history, entity.PackageHistoryEntry{
+			Commit:  commits[index],
+			Changes: parent.Diff(contents[index]),
+		})
+	}
+	return history, nil
+}
+
+// RollbackPackage restores the files of a package as they were at ref, or at the previous commit when ref is empty.
+// Business rule: the history is never rewritten, the rollback is a new commit on top of it,
+// so the package can still be pushed and updated from its remote.
+func (p *PackageService) RollbackPackage(packageName string, ref string) (entity.Rollback, error) {
+	if err := p.validatePackageExists(packageName); err != nil {
+		return entity.Rollback{}, err
+	}
+	status, err := p.dbPort.GetPackageStatus(packageName, false)
+	if err != nil {
+		return entity.Rollback{}, err
+	}
+	pins, err := p.dbPort.GetPins()
+	if err != nil {
+		return entity.Rollback{}, err
+	}
+	switch {
+	case !status.Git || status.Commit == "":
+		return entity.Rollback{}, &errorss.BusinessRuleError{
+			Rule:    "rollback_needs_history",
+			Message: fmt.Sprintf("package '%s' has no git history to roll back", packageName),
+		}
+	case pins[packageName] != "":
+		return entity.Rollback{}, &errorss.BusinessRuleError{
+			Rule:    "rollback_not_pinned",
+			Message: fmt.Sprintf("package '%s' is pinned to '%s', pin it to an older ref instead", packageName, pins[packageName]),
+		}
+	case status.Branch == "":
+		return entity.Rollback{}, &errorss.BusinessRuleError{
+			Rule:    "rollback_on_branch",
+			Message: fmt.Sprintf("package '%s' is not on a branch, check a branch out first", packageName),
+		}
+	case len(status.Modified) > 0:
+		return entity.Rollback{}, &errorss.BusinessRuleError{
+			Rule:    "no_local_changes",
+			Message: fmt.Sprintf("package '%s' has local changes, push or discard them before rolling back", packageName),
+		}
+	}
+
+	target := ""
+	if ref == "" {
+		commits, err := p.dbPort.ListPackageCommits(packageName, 2)
+		if err != nil {
+			return entity.Rollback{}, err
+		}
+		if len(commits) < 2 {
+			return entity.Rollback{}, &errorss.BusinessRuleError{
+				Rule:    "rollback_needs_history",
+				Message: fmt.Sprintf("package '%s' has no previous commit", packageName),
+			}
+		}
+		target = commits[1].Hash
+	} else if target, err = p.dbPort.ResolvePackageRef(packageName, ref); err != nil {
+		return entity.Rollback{}, fmt.Errorf("failed to find '%s' in package '%s': %w", ref, packageName, err)
+	}
+	if target == status.Commit {
+		return entity.Rollback{}, &errorss.BusinessRuleError{
+			Rule:    "rollback_to_other_commit",
+			Message: fmt.Sprintf("package '%s' is already at commit %s", packageName, entity.ShortCommit(target)),
+		}
+	}
+
+	current, err := p.dbPort.GetPackageContent(packageName, status.Commit)
+	if err != nil {
+		return entity.Rollback{}, err
+	}
+	restored, err := p.dbPort.GetPackageContent(packageName, target)
+	if err != nil {
+		return entity.Rollback{}, err
+	}
+	rollback := entity.Rollback{
+		Package: packageName,
+		From:    status.Commit,
+		To:      target,
+		Changes: current.Diff(restored),
+	}
+
+	message := fmt.Sprintf("Roll back to %s", entity.ShortCommit(target))
+	if len(rollback.Changes) > 0 {
+		lines := []string{message, ""}
+		for _, change := range rollback.Changes {
+			lines = append(lines, "- "+change.String())
+		}
+		message = strings.Join(lines, "\n")
+	}
+	if err := p.dbPort.RestorePackageCommit(packageName, target, message); err != nil {
+		return entity.Rollback{}, err
+	}
+	return rollback, nil
 }
 
 func (p *PackageService) EditPackage(packageName string) error {
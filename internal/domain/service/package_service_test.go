@@ -0,0 +1,392 @@
+package service
+
+import (
+	"duh/internal/domain/entity"
+	"duh/internal/domain/errorss"
+	"duh/internal/domain/port"
+	"duh/internal/domain/utils/version"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_AddAndEnablePackage_Dependencies(t *testing.T) {
+	dbPort := &port.MockDbAdapter{
+		RemotePackages: map[string]entity.Package{
+			"https://git.example.com/team": {
+				Name:     "team",
+				Manifest: entity.Manifest{Dependencies: []string{"https://git.example.com/base", "https://git.example.com/tools"}},
+			},
+			"https://git.example.com/base": {
+				Name:     "base",
+				Manifest: entity.Manifest{Dependencies: []string{"https://git.example.com/core"}},
+			},
+			"https://git.example.com/core": {Name: "core"},
+			"https://git.example.com/tools": {
+				Name: "tools",
+				// Cycle back to the package being added
+				Manifest: entity.Manifest{Dependencies: []string{"https://git.example.com/team"}},
+			},
+		},
+	}
+
+	added, err := NewPackageService(dbPort).AddAndEnablePackage("https://git.example.com/team", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"core", "base", "tools"}, added)
+	// Dependencies come first, so the package depending on them can override them.
+	// tools depends back on team, which is already installed and gets enabled right away.
+	assert.Equal(t, []string{"core", "base", "team", "tools"}, dbPort.Enabled)
+}
+
+func Test_AddAndEnablePackage_RollbackOnDependencyFailure(t *testing.T) {
+	dbPort := &port.MockDbAdapter{
+		RemotePackages: map[string]entity.Package{
+			"https://git.example.com/team": {
+				Name:     "team",
+				Manifest: entity.Manifest{Dependencies: []string{"https://git.example.com/base", "https://git.example.com/broken"}},
+			},
+			"https://git.example.com/base":   {Name: "base"},
+			"https://git.example.com/broken": {Name: "broken", Manifest: entity.Manifest{Version: "one"}},
+		},
+	}
+
+	added, err := NewPackageService(dbPort).AddAndEnablePackage("https://git.example.com/team#v1.0.0", nil)
+	assert.ErrorContains(t, err, "dependency 'https://git.example.com/broken' of 'team'")
+	assert.Empty(t, added)
+	// Nothing is left behind, so adding the package again starts from scratch
+	assert.Empty(t, dbPort.Packages)
+	assert.Empty(t, dbPort.Enabled)
+	assert.Empty(t, dbPort.Pins)
+}
+
+func Test_AddAndEnablePackage_InstalledDependency(t *testing.T) {
+	dbPort := &port.MockDbAdapter{
+		Packages: []entity.Package{{Name: "my-base"}},
+		Origins:  map[string]string{"my-base": "https://git.example.com/base"},
+		RemotePackages: map[string]entity.Package{
+			"https://git.example.com/team": {
+				Name:     "team",
+				Manifest: entity.Manifest{Dependencies: []string{"https://git.example.com/base"}},
+			},
+		},
+	}
+
+	name := "platform"
+	added, err := NewPackageService(dbPort).AddAndEnablePackage("https://git.example.com/team", &name)
+	assert.NoError(t, err)
+	assert.Empty(t, added)
+	assert.Equal(t, []string{"my-base", "platform"}, dbPort.Enabled)
+}
+
+func Test_AddAndEnablePackage_MinDuhVersion(t *testing.T) {
+	previous := version.Version
+	version.Version = "1.0.0"
+	defer func() { version.Version = previous }()
+
+	dbPort := &port.MockDbAdapter{
+		RemotePackages: map[string]entity.Package{
+			"https://git.example.com/future": {Name: "future", Manifest: entity.Manifest{MinDuhVersion: "2.1.0"}},
+		},
+	}
+
+	_, err := NewPackageService(dbPort).AddAndEnablePackage("https://git.example.com/future", nil)
+	var ruleErr *errorss.BusinessRuleError
+	assert.ErrorAs(t, err, &ruleErr)
+	assert.Equal(t, "min_duh_version", ruleErr.Rule)
+	assert.Empty(t, dbPort.Packages)
+	assert.Empty(t, dbPort.Enabled)
+}
+
+func Test_AddAndEnablePackage_InvalidManifestVersion(t *testing.T) {
+	dbPort := &port.MockDbAdapter{
+		RemotePackages: map[string]entity.Package{
+			"https://git.example.com/sloppy": {Name: "sloppy", Manifest: entity.Manifest{Version: "first"}},
+		},
+	}
+
+	_, err := NewPackageService(dbPort).AddAndEnablePackage("https://git.example.com/sloppy", nil)
+	var validationErr *errorss.ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "manifest.version", validationErr.Field)
+	assert.Contains(t, err.Error(), "package 'sloppy' has an invalid manifest.version: invalid version 'first'")
+	assert.Empty(t, dbPort.Packages)
+	assert.Empty(t, dbPort.Enabled)
+}
+
+func Test_GetPackageInfo(t *testing.T) {
+	manifest := entity.Manifest{
+		Description:  "Aliases of the platform team",
+		Version:      "1.2.0",
+		Dependencies: []string{"https://git.example.com/base", "https://git.example.com/missing"},
+	}
+	dbPort := &port.MockDbAdapter{
+		Packages:    []entity.Package{{Name: "base"}, {Name: "team", Manifest: manifest}},
+		Enabled:     []string{"base", "team"},
+		DefaultRepo: entity.Package{Name: "base"},
+		Origins:     map[string]string{"base": "https://git.example.com/base"},
+	}
+
+	info, err := NewPackageService(dbPort).GetPackageInfo("team")
+	assert.NoError(t, err)
+	assert.Equal(t, manifest, info.Package.Manifest)
+	assert.NoError(t, info.ManifestError)
+	assert.True(t, info.Enabled)
+	assert.False(t, info.Default)
+	assert.Equal(t, []entity.DependencyStatus{
+		{Url: "https://git.example.com/base", InstalledAs: "base"},
+		{Url: "https://git.example.com/missing"},
+	}, info.Dependencies)
+
+	_, err = NewPackageService(dbPort).GetPackageInfo("unknown")
+	var notFoundErr *errorss.NotFoundError
+	assert.ErrorAs(t, err, &notFoundErr)
+
+	// An installed package with an invalid manifest is still described, with the problem
+	dbPort.Packages[0].Manifest = entity.Manifest{MinDuhVersion: "1.x"}
+	info, err = NewPackageService(dbPort).GetPackageInfo("base")
+	assert.NoError(t, err)
+	assert.EqualError(t, info.ManifestError, "package 'base' has an invalid manifest.min_duh_version: invalid version '1.x', expected a semantic version like 1.2.0")
+}
+
+func Test_AddAndEnablePackage_PinnedUrl(t *testing.T) {
+	dbPort := &port.MockDbAdapter{
+		RemotePackages: map[string]entity.Package{
+			"git@github.com:team/duh-pkg.git": {
+				Name:     "duh-pkg",
+				Manifest: entity.Manifest{Dependencies: []string{"https://git.example.com/base@v2.0.0"}},
+			},
+			"https://git.example.com/base": {Name: "base"},
+		},
+	}
+
+	added, err := NewPackageService(dbPort).AddAndEnablePackage("git@github.com:team/duh-pkg.git@v1.2.0", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"base"}, added)
+	assert.Equal(t, map[string]string{"duh-pkg": "v1.2.0", "base": "v2.0.0"}, dbPort.Pins)
+
+	// Refs containing a / or an @ are pinned with the dedicated separator
+	dbPort.RemotePackages["https://git.example.com/tools"] = entity.Package{Name: "tools"}
+	_, err = NewPackageService(dbPort).AddAndEnablePackage("https://git.example.com/tools#release/1.2@rc", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "release/1.2@rc", dbPort.Pins["tools"])
+}
+
+func Test_UpdatePackages_UpgradePins(t *testing.T) {
+	dbPort := &port.MockDbAdapter{
+		Pins: map[string]string{
+			"team":   "v1.2.0",
+			"tools":  "2.0.1",
+			"branch": "main",
+			"latest": "v3.0.0",
+		},
+		Tags: map[string][]string{
+			"team":   {"v1.2.0", "v1.10.0", "v1.3.0", "v2.0.0", "v1.11.0-rc.1", "1.12.0"},
+			"tools":  {"2.0.1", "2.1.0", "v2.2.0"},
+			"branch": {"v9.0.0"},
+			"latest": {"v3.0.0", "v2.9.0"},
+		},
+	}
+
+	results, err := NewPackageService(dbPort).UpdatePackages(entity.UpdateOptions{Strategy: entity.UpdateSafe, Upgrade: true})
+	assert.NoError(t, err)
+	assert.Empty(t, results.OtherErrors)
+	assert.Equal(t, []entity.PinUpgrade{
+		{Package: "team", From: "v1.2.0", To: "v1.10.0"},
+		{Package: "tools", From: "2.0.1", To: "2.1.0"},
+	}, results.UpgradedPins)
+	assert.Equal(t, map[string]string{
+		"team":   "v1.10.0",
+		"tools":  "2.1.0",
+		"branch": "main",
+		"latest": "v3.0.0",
+	}, dbPort.Pins)
+
+	// Without --upgrade, pins are left untouched
+	dbPort.Pins["team"] = "v1.2.0"
+	results, err = NewPackageService(dbPort).UpdatePackages(entity.UpdateOptions{Strategy: entity.UpdateSafe})
+	assert.NoError(t, err)
+	assert.Empty(t, results.UpgradedPins)
+	assert.Equal(t, "v1.2.0", dbPort.Pins["team"])
+}
+
+func Test_UnpinPackage(t *testing.T) {
+	dbPort := &port.MockDbAdapter{
+		Packages: []entity.Package{{Name: "team"}},
+		Pins:     map[string]string{"team": "v1.2.0"},
+	}
+	service := NewPackageService(dbPort)
+
+	err := service.UnpinPackage("team")
+	assert.NoError(t, err)
+	assert.Empty(t, dbPort.Pins)
+
+	err = service.UnpinPackage("team")
+	var ruleErr *errorss.BusinessRuleError
+	assert.ErrorAs(t, err, &ruleErr)
+	assert.Equal(t, "package_not_pinned", ruleErr.Rule)
+}
+
+func Test_SetLazyFunctions(t *testing.T) {
+	dbPort := &port.MockDbAdapter{Packages: []entity.Package{{Name: "team", Aliases: map[string]string{"k": "kubectl"}}}}
+	service := NewPackageService(dbPort)
+
+	assert.NoError(t, service.SetLazyFunctions("team", true))
+	assert.True(t, dbPort.Packages[0].LazyFunctions)
+	assert.Equal(t, map[string]string{"k": "kubectl"}, dbPort.Packages[0].Aliases)
+
+	assert.NoError(t, service.SetLazyFunctions("team", false))
+	assert.False(t, dbPort.Packages[0].LazyFunctions)
+
+	err := service.SetLazyFunctions("unknown", true)
+	var notFoundErr *errorss.NotFoundError
+	assert.ErrorAs(t, err, &notFoundErr)
+}
+
+func Test_PreviewUpdates(t *testing.T) {
+	dbPort := &port.MockDbAdapter{
+		Packages: []entity.Package{{Name: "team"}, {Name: "current"}, {Name: "local"}},
+		Incoming: map[string]*entity.IncomingUpdate{
+			"team": {
+				Package: "team",
+				From:    "aaa",
+				To:      "bbb",
+				Current: entity.PackageContent{
+					Aliases:   map[string]string{"gs": "git status", "old": "ls"},
+					Exports:   map[string]entity.Export{"EDITOR": {Value: "vim"}},
+					Functions: map[string]string{"deploy": "deploy() { v1; }", "same": "same() { :; }"},
+				},
+				Incoming: entity.PackageContent{
+					Aliases:    map[string]string{"gs": "git status -sb"},
+					Exports:    map[string]entity.Export{"EDITOR": {Value: "vim"}, "PATH": {Prepend: []string{"~/bin"}}},
+					Functions:  map[string]string{"deploy": "deploy() { v2; }", "same": "same() { :; }", "build": "build() { make; }"},
+					GitAliases: map[string]string{"co": "checkout"},
+				},
+			},
+			"current": {Package: "current", From: "ccc", To: "ccc"},
+		},
+	}
+
+	preview, err := NewPackageService(dbPort).PreviewUpdates()
+	assert.NoError(t, err)
+	assert.Empty(t, preview.Errors)
+	assert.Equal(t, []string{"current"}, preview.UpToDate)
+	assert.Equal(t, []string{"local"}, preview.Skipped)
+	assert.Len(t, preview.Packages, 1)
+	assert.Equal(t, []entity.EntryChange{
+		{Kind: entity.ConflictAlias, Name: "gs", Action: entity.ChangeChanged, From: "git status", To: "git status -sb"},
+		{Kind: entity.ConflictAlias, Name: "old", Action: entity.ChangeRemoved, From: "ls"},
+		{Kind: entity.ConflictExport, Name: "PATH", Action: entity.ChangeAdded, To: "[prepend: ~/bin]"},
+		{Kind: entity.ConflictFunction, Name: "build", Action: entity.ChangeAdded},
+		{Kind: entity.ConflictFunction, Name: "deploy", Action: entity.ChangeChanged},
+		{Kind: entity.ConflictGitAlias, Name: "co", Action: entity.ChangeAdded, To: "checkout"},
+	}, preview.Packages[0].Changes)
+	assert.Equal(t, "alias `gs` changed from git status to git status -sb", preview.Packages[0].Changes[0].String())
+	assert.Equal(t, "function `build` added", preview.Packages[0].Changes[3].String())
+}
+
+func Test_GetPackagesStatus(t *testing.T) {
+	dbPort := &port.MockDbAdapter{
+		Packages: []entity.Package{{Name: "local"}, {Name: "team"}, {Name: "old"}},
+		Enabled:  []string{"team", "local"},
+		Pins:     map[string]string{"team": "v1.0.0"},
+		Statuses: map[string]entity.PackageStatus{
+			"team":  {Git: true, Remote: "https://git.example.com/team", Branch: "main", Ahead: 1, Behind: 2},
+			"local": {Git: true, Modified: []string{"db.toml"}},
+		},
+	}
+
+	statuses, err := NewPackageService(dbPort).GetPackagesStatus(false)
+	assert.NoError(t, err)
+	assert.Equal(t, []entity.PackageStatus{
+		{Name: "team", Enabled: true, Pin: "v1.0.0", Git: true, Remote: "https://git.example.com/team", Branch: "main", Ahead: 1, Behind: 2},
+		{Name: "local", Enabled: true, Git: true, Modified: []string{"db.toml"}},
+		{Name: "old"},
+	}, statuses)
+	assert.True(t, statuses[1].LocalOnly())
+	assert.False(t, statuses[1].Clean())
+}
+
+func Test_GetPackageHistory(t *testing.T) {
+	dbPort := &port.MockDbAdapter{
+		Packages: []entity.Package{{Name: "team"}},
+		Commits: map[string][]entity.PackageCommit{
+			"team": {{Hash: "ccc", Subject: "Change gs"}, {Hash: "bbb", Subject: "Add gs"}, {Hash: "aaa", Subject: "initial"}},
+		},
+		Contents: map[string]map[string]entity.PackageContent{
+			"team": {
+				"aaa": {Exports: map[string]entity.Export{"EDITOR": {Value: "vim"}}},
+				"bbb": {Aliases: map[string]string{"gs": "git status"}, Exports: map[string]entity.Export{"EDITOR": {Value: "vim"}}},
+				"ccc": {Aliases: map[string]string{"gs": "git status -sb"}, Exports: map[string]entity.Export{"EDITOR": {Value: "vim"}}},
+			},
+		},
+	}
+	packageService := NewPackageService(dbPort)
+
+	history, err := packageService.GetPackageHistory("team", 10)
+	assert.NoError(t, err)
+	assert.Len(t, history, 3)
+	assert.Equal(t, []entity.EntryChange{
+		{Kind: entity.ConflictAlias, Name: "gs", Action: entity.ChangeChanged, From: "git status", To: "git status -sb"},
+	}, history[0].Changes)
+	assert.Equal(t, []entity.EntryChange{
+		{Kind: entity.ConflictAlias, Name: "gs", Action: entity.ChangeAdded, To: "git status"},
+	}, history[1].Changes)
+	// The first commit is compared with an empty package
+	assert.Equal(t, []entity.EntryChange{
+		{Kind: entity.ConflictExport, Name: "EDITOR", Action: entity.ChangeAdded, To: "vim"},
+	}, history[2].Changes)
+
+	// The oldest commit shown is still compared with its parent
+	history, err = packageService.GetPackageHistory("team", 2)
+	assert.NoError(t, err)
+	assert.Len(t, history, 2)
+	assert.Equal(t, entity.ChangeAdded, history[1].Changes[0].Action)
+	assert.Equal(t, "gs", history[1].Changes[0].Name)
+}
+
+func Test_RollbackPackage(t *testing.T) {
+	dbPort := &port.MockDbAdapter{
+		Packages: []entity.Package{{Name: "team"}},
+		Statuses: map[string]entity.PackageStatus{
+			"team": {Git: true, Branch: "main", Commit: "ccc0000000000000"},
+		},
+		Commits: map[string][]entity.PackageCommit{
+			"team": {{Hash: "ccc0000000000000"}, {Hash: "bbb0000000000000"}, {Hash: "aaa0000000000000"}},
+		},
+		Contents: map[string]map[string]entity.PackageContent{
+			"team": {
+				"aaa0000000000000": {},
+				"bbb0000000000000": {Aliases: map[string]string{"gs": "git status"}},
+				"ccc0000000000000": {Aliases: map[string]string{"gs": "git status -sb"}},
+			},
+		},
+	}
+	packageService := NewPackageService(dbPort)
+
+	// The previous commit by default
+	rollback, err := packageService.RollbackPackage("team", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "bbb0000000000000", rollback.To)
+	assert.Equal(t, []entity.EntryChange{
+		{Kind: entity.ConflictAlias, Name: "gs", Action: entity.ChangeChanged, From: "git status -sb", To: "git status"},
+	}, rollback.Changes)
+	assert.Equal(t, "bbb0000000000000", dbPort.Restored["team"])
+	assert.Equal(t, "Roll back to bbb000000000\n\n- alias `gs` changed from git status -sb to git status", dbPort.RestoreMessages["team"])
+
+	rollback, err = packageService.RollbackPackage("team", "aaa")
+	assert.NoError(t, err)
+	assert.Equal(t, "aaa0000000000000", rollback.To)
+	assert.Equal(t, entity.ChangeRemoved, rollback.Changes[0].Action)
+
+	_, err = packageService.RollbackPackage("team", "ccc")
+	assert.ErrorContains(t, err, "already at commit ccc000000000")
+
+	dbPort.Pins = map[string]string{"team": "v1.0.0"}
+	_, err = packageService.RollbackPackage("team", "")
+	assert.ErrorContains(t, err, "is pinned to 'v1.0.0'")
+	dbPort.Pins = nil
+
+	dbPort.Statuses["team"] = entity.PackageStatus{Git: true, Branch: "main", Commit: "ccc0000000000000", Modified: []string{"db.toml"}}
+	_, err = packageService.RollbackPackage("team", "")
+	assert.ErrorContains(t, err, "has local changes")
+}
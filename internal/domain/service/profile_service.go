@@ -0,0 +1,63 @@
+package service
+
+import (
+	"duh/internal/domain/entity"
+	"duh/internal/domain/port"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+type ProfileService struct {
+	environmentPort port.EnvironmentPort
+}
+
+func NewProfileService(environmentPort port.EnvironmentPort) *ProfileService {
+	return &ProfileService{
+		environmentPort: environmentPort,
+	}
+}
+
+// ResolveActiveProfile selects the profile used on this machine among profiles, the default profile included.
+// hostProfiles holds the profile chosen with duh profile use, by hostname.
+//
+// Business rules:
+// - DUH_PROFILE comes first, then the choice made with duh profile use on this machine,
+// then the first profile (by name) with a matching hostname rule, and finally the default profile
+// - a profile that does not exist never breaks the shell setup: an unknown DUH_PROFILE falls back to the default
+// profile with a warning, and a choice of a removed profile is ignored
+func (p *ProfileService) ResolveActiveProfile(profiles []entity.Profile, hostProfiles map[string]string) entity.ActiveProfile {
+	exists := func(name string) bool {
+		return name == entity.DefaultProfileName ||
+			slices.ContainsFunc(profiles, func(profile entity.Profile) bool { return profile.Name == name })
+	}
+	defaultProfile := entity.ActiveProfile{Name: entity.DefaultProfileName, Reason: entity.ProfileSelectedByDefault}
+
+	if name := p.environmentPort.Getenv(entity.ProfileEnvVar); name != "" {
+		if exists(name) {
+			return entity.ActiveProfile{Name: name, Reason: entity.ProfileSelectedByEnv}
+		}
+		defaultProfile.Warning = fmt.Sprintf("profile '%s' from %s does not exist, using the %s profile",
+			name, entity.ProfileEnvVar, entity.DefaultProfileName)
+		return defaultProfile
+	}
+
+	hostname, err := p.environmentPort.Hostname()
+	if err != nil {
+		return defaultProfile
+	}
+	if name, ok := hostProfiles[hostname]; ok && exists(name) {
+		return entity.ActiveProfile{Name: name, Reason: entity.ProfileSelectedByUse}
+	}
+	byName := slices.Clone(profiles)
+	slices.SortFunc(byName, func(a, b entity.Profile) int { return strings.Compare(a.Name, b.Name) })
+	for _, profile := range byName {
+		for _, pattern := range profile.Hostnames {
+			if matched, _ := path.Match(pattern, hostname); matched {
+				return entity.ActiveProfile{Name: profile.Name, Reason: entity.ProfileSelectedByHostname}
+			}
+		}
+	}
+	return defaultProfile
+}
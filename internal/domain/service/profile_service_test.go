@@ -0,0 +1,51 @@
+package service
+
+import (
+	"duh/internal/domain/entity"
+	"duh/internal/domain/port"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testProfiles = []entity.Profile{
+	{Name: entity.DefaultProfileName},
+	{Name: "work", Hostnames: []string{"corp-*"}},
+	{Name: "lab", Hostnames: []string{"corp-lab"}},
+	{Name: "home"},
+}
+
+func Test_ResolveActiveProfile(t *testing.T) {
+	environment := &port.MockEnvironmentPort{Host: "laptop"}
+	profileService := NewProfileService(environment)
+
+	assert.Equal(t, entity.ActiveProfile{Name: entity.DefaultProfileName, Reason: entity.ProfileSelectedByDefault},
+		profileService.ResolveActiveProfile(testProfiles, nil))
+
+	// The first matching hostname rule, by profile name, wins
+	environment.Host = "corp-lab"
+	assert.Equal(t, entity.ActiveProfile{Name: "lab", Reason: entity.ProfileSelectedByHostname},
+		profileService.ResolveActiveProfile(testProfiles, nil))
+
+	// duh profile use overrides the hostname rules, unless its profile was removed
+	assert.Equal(t, entity.ActiveProfile{Name: "home", Reason: entity.ProfileSelectedByUse},
+		profileService.ResolveActiveProfile(testProfiles, map[string]string{"corp-lab": "home"}))
+	assert.Equal(t, entity.ActiveProfile{Name: "lab", Reason: entity.ProfileSelectedByHostname},
+		profileService.ResolveActiveProfile(testProfiles, map[string]string{"corp-lab": "removed"}))
+
+	// DUH_PROFILE overrides everything
+	environment.Env = map[string]string{entity.ProfileEnvVar: "work"}
+	assert.Equal(t, entity.ActiveProfile{Name: "work", Reason: entity.ProfileSelectedByEnv},
+		profileService.ResolveActiveProfile(testProfiles, map[string]string{"corp-lab": "home"}))
+}
+
+func Test_ResolveActiveProfile_UnknownEnvProfile(t *testing.T) {
+	environment := &port.MockEnvironmentPort{Host: "corp-lab", Env: map[string]string{entity.ProfileEnvVar: "typo"}}
+
+	active := NewProfileService(environment).ResolveActiveProfile(testProfiles, nil)
+	assert.Equal(t, entity.ActiveProfile{
+		Name:    entity.DefaultProfileName,
+		Reason:  entity.ProfileSelectedByDefault,
+		Warning: "profile 'typo' from DUH_PROFILE does not exist, using the default profile",
+	}, active)
+}
@@ -1,6 +1,7 @@
 package gitconfig
 
 import (
+	"duh/internal/domain/entity"
 	"os"
 	"path/filepath"
 	"slices"
@@ -43,6 +44,43 @@ func writeConfigFile(path string, cfg *config.Config) error {
 	return err
 }
 
+// ReadAliases returns the git aliases declared in the [alias] section of the given file
+func ReadAliases(filePath string) (map[string]string, error) {
+	cfg, err := readConfigFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	aliases := map[string]string{}
+	sectionAlias := cfg.Raw.Section("alias")
+	if sectionAlias == nil {
+		return aliases, nil
+	}
+	for _, opt := range sectionAlias.Options {
+		aliases[opt.Key] = opt.Value
+	}
+	return aliases, nil
+}
+
+// ReadEntries returns every variable set in the given file, in order, with keys written as section[.subsection].name
+func ReadEntries(filePath string) ([]entity.GitConfigEntry, error) {
+	cfg, err := readConfigFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	entries := []entity.GitConfigEntry{}
+	for _, section := range cfg.Raw.Sections {
+		for _, opt := range section.Options {
+			entries = append(entries, entity.GitConfigEntry{Key: section.Name + "." + opt.Key, Value: opt.Value})
+		}
+		for _, subsection := range section.Subsections {
+			for _, opt := range subsection.Options {
+				entries = append(entries, entity.GitConfigEntry{Key: section.Name + "." + subsection.Name + "." + opt.Key, Value: opt.Value})
+			}
+		}
+	}
+	return entries, nil
+}
+
 func AddNewIncludeIfNotExists(newInclude string, filePath string) error {
 	cfg, err := readConfigFile(filePath)
 	if err != nil {
@@ -1,6 +1,7 @@
 package gitconfig
 
 import (
+	"duh/internal/domain/entity"
 	"duh/internal/domain/utils"
 	"os"
 	"slices"
@@ -32,3 +33,27 @@ func Test_AddNewInclude(t *testing.T) {
 	})
 	assert.True(t, containsNewPath)
 }
+
+func Test_ReadAliases(t *testing.T) {
+	aliases, err := ReadAliases("gitconfig.ini")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"st": "status -sb",
+		"lg": "log --oneline --graph",
+	}, aliases)
+}
+
+func Test_ReadEntries(t *testing.T) {
+	entries, err := ReadEntries("gitconfig.ini")
+	assert.NoError(t, err)
+	assert.Equal(t, []entity.GitConfigEntry{
+		{Key: "core.gitProxy", Value: "ssh for kernel.org"},
+		{Key: "core.gitProxy", Value: "default-proxy"},
+		{Key: "include.path", Value: "/path/to/foo.inc"},
+		{Key: "include.path", Value: "foo"},
+		{Key: "include.path", Value: "~/foo"},
+		{Key: "alias.st", Value: "status -sb"},
+		{Key: "alias.lg", Value: "log --oneline --graph"},
+		{Key: "url.git@github.com:.insteadOf", Value: "https://github.com/"},
+	}, entries)
+}
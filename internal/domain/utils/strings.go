@@ -8,6 +8,7 @@ func EscapeShellString(input string) string {
 		`\`, `\\`, // backslash must be first
 		`$`, `\$`, // variable expansion
 		`"`, `\"`, // double quote
+		"`", "\\`", // command substitution
 	)
 	return replacer.Replace(input)
 }
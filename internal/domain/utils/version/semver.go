@@ -0,0 +1,86 @@
+package version
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DevVersion is the version of duh binaries built without ldflags
+const DevVersion = "dev"
+
+type semver struct {
+	numbers    [3]int
+	preRelease string
+}
+
+// parseSemver reads versions like 1.2.3, v1.2 or 1.2.3-rc.1, ignoring build metadata
+func parseSemver(value string) (semver, error) {
+	parsed := semver{}
+	trimmed := strings.TrimPrefix(strings.TrimSpace(value), "v")
+	trimmed, _, _ = strings.Cut(trimmed, "+")
+	trimmed, parsed.preRelease, _ = strings.Cut(trimmed, "-")
+	parts := strings.Split(trimmed, ".")
+	if len(parts) > 3 {
+		return parsed, fmt.Errorf("invalid version '%s'", value)
+	}
+	for i, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil || number < 0 {
+			return parsed, fmt.Errorf("invalid version '%s'", value)
+		}
+		parsed.numbers[i] = number
+	}
+	return parsed, nil
+}
+
+// Compare returns -1, 0 or 1 when the semantic version a is lower, equal or greater than b.
+// A pre-release is lower than its release, pre-releases are compared as strings.
+func Compare(a, b string) (int, error) {
+	first, err := parseSemver(a)
+	if err != nil {
+		return 0, err
+	}
+	second, err := parseSemver(b)
+	if err != nil {
+		return 0, err
+	}
+	for i := range first.numbers {
+		if first.numbers[i] != second.numbers[i] {
+			if first.numbers[i] < second.numbers[i] {
+				return -1, nil
+			}
+			return 1, nil
+		}
+	}
+	switch {
+	case first.preRelease == second.preRelease:
+		return 0, nil
+	case first.preRelease == "":
+		return 1, nil
+	case second.preRelease == "":
+		return -1, nil
+	}
+	return strings.Compare(first.preRelease, second.preRelease), nil
+}
+
+// IsRelease tells if value is a complete release version like 1.2.0 or v1.2.0, without pre-release
+func IsRelease(value string) bool {
+	parsed, err := parseSemver(value)
+	return err == nil && parsed.preRelease == "" && strings.Count(value, ".") == 2 && !strings.Contains(value, "+")
+}
+
+// Major returns the major number of a semantic version
+func Major(value string) (int, error) {
+	parsed, err := parseSemver(value)
+	if err != nil {
+		return 0, err
+	}
+	return parsed.numbers[0], nil
+}
+
+// Validate checks that value is a semantic version
+func Validate(value string) error {
+	_, err := parseSemver(value)
+	return err
+}
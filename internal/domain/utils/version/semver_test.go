@@ -0,0 +1,53 @@
+package version
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompare(t *testing.T) {
+	cases := []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"v1.2.3", "1.2.3", 0},
+		{"1.2", "1.2.0", 0},
+		{"1.2.3", "1.10.0", -1},
+		{"2.0.0", "1.99.99", 1},
+		{"1.0.0-rc.1", "1.0.0", -1},
+		{"1.0.0-beta", "1.0.0-alpha", 1},
+		{"1.0.0+build.5", "1.0.0", 0},
+	}
+	for _, c := range cases {
+		result, err := Compare(c.a, c.b)
+		assert.NoError(t, err)
+		assert.Equal(t, c.expected, result, "%s vs %s", c.a, c.b)
+	}
+}
+
+func TestCompare_Invalid(t *testing.T) {
+	_, err := Compare("one.two", "1.0.0")
+	assert.Error(t, err)
+	_, err = Compare("1.0.0", "1.2.3.4")
+	assert.Error(t, err)
+}
+
+func TestIsRelease(t *testing.T) {
+	assert.True(t, IsRelease("1.2.0"))
+	assert.True(t, IsRelease("v1.2.0"))
+	assert.False(t, IsRelease("v1.2"))
+	assert.False(t, IsRelease("1.2.0-rc.1"))
+	assert.False(t, IsRelease("main"))
+	assert.False(t, IsRelease("3f2a9c1"))
+}
+
+func TestMajor(t *testing.T) {
+	major, err := Major("v2.1.0")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, major)
+
+	_, err = Major("3.x")
+	assert.EqualError(t, err, "invalid version '3.x'")
+}
@@ -4,12 +4,39 @@ type Key = string
 type Value = string
 
 type AliasesMap = map[Key]Value
-type ExportsMap = map[Key]Value
+type ExportsMap = map[Key]ExportDto
+
+type ExportDto struct {
+	Value   string
+	Literal bool
+	Prepend []string
+	Append  []string
+}
 
 type RepositoryDto struct {
 	Aliases  AliasesMap
 	Exports  ExportsMap
 	Metadata MetadataDto
+	Manifest ManifestDto
+	// Conditions of the aliases and exports only injected in some environments, by name
+	AliasConditions  map[Key]ConditionDto
+	ExportConditions map[Key]ConditionDto
+	LazyFunctions    bool
+}
+
+type ConditionDto struct {
+	OS        string
+	Hostname  string
+	Shell     string
+	IfCommand string
+}
+
+type ManifestDto struct {
+	Description   string
+	Version       string
+	Authors       []string
+	MinDuhVersion string
+	Dependencies  []string
 }
 
 type MetadataDto struct {
@@ -18,7 +45,45 @@ type MetadataDto struct {
 }
 
 type UserPreferenceDto struct {
+	// Packages of the default profile
 	Repositories RepositoriesPreferenceDto
+	Profiles     map[string]*ProfileDto
+	// Profile chosen with duh profile use, by hostname
+	HostProfiles map[string]string
+	// Ref each pinned package is checked out on, by package name
+	Pins map[string]string
+	// Git identity overrides for the commits duh makes, by package name
+	Identities map[string]IdentityDto
+	// Policy for the code outside functions in function scripts, empty for allow
+	TopLevelCode string
+}
+
+// IdentityDto overrides the git config, empty fields keep its values
+type IdentityDto struct {
+	Name          string
+	Email         string
+	SigningKey    string
+	SigningFormat string
+	// Nil keeps the commit.gpgsign of the git config
+	Sign *bool
+}
+
+type ProfileDto struct {
+	Repositories RepositoriesPreferenceDto
+	// Hostname patterns selecting the profile automatically
+	Hostnames []string
+}
+
+type LockDto struct {
+	Packages []LockedPackageDto
+}
+
+type LockedPackageDto struct {
+	Name    string
+	Url     string
+	Commit  string
+	Enabled bool
+	Pin     string
 }
 
 type RepositoriesPreferenceDto struct {
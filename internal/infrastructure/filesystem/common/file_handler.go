@@ -7,4 +7,12 @@ type FileHandler interface {
 	SaveRepositoryFile(path string, data *RepositoryDto) error
 	LoadUserPreferenceFile(path string) (*UserPreferenceDto, error)
 	SaveUserPreferenceFile(path string, data *UserPreferenceDto) error
+	LoadLockFile(path string) (*LockDto, error)
+	SaveLockFile(path string, data *LockDto) error
+	// Merge the local and remote versions of a repository file changed from a common base, key by key
+	MergeRepositoryFile(base []byte, remote []byte, local []byte, resolve KeyResolver) ([]byte, error)
 }
+
+// KeyResolver picks the value to keep for a key changed differently on both sides of a merge,
+// given the dotted path of the key and its value on each side, empty when the side removed it
+type KeyResolver func(key string, local string, remote string) (keepLocal bool, err error)
@@ -3,9 +3,12 @@ package file_db
 import (
 	"duh/internal/domain/constants"
 	"duh/internal/domain/entity"
+	"duh/internal/domain/errorss"
+	"duh/internal/domain/port"
 	"duh/internal/domain/utils/gitconfig"
 	"duh/internal/infrastructure/filesystem/common"
 	"duh/internal/infrastructure/filesystem/fs_user_repository"
+	"duh/internal/infrastructure/filesystem/function"
 	gitt "duh/internal/infrastructure/filesystem/gitt"
 	"duh/internal/infrastructure/termm"
 	"fmt"
@@ -29,6 +32,7 @@ func NewFileDbAdapter(
 	PathProvider common.PathProvider,
 	gitConfigPathProvider common.PathProvider,
 	fileHandler common.FileHandler,
+	profileResolver port.ProfileResolver,
 ) *FileDbRepository {
 	return &FileDbRepository{
 		DirectoryService:      *common.NewDirectoryService(PathProvider),
@@ -38,6 +42,7 @@ func NewFileDbAdapter(
 		userPreferenceRepository: fs_user_repository.NewFsUserRepository(
 			fileHandler,
 			PathProvider,
+			profileResolver,
 		),
 	}
 }
@@ -48,14 +53,15 @@ func (f *FileDbRepository) GetEnabledPackages() ([]entity.Package, error) {
 		return nil, err
 	}
 
-	userPrefs, err := f.userPreferenceRepository.GetUserPreference()
+	_, profile, err := f.userPreferenceRepository.GetActivePreference()
 	if err != nil {
 		return nil, err
 	}
 
+	// Keep the activation order: later packages take precedence over earlier ones
 	enabledRepos := []entity.Package{}
-	for _, repoName := range allRepoNames {
-		if !slices.Contains(userPrefs.Repositories.ActivatedRepositories, repoName) {
+	for _, repoName := range profile.ActivatedRepositories {
+		if !slices.Contains(allRepoNames, repoName) {
 			continue
 		}
 		repo, err := f.GetRepositoryByName(repoName)
@@ -68,19 +74,27 @@ func (f *FileDbRepository) GetEnabledPackages() ([]entity.Package, error) {
 }
 
 func (f *FileDbRepository) GetDefaultPackage() (*entity.Package, error) {
-	userPrefs, err := f.userPreferenceRepository.GetUserPreference()
+	_, profile, err := f.userPreferenceRepository.GetActivePreference()
 	if err != nil {
 		return nil, err
 	}
-	defaultRepoName := userPrefs.Repositories.DefaultRepositoryName
+	defaultRepoName := profile.DefaultRepositoryName
 	return f.GetRepositoryByName(defaultRepoName)
 }
 
 // Add or update a repository
 func (f *FileDbRepository) UpsertPackage(repo entity.Package) error {
+	exports := common.ExportsMap{}
+	for name, export := range repo.Exports {
+		exports[name] = common.ExportDto(export)
+	}
 	repoDto := common.RepositoryDto{
-		Aliases: repo.Aliases,
-		Exports: repo.Exports,
+		Aliases:          repo.Aliases,
+		Exports:          exports,
+		AliasConditions:  toConditionDtos(repo.AliasConditions),
+		ExportConditions: toConditionDtos(repo.ExportConditions),
+		Manifest:         common.ManifestDto(repo.Manifest),
+		LazyFunctions:    repo.LazyFunctions,
 	}
 
 	repoPath, err := f.DirectoryService.CreatePackage(repo.Name)
@@ -89,52 +103,56 @@ func (f *FileDbRepository) UpsertPackage(repo entity.Package) error {
 	}
 	file_name := constants.PackageDbFileName + "." + f.fileHandler.Extension()
 	dbPath := filepath.Join(repoPath, file_name)
+	// Metadata is not part of the entity, keep the one already saved
+	if existing, err := f.fileHandler.LoadRepositoryFile(dbPath); err == nil {
+		repoDto.Metadata = existing.Metadata
+	}
 	return f.fileHandler.SaveRepositoryFile(dbPath, &repoDto)
 }
 
 func (f *FileDbRepository) DeletePackage(repoName string) error {
-	return f.DirectoryService.DeletePackage(repoName)
+	if err := f.DirectoryService.DeletePackage(repoName); err != nil {
+		return err
+	}
+	return f.movePin(repoName, "")
 }
 
 // Set a repository as the default one
 func (f *FileDbRepository) ChangeDefaultPackage(repoName string) error {
-	userPrefs, err := f.userPreferenceRepository.GetUserPreference()
+	userPrefs, profile, err := f.userPreferenceRepository.GetActivePreference()
 	if err != nil {
 		return err
 	}
-	userPrefs.Repositories.DefaultRepositoryName = repoName
+	profile.DefaultRepositoryName = repoName
 	return f.userPreferenceRepository.SaveUserPreference(userPrefs)
 }
 
 // Enable a repository to be used
 func (f *FileDbRepository) EnablePackage(repoName string) error {
-	userPrefs, err := f.userPreferenceRepository.GetUserPreference()
+	userPrefs, profile, err := f.userPreferenceRepository.GetActivePreference()
 	if err != nil {
 		return err
 	}
-	activatedRepos := userPrefs.Repositories.ActivatedRepositories
-	if !slices.Contains(activatedRepos, repoName) {
-		activatedRepos = append(activatedRepos, repoName)
-		userPrefs.Repositories.ActivatedRepositories = activatedRepos
+	if !slices.Contains(profile.ActivatedRepositories, repoName) {
+		profile.ActivatedRepositories = append(profile.ActivatedRepositories, repoName)
 	}
 	return f.userPreferenceRepository.SaveUserPreference(userPrefs)
 }
 
 // Disable a repository from being used
 func (f *FileDbRepository) DisablePackage(repoName string) error {
-	userPrefs, err := f.userPreferenceRepository.GetUserPreference()
+	userPrefs, profile, err := f.userPreferenceRepository.GetActivePreference()
 	if err != nil {
 		return err
 	}
-	activatedRepos := userPrefs.Repositories.ActivatedRepositories
-	if slices.Contains(activatedRepos, repoName) {
+	if slices.Contains(profile.ActivatedRepositories, repoName) {
 		newActivatedRepos := []string{}
-		for _, r := range activatedRepos {
+		for _, r := range profile.ActivatedRepositories {
 			if r != repoName {
 				newActivatedRepos = append(newActivatedRepos, r)
 			}
 		}
-		userPrefs.Repositories.ActivatedRepositories = newActivatedRepos
+		profile.ActivatedRepositories = newActivatedRepos
 	}
 	return f.userPreferenceRepository.SaveUserPreference(userPrefs)
 }
@@ -145,12 +163,16 @@ func (f *FileDbRepository) RenamePackage(oldName, newName string) error {
 	if err != nil {
 		return err
 	}
-	newRepoPath, err := f.DirectoryService.GetRepositoryPath(newName)
-	if err != nil {
-		return err
+	// GetRepositoryPath would create the new directory, and a directory cannot be renamed onto another one
+	newRepoPath := filepath.Join(filepath.Dir(oldRepoPath), newName)
+	if _, err := os.Stat(newRepoPath); err == nil {
+		return fmt.Errorf("repository with name '%s' already exists", newName)
 	}
 
-	return os.Rename(oldRepoPath, newRepoPath)
+	if err := os.Rename(oldRepoPath, newRepoPath); err != nil {
+		return err
+	}
+	return f.movePin(oldName, newName)
 }
 
 func (f *FileDbRepository) AddPackage(url string, name *string) (string, error) {
@@ -171,6 +193,28 @@ func (f *FileDbRepository) AddPackage(url string, name *string) (string, error)
 	return finalName, gitt.CloneGitRepository(url, repoPath)
 }
 
+func (f *FileDbRepository) FindPackageByUrl(url string) (string, bool, error) {
+	repoNames, err := f.DirectoryService.ListRepositoryNames()
+	if err != nil {
+		return "", false, err
+	}
+	for _, repoName := range repoNames {
+		repoPath, err := f.DirectoryService.GetRepositoryPath(repoName)
+		if err != nil {
+			return "", false, err
+		}
+		// Packages created locally are not git repositories, they cannot match
+		origin, err := gitt.GetOriginUrl(repoPath)
+		if err != nil || origin == "" {
+			continue
+		}
+		if gitt.SameRepositoryUrl(origin, url) {
+			return repoName, true, nil
+		}
+	}
+	return "", false, nil
+}
+
 func (f *FileDbRepository) CreatePackage(name string) (string, error) {
 	repo, err := f.GetRepositoryByName(name)
 	if err == nil && repo != nil {
@@ -184,7 +228,7 @@ func (f *FileDbRepository) CreatePackage(name string) (string, error) {
 	// Initialize empty file
 	repoDto := common.RepositoryDto{
 		Aliases: map[string]string{},
-		Exports: map[string]string{},
+		Exports: common.ExportsMap{},
 		Metadata: common.MetadataDto{
 			NameOrigin: name,
 		},
@@ -199,14 +243,343 @@ func (f *FileDbRepository) CreatePackage(name string) (string, error) {
 	return repoPath, f.EnablePackage(name)
 }
 
-func (f *FileDbRepository) UpdatePackages(strategy string) (entity.PackageUpdateResults, error) {
+func (f *FileDbRepository) UpdatePackages(options entity.UpdateOptions) (entity.PackageUpdateResults, error) {
 	path, err := f.getBasePath()
 	if err != nil {
 		return entity.PackageUpdateResults{}, err
 	}
 	reposPath := filepath.Join(path, constants.PackagesDirName)
 
-	return gitt.PullAllRepositories(reposPath, strategy)
+	userPrefs, err := f.userPreferenceRepository.GetUserPreference()
+	if err != nil {
+		return entity.PackageUpdateResults{}, err
+	}
+	settings := gitt.PullSettings{
+		Pins:    userPrefs.Pins,
+		Mergers: f.getFileMergers(options.Resolve),
+		Identity: func(repoName string, repoPath string) (gitt.CommitIdentity, error) {
+			return identityFor(repoName, repoPath, userPrefs.Identities)
+		},
+		Describe: f.describeLocalChanges,
+	}
+	return gitt.PullAllRepositories(reposPath, settings, options)
+}
+
+// identityFor resolves the identity committing to a package from the git config,
+// overridden by the identity set for the package in the user preferences
+func identityFor(repoName string, repoPath string, overrides map[string]common.IdentityDto) (gitt.CommitIdentity, error) {
+	identity, err := gitt.ResolveIdentity(repoPath)
+	if err != nil {
+		return identity, err
+	}
+	override, ok := overrides[repoName]
+	if !ok {
+		return identity, nil
+	}
+	if override.Name != "" {
+		identity.Name = override.Name
+	}
+	if override.Email != "" {
+		identity.Email = override.Email
+	}
+	if override.SigningKey != "" {
+		identity.SigningKey = override.SigningKey
+	}
+	if override.SigningFormat != "" {
+		identity.SignFormat = override.SigningFormat
+	}
+	if override.Sign != nil {
+		identity.Sign = *override.Sign
+	}
+	return identity, nil
+}
+
+// describeLocalChanges describes the uncommitted changes of a package as a commit message,
+// listing the entries added, removed or changed since the last commit
+func (f *FileDbRepository) describeLocalChanges(repoName string, repoPath string) (string, error) {
+	committed := entity.PackageContent{}
+	// A repository without commits yet has everything added
+	if head, err := gitt.GetHeadCommit(repoPath); err == nil {
+		content, err := f.readPackageContent(repoName, repoPath, head)
+		if err != nil {
+			return "", fmt.Errorf("failed to read the committed content of package '%s': %w", repoName, err)
+		}
+		committed = content
+	}
+	working, err := f.readPackageDirectory(repoName, repoPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the content of package '%s': %w", repoName, err)
+	}
+	return entity.CommitMessage(committed.Diff(working)), nil
+}
+
+// getFileMergers returns how files changed both locally and on the remote are merged during a rebase:
+// the db file is merged key by key, resolve picking the side to keep for keys changed on both sides
+func (f *FileDbRepository) getFileMergers(resolve func(entity.MergeConflict) (string, error)) map[string]gitt.FileMerger {
+	fileName := constants.PackageDbFileName + "." + f.fileHandler.Extension()
+	return map[string]gitt.FileMerger{
+		fileName: func(repoName string, base []byte, remote []byte, local []byte) ([]byte, error) {
+			return f.fileHandler.MergeRepositoryFile(base, remote, local, func(key string, localValue string, remoteValue string) (bool, error) {
+				if resolve == nil {
+					return false, fmt.Errorf("key '%s' changed both locally and on the remote", key)
+				}
+				choice, err := resolve(entity.MergeConflict{
+					Package: repoName,
+					File:    fileName,
+					Key:     key,
+					Local:   localValue,
+					Remote:  remoteValue,
+				})
+				return choice == entity.KeepLocal, err
+			})
+		},
+	}
+}
+
+func (f *FileDbRepository) FetchIncomingUpdate(repoName string) (*entity.IncomingUpdate, error) {
+	repoPath, err := f.DirectoryService.GetRepositoryPath(repoName)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(filepath.Join(repoPath, git.GitDirName)); os.IsNotExist(err) {
+		return nil, nil
+	}
+	userPrefs, err := f.userPreferenceRepository.GetUserPreference()
+	if err != nil {
+		return nil, err
+	}
+	from, base, to, err := gitt.FetchIncoming(repoPath, userPrefs.Pins[repoName])
+	if err != nil {
+		return nil, err
+	}
+	if from == "" {
+		return nil, nil
+	}
+
+	update := entity.IncomingUpdate{Package: repoName, From: from, To: to}
+	if base == to {
+		// Nothing new on the remote, the local commits not pushed yet are not incoming changes
+		update.To = from
+		return &update, nil
+	}
+	if update.Current, err = f.readPackageContent(repoName, repoPath, base); err != nil {
+		return nil, err
+	}
+	if update.Incoming, err = f.readPackageContent(repoName, repoPath, to); err != nil {
+		return nil, err
+	}
+	return &update, nil
+}
+
+// readPackageContent reads what a package injects at a commit, from a temporary copy of its files
+func (f *FileDbRepository) readPackageContent(repoName string, repoPath string, commit string) (entity.PackageContent, error) {
+	dir, err := os.MkdirTemp("", "duh-preview-")
+	if err != nil {
+		return entity.PackageContent{}, err
+	}
+	defer os.RemoveAll(dir)
+	if err := gitt.ExtractCommit(repoPath, commit, dir); err != nil {
+		return entity.PackageContent{}, fmt.Errorf("failed to read commit '%s': %w", commit, err)
+	}
+	return f.readPackageDirectory(repoName, dir)
+}
+
+// readPackageDirectory reads what the files of a package in dir inject
+func (f *FileDbRepository) readPackageDirectory(repoName string, dir string) (entity.PackageContent, error) {
+	content := entity.PackageContent{
+		Aliases:    map[string]string{},
+		Exports:    map[string]entity.Export{},
+		GitAliases: map[string]string{},
+		Functions:  map[string]string{},
+	}
+	dbFilePath := filepath.Join(dir, constants.PackageDbFileName+"."+f.fileHandler.Extension())
+	if _, err := os.Stat(dbFilePath); err == nil {
+		gitConfigPath := filepath.Join(dir, constants.PackageGitconfigName)
+		if _, err := os.Stat(gitConfigPath); os.IsNotExist(err) {
+			gitConfigPath = ""
+		}
+		pkg, err := f.loadPackage(repoName, dbFilePath, gitConfigPath)
+		if err != nil {
+			return content, err
+		}
+		content.Aliases = pkg.Aliases
+		content.Exports = pkg.Exports
+		content.GitAliases = pkg.GitAliases
+	}
+
+	// Scripts which cannot be parsed are left out, they are not injected either
+	scripts, _ := function.GetScripts(filepath.Join(dir, constants.PackageFunctionsDirName))
+	for _, script := range scripts {
+		for _, fn := range script.Functions {
+			content.Functions[fn.Name] = fn.Source
+		}
+	}
+	return content, nil
+}
+
+func (f *FileDbRepository) PinPackage(repoName string, ref string) error {
+	repoPath, err := f.DirectoryService.GetRepositoryPath(repoName)
+	if err != nil {
+		return err
+	}
+	err = gitt.CheckoutRef(repoPath, ref, false)
+	if err == gitt.ErrChangesExist {
+		return fmt.Errorf("package '%s' has local changes, push or discard them before pinning it", repoName)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check out '%s' in package '%s': %w", ref, repoName, err)
+	}
+
+	userPrefs, err := f.userPreferenceRepository.GetUserPreference()
+	if err != nil {
+		return err
+	}
+	if userPrefs.Pins == nil {
+		userPrefs.Pins = map[string]string{}
+	}
+	userPrefs.Pins[repoName] = ref
+	return f.userPreferenceRepository.SaveUserPreference(userPrefs)
+}
+
+func (f *FileDbRepository) UnpinPackage(repoName string) error {
+	repoPath, err := f.DirectoryService.GetRepositoryPath(repoName)
+	if err != nil {
+		return err
+	}
+	if err := gitt.CheckoutDefaultBranch(repoPath); err != nil {
+		return fmt.Errorf("failed to check out the default branch of package '%s': %w", repoName, err)
+	}
+	return f.movePin(repoName, "")
+}
+
+func (f *FileDbRepository) GetPins() (map[string]string, error) {
+	userPrefs, err := f.userPreferenceRepository.GetUserPreference()
+	if err != nil {
+		return nil, err
+	}
+	pins := map[string]string{}
+	for name, ref := range userPrefs.Pins {
+		pins[name] = ref
+	}
+	return pins, nil
+}
+
+func (f *FileDbRepository) GetPackageRevision(repoName string) (entity.PackageRevision, error) {
+	repoPath, err := f.DirectoryService.GetRepositoryPath(repoName)
+	if err != nil {
+		return entity.PackageRevision{}, err
+	}
+	if _, err := os.Stat(filepath.Join(repoPath, git.GitDirName)); os.IsNotExist(err) {
+		return entity.PackageRevision{}, nil
+	}
+	url, err := gitt.GetOriginUrl(repoPath)
+	if err != nil {
+		return entity.PackageRevision{}, err
+	}
+	commit, err := gitt.GetHeadCommit(repoPath)
+	if err != nil {
+		return entity.PackageRevision{}, err
+	}
+	return entity.PackageRevision{Url: url, Commit: commit}, nil
+}
+
+func (f *FileDbRepository) GetPackageStatus(repoName string, fetch bool) (entity.PackageStatus, error) {
+	repoPath, err := f.DirectoryService.GetRepositoryPath(repoName)
+	if err != nil {
+		return entity.PackageStatus{}, err
+	}
+	return gitt.GetRepositoryStatus(repoPath, fetch)
+}
+
+func (f *FileDbRepository) GetPackagesStatus(repoNames []string, fetch bool) ([]entity.PackageStatus, error) {
+	statuses := make([]entity.PackageStatus, len(repoNames))
+	indexes := []int{}
+	repoPaths := []string{}
+	for index, name := range repoNames {
+		repoPath, err := f.DirectoryService.GetRepositoryPath(name)
+		if err != nil {
+			statuses[index].Error = err
+			continue
+		}
+		indexes = append(indexes, index)
+		repoPaths = append(repoPaths, repoPath)
+	}
+	read, errs := gitt.GetRepositoriesStatus(repoPaths, fetch)
+	for i, index := range indexes {
+		if errs[i] != nil {
+			statuses[index] = entity.PackageStatus{Error: errs[i]}
+			continue
+		}
+		statuses[index] = read[i]
+	}
+	return statuses, nil
+}
+
+func (f *FileDbRepository) ListPackageCommits(repoName string, limit int) ([]entity.PackageCommit, error) {
+	repoPath, err := f.DirectoryService.GetRepositoryPath(repoName)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(filepath.Join(repoPath, git.GitDirName)); os.IsNotExist(err) {
+		return []entity.PackageCommit{}, nil
+	}
+	return gitt.ListCommits(repoPath, limit)
+}
+
+func (f *FileDbRepository) ResolvePackageRef(repoName string, ref string) (string, error) {
+	repoPath, err := f.DirectoryService.GetRepositoryPath(repoName)
+	if err != nil {
+		return "", err
+	}
+	return gitt.ResolveCommit(repoPath, ref)
+}
+
+func (f *FileDbRepository) GetPackageContent(repoName string, commit string) (entity.PackageContent, error) {
+	repoPath, err := f.DirectoryService.GetRepositoryPath(repoName)
+	if err != nil {
+		return entity.PackageContent{}, err
+	}
+	return f.readPackageContent(repoName, repoPath, commit)
+}
+
+func (f *FileDbRepository) RestorePackageCommit(repoName string, commit string, message string) error {
+	repoPath, err := f.DirectoryService.GetRepositoryPath(repoName)
+	if err != nil {
+		return err
+	}
+	userPrefs, err := f.userPreferenceRepository.GetUserPreference()
+	if err != nil {
+		return err
+	}
+	identity, err := identityFor(repoName, repoPath, userPrefs.Identities)
+	if err != nil {
+		return fmt.Errorf("failed to resolve the git identity of repository '%s': %w", repoName, err)
+	}
+	err = gitt.RestoreCommit(repoPath, commit, identity, message)
+	if err == gitt.ErrChangesExist {
+		return errorss.ErrLocalChanges
+	}
+	return err
+}
+
+func (f *FileDbRepository) CheckoutPackageCommit(repoName string, commit string, force bool) error {
+	repoPath, err := f.DirectoryService.GetRepositoryPath(repoName)
+	if err != nil {
+		return err
+	}
+	err = gitt.ResetToCommit(repoPath, commit, force)
+	if err == gitt.ErrChangesExist {
+		return errorss.ErrLocalChanges
+	}
+	return err
+}
+
+func (f *FileDbRepository) ListPackageTags(repoName string) ([]string, error) {
+	repoPath, err := f.DirectoryService.GetRepositoryPath(repoName)
+	if err != nil {
+		return nil, err
+	}
+	return gitt.ListTags(repoPath)
 }
 
 func (f *FileDbRepository) editFile(filePath string) error {
@@ -293,8 +666,24 @@ func (f *FileDbRepository) PushPackage(repoName string) error {
 		return fmt.Errorf("repository '%s' does not have a git remote configured", repoName)
 	}
 
+	userPrefs, err := f.userPreferenceRepository.GetUserPreference()
+	if err != nil {
+		return err
+	}
+	identity, err := identityFor(repoName, repoPath, userPrefs.Identities)
+	if err != nil {
+		return fmt.Errorf("failed to resolve the git identity of repository '%s': %w", repoName, err)
+	}
+	message, err := f.describeLocalChanges(repoName, repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to push repository '%s': %w", repoName, err)
+	}
+	if message == "" {
+		message = "Duh, auto-commit before push"
+	}
+
 	// Commit and push changes
-	err = gitt.CommitAndPushChanges(repoPath)
+	err = gitt.CommitAndPushChanges(repoPath, identity, message)
 	if err != nil {
 		return fmt.Errorf("failed to push repository '%s': %w", repoName, err)
 	}
@@ -370,7 +759,7 @@ func (f *FileDbRepository) getRepositoryGitconfigPath(name string) string {
 	if err != nil {
 		return ""
 	}
-	path := filepath.Join(basePath, constants.PackagesDirName, name, "gitconfig")
+	path := filepath.Join(basePath, constants.PackagesDirName, name, constants.PackageGitconfigName)
 	if _, err := os.Stat(path); os.IsNotExist(err) {
 		return ""
 	}
@@ -382,7 +771,12 @@ func (f *FileDbRepository) GetRepositoryByName(name string) (*entity.Package, er
 	if err != nil {
 		return nil, err
 	}
-	repoDto, err := f.fileHandler.LoadRepositoryFile(repoPath)
+	return f.loadPackage(name, repoPath, f.getRepositoryGitconfigPath(name))
+}
+
+// loadPackage reads a package from its db file and its gitconfig file, empty when it has none
+func (f *FileDbRepository) loadPackage(name string, dbFilePath string, gitConfigPath string) (*entity.Package, error) {
+	repoDto, err := f.fileHandler.LoadRepositoryFile(dbFilePath)
 	if err != nil {
 		return nil, err
 	}
@@ -392,18 +786,36 @@ func (f *FileDbRepository) GetRepositoryByName(name string) (*entity.Package, er
 	} else {
 		aliases = repoDto.Aliases
 	}
-	exports := map[string]string{}
-	if repoDto.Exports == nil {
-		exports = map[string]string{}
-	} else {
-		exports = repoDto.Exports
+	exports := map[string]entity.Export{}
+	for name, export := range repoDto.Exports {
+		exports[name] = entity.Export(export)
+	}
+
+	gitAliases := map[string]string{}
+	gitConfig := []entity.GitConfigEntry{}
+	if gitConfigPath != "" {
+		// A broken gitconfig must not prevent the package from loading,
+		// git itself will report the error when reading it
+		if readAliases, err := gitconfig.ReadAliases(gitConfigPath); err == nil {
+			gitAliases = readAliases
+		}
+		if entries, err := gitconfig.ReadEntries(gitConfigPath); err == nil {
+			gitConfig = entries
+		}
 	}
 
 	repo := entity.Package{
 		Name:                 name,
 		Aliases:              aliases,
 		Exports:              exports,
-		GitConfigIncludePath: f.getRepositoryGitconfigPath(name),
+		DbFilePath:           dbFilePath,
+		GitConfigIncludePath: gitConfigPath,
+		GitAliases:           gitAliases,
+		GitConfig:            gitConfig,
+		AliasConditions:      toConditions(repoDto.AliasConditions),
+		ExportConditions:     toConditions(repoDto.ExportConditions),
+		Manifest:             entity.Manifest(repoDto.Manifest),
+		LazyFunctions:        repoDto.LazyFunctions,
 	}
 	return &repo, nil
 }
@@ -427,3 +839,36 @@ func (f *FileDbRepository) GetAllPackages() ([]entity.Package, error) {
 	}
 	return allRepos, nil
 }
+
+// movePin moves the pin of a package to its new name, or drops it when newName is empty
+func (f *FileDbRepository) movePin(oldName, newName string) error {
+	userPrefs, err := f.userPreferenceRepository.GetUserPreference()
+	if err != nil {
+		return err
+	}
+	ref, ok := userPrefs.Pins[oldName]
+	if !ok {
+		return nil
+	}
+	delete(userPrefs.Pins, oldName)
+	if newName != "" {
+		userPrefs.Pins[newName] = ref
+	}
+	return f.userPreferenceRepository.SaveUserPreference(userPrefs)
+}
+
+func toConditions(dtos map[string]common.ConditionDto) map[string]entity.Condition {
+	conditions := map[string]entity.Condition{}
+	for name, condition := range dtos {
+		conditions[name] = entity.Condition(condition)
+	}
+	return conditions
+}
+
+func toConditionDtos(conditions map[string]entity.Condition) map[string]common.ConditionDto {
+	dtos := map[string]common.ConditionDto{}
+	for name, condition := range conditions {
+		dtos[name] = common.ConditionDto(condition)
+	}
+	return dtos
+}
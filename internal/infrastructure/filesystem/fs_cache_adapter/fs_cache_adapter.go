@@ -0,0 +1,206 @@
+package fs_cache_adapter
+
+import (
+	"crypto/sha256"
+	"duh/internal/domain/constants"
+	"duh/internal/domain/entity"
+	"duh/internal/domain/port"
+	"duh/internal/infrastructure/filesystem/common"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type FSCacheAdapter struct {
+	pathProvider          common.PathProvider
+	gitConfigPathProvider common.PathProvider
+	fileHandler           common.FileHandler
+	environmentPort       port.EnvironmentPort
+}
+
+func NewFSCacheAdapter(
+	pathProvider common.PathProvider,
+	gitConfigPathProvider common.PathProvider,
+	fileHandler common.FileHandler,
+	environmentPort port.EnvironmentPort,
+) *FSCacheAdapter {
+	return &FSCacheAdapter{
+		pathProvider:          pathProvider,
+		gitConfigPathProvider: gitConfigPathProvider,
+		fileHandler:           fileHandler,
+		environmentPort:       environmentPort,
+	}
+}
+
+// InjectionFingerprint hashes the content and modification time of the user preferences, and of the db file,
+// gitconfig file and function scripts of every package.
+// The user gitconfig is hashed too, so a removed include is added back on the next injection.
+// The hostname and DUH_PROFILE are part of the fingerprint, as entry conditions and the active profile depend on them.
+// Files are not parsed here: the commands named by if_command conditions are stored with the cache entry instead.
+func (c *FSCacheAdapter) InjectionFingerprint() (string, error) {
+	basePath, err := c.pathProvider.GetPath()
+	if err != nil {
+		return "", err
+	}
+	hasher := sha256.New()
+
+	userPrefPath := filepath.Join(basePath, constants.DuhConfigFileName+"."+c.fileHandler.Extension())
+	if err := hashFile(hasher, userPrefPath); err != nil {
+		return "", err
+	}
+
+	packagesPath := filepath.Join(basePath, constants.PackagesDirName)
+	entries, err := os.ReadDir(packagesPath)
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		packagePath := filepath.Join(packagesPath, entry.Name())
+		files := []string{
+			filepath.Join(packagePath, constants.PackageDbFileName+"."+c.fileHandler.Extension()),
+			filepath.Join(packagePath, constants.PackageGitconfigName),
+		}
+		scriptEntries, err := os.ReadDir(filepath.Join(packagePath, constants.PackageFunctionsDirName))
+		if err == nil {
+			for _, script := range scriptEntries {
+				if !script.IsDir() {
+					files = append(files, filepath.Join(packagePath, constants.PackageFunctionsDirName, script.Name()))
+				}
+			}
+		}
+		for _, file := range files {
+			if err := hashFile(hasher, file); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	if gitConfigPath, err := c.gitConfigPathProvider.GetPath(); err == nil {
+		if err := hashFile(hasher, gitConfigPath); err != nil {
+			return "", err
+		}
+	}
+
+	hostname, _ := c.environmentPort.Hostname()
+	fmt.Fprintf(hasher, "hostname:%s\nprofile:%s\n", hostname, c.environmentPort.Getenv(entity.ProfileEnvVar))
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// Get reads a cache file: its first line holds the key, the second one the commands, the rest the content
+func (c *FSCacheAdapter) Get(name string, key string) (entity.CacheEntry, bool) {
+	cachePath, err := c.getCachePath(name)
+	if err != nil {
+		return entity.CacheEntry{}, false
+	}
+	data, err := os.ReadFile(cachePath)
+	if err != nil {
+		return entity.CacheEntry{}, false
+	}
+	storedKey, rest, found := strings.Cut(string(data), "\n")
+	if !found || storedKey != key {
+		return entity.CacheEntry{}, false
+	}
+	commandsLine, content, found := strings.Cut(rest, "\n")
+	if !found {
+		return entity.CacheEntry{}, false
+	}
+	commands, ok := parseCommands(commandsLine)
+	if !ok {
+		return entity.CacheEntry{}, false
+	}
+	return entity.CacheEntry{Key: storedKey, Commands: commands, Content: content}, true
+}
+
+func (c *FSCacheAdapter) Set(name string, entry entity.CacheEntry) error {
+	cachePath, err := c.getCachePath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(cachePath), os.ModePerm); err != nil {
+		return err
+	}
+	// Write to a temporary file first, so a concurrent shell never reads a partial cache
+	tempFile, err := os.CreateTemp(filepath.Dir(cachePath), name+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempFile.Name())
+	if _, err := tempFile.WriteString(entry.Key + "\n" + formatCommands(entry.Commands) + "\n" + entry.Content); err != nil {
+		tempFile.Close()
+		return err
+	}
+	if err := tempFile.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tempFile.Name(), cachePath)
+}
+
+// formatCommands writes the commands as tab separated name=found pairs, sorted by name
+func formatCommands(commands map[string]bool) string {
+	pairs := []string{}
+	for _, command := range slices.Sorted(maps.Keys(commands)) {
+		pairs = append(pairs, fmt.Sprintf("%s=%t", command, commands[command]))
+	}
+	return strings.Join(pairs, "\t")
+}
+
+// parseCommands reads the commands written by formatCommands, false when the line is malformed
+func parseCommands(line string) (map[string]bool, bool) {
+	commands := map[string]bool{}
+	if line == "" {
+		return commands, true
+	}
+	for _, pair := range strings.Split(line, "\t") {
+		separator := strings.LastIndex(pair, "=")
+		if separator <= 0 {
+			return nil, false
+		}
+		found, err := strconv.ParseBool(pair[separator+1:])
+		if err != nil {
+			return nil, false
+		}
+		commands[pair[:separator]] = found
+	}
+	return commands, true
+}
+
+func (c *FSCacheAdapter) getCachePath(name string) (string, error) {
+	basePath, err := c.pathProvider.GetPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(basePath, constants.CacheDirName, name), nil
+}
+
+// hashFile adds the path, modification time and content of a file to the hash, missing files are hashed as such
+func hashFile(hasher hash.Hash, path string) error {
+	fmt.Fprintf(hasher, "%s\x00", path)
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		fmt.Fprint(hasher, "missing\x00")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(hasher, "%d\x00", info.ModTime().UnixNano())
+	_, err = io.Copy(hasher, file)
+	fmt.Fprint(hasher, "\x00")
+	return err
+}
@@ -0,0 +1,113 @@
+package fs_cache_adapter
+
+import (
+	"duh/internal/domain/constants"
+	"duh/internal/domain/entity"
+	"duh/internal/domain/port"
+	"duh/internal/infrastructure/filesystem/common"
+	"duh/internal/infrastructure/filesystem/tomll"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setupCacheAdapter(t *testing.T) (*FSCacheAdapter, string) {
+	adapter, basePath, _ := setupCacheAdapterWithEnvironment(t)
+	return adapter, basePath
+}
+
+func setupCacheAdapterWithEnvironment(t *testing.T) (*FSCacheAdapter, string, *port.MockEnvironmentPort) {
+	basePath := t.TempDir()
+	functionsPath := filepath.Join(basePath, constants.PackagesDirName, "local", constants.PackageFunctionsDirName)
+	assert.NoError(t, os.MkdirAll(functionsPath, 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(basePath, constants.DuhConfigFileName+".toml"), []byte("[repositories]\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(basePath, constants.PackagesDirName, "local", "db.toml"), []byte("[aliases]\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(functionsPath, "deploy.sh"), []byte("deploy() { :; }"), 0644))
+
+	gitConfigPath := filepath.Join(t.TempDir(), ".gitconfig")
+	environment := &port.MockEnvironmentPort{Host: "laptop"}
+	adapter := NewFSCacheAdapter(
+		common.NewCustomPathProvider(basePath),
+		common.NewCustomPathProvider(gitConfigPath),
+		&tomll.TomlFileHandler{},
+		environment,
+	)
+	return adapter, basePath, environment
+}
+
+func Test_InjectionFingerprint_ChangesWithPackageFiles(t *testing.T) {
+	adapter, basePath := setupCacheAdapter(t)
+
+	first, err := adapter.InjectionFingerprint()
+	assert.NoError(t, err)
+	again, err := adapter.InjectionFingerprint()
+	assert.NoError(t, err)
+	assert.Equal(t, first, again)
+
+	// Editing a function script changes the fingerprint
+	scriptPath := filepath.Join(basePath, constants.PackagesDirName, "local", constants.PackageFunctionsDirName, "deploy.sh")
+	assert.NoError(t, os.WriteFile(scriptPath, []byte("deploy() { echo; }"), 0644))
+	afterScript, err := adapter.InjectionFingerprint()
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, afterScript)
+
+	// Adding a package changes the fingerprint
+	assert.NoError(t, os.MkdirAll(filepath.Join(basePath, constants.PackagesDirName, "team"), 0755))
+	afterPackage, err := adapter.InjectionFingerprint()
+	assert.NoError(t, err)
+	assert.NotEqual(t, afterScript, afterPackage)
+
+	// Editing the user preferences changes the fingerprint
+	userPrefPath := filepath.Join(basePath, constants.DuhConfigFileName+".toml")
+	assert.NoError(t, os.WriteFile(userPrefPath, []byte("[repositories]\nactivated_repos = [\"local\"]\n"), 0644))
+	afterPrefs, err := adapter.InjectionFingerprint()
+	assert.NoError(t, err)
+	assert.NotEqual(t, afterPackage, afterPrefs)
+}
+
+func Test_InjectionFingerprint_IgnoresConditionCommands(t *testing.T) {
+	adapter, basePath, environment := setupCacheAdapterWithEnvironment(t)
+	packagePath := filepath.Join(basePath, constants.PackagesDirName, "local")
+	assert.NoError(t, os.WriteFile(filepath.Join(packagePath, "db.toml"),
+		[]byte("[aliases]\nls = { value = \"eza\", if_command = \"eza\" }\n"), 0644))
+
+	first, err := adapter.InjectionFingerprint()
+	assert.NoError(t, err)
+
+	// The commands named by conditions are stored with the cache entry, the fingerprint only covers the files
+	environment.Commands = []string{"eza"}
+	withEza, err := adapter.InjectionFingerprint()
+	assert.NoError(t, err)
+	assert.Equal(t, first, withEza)
+}
+
+func Test_GetSet(t *testing.T) {
+	adapter, basePath := setupCacheAdapter(t)
+
+	_, ok := adapter.Get("inject-bash", "key1")
+	assert.False(t, ok)
+
+	entry := entity.CacheEntry{
+		Key:      "key1",
+		Commands: map[string]bool{"eza": true, "pbcopy": false},
+		Content:  "alias ll=\"ls -la\"\nexport A=\"b\"",
+	}
+	assert.NoError(t, adapter.Set("inject-bash", entry))
+	assert.FileExists(t, filepath.Join(basePath, constants.CacheDirName, "inject-bash"))
+
+	cached, ok := adapter.Get("inject-bash", "key1")
+	assert.True(t, ok)
+	assert.Equal(t, entry, cached)
+
+	_, ok = adapter.Get("inject-bash", "key2")
+	assert.False(t, ok)
+
+	// An entry without commands is read back with none
+	assert.NoError(t, adapter.Set("inject-fish", entity.CacheEntry{Key: "key1", Content: "alias ll \"ls -la\""}))
+	cached, ok = adapter.Get("inject-fish", "key1")
+	assert.True(t, ok)
+	assert.Empty(t, cached.Commands)
+	assert.Equal(t, "alias ll \"ls -la\"", cached.Content)
+}
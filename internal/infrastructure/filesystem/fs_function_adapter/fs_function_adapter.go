@@ -9,6 +9,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 )
 
 type FSFunctionAdapter struct {
@@ -37,11 +39,11 @@ func GetInternalScripts() ([]entity.Script, error) {
 }
 
 func (f *FSFunctionAdapter) GetActivatedScripts() ([]entity.Script, error) {
-	userPrefs, err := f.userPreferenceRepository.GetUserPreference()
+	_, profile, err := f.userPreferenceRepository.GetActivePreference()
 	if err != nil {
 		return nil, err
 	}
-	return f.getScriptsForRepos(userPrefs.Repositories.ActivatedRepositories)
+	return f.getScriptsForRepos(profile.ActivatedRepositories)
 }
 
 func (f *FSFunctionAdapter) GetAllScripts() ([]entity.Script, error) {
@@ -60,7 +62,32 @@ func (f *FSFunctionAdapter) GetFunctionsPath(repoName string) (string, error) {
 	return filepath.Join(path, constants.PackagesDirName, repoName, constants.PackageFunctionsDirName), nil
 }
 
+// GetTopLevelCodePreference reads the top_level_code preference, allow when it is not set.
+// An invalid value falls back to allow with a warning, a typo must not leave the shell without any injection.
+func (f *FSFunctionAdapter) GetTopLevelCodePreference() (entity.TopLevelCodePreference, error) {
+	userPrefs, err := f.userPreferenceRepository.GetUserPreference()
+	if err != nil {
+		return entity.TopLevelCodePreference{}, err
+	}
+	if userPrefs.TopLevelCode == "" {
+		return entity.TopLevelCodePreference{Policy: entity.TopLevelCodeAllow}, nil
+	}
+	if !slices.Contains(entity.TopLevelCodePolicies, userPrefs.TopLevelCode) {
+		return entity.TopLevelCodePreference{
+			Policy: entity.TopLevelCodeAllow,
+			Warning: fmt.Sprintf("invalid top_level_code '%s' in user preferences, expected one of: %s, using %s",
+				userPrefs.TopLevelCode, strings.Join(entity.TopLevelCodePolicies, ", "), entity.TopLevelCodeAllow),
+		}, nil
+	}
+	return entity.TopLevelCodePreference{Policy: userPrefs.TopLevelCode}, nil
+}
+
+// getScriptsForRepos loads the scripts of the packages, applying the top_level_code preference to them
 func (f *FSFunctionAdapter) getScriptsForRepos(repoNames []string) ([]entity.Script, error) {
+	preference, err := f.GetTopLevelCodePreference()
+	if err != nil {
+		return nil, err
+	}
 	var scripts []entity.Script
 	var errors []error
 	for _, repoName := range repoNames {
@@ -78,6 +105,12 @@ func (f *FSFunctionAdapter) getScriptsForRepos(repoNames []string) ([]entity.Scr
 			errors = append(errors, err)
 			continue
 		}
+		for i := range script {
+			script[i].Package = repoName
+			if err := function.ApplyTopLevelCodePolicy(&script[i], preference.Policy); err != nil {
+				errors = append(errors, err)
+			}
+		}
 		scripts = append(scripts, script...)
 	}
 	if len(errors) > 0 {
@@ -91,11 +124,11 @@ func (f *FSFunctionAdapter) GetInternalScripts() ([]entity.Script, error) {
 }
 
 func (f *FSFunctionAdapter) CreateScriptByName(scriptName string) (string, error) {
-	defaultRepo, err := f.userPreferenceRepository.GetUserPreference()
+	_, profile, err := f.userPreferenceRepository.GetActivePreference()
 	if err != nil {
 		return "", err
 	}
-	defaultRepoName := defaultRepo.Repositories.DefaultRepositoryName
+	defaultRepoName := profile.DefaultRepositoryName
 	funcPath, err := f.GetFunctionsPath(defaultRepoName)
 	if err != nil {
 		return "", err
@@ -107,3 +140,47 @@ func (f *FSFunctionAdapter) CreateScriptByName(scriptName string) (string, error
 	}
 	return filePath, nil
 }
+
+func (f *FSFunctionAdapter) WriteScript(repoName string, script entity.Script) (string, error) {
+	funcPath, err := f.GetFunctionsPath(repoName)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(funcPath, 0755); err != nil {
+		return "", err
+	}
+	filePath := filepath.Join(funcPath, script.Name+".sh")
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	if _, err := file.WriteString(script.DataToInject); err != nil {
+		return "", err
+	}
+	return filePath, nil
+}
+
+func (f *FSFunctionAdapter) ParseDotfile(path string) (entity.Dotfile, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return entity.Dotfile{}, err
+	}
+	return function.ParseDotfile(string(content))
+}
+
+func (f *FSFunctionAdapter) LintScript(script entity.Script) ([]entity.LintFinding, error) {
+	content, err := os.ReadFile(script.PathToFile)
+	if err != nil {
+		return nil, err
+	}
+	findings, err := function.LintScript(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", script.PathToFile, err)
+	}
+	for i := range findings {
+		findings[i].Package = script.Package
+		findings[i].File = script.PathToFile
+	}
+	return findings, nil
+}
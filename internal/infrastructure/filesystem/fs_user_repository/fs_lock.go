@@ -0,0 +1,51 @@
+package fs_user_repository
+
+import (
+	"duh/internal/domain/constants"
+	"duh/internal/domain/entity"
+	"duh/internal/domain/errorss"
+	"duh/internal/infrastructure/filesystem/common"
+	"os"
+	"path/filepath"
+)
+
+// getLockPath returns the path of the lockfile, next to the user preferences
+func (u *FsUserRepository) getLockPath() (string, error) {
+	basePath, err := u.pathProvider.GetPath()
+	if err != nil {
+		return "", err
+	}
+	fileName := constants.LockFileName + "." + u.fileHandler.Extension()
+	return filepath.Join(basePath, fileName), nil
+}
+
+func (u *FsUserRepository) ReadLock() (*entity.Lock, error) {
+	lockPath, err := u.getLockPath()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(lockPath); os.IsNotExist(err) {
+		return nil, &errorss.NotFoundError{Resource: "lockfile", ID: lockPath}
+	}
+	lockDto, err := u.fileHandler.LoadLockFile(lockPath)
+	if err != nil {
+		return nil, err
+	}
+	lock := &entity.Lock{Packages: []entity.LockedPackage{}}
+	for _, pkg := range lockDto.Packages {
+		lock.Packages = append(lock.Packages, entity.LockedPackage(pkg))
+	}
+	return lock, nil
+}
+
+func (u *FsUserRepository) WriteLock(lock entity.Lock) error {
+	lockPath, err := u.getLockPath()
+	if err != nil {
+		return err
+	}
+	lockDto := &common.LockDto{Packages: []common.LockedPackageDto{}}
+	for _, pkg := range lock.Packages {
+		lockDto.Packages = append(lockDto.Packages, common.LockedPackageDto(pkg))
+	}
+	return u.fileHandler.SaveLockFile(lockPath, lockDto)
+}
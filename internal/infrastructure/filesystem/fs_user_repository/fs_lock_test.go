@@ -0,0 +1,38 @@
+package fs_user_repository
+
+import (
+	"duh/internal/domain/entity"
+	"duh/internal/domain/errorss"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WriteLock_ReadLock(t *testing.T) {
+	userRepository, _ := setupProfiles(t, profilesContent)
+
+	_, err := userRepository.ReadLock()
+	var notFoundErr *errorss.NotFoundError
+	assert.ErrorAs(t, err, &notFoundErr)
+
+	lock := entity.Lock{Packages: []entity.LockedPackage{
+		{Name: "local", Enabled: true},
+		{Name: "team", Url: "https://github.com/team/duh-pkg", Commit: "3f2a9c1d", Enabled: true, Pin: "v1.2.0"},
+		{Name: "games", Url: "https://github.com/me/games", Commit: "9b8e7f6a"},
+	}}
+	err = userRepository.WriteLock(lock)
+	assert.NoError(t, err)
+
+	lockPath, err := userRepository.getLockPath()
+	assert.NoError(t, err)
+	assert.Equal(t, "duh_lock.toml", filepath.Base(lockPath))
+	content, err := os.ReadFile(lockPath)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "[[packages]]")
+
+	loaded, err := userRepository.ReadLock()
+	assert.NoError(t, err)
+	assert.Equal(t, lock, *loaded)
+}
@@ -0,0 +1,112 @@
+package fs_user_repository
+
+import (
+	"duh/internal/domain/entity"
+	"duh/internal/infrastructure/filesystem/common"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// GetActivePreference loads the user preferences, along with the packages preferences of the active profile.
+// Changes made to the returned packages preferences are saved with SaveUserPreference.
+func (u *FsUserRepository) GetActivePreference() (*common.UserPreferenceDto, *common.RepositoriesPreferenceDto, error) {
+	userPrefs, err := u.GetUserPreference()
+	if err != nil {
+		return nil, nil, err
+	}
+	activeProfile := u.resolveProfile(userPrefs)
+	return userPrefs, profileRepositories(userPrefs, activeProfile.Name), nil
+}
+
+func (u *FsUserRepository) ListProfiles() ([]entity.Profile, error) {
+	userPrefs, err := u.GetUserPreference()
+	if err != nil {
+		return nil, err
+	}
+	return toProfiles(userPrefs), nil
+}
+
+func (u *FsUserRepository) GetActiveProfile() (entity.ActiveProfile, error) {
+	userPrefs, err := u.GetUserPreference()
+	if err != nil {
+		return entity.ActiveProfile{}, err
+	}
+	return u.resolveProfile(userPrefs), nil
+}
+
+func (u *FsUserRepository) CreateProfile(name string, hostnames []string) error {
+	userPrefs, active, err := u.GetActivePreference()
+	if err != nil {
+		return err
+	}
+	if profileExists(userPrefs, name) {
+		return fmt.Errorf("profile '%s' already exists", name)
+	}
+	if userPrefs.Profiles == nil {
+		userPrefs.Profiles = map[string]*common.ProfileDto{}
+	}
+	userPrefs.Profiles[name] = &common.ProfileDto{
+		Repositories: common.RepositoriesPreferenceDto{
+			ActivatedRepositories: slices.Clone(active.ActivatedRepositories),
+			DefaultRepositoryName: active.DefaultRepositoryName,
+		},
+		Hostnames: hostnames,
+	}
+	return u.SaveUserPreference(userPrefs)
+}
+
+// UseProfile stores the choice for the given hostname only,
+// so the user preferences can be synced between machines using different profiles
+func (u *FsUserRepository) UseProfile(name string, hostname string) error {
+	userPrefs, err := u.GetUserPreference()
+	if err != nil {
+		return err
+	}
+	if !profileExists(userPrefs, name) {
+		return fmt.Errorf("profile '%s' does not exist", name)
+	}
+	if userPrefs.HostProfiles == nil {
+		userPrefs.HostProfiles = map[string]string{}
+	}
+	userPrefs.HostProfiles[hostname] = name
+	return u.SaveUserPreference(userPrefs)
+}
+
+// resolveProfile selects the active profile with the profile resolver
+func (u *FsUserRepository) resolveProfile(userPrefs *common.UserPreferenceDto) entity.ActiveProfile {
+	return u.profileResolver.ResolveActiveProfile(toProfiles(userPrefs), userPrefs.HostProfiles)
+}
+
+func profileExists(userPrefs *common.UserPreferenceDto, name string) bool {
+	_, ok := userPrefs.Profiles[name]
+	return ok || name == entity.DefaultProfileName
+}
+
+func profileRepositories(userPrefs *common.UserPreferenceDto, name string) *common.RepositoriesPreferenceDto {
+	if name == entity.DefaultProfileName {
+		return &userPrefs.Repositories
+	}
+	return &userPrefs.Profiles[name].Repositories
+}
+
+// toProfiles lists the profiles of the user preferences, the default profile first then the others by name
+func toProfiles(userPrefs *common.UserPreferenceDto) []entity.Profile {
+	profiles := []entity.Profile{toProfile(entity.DefaultProfileName, &common.ProfileDto{Repositories: userPrefs.Repositories})}
+	for _, name := range slices.Sorted(maps.Keys(userPrefs.Profiles)) {
+		if name == entity.DefaultProfileName {
+			continue
+		}
+		profiles = append(profiles, toProfile(name, userPrefs.Profiles[name]))
+	}
+	return profiles
+}
+
+func toProfile(name string, profile *common.ProfileDto) entity.Profile {
+	return entity.Profile{
+		Name:            name,
+		EnabledPackages: profile.Repositories.ActivatedRepositories,
+		DefaultPackage:  profile.Repositories.DefaultRepositoryName,
+		Hostnames:       profile.Hostnames,
+	}
+}
@@ -0,0 +1,120 @@
+package fs_user_repository
+
+import (
+	"duh/internal/domain/entity"
+	"duh/internal/domain/port"
+	"duh/internal/domain/service"
+	"duh/internal/infrastructure/filesystem/common"
+	"duh/internal/infrastructure/filesystem/tomll"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setupProfiles writes the user preferences of a test, read on a machine named laptop
+func setupProfiles(t *testing.T, content string) (*FsUserRepository, *port.MockEnvironmentPort) {
+	basePath := t.TempDir()
+	err := os.WriteFile(filepath.Join(basePath, "user_preferences.toml"), []byte(content), 0644)
+	assert.NoError(t, err)
+	environment := &port.MockEnvironmentPort{Host: "laptop"}
+	resolver := service.NewProfileService(environment)
+	return NewFsUserRepository(&tomll.TomlFileHandler{}, common.NewCustomPathProvider(basePath), resolver), environment
+}
+
+const profilesContent = `[repositories]
+activated_repos = ["local"]
+default_repo_name = "local"
+
+[profiles.work]
+activated_repos = ["local", "team"]
+default_repo_name = "team"
+hostnames = ["corp-*"]
+
+[profiles.home]
+activated_repos = ["local", "games"]
+default_repo_name = "local"
+`
+
+func Test_GetActiveProfile_Default(t *testing.T) {
+	userRepository, _ := setupProfiles(t, profilesContent)
+
+	active, err := userRepository.GetActiveProfile()
+	assert.NoError(t, err)
+	assert.Equal(t, entity.ActiveProfile{Name: entity.DefaultProfileName, Reason: entity.ProfileSelectedByDefault}, active)
+
+	_, profile, err := userRepository.GetActivePreference()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"local"}, profile.ActivatedRepositories)
+}
+
+func Test_GetActiveProfile_HostnameRule(t *testing.T) {
+	userRepository, environment := setupProfiles(t, profilesContent+`
+[profiles.lab]
+activated_repos = ["local", "lab"]
+default_repo_name = "lab"
+hostnames = ["lab-*"]
+`)
+	environment.Host = "lab-01"
+
+	active, err := userRepository.GetActiveProfile()
+	assert.NoError(t, err)
+	assert.Equal(t, entity.ActiveProfile{Name: "lab", Reason: entity.ProfileSelectedByHostname}, active)
+}
+
+func Test_UseProfile(t *testing.T) {
+	userRepository, _ := setupProfiles(t, profilesContent)
+
+	assert.Error(t, userRepository.UseProfile("unknown", "laptop"))
+	assert.NoError(t, userRepository.UseProfile("home", "laptop"))
+
+	active, err := userRepository.GetActiveProfile()
+	assert.NoError(t, err)
+	assert.Equal(t, entity.ActiveProfile{Name: "home", Reason: entity.ProfileSelectedByUse}, active)
+
+	// Changes are saved in the active profile only
+	userPrefs, profile, err := userRepository.GetActivePreference()
+	assert.NoError(t, err)
+	profile.DefaultRepositoryName = "games"
+	assert.NoError(t, userRepository.SaveUserPreference(userPrefs))
+
+	userPrefs, err = userRepository.GetUserPreference()
+	assert.NoError(t, err)
+	assert.Equal(t, "games", userPrefs.Profiles["home"].Repositories.DefaultRepositoryName)
+	assert.Equal(t, "local", userPrefs.Repositories.DefaultRepositoryName)
+	assert.Equal(t, "team", userPrefs.Profiles["work"].Repositories.DefaultRepositoryName)
+}
+
+func Test_GetActiveProfile_EnvVar(t *testing.T) {
+	userRepository, environment := setupProfiles(t, profilesContent)
+	assert.NoError(t, userRepository.UseProfile("home", "laptop"))
+
+	environment.Env = map[string]string{entity.ProfileEnvVar: "work"}
+	active, err := userRepository.GetActiveProfile()
+	assert.NoError(t, err)
+	assert.Equal(t, entity.ActiveProfile{Name: "work", Reason: entity.ProfileSelectedByEnv}, active)
+
+	// An unknown profile falls back to the default one instead of breaking every command
+	environment.Env = map[string]string{entity.ProfileEnvVar: "unknown"}
+	_, profile, err := userRepository.GetActivePreference()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"local"}, profile.ActivatedRepositories)
+}
+
+func Test_CreateProfile(t *testing.T) {
+	userRepository, _ := setupProfiles(t, profilesContent)
+
+	assert.NoError(t, userRepository.CreateProfile("ci", []string{"runner-*"}))
+	assert.Error(t, userRepository.CreateProfile("ci", nil))
+	assert.Error(t, userRepository.CreateProfile(entity.DefaultProfileName, nil))
+
+	profiles, err := userRepository.ListProfiles()
+	assert.NoError(t, err)
+	assert.Equal(t, []entity.Profile{
+		{Name: entity.DefaultProfileName, EnabledPackages: []string{"local"}, DefaultPackage: "local"},
+		{Name: "ci", EnabledPackages: []string{"local"}, DefaultPackage: "local", Hostnames: []string{"runner-*"}},
+		{Name: "home", EnabledPackages: []string{"local", "games"}, DefaultPackage: "local"},
+		{Name: "work", EnabledPackages: []string{"local", "team"}, DefaultPackage: "team", Hostnames: []string{"corp-*"}},
+	}, profiles)
+}
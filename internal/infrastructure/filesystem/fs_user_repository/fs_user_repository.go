@@ -2,19 +2,22 @@ package fs_user_repository
 
 import (
 	"duh/internal/domain/constants"
+	"duh/internal/domain/port"
 	"duh/internal/infrastructure/filesystem/common"
 	"path/filepath"
 )
 
 type FsUserRepository struct {
-	fileHandler  common.FileHandler
-	pathProvider common.PathProvider
+	fileHandler     common.FileHandler
+	pathProvider    common.PathProvider
+	profileResolver port.ProfileResolver
 }
 
-func NewFsUserRepository(fileHandler common.FileHandler, pathProvider common.PathProvider) *FsUserRepository {
+func NewFsUserRepository(fileHandler common.FileHandler, pathProvider common.PathProvider, profileResolver port.ProfileResolver) *FsUserRepository {
 	return &FsUserRepository{
-		fileHandler:  fileHandler,
-		pathProvider: pathProvider,
+		fileHandler:     fileHandler,
+		pathProvider:    pathProvider,
+		profileResolver: profileResolver,
 	}
 }
 
@@ -0,0 +1,75 @@
+package function
+
+import (
+	"duh/internal/domain/entity"
+	"fmt"
+	"strings"
+)
+
+// Comment setting the condition and loading of a whole script, e.g. "# duh: os=darwin if_command=pbcopy lazy"
+// It must be part of the comments at the top of the script.
+const conditionDirectivePrefix = "duh:"
+
+// scriptDirective is what the "# duh:" directive of a script sets
+type scriptDirective struct {
+	condition entity.Condition
+	// nil when the directive does not mention lazy
+	lazy *bool
+}
+
+// ReadScriptCondition returns the condition set by the directive of a script, empty when it has none
+func ReadScriptCondition(scriptContent string) entity.Condition {
+	directive, _ := readConditionDirective(scriptContent)
+	return directive.condition
+}
+
+// readConditionDirective reads the condition directive from the leading comments of a script.
+// Unknown keys are reported as warnings, so a typo does not prevent the script from loading.
+func readConditionDirective(scriptContent string) (scriptDirective, []entity.Warning) {
+	directive := scriptDirective{}
+	condition := &directive.condition
+	warnings := []entity.Warning{}
+	for index, line := range strings.Split(scriptContent, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || isShebangLine(line) {
+			continue
+		}
+		if !strings.HasPrefix(line, "#") {
+			break
+		}
+		fields, found := strings.CutPrefix(strings.TrimSpace(strings.TrimPrefix(line, "#")), conditionDirectivePrefix)
+		if !found {
+			continue
+		}
+		for _, field := range strings.Fields(fields) {
+			key, value, _ := strings.Cut(field, "=")
+			switch key {
+			case "os":
+				condition.OS = value
+			case "hostname":
+				condition.Hostname = value
+			case "shell":
+				condition.Shell = value
+			case "if_command":
+				condition.IfCommand = value
+			case "lazy":
+				// A bare "lazy" turns lazy loading on, "lazy=false" keeps a script of a lazy package eager
+				lazy := value != "false"
+				if value != "" && value != "true" && value != "false" {
+					warnings = append(warnings, entity.Warning{
+						Line:    index + 1,
+						Details: fmt.Sprintf("Invalid value '%s' for lazy in duh directive, expected true or false", value),
+					})
+					continue
+				}
+				directive.lazy = &lazy
+			default:
+				warnings = append(warnings, entity.Warning{
+					Line:    index + 1,
+					Details: fmt.Sprintf("Unknown condition '%s' in duh directive", key),
+				})
+			}
+		}
+	}
+	return directive, warnings
+}
@@ -0,0 +1,80 @@
+package function
+
+import (
+	"duh/internal/domain/entity"
+	"strings"
+)
+
+// Tags of the documentation comments of a function, e.g.
+//
+//	# @description Create a directory and move into it
+//	# @arg dir Directory to create, with its parents
+//	# @example mkcd ~/projects/duh
+//	# @requires mkdir
+//
+// A line without a tag continues the tag above it. Comments without any tag are the description.
+const (
+	docTagDescription = "@description"
+	docTagArg         = "@arg"
+	docTagExample     = "@example"
+	docTagRequires    = "@requires"
+)
+
+// parseDocumentation fills the description, arguments, examples and requirements of a function
+// from its documentation lines
+func parseDocumentation(function *entity.Function) {
+	description := []string{}
+	// Field continued by the lines without a tag
+	current := docTagDescription
+	for _, line := range function.Documentation {
+		// Tags and argument names can be followed by any blank, not only a space
+		tag := ""
+		if fields := strings.Fields(line); len(fields) > 0 {
+			tag = fields[0]
+		}
+		rest := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), tag))
+		switch tag {
+		case docTagDescription:
+			current = docTagDescription
+			if rest != "" {
+				description = append(description, rest)
+			}
+		case docTagArg:
+			fields := strings.Fields(rest)
+			if len(fields) == 0 {
+				// An @arg without name documents nothing, it is skipped with the lines continuing it
+				current = ""
+				continue
+			}
+			current = docTagArg
+			argDescription := strings.TrimSpace(strings.TrimPrefix(rest, fields[0]))
+			function.Args = append(function.Args, entity.FunctionArg{Name: fields[0], Description: argDescription})
+		case docTagExample:
+			current = docTagExample
+			function.Examples = append(function.Examples, rest)
+		case docTagRequires:
+			current = docTagRequires
+			function.Requires = append(function.Requires, strings.Fields(strings.ReplaceAll(rest, ",", " "))...)
+		default:
+			if strings.HasPrefix(tag, "@") {
+				// Unknown tags are kept in the description
+				current = docTagDescription
+			}
+			switch current {
+			case "":
+				// Continues a skipped tag
+			case docTagArg:
+				arg := &function.Args[len(function.Args)-1]
+				arg.Description = strings.TrimSpace(arg.Description + " " + line)
+			case docTagExample:
+				example := &function.Examples[len(function.Examples)-1]
+				*example = strings.TrimPrefix(*example+"\n"+line, "\n")
+			case docTagRequires:
+				function.Requires = append(function.Requires, strings.Fields(strings.ReplaceAll(line, ",", " "))...)
+			default:
+				description = append(description, line)
+			}
+		}
+	}
+	function.Description = strings.Join(description, "\n")
+}
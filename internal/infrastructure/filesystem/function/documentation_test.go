@@ -0,0 +1,73 @@
+package function
+
+import (
+	"duh/internal/domain/entity"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseDocumentation(t *testing.T) {
+	script := `#!/bin/bash
+
+# @description Clone a repository and move into it
+# Uses a shallow clone when asked to.
+# @arg url Url of the repository
+# @arg [depth] Number of commits to fetch,
+#   all of them by default
+# @example gclone https://github.com/Fabbbou/duh.git
+# @example gclone https://github.com/Fabbbou/duh.git 1
+# ls
+# @requires git, basename
+# @requires cd
+# @since 1.0
+gclone() {
+    git clone ${2:+--depth "$2"} "$1" && cd "$(basename "$1" .git)"
+}
+`
+	parsed, err := GetScriptFromString("gclone", script, "/pkgs/local/functions/gclone.sh")
+	assert.NoError(t, err)
+	function := parsed.Functions[0]
+	assert.Equal(t, "Clone a repository and move into it\nUses a shallow clone when asked to.\n@since 1.0", function.Description)
+	assert.Equal(t, []entity.FunctionArg{
+		{Name: "url", Description: "Url of the repository"},
+		{Name: "[depth]", Description: "Number of commits to fetch, all of them by default"},
+	}, function.Args)
+	assert.Equal(t, []string{
+		"gclone https://github.com/Fabbbou/duh.git",
+		"gclone https://github.com/Fabbbou/duh.git 1\nls",
+	}, function.Examples)
+	assert.Equal(t, []string{"git", "basename", "cd"}, function.Requires)
+	assert.Equal(t, "gclone <url> [depth]", function.Usage())
+	assert.Equal(t, "Clone a repository and move into it", function.Summary())
+}
+
+func Test_parseDocumentation_WithoutTags(t *testing.T) {
+	parsed, err := GetScriptFromString("mkcd", "# Create a directory\n# and move into it\nmkcd() { mkdir -p \"$1\" && cd \"$1\"; }\n", "")
+	assert.NoError(t, err)
+	function := parsed.Functions[0]
+	assert.Equal(t, "Create a directory\nand move into it", function.Description)
+	assert.Empty(t, function.Args)
+	assert.Empty(t, function.Examples)
+	assert.Empty(t, function.Requires)
+	assert.Equal(t, "mkcd", function.Usage())
+}
+
+func Test_parseDocumentation_ArgSeparatorsAndMissingNames(t *testing.T) {
+	function := entity.Function{Name: "deploy", Documentation: []string{
+		"Deploy the application",
+		"@arg\t<env>\tEnvironment to deploy",
+		"@arg",
+		"  ignored with the @arg above",
+		"@arg\t",
+		"@arg  [ref]   Git ref",
+	}}
+	parseDocumentation(&function)
+	// Names are read after any blank, @arg without a name is skipped
+	assert.Equal(t, []entity.FunctionArg{
+		{Name: "<env>", Description: "Environment to deploy"},
+		{Name: "[ref]", Description: "Git ref"},
+	}, function.Args)
+	assert.Equal(t, "Deploy the application", function.Description)
+	assert.Equal(t, "deploy <env> [ref]", function.Usage())
+}
@@ -0,0 +1,373 @@
+package function
+
+import (
+	"bytes"
+	"duh/internal/domain/entity"
+	"errors"
+	"strings"
+	"unicode"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// ParseDotfile reads the aliases, exports and functions of a shell configuration file like .bashrc.
+// Only top-level statements are considered: a statement which is not entirely made of
+// alias, export or function definitions is reported as unclassified, with its line.
+//
+// Dotfiles are parsed as bash. A block bash can't parse, like a zsh glob qualifier, is reported
+// as unclassified too, and the statements after it are still imported.
+func ParseDotfile(content string) (entity.Dotfile, error) {
+	dotfile := entity.Dotfile{
+		Aliases:      map[string]string{},
+		Exports:      map[string]entity.Export{},
+		Functions:    []entity.Script{},
+		Unclassified: []entity.Warning{},
+	}
+	lines := strings.Split(content, "\n")
+	for start := 0; start < len(lines); {
+		stmts, parseErr := parseStatementsFrom(lines, start)
+		next := start
+		for _, stmt := range stmts {
+			if !classifyStatement(&dotfile, stmt, content, lines) {
+				source := content[stmt.Pos().Offset():stmt.End().Offset()]
+				dotfile.Unclassified = append(dotfile.Unclassified, entity.Warning{
+					Line:    int(stmt.Pos().Line()),
+					Details: strings.TrimSpace(strings.SplitN(source, "\n", 2)[0]),
+				})
+			}
+			next = max(next, int(stmt.End().Line()))
+		}
+		if parseErr == nil {
+			break
+		}
+
+		// Skip the block holding the error, the next statements are parsed on their own
+		for next < len(lines) && isBlankOrComment(lines[next]) {
+			next++
+		}
+		if next >= len(lines) {
+			break
+		}
+		blockEnd := unparsableBlockEnd(lines, next, errorLine(parseErr, len(lines)))
+		dotfile.Unclassified = append(dotfile.Unclassified, entity.Warning{
+			Line:    next + 1,
+			Details: strings.TrimSpace(lines[next]),
+		})
+		start = blockEnd + 1
+	}
+	return dotfile, nil
+}
+
+// parseStatementsFrom parses the lines from start, and returns the statements parsed before the first error.
+// The lines before start are blanked rather than removed, so positions and offsets match the whole content.
+func parseStatementsFrom(lines []string, start int) ([]*syntax.Stmt, error) {
+	blanked := make([]string, len(lines))
+	for index, line := range lines {
+		if index < start {
+			line = strings.Repeat(" ", len(line))
+		}
+		blanked[index] = line
+	}
+	stmts := []*syntax.Stmt{}
+	err := syntax.NewParser(syntax.KeepComments(true)).Stmts(strings.NewReader(strings.Join(blanked, "\n")), func(stmt *syntax.Stmt) bool {
+		stmts = append(stmts, stmt)
+		return true
+	})
+	return stmts, err
+}
+
+// errorLine returns the index of the line a parse error points at, the last line when it is unknown
+func errorLine(err error, lineCount int) int {
+	var parseErr syntax.ParseError
+	if errors.As(err, &parseErr) && parseErr.Pos.IsValid() {
+		return int(parseErr.Pos.Line()) - 1
+	}
+	var langErr syntax.LangError
+	if errors.As(err, &langErr) && langErr.Pos.IsValid() {
+		return int(langErr.Pos.Line()) - 1
+	}
+	return lineCount - 1
+}
+
+// unparsableBlockEnd returns the last line of the block starting at start, which holds an error at errorLine.
+// The block ends once the keywords opening compound commands, like if or for, are all closed.
+func unparsableBlockEnd(lines []string, start int, errorLine int) int {
+	depth := 0
+	for index := start; index < len(lines); index++ {
+		for _, word := range strings.FieldsFunc(lines[index], func(r rune) bool {
+			return unicode.IsSpace(r) || r == ';' || r == '&' || r == '|'
+		}) {
+			if strings.HasPrefix(word, "#") {
+				break
+			}
+			switch word {
+			case "if", "case", "for", "while", "until", "select", "{":
+				depth++
+			case "fi", "esac", "done", "}":
+				depth--
+			}
+		}
+		if index >= errorLine && depth <= 0 {
+			return index
+		}
+	}
+	return len(lines) - 1
+}
+
+func isBlankOrComment(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed == "" || strings.HasPrefix(trimmed, "#")
+}
+
+// classifyStatement adds the definitions of the statement to the dotfile.
+// It returns false, leaving the dotfile untouched, when the statement cannot be imported as a whole.
+func classifyStatement(dotfile *entity.Dotfile, stmt *syntax.Stmt, content string, lines []string) bool {
+	if stmt.Negated || stmt.Background || stmt.Coprocess || len(stmt.Redirs) > 0 {
+		return false
+	}
+	switch cmd := stmt.Cmd.(type) {
+	case *syntax.FuncDecl:
+		script, err := functionScript(cmd, content, lines)
+		if err != nil {
+			return false
+		}
+		// A function declared again replaces the previous declaration, as in the shell
+		for i, existing := range dotfile.Functions {
+			if existing.Name == script.Name {
+				dotfile.Functions = append(dotfile.Functions[:i], dotfile.Functions[i+1:]...)
+				break
+			}
+		}
+		dotfile.Functions = append(dotfile.Functions, *script)
+		return true
+	case *syntax.CallExpr:
+		aliases, ok := aliasDefinitions(cmd)
+		if !ok {
+			return false
+		}
+		for _, alias := range aliases {
+			dotfile.Aliases[alias[0]] = alias[1]
+		}
+		return true
+	case *syntax.DeclClause:
+		exports, ok := exportDefinitions(cmd)
+		if !ok {
+			return false
+		}
+		for _, export := range exports {
+			dotfile.Exports[export.name] = dotfile.Exports[export.name].Then(export.export)
+		}
+		return true
+	}
+	return false
+}
+
+// functionScript returns a script holding the function, with the comments documenting it
+func functionScript(decl *syntax.FuncDecl, content string, lines []string) (*entity.Script, error) {
+	start := int(decl.Pos().Line())
+	docStart := start
+	for docStart > 1 {
+		line := strings.TrimSpace(lines[docStart-2])
+		if !strings.HasPrefix(line, "#") || isShebangLine(line) {
+			break
+		}
+		docStart--
+	}
+	documentation := lines[docStart-1 : start-1]
+	// The function may share its line with other statements, keep only the declaration
+	source := content[decl.Pos().Offset():decl.End().Offset()]
+	scriptContent := source + "\n"
+	if len(documentation) > 0 {
+		scriptContent = strings.Join(documentation, "\n") + "\n" + scriptContent
+	}
+	return GetScriptFromString(decl.Name.Value, scriptContent, "")
+}
+
+// aliasDefinitions returns the name and value of each alias defined by an alias command
+func aliasDefinitions(call *syntax.CallExpr) ([][2]string, bool) {
+	if len(call.Assigns) > 0 || len(call.Args) < 2 || call.Args[0].Lit() != "alias" {
+		return nil, false
+	}
+	aliases := [][2]string{}
+	for _, arg := range call.Args[1:] {
+		definition, _, ok := wordValue(arg)
+		if !ok {
+			return nil, false
+		}
+		name, value, found := strings.Cut(definition, "=")
+		if !found || name == "" || strings.ContainsAny(name, " \t$`'\"") {
+			return nil, false
+		}
+		aliases = append(aliases, [2]string{name, value})
+	}
+	return aliases, true
+}
+
+type exportDefinition struct {
+	name   string
+	export entity.Export
+}
+
+// exportDefinitions returns the exports defined by an export command, in their order
+func exportDefinitions(decl *syntax.DeclClause) ([]exportDefinition, bool) {
+	if decl.Variant.Value != "export" || len(decl.Args) == 0 {
+		return nil, false
+	}
+	exports := []exportDefinition{}
+	for _, assign := range decl.Args {
+		// Options like -n, exporting an existing variable, arrays and += are not definitions duh can hold
+		if assign.Naked || assign.Append || assign.Array != nil || assign.Index != nil || assign.Name == nil {
+			return nil, false
+		}
+		value, literal := "", false
+		if assign.Value != nil {
+			var ok bool
+			value, literal, ok = assignedValue(assign.Value)
+			if !ok {
+				return nil, false
+			}
+		}
+		exports = append(exports, exportDefinition{name: assign.Name.Value, export: exportOf(assign.Name.Value, value, literal)})
+	}
+	return exports, true
+}
+
+// exportOf turns NAME=value into an export.
+// A value made of entries around $NAME, like PATH="$HOME/bin:$PATH", extends the list instead of replacing it.
+func exportOf(name string, value string, literal bool) entity.Export {
+	if !literal {
+		entries := strings.Split(value, ":")
+		for i, entry := range entries {
+			if entry != "$"+name && entry != "${"+name+"}" {
+				continue
+			}
+			export := entity.Export{Prepend: nonEmptyEntries(entries[:i]), Append: nonEmptyEntries(entries[i+1:])}
+			if export.IsPathList() {
+				return export
+			}
+			break
+		}
+	}
+	return entity.Export{Value: value, Literal: literal}
+}
+
+func nonEmptyEntries(entries []string) []string {
+	result := []string{}
+	for _, entry := range entries {
+		if entry != "" {
+			result = append(result, entry)
+		}
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+// assignedValue returns the value of an assignment like wordValue, with the unquoted tildes the shell
+// expands in assignments, at the start and after each colon, replaced by $HOME.
+// The value is rendered inside double quotes, where a tilde is not expanded anymore.
+func assignedValue(word *syntax.Word) (string, bool, bool) {
+	parts := make([]syntax.WordPart, len(word.Parts))
+	copy(parts, word.Parts)
+	expanded := false
+	for index, part := range parts {
+		lit, ok := part.(*syntax.Lit)
+		if !ok {
+			continue
+		}
+		segments := strings.Split(lit.Value, ":")
+		for segment, text := range segments {
+			if segment == 0 && index > 0 {
+				continue
+			}
+			if text == "~" || strings.HasPrefix(text, "~/") {
+				segments[segment] = "$HOME" + text[1:]
+				expanded = true
+			}
+		}
+		parts[index] = &syntax.Lit{ValuePos: lit.ValuePos, ValueEnd: lit.ValueEnd, Value: strings.Join(segments, ":")}
+	}
+	value, literal, ok := wordValue(&syntax.Word{Parts: parts})
+	if expanded && literal {
+		// $HOME would be protected by the single quotes of the rest of the value
+		return "", false, false
+	}
+	return value, literal, ok
+}
+
+// wordValue returns a word as the shell reads it, without its quotes and backslashes.
+// literal tells the value holds a $ or ` protected by single quotes or a backslash, so it must not be expanded.
+// Words mixing protected and expanded parts, or using other constructs, are not supported.
+func wordValue(word *syntax.Word) (string, bool, bool) {
+	var value strings.Builder
+	literal, expanded := false, false
+	for _, part := range word.Parts {
+		switch part := part.(type) {
+		case *syntax.Lit:
+			unescaped, protected := unescapeLit(part.Value, false)
+			value.WriteString(unescaped)
+			literal = literal || protected
+		case *syntax.SglQuoted:
+			if part.Dollar {
+				return "", false, false
+			}
+			value.WriteString(part.Value)
+			literal = literal || strings.ContainsAny(part.Value, "$`")
+		case *syntax.DblQuoted:
+			for _, inner := range part.Parts {
+				if lit, ok := inner.(*syntax.Lit); ok {
+					unescaped, protected := unescapeLit(lit.Value, true)
+					value.WriteString(unescaped)
+					literal = literal || protected
+					continue
+				}
+				value.WriteString(printNode(inner))
+				expanded = true
+			}
+		case *syntax.ParamExp, *syntax.CmdSubst:
+			value.WriteString(printNode(part))
+			expanded = true
+		default:
+			return "", false, false
+		}
+	}
+	if literal && expanded {
+		return "", false, false
+	}
+	return value.String(), literal, true
+}
+
+// unescapeLit removes the backslashes of a literal as the shell does, and the escaped line breaks.
+// Inside double quotes, a backslash only escapes $, `, ", \ and line breaks, and is kept before other characters.
+// It also tells if a $ or ` was escaped.
+func unescapeLit(text string, doubleQuoted bool) (string, bool) {
+	var unescaped strings.Builder
+	protected := false
+	for index := 0; index < len(text); index++ {
+		if text[index] != '\\' || index+1 == len(text) {
+			unescaped.WriteByte(text[index])
+			continue
+		}
+		next := text[index+1]
+		if doubleQuoted && !strings.ContainsRune("$`\"\\\n", rune(next)) {
+			unescaped.WriteByte(text[index])
+			continue
+		}
+		index++
+		if next == '\n' {
+			continue
+		}
+		unescaped.WriteByte(next)
+		protected = protected || next == '$' || next == '`'
+	}
+	return unescaped.String(), protected
+}
+
+func printNode(node syntax.Node) string {
+	var buffer bytes.Buffer
+	if err := syntax.NewPrinter().Print(&buffer, node); err != nil {
+		return ""
+	}
+	return buffer.String()
+}
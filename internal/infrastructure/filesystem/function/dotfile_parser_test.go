@@ -0,0 +1,118 @@
+package function
+
+import (
+	"duh/internal/domain/entity"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseDotfile(t *testing.T) {
+	dotfile := `#!/bin/bash
+# ~/.bashrc: executed by bash(1) for non-login shells.
+[ -z "$PS1" ] && return
+
+alias ll='ls -la' la="ls -A"
+alias gs='git status'
+export EDITOR=vim
+export GREETING='Hello $USER'
+export PROJECTS="$HOME/projects"
+export PATH="$HOME/bin:$PATH"
+export PATH=$PATH:/opt/tools/bin
+
+# Create a directory and move into it
+# Usage: mkcd <dir>
+mkcd() {
+    mkdir -p "$1" && cd "$1"
+}
+
+if [ -f ~/.bash_local ]; then
+    . ~/.bash_local
+fi
+HISTSIZE=1000
+alias broken
+export -n OLD
+`
+	parsed, err := ParseDotfile(dotfile)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"ll": "ls -la", "la": "ls -A", "gs": "git status"}, parsed.Aliases)
+	assert.Equal(t, map[string]entity.Export{
+		"EDITOR":   {Value: "vim"},
+		"GREETING": {Value: "Hello $USER", Literal: true},
+		"PROJECTS": {Value: "$HOME/projects"},
+		"PATH":     {Prepend: []string{"$HOME/bin"}, Append: []string{"/opt/tools/bin"}},
+	}, parsed.Exports)
+
+	assert.Len(t, parsed.Functions, 1)
+	assert.Equal(t, "mkcd", parsed.Functions[0].Name)
+	assert.Equal(t, "# Create a directory and move into it\n# Usage: mkcd <dir>\nmkcd() {\n    mkdir -p \"$1\" && cd \"$1\"\n}\n", parsed.Functions[0].DataToInject)
+	assert.Equal(t, []string{"Create a directory and move into it", "Usage: mkcd <dir>"}, parsed.Functions[0].Functions[0].Documentation)
+
+	assert.Equal(t, []entity.Warning{
+		{Line: 3, Details: `[ -z "$PS1" ] && return`},
+		{Line: 19, Details: "if [ -f ~/.bash_local ]; then"},
+		{Line: 22, Details: "HISTSIZE=1000"},
+		{Line: 23, Details: "alias broken"},
+		{Line: 24, Details: "export -n OLD"},
+	}, parsed.Unclassified)
+}
+
+func Test_ParseDotfile_RedeclaredFunction(t *testing.T) {
+	parsed, err := ParseDotfile("greet() { echo hi; }\nalias g=greet\ngreet() { echo hello; }\n")
+	assert.NoError(t, err)
+	assert.Len(t, parsed.Functions, 1)
+	assert.Equal(t, "greet() { echo hello; }\n", parsed.Functions[0].DataToInject)
+	assert.Equal(t, map[string]string{"g": "greet"}, parsed.Aliases)
+}
+
+func Test_ParseDotfile_InvalidSyntax(t *testing.T) {
+	parsed, err := ParseDotfile("alias ll='ls -la'\nif true; then\n")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"ll": "ls -la"}, parsed.Aliases)
+	assert.Equal(t, []entity.Warning{{Line: 2, Details: "if true; then"}}, parsed.Unclassified)
+}
+
+func Test_ParseDotfile_ZshSyntax(t *testing.T) {
+	dotfile := `alias ll='ls -la'
+for f in ~/.zsh/*.zsh(N); do
+    alias inside=loop
+    source "$f"
+done
+export EDITOR=~/bin/vim
+path_entries=(${(s.:.)PATH})
+export PATH=~/bin:$PATH:~
+export QUOTED="~/not-expanded"
+`
+	parsed, err := ParseDotfile(dotfile)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"ll": "ls -la"}, parsed.Aliases)
+	assert.Equal(t, map[string]entity.Export{
+		"EDITOR": {Value: "$HOME/bin/vim"},
+		"PATH":   {Prepend: []string{"$HOME/bin"}, Append: []string{"$HOME"}},
+		"QUOTED": {Value: "~/not-expanded"},
+	}, parsed.Exports)
+	assert.Equal(t, []entity.Warning{
+		{Line: 2, Details: "for f in ~/.zsh/*.zsh(N); do"},
+		{Line: 7, Details: "path_entries=(${(s.:.)PATH})"},
+	}, parsed.Unclassified)
+}
+
+func Test_ParseDotfile_EscapedCharacters(t *testing.T) {
+	dotfile := `alias ll=ls\ -la
+alias say="echo a\"b"
+export PRICE="\$5 \d"
+export NOTE=it\'s
+export MIXED="\$HOME is $HOME"
+`
+	parsed, err := ParseDotfile(dotfile)
+	assert.NoError(t, err)
+	// Values are read as the shell reads them, without the backslashes
+	assert.Equal(t, map[string]string{"ll": "ls -la", "say": `echo a"b`}, parsed.Aliases)
+	assert.Equal(t, map[string]entity.Export{
+		// An escaped $ is not expanded, a backslash before another character is kept in double quotes
+		"PRICE": {Value: `$5 \d`, Literal: true},
+		"NOTE":  {Value: "it's"},
+	}, parsed.Exports)
+	// A value both protecting and expanding a $ cannot be held by an export
+	assert.Equal(t, []entity.Warning{{Line: 5, Details: `export MIXED="\$HOME is $HOME"`}}, parsed.Unclassified)
+}
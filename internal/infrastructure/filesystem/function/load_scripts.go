@@ -41,13 +41,17 @@ func GetScript(scriptFilePath string) (*entity.Script, error) {
 	if err != nil {
 		return nil, err
 	}
+	directive, directiveWarnings := readConditionDirective(scriptContent)
 	scriptContent = removeShabangLine(scriptContent)
 	script := entity.Script{
 		Name:         utils.GetFileNameWithoutExtension(scriptFilePath),
 		PathToFile:   scriptFilePath,
 		Functions:    analyzer.GetFunctions(),
 		DataToInject: scriptContent,
-		Warnings:     analyzer.GetWarnings(),
+		Warnings:     append(analyzer.GetWarnings(), directiveWarnings...),
+		Condition:    directive.condition,
+		Lazy:         directive.lazy,
+		TopLevelCode: analyzer.GetTopLevelCode(),
 	}
 	return &script, nil
 }
@@ -57,13 +61,17 @@ func GetScriptFromString(scriptName string, scriptContent string, pathToFile str
 	if err != nil {
 		return nil, err
 	}
+	directive, directiveWarnings := readConditionDirective(scriptContent)
 	scriptContent = removeShabangLine(scriptContent)
 	script := entity.Script{
 		Name:         scriptName,
 		PathToFile:   pathToFile,
 		Functions:    analyzer.GetFunctions(),
 		DataToInject: scriptContent,
-		Warnings:     analyzer.GetWarnings(),
+		Warnings:     append(analyzer.GetWarnings(), directiveWarnings...),
+		Condition:    directive.condition,
+		Lazy:         directive.lazy,
+		TopLevelCode: analyzer.GetTopLevelCode(),
 	}
 	return &script, nil
 }
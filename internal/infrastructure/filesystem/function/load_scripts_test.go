@@ -331,3 +331,70 @@ function test_func() {
 		}
 	}
 }
+
+func TestGetScriptFromString_ConditionDirective(t *testing.T) {
+	scriptContent := `#!/bin/bash
+# Clipboard helpers
+# duh: os=darwin if_command=pbcopy colour=blue
+
+# Copy stdin to the clipboard
+function copy() {
+    pbcopy
+}`
+
+	script, err := GetScriptFromString("clipboard", scriptContent, "/tmp/clipboard.sh")
+	if err != nil {
+		t.Fatalf("GetScriptFromString failed: %v", err)
+	}
+
+	expected := entity.Condition{OS: "darwin", IfCommand: "pbcopy"}
+	if script.Condition != expected {
+		t.Errorf("Expected condition %+v, got %+v", expected, script.Condition)
+	}
+	if len(script.Warnings) != 1 || script.Warnings[0].Line != 3 {
+		t.Errorf("Expected one warning on line 3 for the unknown condition, got %+v", script.Warnings)
+	}
+	if len(script.Functions) != 1 || len(script.Functions[0].Documentation) != 1 {
+		t.Errorf("Expected the directive to be kept out of the function documentation, got %+v", script.Functions)
+	}
+}
+
+func TestGetScriptFromString_LazyDirective(t *testing.T) {
+	lazy, eager := true, false
+	for directive, expected := range map[string]*bool{
+		"# duh: lazy":               &lazy,
+		"# duh: os=linux lazy=true": &lazy,
+		"# duh: lazy=false":         &eager,
+		"# duh: os=linux":           nil,
+		"# duh: lazy=sometimes":     nil,
+		"# Not a directive: lazy":   nil,
+	} {
+		script, err := GetScriptFromString("kube", directive+"\nkctx() {\n    :\n}", "/tmp/kube.sh")
+		if err != nil {
+			t.Fatalf("GetScriptFromString failed: %v", err)
+		}
+		if (expected == nil) != (script.Lazy == nil) || (expected != nil && *expected != *script.Lazy) {
+			t.Errorf("%s: expected lazy %v, got %v", directive, expected, script.Lazy)
+		}
+	}
+
+	script, _ := GetScriptFromString("kube", "# duh: lazy=sometimes\nkctx() { :; }", "/tmp/kube.sh")
+	if len(script.Warnings) != 1 || script.Warnings[0].Line != 1 {
+		t.Errorf("Expected one warning on line 1 for the invalid lazy value, got %+v", script.Warnings)
+	}
+}
+
+func TestGetScriptFromString_DirectiveAfterCodeIsIgnored(t *testing.T) {
+	scriptContent := `function copy() {
+    pbcopy
+}
+# duh: os=darwin`
+
+	script, err := GetScriptFromString("clipboard", scriptContent, "/tmp/clipboard.sh")
+	if err != nil {
+		t.Fatalf("GetScriptFromString failed: %v", err)
+	}
+	if !script.Condition.IsEmpty() {
+		t.Errorf("Expected no condition, got %+v", script.Condition)
+	}
+}
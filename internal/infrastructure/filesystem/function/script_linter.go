@@ -0,0 +1,226 @@
+package function
+
+import (
+	"duh/internal/domain/entity"
+	"fmt"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// Interpreters of the shebang lines declaring a POSIX script
+var posixInterpreters = []string{"sh", "dash", "ash"}
+
+// LintScript runs the static checks of duh functions lint over the content of a script.
+// Findings checking the script against other packages, like shadowed aliases, are left to the caller,
+// as well as the file and package of the findings.
+func LintScript(scriptContent string) ([]entity.LintFinding, error) {
+	analyzer, err := GetScriptAnalysis(scriptContent)
+	if err != nil {
+		return nil, err
+	}
+	file, err := syntax.NewParser(syntax.KeepComments(true)).Parse(strings.NewReader(scriptContent), "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse script: %w", err)
+	}
+
+	linter := &scriptLinter{findings: []entity.LintFinding{}}
+	for _, code := range analyzer.CodeOutside {
+		linter.report(int(code.Line), firstColumn(analyzer.sourceLines, code.Line), entity.SeverityWarning, entity.LintCodeOutsideFunction,
+			fmt.Sprintf("code outside function runs in every shell at startup: %s", code.Content))
+	}
+	for _, fn := range analyzer.Functions {
+		if !fn.HasDocs {
+			linter.report(int(fn.StartLine), firstColumn(analyzer.sourceLines, fn.StartLine), entity.SeverityInfo, entity.LintMissingDocumentation,
+				fmt.Sprintf("function '%s' has no documentation comment, add one right above it", fn.Name))
+		}
+	}
+
+	posix := declaresPosix(scriptContent)
+	syntax.Walk(file, func(node syntax.Node) bool {
+		switch node := node.(type) {
+		case *syntax.FuncDecl:
+			linter.checkExit(node)
+		case *syntax.CallExpr:
+			linter.checkUnquoted(node)
+		}
+		if posix {
+			linter.checkBashOnly(node)
+		}
+		return true
+	})
+	return linter.findings, nil
+}
+
+type scriptLinter struct {
+	findings []entity.LintFinding
+}
+
+func (l *scriptLinter) report(line int, column int, severity string, rule string, message string) {
+	l.findings = append(l.findings, entity.LintFinding{
+		Line:     line,
+		Column:   column,
+		Severity: severity,
+		Rule:     rule,
+		Message:  message,
+	})
+}
+
+func (l *scriptLinter) reportAt(pos syntax.Pos, severity string, rule string, message string) {
+	l.report(int(pos.Line()), int(pos.Col()), severity, rule, message)
+}
+
+// checkExit reports the exit commands of a function body. Functions run in the shell of the user,
+// so exit closes it. Subshells and command substitutions run in a child process, exiting them is fine.
+func (l *scriptLinter) checkExit(funcDecl *syntax.FuncDecl) {
+	syntax.Walk(funcDecl.Body, func(node syntax.Node) bool {
+		switch node := node.(type) {
+		case *syntax.Subshell, *syntax.CmdSubst, *syntax.ProcSubst:
+			return false
+		case *syntax.FuncDecl:
+			// Nested functions are checked on their own
+			return false
+		case *syntax.CallExpr:
+			if commandName(node) == "exit" {
+				l.reportAt(node.Pos(), entity.SeverityError, entity.LintExitInFunction,
+					fmt.Sprintf("exit in function '%s' closes the shell of the user, use return instead", funcDecl.Name.Value))
+			}
+		}
+		return true
+	})
+}
+
+// checkUnquoted reports the arguments expanding a variable or a command without double quotes,
+// which are split on spaces and expanded as globs
+func (l *scriptLinter) checkUnquoted(call *syntax.CallExpr) {
+	for _, word := range call.Args[min(1, len(call.Args)):] {
+		for _, part := range word.Parts {
+			switch part := part.(type) {
+			case *syntax.ParamExp:
+				if part.Length || part.Param == nil || isSplitSafeParam(part.Param.Value) {
+					continue
+				}
+				l.reportAt(part.Pos(), entity.SeverityWarning, entity.LintUnquotedExpansion,
+					fmt.Sprintf("unquoted expansion of $%s is split on spaces and globbed, wrap it in double quotes", part.Param.Value))
+			case *syntax.CmdSubst:
+				l.reportAt(part.Pos(), entity.SeverityWarning, entity.LintUnquotedExpansion,
+					"unquoted command substitution is split on spaces and globbed, wrap it in double quotes")
+			}
+		}
+	}
+}
+
+// isSplitSafeParam tells if a special parameter always expands to a single word without glob characters
+func isSplitSafeParam(name string) bool {
+	switch name {
+	case "#", "?", "$", "!", "-":
+		return true
+	}
+	return false
+}
+
+// checkBashOnly reports the syntax that POSIX shells like dash do not understand
+func (l *scriptLinter) checkBashOnly(node syntax.Node) {
+	feature := ""
+	switch node := node.(type) {
+	case *syntax.TestClause:
+		feature = "[[ ]] tests, use [ ] instead"
+	case *syntax.ArithmCmd:
+		feature = "(( )) commands, use [ $(( )) -ne 0 ] instead"
+	case *syntax.LetClause:
+		feature = "let, use $(( )) instead"
+	case *syntax.ArrayExpr:
+		feature = "arrays"
+	case *syntax.ProcSubst:
+		feature = "process substitution"
+	case *syntax.ExtGlob:
+		feature = "extended globs"
+	case *syntax.CoprocClause:
+		feature = "coproc"
+	case *syntax.FuncDecl:
+		if node.RsrvWord {
+			feature = "the function keyword, declare it as name() { ... }"
+		}
+	case *syntax.DeclClause:
+		if node.Variant.Value == "declare" || node.Variant.Value == "typeset" {
+			feature = node.Variant.Value
+		}
+	case *syntax.ForClause:
+		if node.Select {
+			feature = "select loops"
+		} else if _, ok := node.Loop.(*syntax.CStyleLoop); ok {
+			feature = "C-style for loops"
+		}
+	case *syntax.Redirect:
+		switch node.Op {
+		case syntax.WordHdoc:
+			feature = "<<< here-strings"
+		case syntax.RdrAll, syntax.AppAll:
+			feature = node.Op.String() + " redirections, use > file 2>&1 instead"
+		}
+	case *syntax.ParamExp:
+		switch {
+		case node.Slice != nil:
+			feature = "substring expansions like ${var:0:1}"
+		case node.Repl != nil:
+			feature = "replacement expansions like ${var/a/b}"
+		case node.Excl:
+			feature = "indirect expansions like ${!var}"
+		}
+	case *syntax.SglQuoted:
+		if node.Dollar {
+			feature = "$'...' strings"
+		}
+	case *syntax.DblQuoted:
+		if node.Dollar {
+			feature = "$\"...\" strings"
+		}
+	case *syntax.CallExpr:
+		if commandName(node) == "source" {
+			feature = "source, use . instead"
+		}
+	}
+	if feature != "" {
+		l.reportAt(node.Pos(), entity.SeverityError, entity.LintBashOnlySyntax,
+			fmt.Sprintf("the script is declared POSIX but uses bash-only %s", feature))
+	}
+}
+
+// declaresPosix tells if the script is meant for any POSIX shell, from its shebang line
+// or from a "# duh: shell=sh" directive
+func declaresPosix(scriptContent string) bool {
+	firstLine, _, _ := strings.Cut(scriptContent, "\n")
+	if isShebangLine(firstLine) {
+		fields := strings.Fields(strings.TrimPrefix(firstLine, "#!"))
+		if len(fields) > 0 {
+			interpreter := fields[0]
+			if strings.HasSuffix(interpreter, "/env") && len(fields) > 1 {
+				interpreter = fields[1]
+			}
+			for _, posixInterpreter := range posixInterpreters {
+				if interpreter == posixInterpreter || strings.HasSuffix(interpreter, "/"+posixInterpreter) {
+					return true
+				}
+			}
+		}
+	}
+	directive, _ := readConditionDirective(scriptContent)
+	return directive.condition.Shell == "sh"
+}
+
+// commandName returns the name of the command called, empty when it is not a plain word
+func commandName(call *syntax.CallExpr) string {
+	if len(call.Args) == 0 {
+		return ""
+	}
+	return call.Args[0].Lit()
+}
+
+// firstColumn returns the column of the first character of a line, which is where its statement starts
+func firstColumn(sourceLines []string, line uint) int {
+	if line == 0 || int(line) > len(sourceLines) {
+		return 1
+	}
+	content := sourceLines[line-1]
+	return len(content) - len(strings.TrimLeft(content, " \t")) + 1
+}
@@ -0,0 +1,92 @@
+package function
+
+import (
+	"duh/internal/domain/entity"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// findingsByRule keeps the position of the findings, grouped by rule
+func findingsByRule(findings []entity.LintFinding) map[string][][2]int {
+	byRule := map[string][][2]int{}
+	for _, finding := range findings {
+		byRule[finding.Rule] = append(byRule[finding.Rule], [2]int{finding.Line, finding.Column})
+	}
+	return byRule
+}
+
+func Test_LintScript(t *testing.T) {
+	script := `#!/bin/bash
+
+# Open every file given
+open_all() {
+    for file in "$@"; do
+        $EDITOR $file "$(basename "$file")" ${#file}
+        echo "$file" $?
+    done
+}
+
+goto() {
+    cd "$1" || exit 1
+    (cd /tmp && exit 0)
+    result="$(exit 2)"
+}
+
+echo $(date)
+`
+	findings, err := LintScript(script)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][][2]int{
+		entity.LintUnquotedExpansion:    {{6, 17}, {17, 6}},
+		entity.LintMissingDocumentation: {{11, 1}},
+		entity.LintExitInFunction:       {{12, 16}},
+		entity.LintCodeOutsideFunction:  {{17, 1}},
+	}, findingsByRule(findings))
+
+	for _, finding := range findings {
+		if finding.Rule == entity.LintExitInFunction {
+			assert.Equal(t, entity.SeverityError, finding.Severity)
+			assert.Contains(t, finding.Message, "goto")
+		}
+	}
+}
+
+func Test_LintScript_BashOnlySyntax(t *testing.T) {
+	script := `#!/usr/bin/env sh
+
+# Print the first letter of each argument
+initials() {
+    if [[ -n "$1" ]]; then
+        echo "${1:0:1}"
+    fi
+    local letters=(a b)
+    cat <<< "$letters"
+    source ~/.profile
+}
+
+# Portable version
+portable() {
+    [ -n "$1" ] && printf '%s\n' "$1" | cut -c1
+}
+`
+	findings, err := LintScript(script)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][][2]int{
+		entity.LintBashOnlySyntax: {{5, 8}, {6, 15}, {8, 19}, {9, 9}, {10, 5}},
+	}, findingsByRule(findings))
+
+	// The same syntax is fine in a bash script
+	findings, err = LintScript("#!/bin/bash\n" + script[len("#!/usr/bin/env sh\n"):])
+	assert.NoError(t, err)
+	assert.Empty(t, findings)
+}
+
+func Test_declaresPosix(t *testing.T) {
+	assert.True(t, declaresPosix("#!/bin/sh\n"))
+	assert.True(t, declaresPosix("#!/usr/bin/env dash\n"))
+	assert.True(t, declaresPosix("# duh: shell=sh\n\nf() { :; }"))
+	assert.False(t, declaresPosix("#!/bin/bash\n"))
+	assert.False(t, declaresPosix("#!/usr/bin/env zsh\n"))
+	assert.False(t, declaresPosix("f() { :; }"))
+}
@@ -1,15 +1,8 @@
 #!/usr/bin/env bash
-# Safety check: ensure required commands are available
-#
-# Usage:
-# require <cmd1> <cmd2>
-#
-# Example:
-# require curl yq || return 1
-#
-# output:
-# Missing curl
-# Missing yq
+# @description Safety check: ensure required commands are available
+# Prints each missing command on the standard error, and fails if any is missing.
+# @arg cmd... Commands that must be available in the PATH
+# @example require curl yq || return 1
 require() {
     missing=0
     for cmd in "$@"; do
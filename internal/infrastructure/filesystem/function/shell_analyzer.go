@@ -28,10 +28,14 @@ func GetScriptAnalysis(script string) (*ShellAnalyzer, error) {
 func (analyzer *ShellAnalyzer) GetFunctions() []entity.Function {
 	var functions []entity.Function
 	for _, fn := range analyzer.Functions {
-		functions = append(functions, entity.Function{
+		function := entity.Function{
 			Name:          fn.Name,
 			Documentation: fn.Documentation,
-		})
+			Source:        analyzer.getSource(fn),
+			Line:          int(fn.StartLine),
+		}
+		parseDocumentation(&function)
+		functions = append(functions, function)
 	}
 	return functions
 }
@@ -47,6 +51,26 @@ func (analyzer *ShellAnalyzer) GetWarnings() []entity.Warning {
 	return warnings
 }
 
+// GetTopLevelCode returns the statements outside functions, each with its line
+func (analyzer *ShellAnalyzer) GetTopLevelCode() []entity.Warning {
+	var statements []entity.Warning
+	for _, code := range analyzer.CodeOutside {
+		statements = append(statements, entity.Warning{
+			Line:    int(code.Line),
+			Details: code.Content,
+		})
+	}
+	return statements
+}
+
+// getSource returns the lines of the script declaring the function
+func (analyzer *ShellAnalyzer) getSource(fn FunctionInfo) string {
+	if fn.StartLine == 0 || int(fn.EndLine) > len(analyzer.sourceLines) {
+		return ""
+	}
+	return strings.Join(analyzer.sourceLines[fn.StartLine-1:fn.EndLine], "\n")
+}
+
 /*
 ///////////////////////////////////////////////////////////
 Private methods and helper functions below
@@ -116,8 +140,8 @@ func (sa *ShellAnalyzer) analyzeFunctionWithDocs(funcDecl *syntax.FuncDecl, comm
 			if comment.Pos().Line() == uint(lineNum) {
 				// This is a comment line directly above the function
 				commentText := strings.TrimSpace(strings.TrimPrefix(comment.Text, "#"))
-				// Skip shebang lines
-				if !strings.HasPrefix(commentText, "!") && commentText != "" {
+				// Skip shebang lines and duh directives
+				if !strings.HasPrefix(commentText, "!") && !strings.HasPrefix(commentText, conditionDirectivePrefix) && commentText != "" {
 					docComments = append([]string{commentText}, docComments...) // prepend
 				}
 				commentFound = true
@@ -154,3 +154,29 @@ main() {
 	t.Log("Clean script analysis:")
 	analyzer.printReport()
 }
+
+func TestShellAnalyzer_FunctionSource(t *testing.T) {
+	script := `#!/bin/bash
+
+# Say hello
+hello() {
+    echo "hello $1"
+}
+bye() { echo "bye"; }`
+
+	analyzer, err := GetScriptAnalysis(script)
+	if err != nil {
+		t.Fatalf("Failed to analyze script: %v", err)
+	}
+
+	functions := analyzer.GetFunctions()
+	if len(functions) != 2 {
+		t.Fatalf("Expected 2 functions, got %d", len(functions))
+	}
+	if expected := "hello() {\n    echo \"hello $1\"\n}"; functions[0].Source != expected {
+		t.Errorf("Expected source %q, got %q", expected, functions[0].Source)
+	}
+	if expected := `bye() { echo "bye"; }`; functions[1].Source != expected {
+		t.Errorf("Expected source %q, got %q", expected, functions[1].Source)
+	}
+}
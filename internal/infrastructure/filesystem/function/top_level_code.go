@@ -0,0 +1,80 @@
+package function
+
+import (
+	"duh/internal/domain/entity"
+	"fmt"
+	"slices"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// ApplyTopLevelCodePolicy applies the top_level_code preference to a loaded script, when it has code outside functions.
+// With strip, only the function declarations are kept, printed back from the AST.
+// With refuse, nothing of the script is kept to inject.
+func ApplyTopLevelCodePolicy(script *entity.Script, policy string) error {
+	if !slices.Contains(entity.TopLevelCodePolicies, policy) {
+		return fmt.Errorf("invalid top_level_code policy '%s', expected one of: %s", policy, strings.Join(entity.TopLevelCodePolicies, ", "))
+	}
+	if len(script.TopLevelCode) == 0 {
+		return nil
+	}
+	script.TopLevelPolicy = policy
+	switch policy {
+	case entity.TopLevelCodeStrip:
+		functionsOnly, dropped, err := stripTopLevelCode(script.DataToInject)
+		if err != nil {
+			// Fail closed, the code that should have been stripped must not run
+			script.DataToInject = ""
+			script.Functions = nil
+			return fmt.Errorf("failed to strip the code outside functions of %s: %w", script.PathToFile, err)
+		}
+		script.DataToInject = functionsOnly
+		script.DroppedFunctions = dropped
+		script.Functions = slices.DeleteFunc(script.Functions, func(function entity.Function) bool {
+			return slices.Contains(dropped, function.Name)
+		})
+	case entity.TopLevelCodeRefuse:
+		script.DataToInject = ""
+	}
+	return nil
+}
+
+// stripTopLevelCode keeps the function declarations of a script, with the comments above them.
+// It also returns the functions declared inside the statements stripped, like an if block,
+// which are dropped with them.
+func stripTopLevelCode(scriptContent string) (string, []string, error) {
+	file, err := syntax.NewParser(syntax.KeepComments(true)).Parse(strings.NewReader(scriptContent), "")
+	if err != nil {
+		return "", nil, err
+	}
+	kept := []*syntax.Stmt{}
+	keptNames := []string{}
+	dropped := []string{}
+	for _, stmt := range file.Stmts {
+		if decl, ok := stmt.Cmd.(*syntax.FuncDecl); ok {
+			kept = append(kept, stmt)
+			keptNames = append(keptNames, decl.Name.Value)
+			continue
+		}
+		syntax.Walk(stmt, func(node syntax.Node) bool {
+			if decl, ok := node.(*syntax.FuncDecl); ok {
+				dropped = append(dropped, decl.Name.Value)
+				return false
+			}
+			return true
+		})
+	}
+	file.Stmts = kept
+	// Comments after the last statement belong to no function
+	file.Last = nil
+
+	var builder strings.Builder
+	if err := syntax.NewPrinter().Print(&builder, file); err != nil {
+		return "", nil, err
+	}
+	// A function also declared at the top level is still defined
+	dropped = slices.DeleteFunc(dropped, func(name string) bool { return slices.Contains(keptNames, name) })
+	slices.Sort(dropped)
+	return builder.String(), slices.Compact(dropped), nil
+}
@@ -0,0 +1,90 @@
+package function
+
+import (
+	"duh/internal/domain/entity"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const scriptWithTopLevelCode = `#!/bin/bash
+cd /tmp
+set -e
+
+# Say hello
+hello() {
+    echo "hello $1"
+}
+curl -fsSL https://example.com/install.sh | sh
+bye() { echo bye; }
+`
+
+func Test_ApplyTopLevelCodePolicy(t *testing.T) {
+	load := func() *entity.Script {
+		script, err := GetScriptFromString("greet", scriptWithTopLevelCode, "/pkgs/team/functions/greet.sh")
+		assert.NoError(t, err)
+		return script
+	}
+	assert.Equal(t, []entity.Warning{
+		{Line: 2, Details: "cd /tmp"},
+		{Line: 3, Details: "set -e"},
+		{Line: 9, Details: "curl -fsSL https://example.com/install.sh | sh"},
+	}, load().TopLevelCode)
+
+	for _, policy := range []string{entity.TopLevelCodeAllow, entity.TopLevelCodeWarn} {
+		script := load()
+		assert.NoError(t, ApplyTopLevelCodePolicy(script, policy))
+		assert.Equal(t, policy, script.TopLevelPolicy)
+		assert.Contains(t, script.DataToInject, "cd /tmp")
+	}
+
+	script := load()
+	assert.NoError(t, ApplyTopLevelCodePolicy(script, entity.TopLevelCodeStrip))
+	assert.Equal(t, "# Say hello\nhello() {\n\techo \"hello $1\"\n}\n\nbye() { echo bye; }\n", script.DataToInject)
+	// The functions are still listed with their documentation
+	assert.Len(t, script.Functions, 2)
+
+	script = load()
+	assert.NoError(t, ApplyTopLevelCodePolicy(script, entity.TopLevelCodeRefuse))
+	assert.Equal(t, entity.TopLevelCodeRefuse, script.TopLevelPolicy)
+	assert.Empty(t, script.DataToInject)
+
+	assert.Error(t, ApplyTopLevelCodePolicy(load(), "block"))
+}
+
+func Test_ApplyTopLevelCodePolicy_StripConditionalFunctions(t *testing.T) {
+	content := "#!/bin/bash\nif command -v fzf >/dev/null; then\n  pick() { fzf; }\nfi\ncase $OSTYPE in\n  darwin*) open_url() { open \"$1\"; } ;;\nesac\nhello() { echo hello; }\n"
+	script, err := GetScriptFromString("pick", content, "/pkgs/team/functions/pick.sh")
+	assert.NoError(t, err)
+
+	assert.NoError(t, ApplyTopLevelCodePolicy(script, entity.TopLevelCodeStrip))
+	assert.Equal(t, "hello() { echo hello; }\n", script.DataToInject)
+	// Functions declared in the stripped code are not injected, so they are not listed either
+	assert.Equal(t, []string{"open_url", "pick"}, script.DroppedFunctions)
+	assert.Len(t, script.Functions, 1)
+	assert.Equal(t, "hello", script.Functions[0].Name)
+}
+
+func Test_ApplyTopLevelCodePolicy_FunctionsOnly(t *testing.T) {
+	script, err := GetScriptFromString("bye", "#!/bin/bash\n# Say bye\nbye() { echo bye; }\n", "/pkgs/team/functions/bye.sh")
+	assert.NoError(t, err)
+
+	assert.NoError(t, ApplyTopLevelCodePolicy(script, entity.TopLevelCodeRefuse))
+	assert.Empty(t, script.TopLevelPolicy)
+	assert.Equal(t, "# Say bye\nbye() { echo bye; }\n", script.DataToInject)
+}
+
+func Test_ApplyTopLevelCodePolicy_StripFailsClosed(t *testing.T) {
+	script := &entity.Script{
+		Name:         "broken",
+		PathToFile:   "/pkgs/team/functions/broken.sh",
+		DataToInject: "cd /tmp\nbroken() {\n",
+		TopLevelCode: []entity.Warning{{Line: 1, Details: "cd /tmp"}},
+		Functions:    []entity.Function{{Name: "broken"}},
+	}
+
+	assert.Error(t, ApplyTopLevelCodePolicy(script, entity.TopLevelCodeStrip))
+	// The code outside functions is not injected when it cannot be stripped
+	assert.Empty(t, script.DataToInject)
+	assert.Empty(t, script.Functions)
+}
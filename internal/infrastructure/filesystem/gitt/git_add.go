@@ -4,17 +4,29 @@ import (
 	"os"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/transport"
 )
 
 func CloneGitRepository(url string, outputPath string) error {
-	_, err := git.PlainClone(outputPath, false, &git.CloneOptions{
-		URL:      url,
-		Progress: os.Stdout,
-	})
+	configs, err := readGitConfigs("")
+	if err != nil {
+		return err
+	}
+	auth, err := authForUrl(url, configs)
 	if err != nil {
 		return err
 	}
-	return nil
+	return auth.run(func(method transport.AuthMethod) error {
+		repo, err := git.PlainClone(outputPath, false, &git.CloneOptions{
+			URL:      url,
+			Auth:     method,
+			Progress: os.Stdout,
+		})
+		if err == nil {
+			recordFetch(repo)
+		}
+		return err
+	})
 }
 
 func ExtractGitRepoName(url string) string {
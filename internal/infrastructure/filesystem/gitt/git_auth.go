@@ -0,0 +1,469 @@
+package gitt
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-git/v5"
+	format "github.com/go-git/go-git/v5/plumbing/format/config"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	cryptossh "golang.org/x/crypto/ssh"
+)
+
+// Keys tried, in order, to authenticate over ssh when no ssh agent is running
+var defaultSSHKeys = []string{"id_ed25519", "id_ecdsa", "id_rsa"}
+
+// remoteAuth authenticates the clone, fetch, pull and push of a remote,
+// so every operation on a package uses the same credentials
+type remoteAuth struct {
+	endpoint *transport.Endpoint
+	// Credential helpers from credential.helper, in the order git calls them
+	helpers []string
+	// Program asking for credentials, from GIT_ASKPASS or core.askPass
+	askPass string
+
+	mutex    sync.Mutex
+	resolved bool
+	method   transport.AuthMethod
+	// Why no ssh key could be used, added to the error of a rejected operation
+	sshKeyProblem string
+}
+
+var (
+	// Authentications by url and configuration, so repositories sharing both ask credentials once
+	authByKey   = map[string]*remoteAuth{}
+	authByKeyMu sync.Mutex
+)
+
+// authForRemote returns the authentication of the origin remote of a repository,
+// repositories without remote get one without credentials
+func authForRemote(repo *git.Repository) (*remoteAuth, error) {
+	remote, err := repo.Remote(git.DefaultRemoteName)
+	if err == git.ErrRemoteNotFound || (err == nil && len(remote.Config().URLs) == 0) {
+		return &remoteAuth{resolved: true}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	configs, err := readGitConfigs(gitDirOf(repo))
+	if err != nil {
+		return nil, err
+	}
+	return authForUrl(remote.Config().URLs[0], configs)
+}
+
+// authForUrl returns the authentication of a remote url, shared by every repository using it
+// with the same credential helpers and askpass program.
+// configs are the git configs to read them from, as given by readGitConfigs.
+func authForUrl(remoteUrl string, configs []*format.Config) (*remoteAuth, error) {
+	endpoint, err := transport.NewEndpoint(remoteUrl)
+	if err != nil {
+		return nil, err
+	}
+	auth := &remoteAuth{endpoint: endpoint, askPass: os.Getenv("GIT_ASKPASS")}
+	for _, raw := range configs {
+		auth.helpers = append(auth.helpers, credentialHelpers(raw, remoteUrl)...)
+		if auth.askPass == "" && raw.Section("core").HasOption("askpass") {
+			auth.askPass = raw.Section("core").Option("askpass")
+		}
+	}
+	// An empty helper clears the list of helpers configured before it
+	for index := len(auth.helpers) - 1; index >= 0; index-- {
+		if auth.helpers[index] == "" {
+			auth.helpers = auth.helpers[index+1:]
+			break
+		}
+	}
+	// Like git, ssh falls back to SSH_ASKPASS to ask the passphrase of a key
+	if auth.askPass == "" && endpoint.Protocol == "ssh" {
+		auth.askPass = os.Getenv("SSH_ASKPASS")
+	}
+
+	key := strings.Join(append([]string{remoteUrl, auth.askPass}, auth.helpers...), "\x00")
+	authByKeyMu.Lock()
+	defer authByKeyMu.Unlock()
+	if shared, ok := authByKey[key]; ok {
+		return shared, nil
+	}
+	authByKey[key] = auth
+	return auth, nil
+}
+
+// credentialHelpers lists the credential.helper options of a config applying to a url,
+// the ones of a [credential "<url>"] section only when the url starts with it
+func credentialHelpers(raw *format.Config, remoteUrl string) []string {
+	if !raw.HasSection("credential") {
+		return nil
+	}
+	section := raw.Section("credential")
+	helpers := section.OptionAll("helper")
+	for _, subsection := range section.Subsections {
+		if strings.HasPrefix(remoteUrl, subsection.Name) {
+			helpers = append(helpers, subsection.OptionAll("helper")...)
+		}
+	}
+	return helpers
+}
+
+// run runs a remote operation with the credentials of the remote.
+// When an http remote rejects them, the credentials are asked to the askpass program, and the operation retried.
+func (a *remoteAuth) run(operation func(auth transport.AuthMethod) error) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if !a.resolved {
+		a.method = a.resolve()
+		a.resolved = true
+	}
+	err := operation(a.method)
+	if err != nil && a.sshKeyProblem != "" {
+		return fmt.Errorf("%w (%s)", err, a.sshKeyProblem)
+	}
+	if !a.isHttp() || !isAuthError(err) || a.askPass == "" {
+		return err
+	}
+
+	username, password, askErr := a.ask()
+	if askErr != nil {
+		return fmt.Errorf("%w (asking credentials failed: %v)", err, askErr)
+	}
+	method := &http.BasicAuth{Username: username, Password: password}
+	err = operation(method)
+	if err == nil || !isAuthError(err) {
+		a.method = method
+		a.callHelpers("store", username, password)
+	}
+	return err
+}
+
+func isAuthError(err error) bool {
+	return errors.Is(err, transport.ErrAuthenticationRequired) || errors.Is(err, transport.ErrAuthorizationFailed)
+}
+
+func (a *remoteAuth) isHttp() bool {
+	return a.endpoint != nil && (a.endpoint.Protocol == "http" || a.endpoint.Protocol == "https")
+}
+
+// resolve finds credentials without asking anything, nil when there are none
+func (a *remoteAuth) resolve() transport.AuthMethod {
+	if a.endpoint == nil {
+		return nil
+	}
+	switch a.endpoint.Protocol {
+	case "ssh":
+		return a.sshAuth()
+	case "http", "https":
+		if a.endpoint.User != "" && a.endpoint.Password != "" {
+			return &http.BasicAuth{Username: a.endpoint.User, Password: a.endpoint.Password}
+		}
+		if username, password, ok := a.callHelpers("get", a.endpoint.User, ""); ok {
+			return &http.BasicAuth{Username: username, Password: password}
+		}
+		if username, password, ok := netrcCredentials(a.endpoint.Host); ok {
+			return &http.BasicAuth{Username: username, Password: password}
+		}
+	}
+	return nil
+}
+
+// sshAuth authenticates with the ssh agent when one runs, or else with the first default key found.
+// The passphrase of a protected key is asked to the askpass program, the key is skipped without one.
+func (a *remoteAuth) sshAuth() transport.AuthMethod {
+	user := a.endpoint.User
+	if user == "" {
+		user = ssh.DefaultUsername
+	}
+	if os.Getenv("SSH_AUTH_SOCK") != "" {
+		if auth, err := ssh.NewSSHAgentAuth(user); err == nil {
+			return auth
+		}
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	for _, key := range defaultSSHKeys {
+		path := filepath.Join(home, ".ssh", key)
+		content, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var passphraseMissing *cryptossh.PassphraseMissingError
+		if _, err := cryptossh.ParsePrivateKey(content); !errors.As(err, &passphraseMissing) {
+			if auth, err := ssh.NewPublicKeys(user, content, ""); err == nil {
+				a.sshKeyProblem = ""
+				return auth
+			}
+			continue
+		}
+		if a.askPass == "" {
+			a.sshKeyProblem = fmt.Sprintf("ssh key %s needs a passphrase, add it to an ssh agent or set GIT_ASKPASS or SSH_ASKPASS to be asked for it", path)
+			continue
+		}
+		passphrase, askErr := runAskPass(a.askPass, fmt.Sprintf("Enter passphrase for key '%s': ", path))
+		if askErr != nil {
+			a.sshKeyProblem = fmt.Sprintf("asking the passphrase of ssh key %s failed: %v", path, askErr)
+			continue
+		}
+		auth, err := ssh.NewPublicKeys(user, content, passphrase)
+		if err != nil {
+			a.sshKeyProblem = fmt.Sprintf("ssh key %s could not be decrypted: %v", path, err)
+			continue
+		}
+		a.sshKeyProblem = ""
+		return auth
+	}
+	return nil
+}
+
+// callHelpers runs the credential helpers with an action of the git credential protocol.
+// For "get", the first helper giving both a username and a password wins.
+func (a *remoteAuth) callHelpers(action string, username string, password string) (string, string, bool) {
+	input := fmt.Sprintf("protocol=%s\nhost=%s\n", a.endpoint.Protocol, a.hostWithPort())
+	if username != "" {
+		input += fmt.Sprintf("username=%s\n", username)
+	}
+	if password != "" {
+		input += fmt.Sprintf("password=%s\n", password)
+	}
+
+	for _, helper := range a.helpers {
+		var values map[string]string
+		if name, args := splitHelper(helper); name == "store" {
+			values = storeHelper(action, args, a.endpoint, username, password)
+		} else {
+			values = runHelper(helper, action, input)
+		}
+		if action == "get" && values["username"] != "" && values["password"] != "" {
+			return values["username"], values["password"], true
+		}
+	}
+	return "", "", false
+}
+
+func (a *remoteAuth) hostWithPort() string {
+	if a.endpoint.Port != 0 {
+		return fmt.Sprintf("%s:%d", a.endpoint.Host, a.endpoint.Port)
+	}
+	return a.endpoint.Host
+}
+
+func splitHelper(helper string) (string, []string) {
+	fields := strings.Fields(helper)
+	if len(fields) == 0 {
+		return "", nil
+	}
+	return fields[0], fields[1:]
+}
+
+// runHelper runs an external credential helper, the way git does without needing the git binary:
+// "!command" is run by the shell, an absolute path as is, and "name" as the git-credential-name program
+func runHelper(helper string, action string, input string) map[string]string {
+	command := ""
+	switch {
+	case strings.HasPrefix(helper, "!"):
+		command = helper[1:]
+	case filepath.IsAbs(helper):
+		command = helper
+	default:
+		command = "git-credential-" + helper
+	}
+	cmd := exec.Command("sh", "-c", command+" "+action)
+	cmd.Stdin = strings.NewReader(input + "\n")
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return nil
+	}
+	return parseCredential(stdout.String())
+}
+
+func parseCredential(output string) map[string]string {
+	values := map[string]string{}
+	for line := range strings.SplitSeq(output, "\n") {
+		if key, value, ok := strings.Cut(strings.TrimSpace(line), "="); ok {
+			values[key] = value
+		}
+	}
+	return values
+}
+
+// storeHelper implements the "store" helper of git, reading and writing credentials in plain text files.
+// Without a --file option, ~/.git-credentials and $XDG_CONFIG_HOME/git/credentials are used.
+func storeHelper(action string, args []string, endpoint *transport.Endpoint, username string, password string) map[string]string {
+	files := []string{}
+	for index, arg := range args {
+		if value, ok := strings.CutPrefix(arg, "--file="); ok {
+			files = append(files, value)
+		} else if arg == "--file" && index+1 < len(args) {
+			files = append(files, args[index+1])
+		}
+	}
+	if len(files) == 0 {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+		files = append(files, filepath.Join(home, ".git-credentials"))
+		configHome := os.Getenv("XDG_CONFIG_HOME")
+		if configHome == "" {
+			configHome = filepath.Join(home, ".config")
+		}
+		files = append(files, filepath.Join(configHome, "git", "credentials"))
+	}
+
+	switch action {
+	case "get":
+		for _, file := range files {
+			if values := findStoredCredential(file, endpoint, username); values != nil {
+				return values
+			}
+		}
+	case "store":
+		stored := url.URL{Scheme: endpoint.Protocol, User: url.UserPassword(username, password), Host: endpoint.Host}
+		if endpoint.Port != 0 {
+			stored.Host = fmt.Sprintf("%s:%d", endpoint.Host, endpoint.Port)
+		}
+		if findStoredCredential(files[0], endpoint, username) != nil {
+			return nil
+		}
+		file, err := os.OpenFile(files[0], os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return nil
+		}
+		defer file.Close()
+		fmt.Fprintln(file, stored.String())
+	}
+	return nil
+}
+
+// findStoredCredential reads the first credential of a store file matching the endpoint, nil when there is none
+func findStoredCredential(file string, endpoint *transport.Endpoint, username string) map[string]string {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		stored, err := url.Parse(strings.TrimSpace(scanner.Text()))
+		if err != nil || stored.User == nil || stored.Scheme != endpoint.Protocol || stored.Hostname() != endpoint.Host {
+			continue
+		}
+		if endpoint.Port != 0 && stored.Port() != fmt.Sprint(endpoint.Port) {
+			continue
+		}
+		storedPassword, _ := stored.User.Password()
+		if username != "" && stored.User.Username() != username {
+			continue
+		}
+		return map[string]string{"username": stored.User.Username(), "password": storedPassword}
+	}
+	return nil
+}
+
+// netrcCredentials reads the login and password of a host from $NETRC or ~/.netrc,
+// falling back to its default entry
+func netrcCredentials(host string) (string, string, bool) {
+	path := os.Getenv("NETRC")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", "", false
+		}
+		path = filepath.Join(home, ".netrc")
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", false
+	}
+
+	type entry struct{ login, password string }
+	var matched, fallback *entry
+	var current *entry
+	tokens := strings.Fields(string(content))
+	for index := 0; index < len(tokens); index++ {
+		next := func() string {
+			if index+1 < len(tokens) {
+				index++
+				return tokens[index]
+			}
+			return ""
+		}
+		switch tokens[index] {
+		case "machine":
+			current = &entry{}
+			if next() == host && matched == nil {
+				matched = current
+			}
+		case "default":
+			current = &entry{}
+			fallback = current
+		case "login":
+			if current != nil {
+				current.login = next()
+			}
+		case "password":
+			if current != nil {
+				current.password = next()
+			}
+		}
+	}
+	if matched == nil {
+		matched = fallback
+	}
+	if matched == nil || matched.password == "" {
+		return "", "", false
+	}
+	return matched.login, matched.password, true
+}
+
+// ask asks the username, unless the url has one, and the password to the askpass program
+func (a *remoteAuth) ask() (string, string, error) {
+	base := fmt.Sprintf("%s://%s", a.endpoint.Protocol, a.hostWithPort())
+	username := a.endpoint.User
+	if username == "" {
+		var err error
+		if username, err = runAskPass(a.askPass, fmt.Sprintf("Username for '%s': ", base)); err != nil {
+			return "", "", err
+		}
+	}
+	prompt := fmt.Sprintf("Password for '%s://%s@%s': ", a.endpoint.Protocol, username, a.hostWithPort())
+	password, err := runAskPass(a.askPass, prompt)
+	if err != nil {
+		return "", "", err
+	}
+	return username, password, nil
+}
+
+func runAskPass(program string, prompt string) (string, error) {
+	output, err := exec.Command(program, prompt).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(output), "\r\n"), nil
+}
+
+// fetchRemote fetches the origin remote of a repository, authenticated
+func fetchRemote(repo *git.Repository, options *git.FetchOptions) error {
+	auth, err := authForRemote(repo)
+	if err != nil {
+		return err
+	}
+	err = auth.run(func(method transport.AuthMethod) error {
+		options.Auth = method
+		return repo.Fetch(options)
+	})
+	if err == nil || err == git.NoErrAlreadyUpToDate {
+		recordFetch(repo)
+	}
+	return err
+}
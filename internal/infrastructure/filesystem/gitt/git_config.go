@@ -0,0 +1,206 @@
+package gitt
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	format "github.com/go-git/go-git/v5/plumbing/format/config"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+)
+
+// Deepest chain of included git config files, like git's own limit
+const maxIncludeDepth = 10
+
+// readGitConfigs reads the git configs in the order git does: the system config, the global ones,
+// then the config of the repository whose git directory is gitDir, each followed by the files it includes
+// with include.path and includeIf "gitdir:". gitDir is empty outside of a repository, e.g. before a clone.
+// The options of a config override the ones of the configs before it.
+func readGitConfigs(gitDir string) ([]*format.Config, error) {
+	files, err := configFiles()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate the git config: %w", err)
+	}
+	if gitDir != "" {
+		files = append(files, filepath.Join(gitDir, "config"))
+	}
+	configs := []*format.Config{}
+	for _, file := range files {
+		configs, err = readConfigFile(configs, file, gitDir, 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return configs, nil
+}
+
+// gitDirOf returns the git directory of a repository, empty when it is not stored on disk
+func gitDirOf(repo *git.Repository) string {
+	if storage, ok := repo.Storer.(*filesystem.Storage); ok {
+		return storage.Filesystem().Root()
+	}
+	return ""
+}
+
+// configFiles returns the system then the global git config files, in the order git reads them
+func configFiles() ([]string, error) {
+	var files []string
+	if noSystem, _ := strconv.ParseBool(os.Getenv("GIT_CONFIG_NOSYSTEM")); !noSystem {
+		system := os.Getenv("GIT_CONFIG_SYSTEM")
+		if system == "" {
+			system = "/etc/gitconfig"
+		}
+		files = append(files, system)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	xdg := os.Getenv("XDG_CONFIG_HOME")
+	if xdg == "" {
+		xdg = filepath.Join(home, ".config")
+	}
+	return append(files, filepath.Join(xdg, "git", "config"), filepath.Join(home, ".gitconfig")), nil
+}
+
+// readConfigFile appends a git config file to configs, then the files it includes.
+// A missing file is skipped like git does.
+func readConfigFile(configs []*format.Config, path string, gitDir string, depth int) ([]*format.Config, error) {
+	if depth > maxIncludeDepth {
+		return nil, fmt.Errorf("failed to read the git config %s: exceeded the maximum include depth of %d", path, maxIncludeDepth)
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return configs, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the git config %s: %w", path, err)
+	}
+	defer file.Close()
+	raw := format.New()
+	if err := format.NewDecoder(file).Decode(raw); err != nil {
+		return nil, fmt.Errorf("failed to read the git config %s: %w", path, err)
+	}
+	configs = append(configs, raw)
+
+	for _, include := range includedFiles(raw, gitDir) {
+		included, err := resolveConfigPath(include, filepath.Dir(path))
+		if err != nil {
+			return nil, err
+		}
+		configs, err = readConfigFile(configs, included, gitDir, depth+1)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return configs, nil
+}
+
+// includedFiles returns the include.path files of a git config, then the includeIf ones whose gitdir condition
+// matches the repository, none without repository
+func includedFiles(raw *format.Config, gitDir string) []string {
+	var files []string
+	if raw.HasSection("include") {
+		files = append(files, raw.Section("include").Options.GetAll("path")...)
+	}
+	if !raw.HasSection("includeIf") {
+		return files
+	}
+	for _, subsection := range raw.Section("includeIf").Subsections {
+		if gitDirMatches(subsection.Name, gitDir) {
+			files = append(files, subsection.Options.GetAll("path")...)
+		}
+	}
+	return files
+}
+
+// gitDirMatches tells whether a "gitdir:" or "gitdir/i:" includeIf condition matches the git directory.
+// Other conditions never match.
+func gitDirMatches(condition string, gitDir string) bool {
+	pattern, ok := strings.CutPrefix(condition, "gitdir:")
+	ignoreCase := false
+	if !ok {
+		pattern, ok = strings.CutPrefix(condition, "gitdir/i:")
+		ignoreCase = true
+	}
+	if !ok || pattern == "" || gitDir == "" {
+		return false
+	}
+	if rest, ok := strings.CutPrefix(pattern, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return false
+		}
+		pattern = filepath.ToSlash(home) + "/" + rest
+	} else if !strings.HasPrefix(pattern, "/") && !strings.HasPrefix(pattern, "./") {
+		pattern = "**/" + pattern
+	}
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "**"
+	}
+	expression := globExpression(pattern)
+	if ignoreCase {
+		expression = "(?i)" + expression
+	}
+	matcher, err := regexp.Compile(expression)
+	if err != nil {
+		return false
+	}
+
+	candidates := []string{gitDir}
+	if resolved, err := filepath.EvalSymlinks(gitDir); err == nil {
+		candidates = append(candidates, resolved)
+	}
+	for _, candidate := range candidates {
+		if absolute, err := filepath.Abs(candidate); err == nil && matcher.MatchString(filepath.ToSlash(absolute)) {
+			return true
+		}
+	}
+	return false
+}
+
+// globExpression converts a gitdir pattern to an anchored regular expression, where "**" crosses directories
+// and "*" and "?" do not
+func globExpression(pattern string) string {
+	var expression strings.Builder
+	expression.WriteString("^")
+	for index := 0; index < len(pattern); index++ {
+		switch {
+		case strings.HasPrefix(pattern[index:], "**/"):
+			expression.WriteString("(.*/)?")
+			index += 2
+		case strings.HasPrefix(pattern[index:], "**"):
+			expression.WriteString(".*")
+			index++
+		case pattern[index] == '*':
+			expression.WriteString("[^/]*")
+		case pattern[index] == '?':
+			expression.WriteString("[^/]")
+		default:
+			expression.WriteString(regexp.QuoteMeta(pattern[index : index+1]))
+		}
+	}
+	expression.WriteString("$")
+	return expression.String()
+}
+
+// resolveConfigPath returns the path of an included git config, relative paths being relative to the directory
+// of the including file
+func resolveConfigPath(path string, dir string) (string, error) {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(home, rest), nil
+	}
+	if filepath.IsAbs(path) {
+		return path, nil
+	}
+	return filepath.Join(dir, path), nil
+}
@@ -0,0 +1,185 @@
+package gitt
+
+import (
+	"duh/internal/domain/entity"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// ListCommits returns the commits of the checked out history of a repository, newest first,
+// following first parents, at most limit of them
+func ListCommits(repoPath string, limit int) ([]entity.PackageCommit, error) {
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	head, err := repo.Head()
+	if err == plumbing.ErrReferenceNotFound {
+		return []entity.PackageCommit{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, err
+	}
+
+	commits := []entity.PackageCommit{}
+	for len(commits) < limit {
+		commits = append(commits, entity.PackageCommit{
+			Hash:    commit.Hash.String(),
+			Author:  commit.Author.Name,
+			Date:    commit.Author.When,
+			Subject: subject(commit.Message),
+		})
+		if commit.NumParents() == 0 {
+			break
+		}
+		if commit, err = commit.Parent(0); err != nil {
+			return nil, err
+		}
+	}
+	return commits, nil
+}
+
+func subject(message string) string {
+	first, _, _ := strings.Cut(message, "\n")
+	return first
+}
+
+// ResolveCommit finds the full hash of a tag, a branch or a (possibly abbreviated) commit of a repository
+func ResolveCommit(repoPath string, ref string) (string, error) {
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return "", err
+	}
+	hash, err := resolveRef(repo, ref)
+	if err != nil {
+		return "", err
+	}
+	return hash.String(), nil
+}
+
+// RestoreCommit commits the files of a repository as they were at a commit, on top of the checked out branch.
+// The history is kept as is, so the new commit can be pushed without rewriting the remote history.
+func RestoreCommit(repoPath string, commit string, identity CommitIdentity, message string) error {
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return err
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	status, err := worktree.Status()
+	if err != nil {
+		return err
+	}
+	if !status.IsClean() {
+		return ErrChangesExist
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return err
+	}
+	if !head.Name().IsBranch() {
+		return fmt.Errorf("HEAD is detached, check a branch out first")
+	}
+
+	target, err := repo.CommitObject(plumbing.NewHash(commit))
+	if err != nil {
+		return err
+	}
+	targetTree, err := target.Tree()
+	if err != nil {
+		return err
+	}
+	headCommit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return err
+	}
+	headTree, err := headCommit.Tree()
+	if err != nil {
+		return err
+	}
+	if targetTree.Hash == headTree.Hash {
+		return fmt.Errorf("the files are already as they were at commit %s", entity.ShortCommit(target.Hash.String()))
+	}
+
+	// Files added since the target commit are removed, the others restored
+	err = headTree.Files().ForEach(func(file *object.File) error {
+		if _, err := targetTree.File(file.Name); err == object.ErrFileNotFound {
+			_, err := worktree.Remove(file.Name)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	err = targetTree.Files().ForEach(func(file *object.File) error {
+		if err := restoreFile(repoPath, file); err != nil {
+			return err
+		}
+		_, err := worktree.Add(file.Name)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = worktree.Commit(message, identity.commitOptions())
+	return err
+}
+
+// restoreFile writes a file of a commit in the worktree, as a symbolic link when it is one,
+// with the executable bit it has in the commit
+func restoreFile(repoPath string, file *object.File) error {
+	path := filepath.Join(repoPath, file.Name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	// Replace the file instead of writing through it, it may be a symbolic link now
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if file.Mode == filemode.Symlink {
+		target, err := file.Contents()
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, path)
+	}
+
+	mode, err := file.Mode.ToOSFileMode()
+	if err != nil {
+		return err
+	}
+	reader, err := file.Reader()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	output, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(output, reader); err != nil {
+		output.Close()
+		return err
+	}
+	if err := output.Close(); err != nil {
+		return err
+	}
+	// The mode given to OpenFile is reduced by the umask
+	return os.Chmod(path, mode.Perm())
+}
@@ -0,0 +1,112 @@
+package gitt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ListCommits(t *testing.T) {
+	repoPath := t.TempDir()
+	_, err := git.PlainInit(repoPath, false)
+	assert.NoError(t, err)
+	commits, err := ListCommits(repoPath, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, commits)
+
+	first := commitFiles(t, repoPath, map[string]string{"db.toml": "v1"}, "initial")
+	second := commitFiles(t, repoPath, map[string]string{"db.toml": "v2"}, "Add alias `gs`\n\n- alias `gs` added: git status")
+	commits, err = ListCommits(repoPath, 10)
+	assert.NoError(t, err)
+	assert.Len(t, commits, 2)
+	assert.Equal(t, second.String(), commits[0].Hash)
+	assert.Equal(t, "Add alias `gs`", commits[0].Subject)
+	assert.Equal(t, "Test User", commits[0].Author)
+	assert.Equal(t, first.String(), commits[1].Hash)
+
+	commits, err = ListCommits(repoPath, 1)
+	assert.NoError(t, err)
+	assert.Len(t, commits, 1)
+
+	resolved, err := ResolveCommit(repoPath, first.String()[:7])
+	assert.NoError(t, err)
+	assert.Equal(t, first.String(), resolved)
+}
+
+func Test_RestoreCommit(t *testing.T) {
+	repoPath := t.TempDir()
+	repo, err := git.PlainInit(repoPath, false)
+	assert.NoError(t, err)
+	first := commitFiles(t, repoPath, map[string]string{"db.toml": "v1"}, "initial")
+	second := commitFiles(t, repoPath, map[string]string{"db.toml": "v2", "functions/deploy.sh": "deploy() { :; }"}, "break things")
+
+	// Local changes are never overwritten
+	assert.NoError(t, os.WriteFile(filepath.Join(repoPath, "db.toml"), []byte("edited"), 0644))
+	err = RestoreCommit(repoPath, first.String(), fallbackIdentity, "Roll back")
+	assert.Equal(t, ErrChangesExist, err)
+	assert.NoError(t, os.WriteFile(filepath.Join(repoPath, "db.toml"), []byte("v2"), 0644))
+
+	err = RestoreCommit(repoPath, first.String(), fallbackIdentity, "Roll back")
+	assert.NoError(t, err)
+	content, err := os.ReadFile(filepath.Join(repoPath, "db.toml"))
+	assert.NoError(t, err)
+	assert.Equal(t, "v1", string(content))
+	assert.NoFileExists(t, filepath.Join(repoPath, "functions", "deploy.sh"))
+
+	// The rollback is a new commit on top of the history
+	head, err := repo.Head()
+	assert.NoError(t, err)
+	commit, err := repo.CommitObject(head.Hash())
+	assert.NoError(t, err)
+	assert.Equal(t, "Roll back", commit.Message)
+	assert.Equal(t, []string{second.String()}, []string{commit.ParentHashes[0].String()})
+	worktree, err := repo.Worktree()
+	assert.NoError(t, err)
+	status, err := worktree.Status()
+	assert.NoError(t, err)
+	assert.True(t, status.IsClean())
+
+	// Restoring the same files again is refused
+	assert.Error(t, RestoreCommit(repoPath, first.String(), fallbackIdentity, "Roll back"))
+}
+
+func Test_RestoreCommit_SymlinksAndModes(t *testing.T) {
+	repoPath := t.TempDir()
+	repo, err := git.PlainInit(repoPath, false)
+	assert.NoError(t, err)
+	worktree, err := repo.Worktree()
+	assert.NoError(t, err)
+	scriptPath := filepath.Join(repoPath, "bin", "deploy")
+	linkPath := filepath.Join(repoPath, "gitconfig")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(scriptPath), 0755))
+	assert.NoError(t, os.WriteFile(scriptPath, []byte("#!/bin/sh\n"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(repoPath, "team.gitconfig"), []byte("[alias]\n"), 0644))
+	assert.NoError(t, os.Symlink("team.gitconfig", linkPath))
+	_, err = worktree.Add(".")
+	assert.NoError(t, err)
+	first, err := worktree.Commit("initial", fallbackIdentity.commitOptions())
+	assert.NoError(t, err)
+
+	// The script loses its executable bit and the link is replaced by a file
+	assert.NoError(t, os.Chmod(scriptPath, 0644))
+	assert.NoError(t, os.Remove(linkPath))
+	assert.NoError(t, os.WriteFile(linkPath, []byte("[alias]\n\tst = status\n"), 0644))
+	_, err = worktree.Add(".")
+	assert.NoError(t, err)
+	_, err = worktree.Commit("break things", fallbackIdentity.commitOptions())
+	assert.NoError(t, err)
+
+	assert.NoError(t, RestoreCommit(repoPath, first.String(), fallbackIdentity, "Roll back"))
+	info, err := os.Stat(scriptPath)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+	target, err := os.Readlink(linkPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "team.gitconfig", target)
+	status, err := worktree.Status()
+	assert.NoError(t, err)
+	assert.True(t, status.IsClean(), status.String())
+}
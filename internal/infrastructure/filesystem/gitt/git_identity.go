@@ -0,0 +1,214 @@
+package gitt
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	format "github.com/go-git/go-git/v5/plumbing/format/config"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Formats of signatures, from the gpg.format git config
+const (
+	SignFormatOpenPGP = "openpgp"
+	SignFormatX509    = "x509"
+	SignFormatSSH     = "ssh"
+)
+
+// Author of the commits of a repository when neither the git config nor duh gives one
+var fallbackIdentity = CommitIdentity{Name: "duh", Email: "duh@localhost"}
+
+// CommitIdentity is who commits the changes duh makes to a repository, and how the commits are signed
+type CommitIdentity struct {
+	Name  string
+	Email string
+	// Sign commits, from commit.gpgsign
+	Sign bool
+	// Key used to sign, from user.signingkey: a GPG key id, or an SSH key file or "key::" literal
+	SigningKey string
+	// One of the SignFormat values, from gpg.format
+	SignFormat string
+	// Program signing commits, from gpg.program, gpg.x509.program or gpg.ssh.program
+	SignProgram string
+}
+
+// ResolveIdentity reads the identity from the git configs of a repository, each overriding the previous one
+// like git itself does. The name and the email set by none of them fall back to fallbackIdentity.
+func ResolveIdentity(repoPath string) (CommitIdentity, error) {
+	identity := CommitIdentity{SignFormat: SignFormatOpenPGP}
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return identity, err
+	}
+	configs, err := readGitConfigs(gitDirOf(repo))
+	if err != nil {
+		return identity, err
+	}
+	for _, raw := range configs {
+		identity.apply(raw)
+	}
+	if identity.Name == "" {
+		identity.Name = fallbackIdentity.Name
+	}
+	if identity.Email == "" {
+		identity.Email = fallbackIdentity.Email
+	}
+	return identity, nil
+}
+
+// apply overrides the identity with the options set in a git config
+func (i *CommitIdentity) apply(raw *format.Config) {
+	setString := func(target *string, section string, subsection string, key string) {
+		if !raw.HasSection(section) {
+			return
+		}
+		options := raw.Section(section).Options
+		if subsection != "" {
+			if !raw.Section(section).HasSubsection(subsection) {
+				return
+			}
+			options = raw.Section(section).Subsection(subsection).Options
+		}
+		if options.Has(key) {
+			*target = options.Get(key)
+		}
+	}
+
+	setString(&i.Name, "user", "", "name")
+	setString(&i.Email, "user", "", "email")
+	setString(&i.SigningKey, "user", "", "signingkey")
+	setString(&i.SignFormat, "gpg", "", "format")
+	sign := ""
+	setString(&sign, "commit", "", "gpgsign")
+	if parsed, err := strconv.ParseBool(sign); err == nil {
+		i.Sign = parsed
+	}
+	switch i.SignFormat {
+	case SignFormatSSH:
+		setString(&i.SignProgram, "gpg", "ssh", "program")
+	case SignFormatX509:
+		setString(&i.SignProgram, "gpg", "x509", "program")
+	default:
+		setString(&i.SignProgram, "gpg", "", "program")
+	}
+}
+
+// commitOptions returns the options committing as the identity, signing the commit when required
+func (i CommitIdentity) commitOptions() *git.CommitOptions {
+	signature := &object.Signature{Name: i.Name, Email: i.Email, When: time.Now()}
+	options := &git.CommitOptions{Author: signature, Committer: signature}
+	if i.Sign {
+		options.Signer = commandSigner{identity: i}
+	}
+	return options
+}
+
+// committer commits the changes duh makes to a repository
+type committer struct {
+	identity CommitIdentity
+	// Describes the local changes of the repository as a commit message, empty when there is nothing to tell
+	describe func() (string, error)
+}
+
+// commitLocalChanges stages and commits every change of the working tree,
+// with a message describing them or fallbackMessage
+func (c committer) commitLocalChanges(worktree *git.Worktree, fallbackMessage string) error {
+	message := fallbackMessage
+	if c.describe != nil {
+		description, err := c.describe()
+		if err != nil {
+			return fmt.Errorf("failed to describe the local changes: %w", err)
+		}
+		if description != "" {
+			message = description
+		}
+	}
+	_, err := worktree.Add(".")
+	if err != nil {
+		return fmt.Errorf("failed to stage changes: %w", err)
+	}
+	_, err = worktree.Commit(message, c.identity.commitOptions())
+	if err != nil {
+		return fmt.Errorf("failed to commit changes: %w", err)
+	}
+	return nil
+}
+
+// commandSigner signs commits with an external program, the way git does
+type commandSigner struct {
+	identity CommitIdentity
+}
+
+func (s commandSigner) Sign(message io.Reader) ([]byte, error) {
+	program := s.identity.SignProgram
+	var args []string
+	switch s.identity.SignFormat {
+	case SignFormatSSH:
+		if program == "" {
+			program = "ssh-keygen"
+		}
+		keyFile, cleanup, err := sshKeyFile(s.identity.SigningKey)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+		args = []string{"-Y", "sign", "-n", "git", "-f", keyFile}
+	default:
+		if program == "" {
+			program = "gpg"
+			if s.identity.SignFormat == SignFormatX509 {
+				program = "gpgsm"
+			}
+		}
+		key := s.identity.SigningKey
+		if key == "" {
+			key = fmt.Sprintf("%s <%s>", s.identity.Name, s.identity.Email)
+		}
+		args = []string{"--status-fd=2", "-bsau", key}
+	}
+
+	cmd := exec.Command(program, args...)
+	cmd.Stdin = message
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to sign the commit with %s: %v\nOutput: %s", program, err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
+
+// sshKeyFile returns the path of the key to sign with, writing "key::" literals to a temporary file
+func sshKeyFile(signingKey string) (string, func(), error) {
+	noCleanup := func() {}
+	if signingKey == "" {
+		return "", noCleanup, fmt.Errorf("user.signingkey is required to sign commits with ssh")
+	}
+	if literal, ok := strings.CutPrefix(signingKey, "key::"); ok {
+		file, err := os.CreateTemp("", "duh-signing-key-")
+		if err != nil {
+			return "", noCleanup, err
+		}
+		defer file.Close()
+		if _, err := file.WriteString(literal + "\n"); err != nil {
+			return "", noCleanup, err
+		}
+		return file.Name(), func() { os.Remove(file.Name()) }, nil
+	}
+	if rest, ok := strings.CutPrefix(signingKey, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", noCleanup, err
+		}
+		return filepath.Join(home, rest), noCleanup, nil
+	}
+	return signingKey, noCleanup, nil
+}
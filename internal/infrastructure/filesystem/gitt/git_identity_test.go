@@ -0,0 +1,169 @@
+package gitt
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// isolateGlobalConfig points the global git config to a file of the test, with the given content,
+// and the system git config to a missing file of the test
+func isolateGlobalConfig(t *testing.T, content string) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("GIT_CONFIG_SYSTEM", filepath.Join(home, "system-gitconfig"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "")
+	assert.NoError(t, os.WriteFile(filepath.Join(home, ".gitconfig"), []byte(content), 0644))
+	// Shared authentications would keep credentials from the previous test
+	resetAuthCache := func() {
+		authByKeyMu.Lock()
+		defer authByKeyMu.Unlock()
+		clear(authByKey)
+	}
+	resetAuthCache()
+	t.Cleanup(resetAuthCache)
+}
+
+func Test_ResolveIdentity(t *testing.T) {
+	isolateGlobalConfig(t, "[user]\n\tname = Global User\n\temail = global@example.com\n[commit]\n\tgpgsign = true\n")
+	repoPath := t.TempDir()
+	_, err := git.PlainInit(repoPath, false)
+	assert.NoError(t, err)
+
+	identity, err := ResolveIdentity(repoPath)
+	assert.NoError(t, err)
+	assert.Equal(t, CommitIdentity{
+		Name:       "Global User",
+		Email:      "global@example.com",
+		Sign:       true,
+		SignFormat: SignFormatOpenPGP,
+	}, identity)
+
+	// The config of the repository overrides the global one
+	localConfig := "[user]\n\temail = local@example.com\n\tsigningkey = ~/.ssh/id_ed25519.pub\n[gpg]\n\tformat = ssh\n[gpg \"ssh\"]\n\tprogram = /usr/local/bin/ssh-sign\n"
+	assert.NoError(t, os.WriteFile(filepath.Join(repoPath, ".git", "config"), []byte(localConfig), 0644))
+	identity, err = ResolveIdentity(repoPath)
+	assert.NoError(t, err)
+	assert.Equal(t, CommitIdentity{
+		Name:        "Global User",
+		Email:       "local@example.com",
+		Sign:        true,
+		SigningKey:  "~/.ssh/id_ed25519.pub",
+		SignFormat:  SignFormatSSH,
+		SignProgram: "/usr/local/bin/ssh-sign",
+	}, identity)
+}
+
+func Test_ResolveIdentity_Fallback(t *testing.T) {
+	isolateGlobalConfig(t, "")
+	repoPath := t.TempDir()
+	_, err := git.PlainInit(repoPath, false)
+	assert.NoError(t, err)
+
+	identity, err := ResolveIdentity(repoPath)
+	assert.NoError(t, err)
+	assert.Equal(t, fallbackIdentity.Name, identity.Name)
+	assert.Equal(t, fallbackIdentity.Email, identity.Email)
+	assert.False(t, identity.Sign)
+}
+
+func Test_ResolveIdentity_FallbackPerField(t *testing.T) {
+	isolateGlobalConfig(t, "[user]\n\tname = Global User\n")
+	repoPath := t.TempDir()
+	_, err := git.PlainInit(repoPath, false)
+	assert.NoError(t, err)
+
+	identity, err := ResolveIdentity(repoPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "Global User", identity.Name)
+	assert.Equal(t, fallbackIdentity.Email, identity.Email)
+}
+
+func Test_ResolveIdentity_SystemConfigAndIncludes(t *testing.T) {
+	isolateGlobalConfig(t, "[include]\n\tpath = identity.gitconfig\n[includeIf \"gitdir:work/\"]\n\tpath = ~/work.gitconfig\n[includeIf \"gitdir:other/\"]\n\tpath = ~/other.gitconfig\n")
+	home := os.Getenv("HOME")
+	assert.NoError(t, os.WriteFile(os.Getenv("GIT_CONFIG_SYSTEM"), []byte("[user]\n\tname = System User\n\temail = system@example.com\n[commit]\n\tgpgsign = true\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(home, "identity.gitconfig"), []byte("[user]\n\tname = Included User\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(home, "work.gitconfig"), []byte("[user]\n\temail = work@example.com\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(home, "other.gitconfig"), []byte("[user]\n\temail = other@example.com\n"), 0644))
+
+	repoPath := filepath.Join(t.TempDir(), "work", "repo")
+	_, err := git.PlainInit(repoPath, false)
+	assert.NoError(t, err)
+
+	// The system config is overridden by the global one and the files it includes
+	identity, err := ResolveIdentity(repoPath)
+	assert.NoError(t, err)
+	assert.Equal(t, CommitIdentity{
+		Name:       "Included User",
+		Email:      "work@example.com",
+		Sign:       true,
+		SignFormat: SignFormatOpenPGP,
+	}, identity)
+
+	// Only the includeIf matching the repository applies
+	repoPath = filepath.Join(t.TempDir(), "personal")
+	_, err = git.PlainInit(repoPath, false)
+	assert.NoError(t, err)
+	identity, err = ResolveIdentity(repoPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "system@example.com", identity.Email)
+}
+
+func Test_CommitLocalChanges_Signed(t *testing.T) {
+	// A fake gpg, checking the key it is asked to sign with
+	program := filepath.Join(t.TempDir(), "fake-gpg")
+	script := "#!/bin/sh\n[ \"$3\" = \"ABCD1234\" ] || exit 1\ncat > /dev/null\necho '-----BEGIN PGP SIGNATURE-----'\necho 'fake'\necho '-----END PGP SIGNATURE-----'\n"
+	assert.NoError(t, os.WriteFile(program, []byte(script), 0755))
+
+	repoPath := t.TempDir()
+	repo, err := git.PlainInit(repoPath, false)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(filepath.Join(repoPath, "db.toml"), []byte("[aliases]\nl = \"ls\"\n"), 0644))
+	worktree, err := repo.Worktree()
+	assert.NoError(t, err)
+
+	identity := CommitIdentity{
+		Name:        "Signing User",
+		Email:       "signing@example.com",
+		Sign:        true,
+		SigningKey:  "ABCD1234",
+		SignFormat:  SignFormatOpenPGP,
+		SignProgram: program,
+	}
+	repoCommitter := committer{identity: identity, describe: func() (string, error) { return "Add alias `l`", nil }}
+	assert.NoError(t, repoCommitter.commitLocalChanges(worktree, "Auto-commit"))
+
+	head, err := repo.Head()
+	assert.NoError(t, err)
+	commit, err := repo.CommitObject(head.Hash())
+	assert.NoError(t, err)
+	assert.Equal(t, "Add alias `l`", commit.Message)
+	assert.Equal(t, "Signing User", commit.Author.Name)
+	assert.Equal(t, "signing@example.com", commit.Committer.Email)
+	assert.Contains(t, commit.PGPSignature, "fake")
+
+	// The commit is not created when signing fails
+	identity.SigningKey = "unknown"
+	assert.NoError(t, os.WriteFile(filepath.Join(repoPath, "db.toml"), []byte("[aliases]\n"), 0644))
+	repoCommitter = committer{identity: identity}
+	assert.Error(t, repoCommitter.commitLocalChanges(worktree, "Auto-commit"))
+	after, err := repo.Head()
+	assert.NoError(t, err)
+	assert.Equal(t, head.Hash(), after.Hash())
+
+	// Nor when the local changes cannot be described
+	repoCommitter = committer{identity: CommitIdentity{Name: "User", Email: "user@example.com"}, describe: func() (string, error) {
+		return "", errors.New("corrupted db file")
+	}}
+	err = repoCommitter.commitLocalChanges(worktree, "Auto-commit")
+	assert.ErrorContains(t, err, "corrupted db file")
+	after, err = repo.Head()
+	assert.NoError(t, err)
+	assert.Equal(t, head.Hash(), after.Hash())
+}
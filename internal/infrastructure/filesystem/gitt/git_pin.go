@@ -0,0 +1,188 @@
+package gitt
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// CheckoutRef checks out a tag, a remote branch or a commit hash on a detached HEAD.
+// The remote is fetched first, so refs published since the clone can be used.
+// Unless force is set, the checkout is refused when the working tree has local changes.
+func CheckoutRef(repoPath string, ref string, force bool) error {
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return err
+	}
+	return checkoutRef(repo, ref, force)
+}
+
+func checkoutRef(repo *git.Repository, ref string, force bool) error {
+	fetchErr := fetchWithTags(repo)
+
+	hash, err := resolveRef(repo, ref)
+	if err != nil {
+		if fetchErr != nil {
+			return fmt.Errorf("ref '%s' not found, and fetching the remote failed: %w", ref, fetchErr)
+		}
+		return err
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	if !force {
+		status, err := worktree.Status()
+		if err != nil {
+			return err
+		}
+		if !status.IsClean() {
+			return ErrChangesExist
+		}
+	}
+	return worktree.Checkout(&git.CheckoutOptions{Hash: hash, Force: force})
+}
+
+// ResetToCommit moves a repository to a commit, fetching the remote first when the commit is unknown.
+// A repository on a branch has its branch reset to the commit, so later pulls fast-forward from there,
+// while a repository on a detached HEAD, like a pinned one, checks the commit out.
+// Unless force is set, ErrChangesExist is returned when the working tree has local changes.
+func ResetToCommit(repoPath string, commit string, force bool) error {
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return err
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	if !force {
+		status, err := worktree.Status()
+		if err != nil {
+			return err
+		}
+		if !status.IsClean() {
+			return ErrChangesExist
+		}
+	}
+
+	hash, err := repo.ResolveRevision(plumbing.Revision(commit))
+	if err != nil {
+		if err := fetchWithTags(repo); err != nil {
+			return fmt.Errorf("commit '%s' not found, and fetching the remote failed: %w", commit, err)
+		}
+		if hash, err = repo.ResolveRevision(plumbing.Revision(commit)); err != nil {
+			return fmt.Errorf("commit '%s' not found in the repository: %w", commit, err)
+		}
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return err
+	}
+	if head.Name().IsBranch() {
+		return worktree.Reset(&git.ResetOptions{Commit: *hash, Mode: git.HardReset})
+	}
+	return worktree.Checkout(&git.CheckoutOptions{Hash: *hash, Force: true})
+}
+
+// GetHeadCommit returns the hash of the commit checked out in a repository, empty when it has no commit yet
+func GetHeadCommit(repoPath string) (string, error) {
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return "", err
+	}
+	head, err := repo.Head()
+	if err == plumbing.ErrReferenceNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return head.Hash().String(), nil
+}
+
+// CheckoutDefaultBranch moves a repository checked out on a pinned ref back to its local branch.
+// Clones have a single local branch, the default branch of the remote.
+func CheckoutDefaultBranch(repoPath string) error {
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return err
+	}
+	branches, err := repo.Branches()
+	if err != nil {
+		return err
+	}
+	names := []plumbing.ReferenceName{}
+	err = branches.ForEach(func(branch *plumbing.Reference) error {
+		names = append(names, branch.Name())
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("repository '%s' has no local branch", repoPath)
+	}
+	slices.Sort(names)
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	return worktree.Checkout(&git.CheckoutOptions{Branch: names[0]})
+}
+
+// ListTags fetches the remote and returns the names of every tag of the repository
+func ListTags(repoPath string) ([]string, error) {
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := fetchWithTags(repo); err != nil {
+		return nil, err
+	}
+	tags, err := repo.Tags()
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	err = tags.ForEach(func(tag *plumbing.Reference) error {
+		names = append(names, tag.Name().Short())
+		return nil
+	})
+	return names, err
+}
+
+// fetchWithTags updates the remote branches and tags, repositories without remote have nothing to fetch
+func fetchWithTags(repo *git.Repository) error {
+	err := fetchRemote(repo, &git.FetchOptions{
+		RemoteName: git.DefaultRemoteName,
+		RefSpecs:   []config.RefSpec{"+refs/heads/*:refs/remotes/origin/*", "+refs/tags/*:refs/tags/*"},
+	})
+	if err == nil || err == git.NoErrAlreadyUpToDate || err == git.ErrRemoteNotFound {
+		return nil
+	}
+	return err
+}
+
+// resolveRef finds the commit of a tag, a remote branch or a (possibly abbreviated) commit hash
+func resolveRef(repo *git.Repository, ref string) (plumbing.Hash, error) {
+	candidates := []plumbing.Revision{
+		plumbing.Revision(plumbing.NewTagReferenceName(ref)),
+		plumbing.Revision(plumbing.NewRemoteReferenceName(git.DefaultRemoteName, ref)),
+		plumbing.Revision(plumbing.NewBranchReferenceName(ref)),
+		plumbing.Revision(ref),
+	}
+	for _, candidate := range candidates {
+		hash, err := repo.ResolveRevision(candidate)
+		if err == nil {
+			return *hash, nil
+		}
+	}
+	return plumbing.ZeroHash, fmt.Errorf("ref '%s' is not a tag, branch or commit of the repository", ref)
+}
@@ -0,0 +1,158 @@
+package gitt
+
+import (
+	"duh/internal/domain/entity"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+)
+
+// commitFile writes content to db.toml and commits it, returning the commit hash
+func commitFile(t *testing.T, repo *git.Repository, repoPath string, content string) plumbing.Hash {
+	err := os.WriteFile(filepath.Join(repoPath, "db.toml"), []byte(content), 0644)
+	assert.NoError(t, err)
+	worktree, err := repo.Worktree()
+	assert.NoError(t, err)
+	_, err = worktree.Add("db.toml")
+	assert.NoError(t, err)
+	hash, err := worktree.Commit(content, &git.CommitOptions{
+		Author: &object.Signature{Name: "Test User", Email: "test@example.com"},
+	})
+	assert.NoError(t, err)
+	return hash
+}
+
+// createTaggedRemote creates a repository with tags v1.0.0 (lightweight) and v1.1.0 (annotated) and a newer commit
+func createTaggedRemote(t *testing.T) (string, *git.Repository) {
+	remotePath := filepath.Join(t.TempDir(), "remote")
+	repo, err := git.PlainInit(remotePath, false)
+	assert.NoError(t, err)
+
+	first := commitFile(t, repo, remotePath, "v1.0.0")
+	_, err = repo.CreateTag("v1.0.0", first, nil)
+	assert.NoError(t, err)
+	second := commitFile(t, repo, remotePath, "v1.1.0")
+	_, err = repo.CreateTag("v1.1.0", second, &git.CreateTagOptions{
+		Tagger:  &object.Signature{Name: "Test User", Email: "test@example.com"},
+		Message: "release v1.1.0",
+	})
+	assert.NoError(t, err)
+	commitFile(t, repo, remotePath, "unreleased")
+	return remotePath, repo
+}
+
+func readDbFile(t *testing.T, repoPath string) string {
+	content, err := os.ReadFile(filepath.Join(repoPath, "db.toml"))
+	assert.NoError(t, err)
+	return string(content)
+}
+
+func Test_CheckoutRef(t *testing.T) {
+	remotePath, _ := createTaggedRemote(t)
+	repoPath := filepath.Join(t.TempDir(), "clone")
+	err := CloneGitRepository(remotePath, repoPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "unreleased", readDbFile(t, repoPath))
+
+	err = CheckoutRef(repoPath, "v1.0.0", false)
+	assert.NoError(t, err)
+	assert.Equal(t, "v1.0.0", readDbFile(t, repoPath))
+
+	// Annotated tags are resolved to their commit
+	err = CheckoutRef(repoPath, "v1.1.0", false)
+	assert.NoError(t, err)
+	assert.Equal(t, "v1.1.0", readDbFile(t, repoPath))
+
+	err = CheckoutRef(repoPath, "v9.9.9", false)
+	assert.Error(t, err)
+
+	// Local changes are kept unless forced
+	err = os.WriteFile(filepath.Join(repoPath, "db.toml"), []byte("local change"), 0644)
+	assert.NoError(t, err)
+	err = CheckoutRef(repoPath, "v1.0.0", false)
+	assert.Equal(t, ErrChangesExist, err)
+	err = CheckoutRef(repoPath, "v1.0.0", true)
+	assert.NoError(t, err)
+	assert.Equal(t, "v1.0.0", readDbFile(t, repoPath))
+
+	err = CheckoutDefaultBranch(repoPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "unreleased", readDbFile(t, repoPath))
+}
+
+func Test_ListTags(t *testing.T) {
+	remotePath, remote := createTaggedRemote(t)
+	repoPath := filepath.Join(t.TempDir(), "clone")
+	err := CloneGitRepository(remotePath, repoPath)
+	assert.NoError(t, err)
+
+	// Tags published after the clone are fetched
+	head, err := remote.Head()
+	assert.NoError(t, err)
+	_, err = remote.CreateTag("v2.0.0", head.Hash(), nil)
+	assert.NoError(t, err)
+
+	tags, err := ListTags(repoPath)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"v1.0.0", "v1.1.0", "v2.0.0"}, tags)
+}
+
+func Test_PullAllRepositories_Pinned(t *testing.T) {
+	remotePath, remote := createTaggedRemote(t)
+	basePath := t.TempDir()
+	for _, name := range []string{"pinned", "following"} {
+		err := CloneGitRepository(remotePath, filepath.Join(basePath, name))
+		assert.NoError(t, err)
+	}
+	err := CheckoutRef(filepath.Join(basePath, "pinned"), "v1.0.0", false)
+	assert.NoError(t, err)
+
+	commitFile(t, remote, remotePath, "newer")
+	results, err := PullAllRepositories(basePath, PullSettings{Pins: map[string]string{"pinned": "v1.0.0"}}, entity.UpdateOptions{Strategy: entity.UpdateSafe})
+	assert.NoError(t, err)
+	assert.Empty(t, results.OtherErrors)
+	assert.Empty(t, results.LocalChangesDetected)
+	assert.Equal(t, "v1.0.0", readDbFile(t, filepath.Join(basePath, "pinned")))
+	assert.Equal(t, "newer", readDbFile(t, filepath.Join(basePath, "following")))
+}
+
+func Test_ResetToCommit(t *testing.T) {
+	remotePath, remote := createTaggedRemote(t)
+	repoPath := filepath.Join(t.TempDir(), "clone")
+	err := CloneGitRepository(remotePath, repoPath)
+	assert.NoError(t, err)
+
+	tag, err := remote.Tag("v1.0.0")
+	assert.NoError(t, err)
+	err = ResetToCommit(repoPath, tag.Hash().String(), false)
+	assert.NoError(t, err)
+	assert.Equal(t, "v1.0.0", readDbFile(t, repoPath))
+	commit, err := GetHeadCommit(repoPath)
+	assert.NoError(t, err)
+	assert.Equal(t, tag.Hash().String(), commit)
+
+	// The branch is reset, so the repository can still be pulled
+	repo, err := git.PlainOpen(repoPath)
+	assert.NoError(t, err)
+	head, err := repo.Head()
+	assert.NoError(t, err)
+	assert.True(t, head.Name().IsBranch())
+	err = updateRepositoryFromRemote(repoPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "unreleased", readDbFile(t, repoPath))
+
+	// Commits made on the remote after the clone are fetched
+	newer := commitFile(t, remote, remotePath, "newer")
+	err = os.WriteFile(filepath.Join(repoPath, "db.toml"), []byte("local change"), 0644)
+	assert.NoError(t, err)
+	err = ResetToCommit(repoPath, newer.String(), false)
+	assert.Equal(t, ErrChangesExist, err)
+	err = ResetToCommit(repoPath, newer.String(), true)
+	assert.NoError(t, err)
+	assert.Equal(t, "newer", readDbFile(t, repoPath))
+}
@@ -0,0 +1,118 @@
+package gitt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// FetchIncoming fetches the remote of a repository, without touching its working tree,
+// and returns the commit checked out, the commit the incoming changes start from, and the commit an update would move it to.
+// A pinned repository, given its ref, moves to the commit of its ref,
+// others to the remote counterpart of their branch.
+// The incoming changes start from the merge base of both, so the local commits not pushed yet are not part of them.
+// The commits are empty for a repository without remote or without commit.
+func FetchIncoming(repoPath string, ref string) (string, string, string, error) {
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return "", "", "", err
+	}
+	if _, err := repo.Remote(git.DefaultRemoteName); err == git.ErrRemoteNotFound {
+		return "", "", "", nil
+	}
+	head, err := repo.Head()
+	if err == plumbing.ErrReferenceNotFound {
+		return "", "", "", nil
+	}
+	if err != nil {
+		return "", "", "", err
+	}
+	if err := fetchWithTags(repo); err != nil {
+		return "", "", "", fmt.Errorf("failed to fetch: %w", err)
+	}
+
+	var target plumbing.Hash
+	if ref != "" {
+		target, err = resolveRef(repo, ref)
+	} else {
+		target, err = remoteCounterpart(repo, head)
+	}
+	if err != nil {
+		return "", "", "", err
+	}
+	base, err := mergeBase(repo, head.Hash(), target)
+	if err != nil {
+		return "", "", "", err
+	}
+	return head.Hash().String(), base.String(), target.String(), nil
+}
+
+// mergeBase returns the best common ancestor of two commits, the first one when they have no common history
+func mergeBase(repo *git.Repository, first plumbing.Hash, second plumbing.Hash) (plumbing.Hash, error) {
+	if first == second {
+		return first, nil
+	}
+	firstCommit, err := repo.CommitObject(first)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	secondCommit, err := repo.CommitObject(second)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	bases, err := firstCommit.MergeBase(secondCommit)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if len(bases) == 0 {
+		return first, nil
+	}
+	return bases[0].Hash, nil
+}
+
+// remoteCounterpart returns the commit of the remote branch tracked by the checked out branch,
+// falling back to the remote HEAD
+func remoteCounterpart(repo *git.Repository, head *plumbing.Reference) (plumbing.Hash, error) {
+	if head.Name().IsBranch() {
+		remoteRefName := plumbing.NewRemoteReferenceName(git.DefaultRemoteName, head.Name().Short())
+		if remoteRef, err := repo.Reference(remoteRefName, true); err == nil {
+			return remoteRef.Hash(), nil
+		}
+	}
+	remoteRef, err := repo.Reference("refs/remotes/origin/HEAD", true)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to get remote reference: %w", err)
+	}
+	return remoteRef.Hash(), nil
+}
+
+// ExtractCommit writes the files of a commit to destDir, leaving the working tree untouched
+func ExtractCommit(repoPath string, commit string, destDir string) error {
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return err
+	}
+	commitObject, err := repo.CommitObject(plumbing.NewHash(commit))
+	if err != nil {
+		return err
+	}
+	tree, err := commitObject.Tree()
+	if err != nil {
+		return err
+	}
+	return tree.Files().ForEach(func(file *object.File) error {
+		contents, err := file.Contents()
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(destDir, filepath.FromSlash(file.Name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		return os.WriteFile(path, []byte(contents), 0644)
+	})
+}
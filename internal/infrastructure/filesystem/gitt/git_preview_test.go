@@ -0,0 +1,66 @@
+package gitt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FetchIncoming(t *testing.T) {
+	remotePath, remote := createTaggedRemote(t)
+	repoPath := filepath.Join(t.TempDir(), "repo")
+	err := CloneGitRepository(remotePath, repoPath)
+	assert.NoError(t, err)
+	head, err := GetHeadCommit(repoPath)
+	assert.NoError(t, err)
+
+	newer := commitFile(t, remote, remotePath, "newer")
+	from, base, to, err := FetchIncoming(repoPath, "")
+	assert.NoError(t, err)
+	assert.Equal(t, head, from)
+	assert.Equal(t, head, base)
+	assert.Equal(t, newer.String(), to)
+	// The working tree is left untouched
+	assert.Equal(t, "unreleased", readDbFile(t, repoPath))
+
+	tag, err := remote.ResolveRevision("v1.0.0")
+	assert.NoError(t, err)
+	_, _, to, err = FetchIncoming(repoPath, "v1.0.0")
+	assert.NoError(t, err)
+	assert.Equal(t, tag.String(), to)
+
+	// Local commits not pushed yet are not part of the incoming changes
+	local := commitFiles(t, repoPath, map[string]string{"db.toml": "local"}, "local change")
+	from, base, to, err = FetchIncoming(repoPath, "")
+	assert.NoError(t, err)
+	assert.Equal(t, local.String(), from)
+	assert.Equal(t, head, base)
+	assert.Equal(t, newer.String(), to)
+}
+
+func Test_FetchIncoming_NoRemote(t *testing.T) {
+	repoPath := filepath.Join(t.TempDir(), "local")
+	createTestRepoWithContent(t, repoPath)
+
+	from, base, to, err := FetchIncoming(repoPath, "")
+	assert.NoError(t, err)
+	assert.Empty(t, from)
+	assert.Empty(t, base)
+	assert.Empty(t, to)
+}
+
+func Test_ExtractCommit(t *testing.T) {
+	remotePath, remote := createTaggedRemote(t)
+	tag, err := remote.ResolveRevision("v1.0.0")
+	assert.NoError(t, err)
+
+	destDir := t.TempDir()
+	err = ExtractCommit(remotePath, tag.String(), destDir)
+	assert.NoError(t, err)
+	content, err := os.ReadFile(filepath.Join(destDir, "db.toml"))
+	assert.NoError(t, err)
+	assert.Equal(t, "v1.0.0", string(content))
+	assert.Equal(t, "unreleased", readDbFile(t, remotePath))
+}
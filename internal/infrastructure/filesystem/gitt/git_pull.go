@@ -5,49 +5,37 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"time"
+	"slices"
+	"sync"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
-	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
 )
 
-// Check whether there are updates pending to be pulled from the remote repository
-func hasUpdatesPending(repoPath string) (bool, error) {
-	repo, err := git.PlainOpen(repoPath)
+// commitsAheadBehind counts the commits of HEAD missing from its remote counterpart, and the other way round
+func commitsAheadBehind(repo *git.Repository, head *plumbing.Reference) (int, int, error) {
+	remoteHash, err := remoteCounterpart(repo, head)
 	if err != nil {
-		return false, err
+		return 0, 0, err
 	}
-
-	// Fetch latest changes from remote to update remote references
-	err = repo.Fetch(&git.FetchOptions{})
-	if err != nil && err != git.NoErrAlreadyUpToDate {
-		return false, err
+	local, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return 0, 0, err
 	}
-
-	// Get local HEAD commit hash
-	localRef, err := repo.Head()
+	remote, err := repo.CommitObject(remoteHash)
 	if err != nil {
-		return false, err
+		return 0, 0, err
 	}
-	localCommitHash := localRef.Hash()
-
-	// Get remote HEAD commit hash
-	remoteRef, err := repo.Reference("refs/remotes/origin/HEAD", true)
+	ahead, err := localCommits(local, remote)
 	if err != nil {
-		// If remote HEAD doesn't exist, try to get the default branch
-		// Get the current branch name and check its remote counterpart
-		branchName := localRef.Name().Short()
-		remoteRefName := "refs/remotes/origin/" + branchName
-		remoteRef, err = repo.Reference(plumbing.ReferenceName(remoteRefName), true)
-		if err != nil {
-			return false, err
-		}
+		return 0, 0, err
 	}
-	remoteCommitHash := remoteRef.Hash()
-
-	// Compare local and remote commit hashes
-	return localCommitHash != remoteCommitHash, nil
+	behind, err := localCommits(remote, local)
+	if err != nil {
+		return 0, 0, err
+	}
+	return len(ahead), len(behind), nil
 }
 
 func updateRepositoryFromRemote(repoPath string) error {
@@ -59,7 +47,20 @@ func updateRepositoryFromRemote(repoPath string) error {
 	if err != nil {
 		return err
 	}
-	err = w.Pull(&git.PullOptions{RemoteName: "origin"})
+	return pullWorktree(repo, w)
+}
+
+func pullWorktree(repo *git.Repository, w *git.Worktree) error {
+	auth, err := authForRemote(repo)
+	if err != nil {
+		return err
+	}
+	err = auth.run(func(method transport.AuthMethod) error {
+		return w.Pull(&git.PullOptions{RemoteName: "origin", Auth: method})
+	})
+	if err == nil || err == git.NoErrAlreadyUpToDate {
+		recordFetch(repo)
+	}
 	if err != nil && err != git.NoErrAlreadyUpToDate {
 		return err
 	}
@@ -78,12 +79,28 @@ func checkWorkingTreeClean(repoPath string) (bool, error) {
 		return false, err
 	}
 
-	status, err := w.Status()
+	modified, err := modifiedFiles(w)
 	if err != nil {
 		return false, err
 	}
 
-	return status.IsClean(), nil
+	return len(modified) == 0, nil
+}
+
+// modifiedFiles lists the files of a working tree with uncommitted changes, untracked files included, sorted
+func modifiedFiles(w *git.Worktree) ([]string, error) {
+	status, err := w.Status()
+	if err != nil {
+		return nil, err
+	}
+	modified := []string{}
+	for path, file := range status {
+		if file.Staging != git.Unmodified || file.Worktree != git.Unmodified {
+			modified = append(modified, path)
+		}
+	}
+	slices.Sort(modified)
+	return modified, nil
 }
 
 // Pulls changes from remote, handling local changes based on the specified strategy
@@ -91,51 +108,34 @@ func checkWorkingTreeClean(repoPath string) (bool, error) {
 // - entity.UpdateSafe: Do not pull if local changes exist, return ErrChangesExist if changes are present
 // - entity.UpdateKeep: Commit local changes before pulling
 // - entity.UpdateForce: Discard local changes and reset to remote state
-func pullWithLocalChanges(repoPath string, strategy string) error {
-	repo, err := git.PlainOpen(repoPath)
-	if err != nil {
-		return err
-	}
-
+func pullWithLocalChanges(repo *git.Repository, strategy string, committer committer) error {
 	worktree, err := repo.Worktree()
 	if err != nil {
 		return err
 	}
 
 	// Check if working tree is clean
-	isClean, err := checkWorkingTreeClean(repoPath)
+	status, err := worktree.Status()
 	if err != nil {
 		return err
 	}
 
-	if isClean {
+	if status.IsClean() {
 		// No local changes, just do a normal pull
-		return updateRepositoryFromRemote(repoPath)
+		return pullWorktree(repo, worktree)
 	}
 
 	// Handle local changes based on strategy
 	switch strategy {
 	case entity.UpdateKeep:
 		// Commit local changes first, then pull
-		_, err = worktree.Add(".")
-		if err != nil {
-			return fmt.Errorf("failed to stage changes: %w", err)
-		}
-
-		_, err = worktree.Commit("Auto-commit before pull", &git.CommitOptions{
-			Author: &object.Signature{
-				Name:  "duh",
-				Email: "duh@localhost",
-				When:  time.Now(),
-			},
-		})
-		if err != nil {
-			return fmt.Errorf("failed to commit changes: %w", err)
+		if err := committer.commitLocalChanges(worktree, "Auto-commit before pull"); err != nil {
+			return err
 		}
 
 		// Now try to pull
-		err = worktree.Pull(&git.PullOptions{RemoteName: "origin"})
-		if err != nil && err != git.NoErrAlreadyUpToDate {
+		err = pullWorktree(repo, worktree)
+		if err != nil {
 			return fmt.Errorf("failed to pull after commit: %w", err)
 		}
 
@@ -143,7 +143,7 @@ func pullWithLocalChanges(repoPath string, strategy string) error {
 
 	case entity.UpdateForce:
 		// Reset to remote HEAD (WARNING: this will lose local changes)
-		err = repo.Fetch(&git.FetchOptions{})
+		err = fetchRemote(repo, &git.FetchOptions{})
 		if err != nil && err != git.NoErrAlreadyUpToDate {
 			return fmt.Errorf("failed to fetch: %w", err)
 		}
@@ -179,69 +179,227 @@ func pullWithLocalChanges(repoPath string, strategy string) error {
 		// Do not pull and return an error indicating local changes exist
 		return ErrChangesExist
 	default:
-		return fmt.Errorf("unknown strategy '%s'. Available strategies: 'keep', 'force', 'safe'", strategy)
+		return fmt.Errorf("unknown strategy '%s'. Available strategies: 'keep', 'force', 'safe', 'rebase'", strategy)
+	}
+}
+
+// updateOutcome is the result of the update of a single repository
+type updateOutcome struct {
+	status string
+	update entity.PackageUpdate
+	err    error
+}
+
+// PullSettings tells PullAllRepositories how to handle each repository
+type PullSettings struct {
+	// Ref of each pinned repository, by name
+	Pins map[string]string
+	// Merge a file changed on both sides of a rebase, by path
+	Mergers map[string]FileMerger
+	// Identity committing the local changes of a repository, resolved from its git config when nil
+	Identity func(repoName string, repoPath string) (CommitIdentity, error)
+	// Message describing the local changes of a repository, a generic one is used when nil or empty
+	Describe func(repoName string, repoPath string) (string, error)
+}
+
+func (s PullSettings) committer(repoName string, repoPath string) (committer, error) {
+	var identity CommitIdentity
+	var err error
+	if s.Identity != nil {
+		identity, err = s.Identity(repoName, repoPath)
+	} else {
+		identity, err = ResolveIdentity(repoPath)
+	}
+	if err != nil {
+		return committer{}, err
+	}
+	describe := func() (string, error) { return "", nil }
+	if s.Describe != nil {
+		describe = func() (string, error) { return s.Describe(repoName, repoPath) }
 	}
+	return committer{identity: identity, describe: describe}, nil
 }
 
-func hasGitRepoLinked(repoPath string) (bool, error) {
+// updateRepository pulls a repository, or checks its pin out again, opening it only once
+func updateRepository(repoPath string, repoName string, strategy string, settings PullSettings) updateOutcome {
 	repo, err := git.PlainOpen(repoPath)
 	if err == git.ErrRepositoryNotExists {
-		return false, nil
+		return updateOutcome{status: entity.UpdateStatusSkipped}
+	}
+	if err != nil {
+		return updateOutcome{status: entity.UpdateStatusFailed, err: err}
 	}
 	remotes, err := repo.Remotes()
+	if err != nil {
+		return updateOutcome{status: entity.UpdateStatusFailed, err: err}
+	}
 	if len(remotes) == 0 {
-		return false, nil
+		return updateOutcome{status: entity.UpdateStatusSkipped}
 	}
-	return true, nil
-}
 
-// Returns a list of all repositories that have a git remote linked
-func getAllRepositoryWithRemotes(repoBasePath string) ([]string, error) {
-	dirs, err := os.ReadDir(repoBasePath)
+	var repoCommitter committer
+	if strategy == entity.UpdateKeep || strategy == entity.UpdateRebase {
+		if repoCommitter, err = settings.committer(repoName, repoPath); err != nil {
+			return updateOutcome{status: entity.UpdateStatusFailed, err: err}
+		}
+	}
+
+	before := headHash(repo)
+	ref, pinned := settings.Pins[repoName]
+	switch {
+	case pinned:
+		err = checkoutRef(repo, ref, strategy == entity.UpdateForce)
+	case strategy == entity.UpdateRebase:
+		err = rebaseOnRemote(repo, repoName, settings.Mergers, repoCommitter)
+	default:
+		err = pullWithLocalChanges(repo, strategy, repoCommitter)
+	}
+	if err == ErrChangesExist {
+		return updateOutcome{status: entity.UpdateStatusLocalChanges}
+	}
 	if err != nil {
-		return nil, err
+		return updateOutcome{status: entity.UpdateStatusFailed, err: err}
 	}
-	var repos []string
-	for _, dir := range dirs {
-		if dir.IsDir() {
-			repoPath := repoBasePath + string(os.PathSeparator) + dir.Name()
-			hasGit, err := hasGitRepoLinked(repoPath)
-			if err != nil {
-				return nil, err
-			}
-			if hasGit {
-				repos = append(repos, dir.Name())
-			}
-		}
+
+	after := headHash(repo)
+	if before == after {
+		return updateOutcome{status: entity.UpdateStatusUpToDate}
+	}
+	update := entity.PackageUpdate{From: "(none)", To: entity.ShortCommit(after.String())}
+	if !before.IsZero() {
+		update.From = entity.ShortCommit(before.String())
+	}
+	return updateOutcome{status: entity.UpdateStatusUpdated, update: update}
+}
+
+// headHash returns the commit checked out in a repository, the zero hash when it has none yet
+func headHash(repo *git.Repository) plumbing.Hash {
+	head, err := repo.Head()
+	if err != nil {
+		return plumbing.ZeroHash
 	}
-	return repos, nil
+	return head.Hash()
 }
 
 // PullAllRepositories pulls updates for all git repositories found in the specified base path
-// using the specified strategy for handling local changes.
+// using options.Strategy for handling local changes.
 // Strategies:
 // - entity.UpdateSafe: Do not pull if local changes exist, return ErrChangesExist if changes are present
 // - entity.UpdateKeep: Commit local changes before pulling
 // - entity.UpdateForce: Discard local changes and reset to remote state
-func PullAllRepositories(repoBasePath string, strategy string) (entity.PackageUpdateResults, error) {
-	repos, err := getAllRepositoryWithRemotes(repoBasePath)
+// - entity.UpdateRebase: Commit local changes, then replay local commits on top of the remote,
+// files changed on both sides being merged by the merger registered for their path in settings.Mergers
+//
+// Local changes are committed as the identity given by settings.Identity, with the message given by settings.Describe.
+// Pinned repositories, given by name in settings.Pins, are checked out on their ref again instead of being pulled:
+// a branch pin moves to the newest commit of the branch, a tag or commit pin stays where it is.
+// Local changes of a pinned repository are only discarded with entity.UpdateForce,
+// they are never committed as the commit would be left behind on the detached HEAD.
+//
+// Up to options.Jobs repositories are updated at the same time, options.OnProgress being called
+// when each of them starts and finishes. Results are listed in the order of the repository names.
+func PullAllRepositories(repoBasePath string, settings PullSettings, options entity.UpdateOptions) (entity.PackageUpdateResults, error) {
+	dirs, err := os.ReadDir(repoBasePath)
 	if err != nil {
 		return entity.PackageUpdateResults{}, err
 	}
+	repos := []string{}
+	for _, dir := range dirs {
+		if dir.IsDir() {
+			repos = append(repos, dir.Name())
+		}
+	}
 
-	localChangesDetected := []string{}
-	otherErrors := []error{}
-	for _, repoName := range repos {
-		repoPath := filepath.Join(repoBasePath, repoName)
-		err := pullWithLocalChanges(repoPath, strategy)
-		if err == ErrChangesExist {
-			localChangesDetected = append(localChangesDetected, repoName)
-		} else if err != nil {
-			otherErrors = append(otherErrors, fmt.Errorf("failed to pull repository '%s': %w", repoName, err))
+	var progressMutex sync.Mutex
+	report := func(progress entity.UpdateProgress) {
+		if options.OnProgress == nil {
+			return
+		}
+		progressMutex.Lock()
+		defer progressMutex.Unlock()
+		options.OnProgress(progress)
+	}
+
+	// Mergers may ask the user how to resolve a conflict, which must not interleave with progress lines
+	serializedMergers := map[string]FileMerger{}
+	for path, merger := range settings.Mergers {
+		serializedMergers[path] = func(repoName string, base []byte, remote []byte, local []byte) ([]byte, error) {
+			progressMutex.Lock()
+			defer progressMutex.Unlock()
+			return merger(repoName, base, remote, local)
+		}
+	}
+
+	repoSettings := settings
+	repoSettings.Mergers = serializedMergers
+
+	jobs := options.Jobs
+	if jobs <= 0 {
+		jobs = entity.DefaultUpdateJobs
+	}
+	outcomes := make([]updateOutcome, len(repos))
+	forEachConcurrently(len(repos), jobs, func(index int) {
+		repoName := repos[index]
+		report(entity.UpdateProgress{Package: repoName})
+		outcome := updateRepository(filepath.Join(repoBasePath, repoName), repoName, options.Strategy, repoSettings)
+		outcome.update.Package = repoName
+		if outcome.err != nil {
+			outcome.err = fmt.Errorf("failed to pull repository '%s': %w", repoName, outcome.err)
 		}
+		outcomes[index] = outcome
+		report(outcome.progress(repoName))
+	})
+
+	results := entity.PackageUpdateResults{
+		LocalChangesDetected: []string{},
+		OtherErrors:          []error{},
+		Updated:              []entity.PackageUpdate{},
+		UpToDate:             []string{},
+		Skipped:              []string{},
+	}
+	for index, outcome := range outcomes {
+		switch outcome.status {
+		case entity.UpdateStatusUpdated:
+			results.Updated = append(results.Updated, outcome.update)
+		case entity.UpdateStatusUpToDate:
+			results.UpToDate = append(results.UpToDate, repos[index])
+		case entity.UpdateStatusSkipped:
+			results.Skipped = append(results.Skipped, repos[index])
+		case entity.UpdateStatusLocalChanges:
+			results.LocalChangesDetected = append(results.LocalChangesDetected, repos[index])
+		case entity.UpdateStatusFailed:
+			results.OtherErrors = append(results.OtherErrors, outcome.err)
+		}
+	}
+	return results, nil
+}
+
+// forEachConcurrently calls work with every index from 0 to count, from up to jobs goroutines at the same time,
+// and returns once every call returned
+func forEachConcurrently(count int, jobs int, work func(index int)) {
+	indexes := make(chan int)
+	var workers sync.WaitGroup
+	for range min(jobs, count) {
+		workers.Go(func() {
+			for index := range indexes {
+				work(index)
+			}
+		})
+	}
+	for index := range count {
+		indexes <- index
+	}
+	close(indexes)
+	workers.Wait()
+}
+
+func (o updateOutcome) progress(repoName string) entity.UpdateProgress {
+	progress := entity.UpdateProgress{Package: repoName, Status: o.status}
+	switch o.status {
+	case entity.UpdateStatusUpdated:
+		progress.Details = fmt.Sprintf("%s → %s", o.update.From, o.update.To)
+	case entity.UpdateStatusFailed:
+		progress.Details = o.err.Error()
 	}
-	return entity.PackageUpdateResults{
-		LocalChangesDetected: localChangesDetected,
-		OtherErrors:          otherErrors,
-	}, nil
+	return progress
 }
@@ -12,7 +12,7 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-func Test_hasUpdatesPending_NoUpdates(t *testing.T) {
+func Test_GetRepositoryStatus_NoUpdates(t *testing.T) {
 	// Test with a fresh clone - should have no updates pending
 	outputPath := t.TempDir()
 	defer os.RemoveAll(outputPath)
@@ -22,12 +22,13 @@ func Test_hasUpdatesPending_NoUpdates(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Fresh clone should have no pending updates
-	hasUpdates, err := hasUpdatesPending(repoPath)
+	status, err := GetRepositoryStatus(repoPath, true)
 	assert.NoError(t, err)
-	assert.False(t, hasUpdates, "Fresh clone should not have pending updates")
+	assert.NoError(t, status.FetchError)
+	assert.Equal(t, 0, status.Behind, "Fresh clone should not have pending updates")
 }
 
-func Test_hasUpdatesPending_WithUpdates(t *testing.T) {
+func Test_GetRepositoryStatus_WithUpdates(t *testing.T) {
 	// Create a test scenario where updates are pending
 	outputPath := t.TempDir()
 	defer os.RemoveAll(outputPath)
@@ -71,9 +72,10 @@ func Test_hasUpdatesPending_WithUpdates(t *testing.T) {
 		assert.NoError(t, err)
 
 		// Now test if updates are pending - should return true
-		hasUpdates, err := hasUpdatesPending(repoPath)
+		status, err := GetRepositoryStatus(repoPath, true)
 		assert.NoError(t, err)
-		assert.True(t, hasUpdates, "Repository should have pending updates after reset")
+		assert.NoError(t, status.FetchError)
+		assert.Positive(t, status.Behind, "Repository should have pending updates after reset")
 	} else {
 		t.Skip("Test repository doesn't have enough commit history")
 	}
@@ -110,7 +112,7 @@ func Test_PullAllRepositories_NoRepositories(t *testing.T) {
 	// Test with empty directory
 	tempDir := t.TempDir()
 
-	results, err := PullAllRepositories(tempDir, entity.UpdateSafe)
+	results, err := PullAllRepositories(tempDir, PullSettings{}, entity.UpdateOptions{Strategy: entity.UpdateSafe})
 	assert.NoError(t, err)
 	assert.Empty(t, results.LocalChangesDetected)
 	assert.Empty(t, results.OtherErrors)
@@ -129,7 +131,7 @@ func Test_PullAllRepositories_NonGitRepositories(t *testing.T) {
 	err = os.Mkdir(nonGitDir2, 0755)
 	assert.NoError(t, err)
 
-	results, err := PullAllRepositories(tempDir, entity.UpdateSafe)
+	results, err := PullAllRepositories(tempDir, PullSettings{}, entity.UpdateOptions{Strategy: entity.UpdateSafe})
 	assert.NoError(t, err)
 	assert.Empty(t, results.LocalChangesDetected)
 	assert.Empty(t, results.OtherErrors)
@@ -142,7 +144,7 @@ func Test_PullAllRepositories_GitRepoWithoutRemote(t *testing.T) {
 	repoPath := filepath.Join(tempDir, "local-repo")
 	createTestRepoWithContent(t, repoPath)
 
-	results, err := PullAllRepositories(tempDir, entity.UpdateSafe)
+	results, err := PullAllRepositories(tempDir, PullSettings{}, entity.UpdateOptions{Strategy: entity.UpdateSafe})
 	assert.NoError(t, err)
 	assert.Empty(t, results.LocalChangesDetected)
 	assert.Empty(t, results.OtherErrors)
@@ -157,19 +159,19 @@ func Test_PullAllRepositories_WithRemoteRepository(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Test safe strategy
-	results, err := PullAllRepositories(tempDir, entity.UpdateSafe)
+	results, err := PullAllRepositories(tempDir, PullSettings{}, entity.UpdateOptions{Strategy: entity.UpdateSafe})
 	assert.NoError(t, err)
 	assert.Empty(t, results.LocalChangesDetected)
 	assert.Empty(t, results.OtherErrors)
 
 	// Test keep strategy
-	results, err = PullAllRepositories(tempDir, entity.UpdateKeep)
+	results, err = PullAllRepositories(tempDir, PullSettings{}, entity.UpdateOptions{Strategy: entity.UpdateKeep})
 	assert.NoError(t, err)
 	assert.Empty(t, results.LocalChangesDetected)
 	assert.Empty(t, results.OtherErrors)
 
 	// Test force strategy
-	results, err = PullAllRepositories(tempDir, entity.UpdateForce)
+	results, err = PullAllRepositories(tempDir, PullSettings{}, entity.UpdateOptions{Strategy: entity.UpdateForce})
 	assert.NoError(t, err)
 	assert.Empty(t, results.LocalChangesDetected)
 	assert.Empty(t, results.OtherErrors)
@@ -189,13 +191,13 @@ func Test_PullAllRepositories_WithLocalChanges(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Test safe strategy - should detect local changes
-	results, err := PullAllRepositories(tempDir, entity.UpdateSafe)
+	results, err := PullAllRepositories(tempDir, PullSettings{}, entity.UpdateOptions{Strategy: entity.UpdateSafe})
 	assert.NoError(t, err)
 	assert.Contains(t, results.LocalChangesDetected, "repo-with-changes")
 	assert.Empty(t, results.OtherErrors)
 
 	// Test keep strategy - should commit and pull
-	results, err = PullAllRepositories(tempDir, entity.UpdateKeep)
+	results, err = PullAllRepositories(tempDir, PullSettings{}, entity.UpdateOptions{Strategy: entity.UpdateKeep})
 	assert.NoError(t, err)
 	assert.Empty(t, results.LocalChangesDetected)
 	assert.Empty(t, results.OtherErrors)
@@ -224,7 +226,7 @@ func Test_PullAllRepositories_ForceStrategy(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Test force strategy - should discard local changes
-	results, err := PullAllRepositories(tempDir, entity.UpdateForce)
+	results, err := PullAllRepositories(tempDir, PullSettings{}, entity.UpdateOptions{Strategy: entity.UpdateForce})
 	assert.NoError(t, err)
 	assert.Empty(t, results.LocalChangesDetected)
 	assert.Empty(t, results.OtherErrors)
@@ -257,7 +259,7 @@ func Test_PullAllRepositories_MultipleRepositories(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Test safe strategy
-	results, err := PullAllRepositories(tempDir, entity.UpdateSafe)
+	results, err := PullAllRepositories(tempDir, PullSettings{}, entity.UpdateOptions{Strategy: entity.UpdateSafe})
 	assert.NoError(t, err)
 	assert.Contains(t, results.LocalChangesDetected, "repo2")
 	assert.NotContains(t, results.LocalChangesDetected, "repo1")
@@ -279,9 +281,45 @@ func Test_PullAllRepositories_InvalidStrategy(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Test with invalid strategy
-	results, err := PullAllRepositories(tempDir, "invalid-strategy")
+	results, err := PullAllRepositories(tempDir, PullSettings{}, entity.UpdateOptions{Strategy: "invalid-strategy"})
 	assert.NoError(t, err)
 	assert.Empty(t, results.LocalChangesDetected)
 	assert.Len(t, results.OtherErrors, 1)
 	assert.Contains(t, results.OtherErrors[0].Error(), "unknown strategy")
 }
+
+func Test_PullAllRepositories_Progress(t *testing.T) {
+	remotePath, remote := createTaggedRemote(t)
+	basePath := t.TempDir()
+	err := CloneGitRepository(remotePath, filepath.Join(basePath, "behind"))
+	assert.NoError(t, err)
+	before, err := GetHeadCommit(filepath.Join(basePath, "behind"))
+	assert.NoError(t, err)
+	after := commitFile(t, remote, remotePath, "newer")
+	err = CloneGitRepository(remotePath, filepath.Join(basePath, "current"))
+	assert.NoError(t, err)
+	createTestRepoWithContent(t, filepath.Join(basePath, "local-only"))
+
+	progress := map[string][]string{}
+	results, err := PullAllRepositories(basePath, PullSettings{}, entity.UpdateOptions{
+		Strategy: entity.UpdateSafe,
+		Jobs:     2,
+		OnProgress: func(p entity.UpdateProgress) {
+			progress[p.Package] = append(progress[p.Package], p.Status)
+		},
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, results.OtherErrors)
+	assert.Empty(t, results.LocalChangesDetected)
+	assert.Equal(t, []entity.PackageUpdate{
+		{Package: "behind", From: entity.ShortCommit(before), To: entity.ShortCommit(after.String())},
+	}, results.Updated)
+	assert.Equal(t, []string{"current"}, results.UpToDate)
+	assert.Equal(t, []string{"local-only"}, results.Skipped)
+	assert.Equal(t, map[string][]string{
+		"behind":     {"", entity.UpdateStatusUpdated},
+		"current":    {"", entity.UpdateStatusUpToDate},
+		"local-only": {"", entity.UpdateStatusSkipped},
+	}, progress)
+	assert.Equal(t, "newer", readDbFile(t, filepath.Join(basePath, "behind")))
+}
@@ -2,33 +2,34 @@ package gitt
 
 import (
 	"fmt"
-	"os/exec"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/transport"
 )
 
-func CommitAndPushChanges(repoPath string) error {
+// CommitAndPushChanges commits the local changes of a repository as identity, with message, then pushes it
+func CommitAndPushChanges(repoPath string, identity CommitIdentity, message string) error {
 	checkWorkingTreeClean, err := checkWorkingTreeClean(repoPath)
 	if err != nil {
 		return err
 	}
 
 	if !checkWorkingTreeClean {
-		err := addAndCommitAllChanges(repoPath, "Duh, auto-commit before push")
+		err := addAndCommitAllChanges(repoPath, identity, message)
 		if err != nil {
 			return err
 		}
 	}
 
-	// Use git CLI for pushing - it handles authentication automatically
-	err = pushUsingGitCLI(repoPath)
+	err = pushToRemote(repoPath)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func addAndCommitAllChanges(repoPath string, message string) error {
+func addAndCommitAllChanges(repoPath string, identity CommitIdentity, message string) error {
 	repo, err := git.PlainOpen(repoPath)
 	if err != nil {
 		return err
@@ -41,25 +42,40 @@ func addAndCommitAllChanges(repoPath string, message string) error {
 	if err != nil {
 		return err
 	}
-	_, err = w.Commit(message, &git.CommitOptions{})
+	_, err = w.Commit(message, identity.commitOptions())
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-// pushUsingGitCLI uses the git CLI to push changes, which handles authentication automatically
-func pushUsingGitCLI(repoPath string) error {
-	// Change to the repository directory
-	cmd := exec.Command("git", "push")
-	cmd.Dir = repoPath
-
-	// Run the command and capture output
-	output, err := cmd.CombinedOutput()
+// pushToRemote pushes the checked out branch to the origin remote, with the credentials of the remote
+func pushToRemote(repoPath string) error {
+	repo, err := git.PlainOpen(repoPath)
 	if err != nil {
-		// Include the git command output in the error for better debugging
-		return fmt.Errorf("git push failed: %v\nOutput: %s", err, string(output))
+		return err
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return err
+	}
+	if !head.Name().IsBranch() {
+		return fmt.Errorf("cannot push a detached HEAD, check a branch out first")
+	}
+	auth, err := authForRemote(repo)
+	if err != nil {
+		return err
+	}
+	refSpec := config.RefSpec(fmt.Sprintf("%s:%s", head.Name(), head.Name()))
+	err = auth.run(func(method transport.AuthMethod) error {
+		return repo.Push(&git.PushOptions{
+			RemoteName: git.DefaultRemoteName,
+			RefSpecs:   []config.RefSpec{refSpec},
+			Auth:       method,
+		})
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return fmt.Errorf("git push failed: %w", err)
 	}
-
 	return nil
 }
@@ -46,7 +46,7 @@ func Test_CommitAndPushChanges_CleanRepo(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Test push with clean repository - should not fail locally (though push to remote will fail in test)
-	err = CommitAndPushChanges(tempDir)
+	err = CommitAndPushChanges(tempDir, fallbackIdentity, "Duh, auto-commit before push")
 	// We expect this to fail because we don't have a real remote, but it should not fail due to working tree issues
 	if err != nil {
 		// Could be authentication error or author field error in CI
@@ -96,7 +96,7 @@ func Test_CommitAndPushChanges_DirtyRepo(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Test push with dirty repository - should auto-commit then try to push
-	commitPushErr := CommitAndPushChanges(tempDir)
+	commitPushErr := CommitAndPushChanges(tempDir, fallbackIdentity, "Duh, auto-commit before push")
 
 	// Check the final status
 	status, err := w.Status()
@@ -147,7 +147,7 @@ func Test_addAndCommitAllChanges(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Test committing all changes
-	err = addAndCommitAllChanges(tempDir, "Test commit")
+	err = addAndCommitAllChanges(tempDir, fallbackIdentity, "Test commit")
 	// This might fail if local git config is not set (user.name, user.email)
 	if err != nil {
 		// Check if it's a git config related error
@@ -207,7 +207,7 @@ func Test_addAndCommitAllChanges_NoChanges(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Test committing when there are no changes - should not fail
-	err = addAndCommitAllChanges(tempDir, "No changes commit")
+	err = addAndCommitAllChanges(tempDir, fallbackIdentity, "No changes commit")
 	// This might fail or succeed depending on git behavior, but should not crash
 	// The important thing is that the repository remains in a valid state
 	status, err := w.Status()
@@ -0,0 +1,250 @@
+package gitt
+
+import (
+	"bytes"
+	"duh/internal/domain/entity"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// FileMerger merges the changes made to a file on both sides of a rebase,
+// given its content in the common ancestor, on the remote and in the local commit being replayed.
+type FileMerger func(repoName string, base []byte, remote []byte, local []byte) ([]byte, error)
+
+// rebaseOnRemote replays the local commits of the checked out branch on top of its remote counterpart.
+// Uncommitted changes are committed first. A file changed on both sides is merged by the merger
+// registered for its path, the rebase being aborted when there is none, leaving the repository as it was,
+// with the uncommitted changes back in the working tree.
+func rebaseOnRemote(repo *git.Repository, repoName string, mergers map[string]FileMerger, committer committer) error {
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	status, err := worktree.Status()
+	if err != nil {
+		return err
+	}
+	if status.IsClean() {
+		return rebaseCommitted(repo, worktree, repoName, mergers, committer)
+	}
+
+	before, headErr := repo.Head()
+	if err := committer.commitLocalChanges(worktree, "Local changes before rebase"); err != nil {
+		return err
+	}
+	if err := rebaseCommitted(repo, worktree, repoName, mergers, committer); err != nil {
+		if headErr == nil {
+			// Undo the commit of the local changes, keeping them in the working tree
+			_ = worktree.Reset(&git.ResetOptions{Commit: before.Hash(), Mode: git.MixedReset})
+		}
+		return err
+	}
+	return nil
+}
+
+// rebaseCommitted rebases the checked out branch once every local change is committed
+func rebaseCommitted(repo *git.Repository, worktree *git.Worktree, repoName string, mergers map[string]FileMerger, committer committer) error {
+	err := fetchRemote(repo, &git.FetchOptions{})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return fmt.Errorf("failed to fetch: %w", err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return err
+	}
+	if !head.Name().IsBranch() {
+		return fmt.Errorf("cannot rebase a detached HEAD")
+	}
+	upstreamHash, err := remoteCounterpart(repo, head)
+	if err != nil {
+		return err
+	}
+	if upstreamHash == head.Hash() {
+		return nil
+	}
+	local, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return err
+	}
+	upstream, err := repo.CommitObject(upstreamHash)
+	if err != nil {
+		return err
+	}
+
+	if behind, err := local.IsAncestor(upstream); err != nil || behind {
+		if err != nil {
+			return err
+		}
+		// No local commit, fast-forward
+		return worktree.Reset(&git.ResetOptions{Commit: upstreamHash, Mode: git.HardReset})
+	}
+	if ahead, err := upstream.IsAncestor(local); err != nil || ahead {
+		return err
+	}
+
+	commits, err := localCommits(local, upstream)
+	if err != nil {
+		return err
+	}
+	if err := worktree.Reset(&git.ResetOptions{Commit: upstreamHash, Mode: git.HardReset}); err != nil {
+		return err
+	}
+	for _, commit := range commits {
+		if err := replayCommit(worktree, commit, repoName, mergers, committer.identity); err != nil {
+			// Every local change was committed, going back to the local commit loses nothing
+			_ = worktree.Reset(&git.ResetOptions{Commit: local.Hash, Mode: git.HardReset})
+			return err
+		}
+	}
+	return nil
+}
+
+// localCommits lists the commits of local missing from upstream, oldest first, following first parents
+func localCommits(local *object.Commit, upstream *object.Commit) ([]*object.Commit, error) {
+	bases, err := local.MergeBase(upstream)
+	if err != nil {
+		return nil, err
+	}
+	if len(bases) == 0 {
+		return nil, fmt.Errorf("local and remote branches have no common history")
+	}
+	commits := []*object.Commit{}
+	for commit := local; commit.Hash != bases[0].Hash; {
+		commits = append(commits, commit)
+		if commit.NumParents() == 0 {
+			break
+		}
+		if commit, err = commit.Parent(0); err != nil {
+			return nil, err
+		}
+	}
+	slices.Reverse(commits)
+	return commits, nil
+}
+
+// replayCommit applies the changes of a commit to the working tree, and commits them again
+// with the same author, identity being the committer
+func replayCommit(worktree *git.Worktree, commit *object.Commit, repoName string, mergers map[string]FileMerger, identity CommitIdentity) error {
+	parent, err := commit.Parent(0)
+	if err != nil {
+		return err
+	}
+	parentTree, err := parent.Tree()
+	if err != nil {
+		return err
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return err
+	}
+	changes, err := object.DiffTree(parentTree, tree)
+	if err != nil {
+		return err
+	}
+
+	for _, change := range changes {
+		path := change.To.Name
+		if path == "" {
+			path = change.From.Name
+		}
+		base, err := fileContent(parentTree, path)
+		if err != nil {
+			return err
+		}
+		local, err := fileContent(tree, path)
+		if err != nil {
+			return err
+		}
+		remote, err := worktreeContent(worktree, path)
+		if err != nil {
+			return err
+		}
+
+		result := local
+		switch {
+		case sameContent(remote, local):
+			continue
+		case sameContent(remote, base):
+			// Only changed by the local commit
+		case mergers[path] != nil && base != nil && remote != nil && local != nil:
+			if result, err = mergers[path](repoName, base, remote, local); err != nil {
+				return fmt.Errorf("failed to merge '%s': %w", path, err)
+			}
+		default:
+			return fmt.Errorf("conflicting changes to '%s' in local commit %s, resolve them manually", path, entity.ShortCommit(commit.Hash.String()))
+		}
+		if err := writeWorktreeFile(worktree, path, result); err != nil {
+			return err
+		}
+	}
+
+	status, err := worktree.Status()
+	if err != nil {
+		return err
+	}
+	if status.IsClean() {
+		// Every change of the commit is already on the remote
+		return nil
+	}
+	options := identity.commitOptions()
+	options.Author = &commit.Author
+	options.All = true
+	_, err = worktree.Commit(commit.Message, options)
+	return err
+}
+
+// fileContent returns the content of a file in a tree, nil when it does not exist
+func fileContent(tree *object.Tree, path string) ([]byte, error) {
+	file, err := tree.File(path)
+	if errors.Is(err, object.ErrFileNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	content, err := file.Contents()
+	return []byte(content), err
+}
+
+// sameContent tells if two file contents are equal, nil standing for a missing file
+func sameContent(a []byte, b []byte) bool {
+	return (a == nil) == (b == nil) && bytes.Equal(a, b)
+}
+
+// worktreeContent returns the content of a file in the working tree, nil when it does not exist
+func worktreeContent(worktree *git.Worktree, path string) ([]byte, error) {
+	content, err := os.ReadFile(filepath.Join(worktree.Filesystem.Root(), path))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if content == nil {
+		content = []byte{}
+	}
+	return content, nil
+}
+
+// writeWorktreeFile writes and stages a file of the working tree, removing it when content is nil
+func writeWorktreeFile(worktree *git.Worktree, path string, content []byte) error {
+	fullPath := filepath.Join(worktree.Filesystem.Root(), path)
+	if content == nil {
+		_, err := worktree.Remove(path)
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(fullPath, content, 0644); err != nil {
+		return err
+	}
+	_, err := worktree.Add(path)
+	return err
+}
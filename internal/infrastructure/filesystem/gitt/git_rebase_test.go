@@ -0,0 +1,129 @@
+package gitt
+
+import (
+	"duh/internal/domain/entity"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+)
+
+// commitFiles writes files relative to the repository and commits them
+func commitFiles(t *testing.T, repoPath string, files map[string]string, message string) plumbing.Hash {
+	repo, err := git.PlainOpen(repoPath)
+	assert.NoError(t, err)
+	worktree, err := repo.Worktree()
+	assert.NoError(t, err)
+	for name, content := range files {
+		path := filepath.Join(repoPath, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		_, err = worktree.Add(name)
+		assert.NoError(t, err)
+	}
+	hash, err := worktree.Commit(message, &git.CommitOptions{
+		Author: &object.Signature{Name: "Test User", Email: "test@example.com"},
+	})
+	assert.NoError(t, err)
+	return hash
+}
+
+// createDivergedClone returns a remote and a clone, each with a commit the other does not have
+func createDivergedClone(t *testing.T, remoteFiles map[string]string, localFiles map[string]string) (string, string) {
+	remotePath := filepath.Join(t.TempDir(), "remote")
+	_, err := git.PlainInit(remotePath, false)
+	assert.NoError(t, err)
+	commitFiles(t, remotePath, map[string]string{"db.toml": "base", "README.md": "base"}, "initial")
+
+	basePath := t.TempDir()
+	err = CloneGitRepository(remotePath, filepath.Join(basePath, "team"))
+	assert.NoError(t, err)
+	commitFiles(t, remotePath, remoteFiles, "remote change")
+	commitFiles(t, filepath.Join(basePath, "team"), localFiles, "local change")
+	return remotePath, basePath
+}
+
+func Test_PullAllRepositories_Rebase(t *testing.T) {
+	remotePath, basePath := createDivergedClone(t,
+		map[string]string{"db.toml": "remote"},
+		map[string]string{"db.toml": "local", "functions/deploy.sh": "deploy() { :; }"},
+	)
+	repoPath := filepath.Join(basePath, "team")
+	// Uncommitted changes are replayed too
+	assert.NoError(t, os.WriteFile(filepath.Join(repoPath, "notes.txt"), []byte("notes"), 0644))
+
+	mergers := map[string]FileMerger{
+		"db.toml": func(repoName string, base []byte, remote []byte, local []byte) ([]byte, error) {
+			assert.Equal(t, "team", repoName)
+			assert.Equal(t, []string{"base", "remote", "local"}, []string{string(base), string(remote), string(local)})
+			return []byte("merged"), nil
+		},
+	}
+	results, err := PullAllRepositories(basePath, PullSettings{Mergers: mergers}, entity.UpdateOptions{Strategy: entity.UpdateRebase})
+	assert.NoError(t, err)
+	assert.Empty(t, results.OtherErrors)
+	assert.Len(t, results.Updated, 1)
+
+	assert.Equal(t, "merged", readDbFile(t, repoPath))
+	for name, expected := range map[string]string{"functions/deploy.sh": "deploy() { :; }", "notes.txt": "notes"} {
+		content, err := os.ReadFile(filepath.Join(repoPath, name))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, string(content))
+	}
+	clean, err := checkWorkingTreeClean(repoPath)
+	assert.NoError(t, err)
+	assert.True(t, clean)
+
+	// Local commits now sit on top of the remote one
+	remoteHead, err := GetHeadCommit(remotePath)
+	assert.NoError(t, err)
+	repo, err := git.PlainOpen(repoPath)
+	assert.NoError(t, err)
+	head, err := repo.Head()
+	assert.NoError(t, err)
+	commit, err := repo.CommitObject(head.Hash())
+	assert.NoError(t, err)
+	parent, err := commit.Parent(0)
+	assert.NoError(t, err)
+	assert.Equal(t, "local change", parent.Message)
+	assert.Equal(t, "Test User", parent.Author.Name)
+	grandParent, err := parent.Parent(0)
+	assert.NoError(t, err)
+	assert.Equal(t, remoteHead, grandParent.Hash.String())
+}
+
+func Test_PullAllRepositories_RebaseConflict(t *testing.T) {
+	_, basePath := createDivergedClone(t,
+		map[string]string{"README.md": "remote"},
+		map[string]string{"README.md": "local"},
+	)
+	repoPath := filepath.Join(basePath, "team")
+	before, err := GetHeadCommit(repoPath)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(filepath.Join(repoPath, "notes.txt"), []byte("notes"), 0644))
+
+	results, err := PullAllRepositories(basePath, PullSettings{}, entity.UpdateOptions{Strategy: entity.UpdateRebase})
+	assert.NoError(t, err)
+	assert.Len(t, results.OtherErrors, 1)
+	assert.Contains(t, results.OtherErrors[0].Error(), "conflicting changes to 'README.md'")
+
+	// The rebase is aborted, leaving the local commit checked out
+	after, err := GetHeadCommit(repoPath)
+	assert.NoError(t, err)
+	assert.Equal(t, before, after)
+	content, err := os.ReadFile(filepath.Join(repoPath, "README.md"))
+	assert.NoError(t, err)
+	assert.Equal(t, "local", string(content))
+
+	// Uncommitted changes are not left behind in a commit
+	content, err = os.ReadFile(filepath.Join(repoPath, "notes.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "notes", string(content))
+	clean, err := checkWorkingTreeClean(repoPath)
+	assert.NoError(t, err)
+	assert.False(t, clean)
+}
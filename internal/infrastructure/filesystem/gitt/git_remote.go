@@ -0,0 +1,38 @@
+package gitt
+
+import (
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// GetOriginUrl returns the url of the origin remote of a repository, empty when it has none
+func GetOriginUrl(repoPath string) (string, error) {
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return "", err
+	}
+	remote, err := repo.Remote(git.DefaultRemoteName)
+	if err == git.ErrRemoteNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	urls := remote.Config().URLs
+	if len(urls) == 0 {
+		return "", nil
+	}
+	return urls[0], nil
+}
+
+// SameRepositoryUrl tells if two urls point to the same repository,
+// ignoring a trailing slash or .git suffix
+func SameRepositoryUrl(first, second string) bool {
+	return normalizeRepositoryUrl(first) == normalizeRepositoryUrl(second)
+}
+
+func normalizeRepositoryUrl(url string) string {
+	url = strings.TrimSuffix(strings.TrimSpace(url), "/")
+	return strings.TrimSuffix(url, ".git")
+}
@@ -0,0 +1,34 @@
+package gitt
+
+import (
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_GetOriginUrl(t *testing.T) {
+	repoPath := t.TempDir()
+	repo, err := git.PlainInit(repoPath, false)
+	assert.NoError(t, err)
+
+	url, err := GetOriginUrl(repoPath)
+	assert.NoError(t, err)
+	assert.Empty(t, url)
+
+	_, err = repo.CreateRemote(&config.RemoteConfig{
+		Name: git.DefaultRemoteName,
+		URLs: []string{"https://github.com/example/base-duh.git"},
+	})
+	assert.NoError(t, err)
+	url, err = GetOriginUrl(repoPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://github.com/example/base-duh.git", url)
+}
+
+func Test_SameRepositoryUrl(t *testing.T) {
+	assert.True(t, SameRepositoryUrl("https://github.com/example/base-duh.git", "https://github.com/example/base-duh/"))
+	assert.True(t, SameRepositoryUrl("git@github.com:example/base-duh.git", "git@github.com:example/base-duh"))
+	assert.False(t, SameRepositoryUrl("https://github.com/example/base-duh", "https://github.com/other/base-duh"))
+}
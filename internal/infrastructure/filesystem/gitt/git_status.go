@@ -0,0 +1,113 @@
+package gitt
+
+import (
+	"duh/internal/domain/entity"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+)
+
+const (
+	// Written by duh in the git directory after every fetch, clone and pull, holding the time of the fetch.
+	// go-git does not write FETCH_HEAD, which only tells about the fetches run by git itself.
+	lastFetchFile = "duh_last_fetch"
+	// Written by git after every fetch, its modification time tells when git last fetched the remote
+	fetchHeadFile = "FETCH_HEAD"
+)
+
+// GetRepositoriesStatus reads the status of several repositories, up to entity.DefaultUpdateJobs at the same time.
+// Statuses and errors are listed in the order of the repository paths.
+func GetRepositoriesStatus(repoPaths []string, fetch bool) ([]entity.PackageStatus, []error) {
+	statuses := make([]entity.PackageStatus, len(repoPaths))
+	errs := make([]error, len(repoPaths))
+	forEachConcurrently(len(repoPaths), entity.DefaultUpdateJobs, func(index int) {
+		statuses[index], errs[index] = GetRepositoryStatus(repoPaths[index], fetch)
+	})
+	return statuses, errs
+}
+
+// GetRepositoryStatus reads the branch, uncommitted changes and commits ahead and behind the remote of a repository.
+// With fetch set, the remote is fetched first: a failure is reported in FetchError,
+// the counts being then from the last fetch.
+func GetRepositoryStatus(repoPath string, fetch bool) (entity.PackageStatus, error) {
+	status := entity.PackageStatus{}
+	repo, err := git.PlainOpen(repoPath)
+	if err == git.ErrRepositoryNotExists {
+		return status, nil
+	}
+	if err != nil {
+		return status, err
+	}
+	status.Git = true
+	if status.Remote, err = GetOriginUrl(repoPath); err != nil {
+		return status, err
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return status, err
+	}
+	if status.Modified, err = modifiedFiles(worktree); err != nil {
+		return status, err
+	}
+
+	head, err := repo.Head()
+	if err == plumbing.ErrReferenceNotFound {
+		return status, nil
+	}
+	if err != nil {
+		return status, err
+	}
+	status.Commit = head.Hash().String()
+	if head.Name().IsBranch() {
+		status.Branch = head.Name().Short()
+	}
+	if status.LocalOnly() {
+		return status, nil
+	}
+
+	if fetch {
+		status.FetchError = fetchWithTags(repo)
+	}
+	status.LastFetched = lastFetched(repo)
+	if _, err := remoteCounterpart(repo, head); err != nil {
+		// Never fetched, or the branch does not exist on the remote yet
+		return status, nil
+	}
+	status.Ahead, status.Behind, err = commitsAheadBehind(repo, head)
+	return status, err
+}
+
+// lastFetched returns when the remote of a repository was last fetched, by duh or by git, zero when it never was
+func lastFetched(repo *git.Repository) time.Time {
+	storage, ok := repo.Storer.(*filesystem.Storage)
+	if !ok {
+		return time.Time{}
+	}
+	fetched := time.Time{}
+	if content, err := os.ReadFile(filepath.Join(storage.Filesystem().Root(), lastFetchFile)); err == nil {
+		if parsed, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(string(content))); err == nil {
+			fetched = parsed
+		}
+	}
+	if info, err := os.Stat(filepath.Join(storage.Filesystem().Root(), fetchHeadFile)); err == nil && info.ModTime().After(fetched) {
+		fetched = info.ModTime()
+	}
+	return fetched
+}
+
+// recordFetch marks the remote of a repository as fetched now, in a file of duh next to the git state.
+// It is only informative, so failing to write it is ignored.
+func recordFetch(repo *git.Repository) {
+	storage, ok := repo.Storer.(*filesystem.Storage)
+	if !ok {
+		return
+	}
+	path := filepath.Join(storage.Filesystem().Root(), lastFetchFile)
+	_ = os.WriteFile(path, []byte(time.Now().UTC().Format(time.RFC3339Nano)+"\n"), 0644)
+}
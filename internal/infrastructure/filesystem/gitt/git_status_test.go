@@ -0,0 +1,86 @@
+package gitt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_GetRepositoryStatus(t *testing.T) {
+	remotePath, basePath := createDivergedClone(t,
+		map[string]string{"db.toml": "remote"},
+		map[string]string{"README.md": "local"},
+	)
+	repoPath := filepath.Join(basePath, "team")
+	assert.NoError(t, os.WriteFile(filepath.Join(repoPath, "db.toml"), []byte("edited"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(repoPath, "notes.txt"), []byte("notes"), 0644))
+
+	// Without fetching, the remote commit is not known yet
+	status, err := GetRepositoryStatus(repoPath, false)
+	assert.NoError(t, err)
+	assert.True(t, status.Git)
+	assert.Equal(t, remotePath, status.Remote)
+	assert.Equal(t, "master", status.Branch)
+	assert.Equal(t, 1, status.Ahead)
+	assert.Equal(t, 0, status.Behind)
+	assert.Equal(t, []string{"db.toml", "notes.txt"}, status.Modified)
+	assert.False(t, status.LastFetched.IsZero(), "the clone counts as a fetch")
+	fetchedAtClone := status.LastFetched
+
+	status, err = GetRepositoryStatus(repoPath, true)
+	assert.NoError(t, err)
+	assert.NoError(t, status.FetchError)
+	assert.Equal(t, 1, status.Ahead)
+	assert.Equal(t, 1, status.Behind)
+	assert.False(t, status.LastFetched.Before(fetchedAtClone))
+	// The fetch time is kept by duh, FETCH_HEAD is left to git
+	assert.NoFileExists(t, filepath.Join(repoPath, git.GitDirName, fetchHeadFile))
+	assert.FileExists(t, filepath.Join(repoPath, git.GitDirName, lastFetchFile))
+}
+
+func Test_GetRepositoriesStatus(t *testing.T) {
+	_, basePath := createDivergedClone(t,
+		map[string]string{"db.toml": "remote"},
+		map[string]string{"README.md": "local"},
+	)
+	localPath := t.TempDir()
+	_, err := git.PlainInit(localPath, false)
+	assert.NoError(t, err)
+	// A repository with a broken config
+	brokenPath := t.TempDir()
+	_, err = git.PlainInit(brokenPath, false)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(filepath.Join(brokenPath, git.GitDirName, "config"), []byte("[remote \"origin"), 0644))
+
+	statuses, errs := GetRepositoriesStatus([]string{filepath.Join(basePath, "team"), localPath, brokenPath}, true)
+	assert.Len(t, statuses, 3)
+	// Listed in the order of the paths
+	assert.NoError(t, errs[0])
+	assert.Equal(t, 1, statuses[0].Behind)
+	assert.NoError(t, errs[1])
+	assert.True(t, statuses[1].LocalOnly())
+	// A broken repository only fails its own status
+	assert.Error(t, errs[2])
+}
+
+func Test_GetRepositoryStatus_LocalOnly(t *testing.T) {
+	repoPath := t.TempDir()
+	_, err := git.PlainInit(repoPath, false)
+	assert.NoError(t, err)
+	commitFiles(t, repoPath, map[string]string{"db.toml": "local"}, "initial")
+
+	status, err := GetRepositoryStatus(repoPath, true)
+	assert.NoError(t, err)
+	assert.True(t, status.Git)
+	assert.True(t, status.LocalOnly())
+	assert.Empty(t, status.Modified)
+	assert.True(t, status.LastFetched.IsZero())
+
+	// A directory which is not a git repository
+	status, err = GetRepositoryStatus(t.TempDir(), true)
+	assert.NoError(t, err)
+	assert.False(t, status.Git)
+}
@@ -1,9 +1,39 @@
 package tomll
 
 type RepositoryToml struct {
-	Aliases  map[string]string `toml:"aliases"`
-	Exports  map[string]string `toml:"exports"`
-	Metadata MetadataMap       `toml:"metadata"`
+	// Aliases and exports are either a plain string value, or a table using the keys below
+	Aliases  map[string]interface{} `toml:"aliases"`
+	Exports  map[string]interface{} `toml:"exports"`
+	Metadata MetadataMap            `toml:"metadata"`
+	// Left out of files of packages without a manifest
+	Manifest *ManifestToml `toml:"manifest,omitempty"`
+	// Left out of files of packages using the default settings
+	Functions *FunctionsToml `toml:"functions,omitempty"`
+}
+
+type FunctionsToml struct {
+	// Load the scripts of the package on the first call of one of their functions
+	Lazy bool `toml:"lazy"`
+}
+
+// Keys of an alias or export defined as a table, e.g. PATH = { prepend = ["$HOME/bin"], os = "linux" }
+const (
+	valueKey              = "value"
+	exportLiteralKey      = "literal"
+	exportPrependKey      = "prepend"
+	exportAppendKey       = "append"
+	conditionOSKey        = "os"
+	conditionHostnameKey  = "hostname"
+	conditionShellKey     = "shell"
+	conditionIfCommandKey = "if_command"
+)
+
+type ManifestToml struct {
+	Description   string   `toml:"description,omitempty"`
+	Version       string   `toml:"version,omitempty"`
+	Authors       []string `toml:"authors,omitempty"`
+	MinDuhVersion string   `toml:"min_duh_version,omitempty"`
+	Dependencies  []string `toml:"dependencies,omitempty"`
 }
 
 type MetadataMap struct {
@@ -16,7 +46,44 @@ type UserPreferenceTomlOld struct {
 }
 
 type UserPreferenceToml struct {
-	Repositories RepositoriesPreference `toml:"repositories"`
+	Repositories RepositoriesPreference   `toml:"repositories"`
+	Profiles     map[string]ProfileToml   `toml:"profiles,omitempty"`
+	HostProfiles map[string]string        `toml:"host_profiles,omitempty"`
+	Pins         map[string]string        `toml:"pins,omitempty"`
+	Identities   map[string]IdentityToml  `toml:"identities,omitempty"`
+	Functions    *FunctionsPreferenceToml `toml:"functions,omitempty"`
+}
+
+// FunctionsPreferenceToml is the [functions] section of user_preferences.toml
+type FunctionsPreferenceToml struct {
+	// allow, warn, strip or refuse
+	TopLevelCode string `toml:"top_level_code,omitempty"`
+}
+
+type IdentityToml struct {
+	Name          string `toml:"name,omitempty"`
+	Email         string `toml:"email,omitempty"`
+	SigningKey    string `toml:"signing_key,omitempty"`
+	SigningFormat string `toml:"signing_format,omitempty"`
+	Sign          *bool  `toml:"sign,omitempty"`
+}
+
+type ProfileToml struct {
+	ActivatedRepositories []string `toml:"activated_repos"`
+	DefaultRepositoryName string   `toml:"default_repo_name"`
+	Hostnames             []string `toml:"hostnames,omitempty"`
+}
+
+type LockToml struct {
+	Packages []LockedPackageToml `toml:"packages"`
+}
+
+type LockedPackageToml struct {
+	Name    string `toml:"name"`
+	Url     string `toml:"url"`
+	Commit  string `toml:"commit"`
+	Enabled bool   `toml:"enabled"`
+	Pin     string `toml:"pin,omitempty"`
 }
 
 type RepositoriesPreference struct {
@@ -2,39 +2,321 @@ package tomll
 
 import (
 	"duh/internal/infrastructure/filesystem/common"
+	"fmt"
 	"strings"
 )
 
 // toRepositoryToml converts a common.RepositoryDto to RepositoryToml
 func toRepositoryToml(dto *common.RepositoryDto) RepositoryToml {
+	var aliases map[string]interface{}
+	if dto.Aliases != nil {
+		aliases = map[string]interface{}{}
+		for name, value := range dto.Aliases {
+			aliases[name] = withCondition(value, dto.AliasConditions[name])
+		}
+	}
+	var exports map[string]interface{}
+	if dto.Exports != nil {
+		exports = map[string]interface{}{}
+		for name, export := range dto.Exports {
+			exports[name] = withCondition(toExportToml(export), dto.ExportConditions[name])
+		}
+	}
+	var manifest *ManifestToml
+	if !isEmptyManifest(dto.Manifest) {
+		converted := ManifestToml(dto.Manifest)
+		manifest = &converted
+	}
+	var functions *FunctionsToml
+	if dto.LazyFunctions {
+		functions = &FunctionsToml{Lazy: true}
+	}
 	return RepositoryToml{
-		Aliases:  dto.Aliases,
-		Exports:  dto.Exports,
-		Metadata: MetadataMap(dto.Metadata),
+		Aliases:   aliases,
+		Exports:   exports,
+		Metadata:  MetadataMap(dto.Metadata),
+		Manifest:  manifest,
+		Functions: functions,
 	}
 }
 
+func isEmptyManifest(manifest common.ManifestDto) bool {
+	return manifest.Description == "" && manifest.Version == "" && len(manifest.Authors) == 0 &&
+		manifest.MinDuhVersion == "" && len(manifest.Dependencies) == 0
+}
+
 // toRepositoryDto converts a RepositoryToml to common.RepositoryDto
-func toRepositoryDto(toml *RepositoryToml) *common.RepositoryDto {
-	return &common.RepositoryDto{
-		Aliases:  toml.Aliases,
-		Exports:  toml.Exports,
-		Metadata: common.MetadataDto(toml.Metadata),
+func toRepositoryDto(toml *RepositoryToml) (*common.RepositoryDto, error) {
+	dto := &common.RepositoryDto{
+		Metadata:         common.MetadataDto(toml.Metadata),
+		AliasConditions:  map[string]common.ConditionDto{},
+		ExportConditions: map[string]common.ConditionDto{},
+	}
+	if toml.Manifest != nil {
+		dto.Manifest = common.ManifestDto(*toml.Manifest)
+	}
+	if toml.Functions != nil {
+		dto.LazyFunctions = toml.Functions.Lazy
+	}
+	if toml.Aliases != nil {
+		dto.Aliases = common.AliasesMap{}
+		for name, value := range toml.Aliases {
+			alias, condition, err := toAliasDto(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid alias '%s': %w", name, err)
+			}
+			dto.Aliases[name] = alias
+			if condition != (common.ConditionDto{}) {
+				dto.AliasConditions[name] = condition
+			}
+		}
+	}
+	if toml.Exports != nil {
+		dto.Exports = common.ExportsMap{}
+		for name, value := range toml.Exports {
+			export, condition, err := toExportDto(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid export '%s': %w", name, err)
+			}
+			dto.Exports[name] = export
+			if condition != (common.ConditionDto{}) {
+				dto.ExportConditions[name] = condition
+			}
+		}
 	}
+	return dto, nil
+}
+
+// toExportToml keeps plain expandable values as strings, so existing files stay unchanged
+func toExportToml(export common.ExportDto) interface{} {
+	if !export.Literal && len(export.Prepend) == 0 && len(export.Append) == 0 {
+		return export.Value
+	}
+	table := map[string]interface{}{}
+	if export.Value != "" {
+		table[valueKey] = export.Value
+	}
+	if export.Literal {
+		table[exportLiteralKey] = true
+	}
+	if len(export.Prepend) > 0 {
+		table[exportPrependKey] = export.Prepend
+	}
+	if len(export.Append) > 0 {
+		table[exportAppendKey] = export.Append
+	}
+	return table
+}
+
+// withCondition turns an entry into a table holding its condition, when it has one
+func withCondition(entry interface{}, condition common.ConditionDto) interface{} {
+	if condition == (common.ConditionDto{}) {
+		return entry
+	}
+	table, ok := entry.(map[string]interface{})
+	if !ok {
+		table = map[string]interface{}{valueKey: entry}
+	}
+	for key, value := range map[string]string{
+		conditionOSKey:        condition.OS,
+		conditionHostnameKey:  condition.Hostname,
+		conditionShellKey:     condition.Shell,
+		conditionIfCommandKey: condition.IfCommand,
+	} {
+		if value != "" {
+			table[key] = value
+		}
+	}
+	return table
+}
+
+func toAliasDto(value interface{}) (string, common.ConditionDto, error) {
+	condition := common.ConditionDto{}
+	switch typed := value.(type) {
+	case string:
+		return typed, condition, nil
+	case map[string]interface{}:
+		alias := ""
+		for key, field := range typed {
+			var ok bool
+			switch key {
+			case valueKey:
+				alias, ok = field.(string)
+			default:
+				var known bool
+				known, ok = readConditionKey(&condition, key, field)
+				if !known {
+					return alias, condition, fmt.Errorf("unknown key '%s'", key)
+				}
+			}
+			if !ok {
+				return alias, condition, fmt.Errorf("unexpected type for key '%s'", key)
+			}
+		}
+		return alias, condition, nil
+	default:
+		return "", condition, fmt.Errorf("expected a string or a table, got %T", value)
+	}
+}
+
+func toExportDto(value interface{}) (common.ExportDto, common.ConditionDto, error) {
+	condition := common.ConditionDto{}
+	switch typed := value.(type) {
+	case string:
+		return common.ExportDto{Value: typed}, condition, nil
+	case map[string]interface{}:
+		export := common.ExportDto{}
+		for key, field := range typed {
+			var ok bool
+			switch key {
+			case valueKey:
+				export.Value, ok = field.(string)
+			case exportLiteralKey:
+				export.Literal, ok = field.(bool)
+			case exportPrependKey:
+				export.Prepend, ok = toStringSlice(field)
+			case exportAppendKey:
+				export.Append, ok = toStringSlice(field)
+			default:
+				var known bool
+				known, ok = readConditionKey(&condition, key, field)
+				if !known {
+					return export, condition, fmt.Errorf("unknown key '%s'", key)
+				}
+			}
+			if !ok {
+				return export, condition, fmt.Errorf("unexpected type for key '%s'", key)
+			}
+		}
+		return export, condition, nil
+	default:
+		return common.ExportDto{}, condition, fmt.Errorf("expected a string or a table, got %T", value)
+	}
+}
+
+// readConditionKey fills the condition field matching key.
+// It returns whether the key is a condition key, and whether its value has the expected type.
+func readConditionKey(condition *common.ConditionDto, key string, field interface{}) (bool, bool) {
+	var target *string
+	switch key {
+	case conditionOSKey:
+		target = &condition.OS
+	case conditionHostnameKey:
+		target = &condition.Hostname
+	case conditionShellKey:
+		target = &condition.Shell
+	case conditionIfCommandKey:
+		target = &condition.IfCommand
+	default:
+		return false, false
+	}
+	value, ok := field.(string)
+	*target = value
+	return true, ok
+}
+
+func toStringSlice(value interface{}) ([]string, bool) {
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	strs := []string{}
+	for _, item := range items {
+		str, ok := item.(string)
+		if !ok {
+			return nil, false
+		}
+		strs = append(strs, str)
+	}
+	return strs, true
 }
 
 // toUserPreferenceToml converts a common.UserPreferenceDto to UserPreferenceToml
 func toUserPreferenceToml(dto *common.UserPreferenceDto) UserPreferenceToml {
+	var profiles map[string]ProfileToml
+	if len(dto.Profiles) > 0 {
+		profiles = map[string]ProfileToml{}
+		for name, profile := range dto.Profiles {
+			profiles[name] = ProfileToml{
+				ActivatedRepositories: profile.Repositories.ActivatedRepositories,
+				DefaultRepositoryName: profile.Repositories.DefaultRepositoryName,
+				Hostnames:             profile.Hostnames,
+			}
+		}
+	}
+	var identities map[string]IdentityToml
+	if len(dto.Identities) > 0 {
+		identities = map[string]IdentityToml{}
+		for name, identity := range dto.Identities {
+			identities[name] = IdentityToml(identity)
+		}
+	}
+	var functions *FunctionsPreferenceToml
+	if dto.TopLevelCode != "" {
+		functions = &FunctionsPreferenceToml{TopLevelCode: dto.TopLevelCode}
+	}
 	return UserPreferenceToml{
 		Repositories: RepositoriesPreference(dto.Repositories),
+		Profiles:     profiles,
+		HostProfiles: dto.HostProfiles,
+		Pins:         dto.Pins,
+		Identities:   identities,
+		Functions:    functions,
 	}
 }
 
 // toUserPreferenceDto converts a UserPreferenceToml to common.UserPreferenceDto
 func toUserPreferenceDto(toml *UserPreferenceToml) *common.UserPreferenceDto {
+	profiles := map[string]*common.ProfileDto{}
+	for name, profile := range toml.Profiles {
+		profiles[name] = &common.ProfileDto{
+			Repositories: common.RepositoriesPreferenceDto{
+				ActivatedRepositories: profile.ActivatedRepositories,
+				DefaultRepositoryName: profile.DefaultRepositoryName,
+			},
+			Hostnames: profile.Hostnames,
+		}
+	}
+	hostProfiles := toml.HostProfiles
+	if hostProfiles == nil {
+		hostProfiles = map[string]string{}
+	}
+	pins := toml.Pins
+	if pins == nil {
+		pins = map[string]string{}
+	}
+	identities := map[string]common.IdentityDto{}
+	for name, identity := range toml.Identities {
+		identities[name] = common.IdentityDto(identity)
+	}
+	topLevelCode := ""
+	if toml.Functions != nil {
+		topLevelCode = toml.Functions.TopLevelCode
+	}
 	return &common.UserPreferenceDto{
 		Repositories: common.RepositoriesPreferenceDto(toml.Repositories),
+		Profiles:     profiles,
+		HostProfiles: hostProfiles,
+		Pins:         pins,
+		Identities:   identities,
+		TopLevelCode: topLevelCode,
+	}
+}
+
+func toLockToml(dto *common.LockDto) LockToml {
+	packages := []LockedPackageToml{}
+	for _, pkg := range dto.Packages {
+		packages = append(packages, LockedPackageToml(pkg))
+	}
+	return LockToml{Packages: packages}
+}
+
+func toLockDto(toml *LockToml) *common.LockDto {
+	packages := []common.LockedPackageDto{}
+	for _, pkg := range toml.Packages {
+		packages = append(packages, common.LockedPackageDto(pkg))
 	}
+	return &common.LockDto{Packages: packages}
 }
 
 // migrateOldVersionUserPref migrates an old version UserPreferenceToml to the new version
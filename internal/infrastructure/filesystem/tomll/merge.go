@@ -0,0 +1,120 @@
+package tomll
+
+import (
+	"duh/internal/infrastructure/filesystem/common"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+)
+
+// MergeThreeWay merges the local and remote versions of a TOML file, changed from a common base, key by key.
+// A key changed on one side only keeps this change, tables are merged recursively,
+// and only keys changed differently on both sides are given to resolve.
+//
+// The result is the local file with only the keys the merge changed edited in place,
+// so the comments and the layout of a hand-edited file are kept.
+func MergeThreeWay(base []byte, remote []byte, local []byte, resolve common.KeyResolver) ([]byte, error) {
+	trees := []*toml.Tree{}
+	for _, content := range [][]byte{base, remote, local} {
+		tree, err := toml.LoadBytes(content)
+		if err != nil {
+			return nil, err
+		}
+		trees = append(trees, tree)
+	}
+
+	localMap := trees[2].ToMap()
+	merged, err := mergeTables("", trees[0].ToMap(), trees[1].ToMap(), localMap, resolve)
+	if err != nil {
+		return nil, err
+	}
+	document := newTomlDocument(local, trees[2])
+	if err := document.update(nil, localMap, merged); err != nil {
+		// The local file uses a layout the document can't edit, like dotted keys, write the merge from scratch
+		tree, err := toml.TreeFromMap(merged)
+		if err != nil {
+			return nil, err
+		}
+		return tree.Marshal()
+	}
+	return document.bytes(), nil
+}
+
+func mergeTables(prefix string, base, remote, local map[string]interface{}, resolve common.KeyResolver) (map[string]interface{}, error) {
+	keys := slices.Collect(maps.Keys(base))
+	keys = append(keys, slices.Collect(maps.Keys(remote))...)
+	keys = append(keys, slices.Collect(maps.Keys(local))...)
+	slices.Sort(keys)
+	keys = slices.Compact(keys)
+
+	merged := map[string]interface{}{}
+	for _, key := range keys {
+		baseValue, inBase := base[key]
+		remoteValue, inRemote := remote[key]
+		localValue, inLocal := local[key]
+
+		value, found := remoteValue, inRemote
+		switch {
+		case sameValue(remoteValue, inRemote, localValue, inLocal):
+		case sameValue(localValue, inLocal, baseValue, inBase):
+		case sameValue(remoteValue, inRemote, baseValue, inBase):
+			value, found = localValue, inLocal
+		default:
+			remoteTable, remoteIsTable := remoteValue.(map[string]interface{})
+			localTable, localIsTable := localValue.(map[string]interface{})
+			baseTable, baseIsTable := baseValue.(map[string]interface{})
+			if remoteIsTable && localIsTable && (baseIsTable || !inBase) {
+				table, err := mergeTables(prefix+key+".", baseTable, remoteTable, localTable, resolve)
+				if err != nil {
+					return nil, err
+				}
+				value = table
+				break
+			}
+			keepLocal, err := resolve(prefix+key, renderValue(localValue, inLocal), renderValue(remoteValue, inRemote))
+			if err != nil {
+				return nil, err
+			}
+			if keepLocal {
+				value, found = localValue, inLocal
+			}
+		}
+		if found {
+			merged[key] = value
+		}
+	}
+	return merged, nil
+}
+
+func sameValue(a interface{}, aFound bool, b interface{}, bFound bool) bool {
+	return aFound == bFound && reflect.DeepEqual(a, b)
+}
+
+// renderValue writes a value as it appears in a TOML file, empty when it is missing
+func renderValue(value interface{}, found bool) string {
+	if !found {
+		return ""
+	}
+	switch typed := value.(type) {
+	case string:
+		return fmt.Sprintf("%q", typed)
+	case []interface{}:
+		items := []string{}
+		for _, item := range typed {
+			items = append(items, renderValue(item, true))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case map[string]interface{}:
+		entries := []string{}
+		for _, key := range slices.Sorted(maps.Keys(typed)) {
+			entries = append(entries, fmt.Sprintf("%s = %s", key, renderValue(typed[key], true)))
+		}
+		return "{ " + strings.Join(entries, ", ") + " }"
+	default:
+		return fmt.Sprint(typed)
+	}
+}
@@ -0,0 +1,163 @@
+package tomll
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pelletier/go-toml"
+	"github.com/stretchr/testify/assert"
+)
+
+const mergeBase = `
+[aliases]
+  gs = "git status"
+  ll = "ls -l"
+
+[exports]
+  EDITOR = "vim"
+`
+
+func Test_MergeThreeWay_DifferentKeys(t *testing.T) {
+	remote := mergeBase + "\n[manifest]\n  version = \"1.1.0\"\n"
+	local := `
+[aliases]
+  gs = "git status"
+  gd = "git diff"
+
+[exports]
+  EDITOR = "vim"
+`
+	noConflict := func(key string, local string, remote string) (bool, error) {
+		t.Errorf("unexpected conflict on %s", key)
+		return false, nil
+	}
+
+	merged, err := MergeThreeWay([]byte(mergeBase), []byte(remote), []byte(local), noConflict)
+	assert.NoError(t, err)
+	tree, err := toml.LoadBytes(merged)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"aliases":  map[string]interface{}{"gs": "git status", "gd": "git diff"},
+		"exports":  map[string]interface{}{"EDITOR": "vim"},
+		"manifest": map[string]interface{}{"version": "1.1.0"},
+	}, tree.ToMap())
+}
+
+func Test_MergeThreeWay_SameKey(t *testing.T) {
+	remote := `
+[aliases]
+  gs = "git status -sb"
+  ll = "ls -l"
+
+[exports]
+  EDITOR = "nano"
+`
+	local := `
+[aliases]
+  gs = "git status --short"
+
+[exports]
+  EDITOR = "vim"
+  PAGER = "less"
+`
+	conflicts := map[string][2]string{}
+	keepLocal := func(key string, local string, remote string) (bool, error) {
+		conflicts[key] = [2]string{local, remote}
+		return true, nil
+	}
+
+	merged, err := MergeThreeWay([]byte(mergeBase), []byte(remote), []byte(local), keepLocal)
+	assert.NoError(t, err)
+	// Only gs was changed on both sides, the remote change of EDITOR and the local removal of ll are kept
+	assert.Equal(t, map[string][2]string{"aliases.gs": {`"git status --short"`, `"git status -sb"`}}, conflicts)
+	tree, err := toml.LoadBytes(merged)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"aliases": map[string]interface{}{"gs": "git status --short"},
+		"exports": map[string]interface{}{"EDITOR": "nano", "PAGER": "less"},
+	}, tree.ToMap())
+
+	_, err = MergeThreeWay([]byte(mergeBase), []byte(remote), []byte(local), func(string, string, string) (bool, error) {
+		return false, errors.New("no choice")
+	})
+	assert.EqualError(t, err, "no choice")
+}
+
+func Test_MergeThreeWay_KeepsLocalLayout(t *testing.T) {
+	base := `[aliases]
+ll = "ls -l"
+gs = "git status"
+
+[exports]
+EDITOR = "vim"
+`
+	remote := `[aliases]
+ll = "ls -la"
+gs = "git status"
+gd = "git diff"
+
+[exports]
+EDITOR = "vim"
+
+[manifest]
+version = "1.1.0"
+`
+	local := `# My shortcuts
+[aliases]
+  # Listing
+  ll = "ls -l"    # keep it short
+  gs = "git status"
+  my-paths = [
+    "a",
+    "b",
+  ]
+
+[exports]
+  EDITOR = "vim"
+  PATH = { prepend = ["$HOME/bin"] }
+`
+	noConflict := func(key string, local string, remote string) (bool, error) {
+		t.Errorf("unexpected conflict on %s", key)
+		return false, nil
+	}
+
+	// Nothing changed on the remote, the file is left untouched
+	merged, err := MergeThreeWay([]byte(base), []byte(base), []byte(local), noConflict)
+	assert.NoError(t, err)
+	assert.Equal(t, local, string(merged))
+
+	// Only the keys changed on the remote are edited, comments and indentation are kept
+	merged, err = MergeThreeWay([]byte(base), []byte(remote), []byte(local), noConflict)
+	assert.NoError(t, err)
+	assert.Equal(t, `# My shortcuts
+[aliases]
+  # Listing
+  ll = "ls -la"
+  gs = "git status"
+  my-paths = [
+    "a",
+    "b",
+  ]
+  gd = "git diff"
+
+[exports]
+  EDITOR = "vim"
+  PATH = { prepend = ["$HOME/bin"] }
+
+[manifest]
+  version = "1.1.0"
+`, string(merged))
+
+	// Keys removed on the remote are removed, values spanning several lines included
+	merged, err = MergeThreeWay([]byte(local), []byte(base), []byte(local), noConflict)
+	assert.NoError(t, err)
+	assert.Equal(t, `# My shortcuts
+[aliases]
+  # Listing
+  ll = "ls -l"    # keep it short
+  gs = "git status"
+
+[exports]
+  EDITOR = "vim"
+`, string(merged))
+}
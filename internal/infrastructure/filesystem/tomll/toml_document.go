@@ -0,0 +1,349 @@
+package tomll
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+)
+
+// Keys written without quotes in a TOML file
+var bareKeyRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// tomlDocument edits the lines of a TOML file in place, keeping its comments and layout
+type tomlDocument struct {
+	lines []string
+	tree  *toml.Tree
+	// Index of the lines holding a table header, like [aliases]
+	headers []int
+	edits   []lineEdit
+}
+
+// lineEdit replaces lines[start:end] with replacement, an insertion when start equals end
+type lineEdit struct {
+	start       int
+	end         int
+	replacement []string
+}
+
+// keySpan is a key of a table, with the first and last lines of its value
+type keySpan struct {
+	key   string
+	first int
+	last  int
+}
+
+func newTomlDocument(content []byte, tree *toml.Tree) *tomlDocument {
+	document := &tomlDocument{
+		lines: strings.Split(strings.TrimSuffix(string(content), "\n"), "\n"),
+		tree:  tree,
+	}
+	for index := 0; index < len(document.lines); index++ {
+		trimmed := strings.TrimSpace(document.lines[index])
+		if strings.HasPrefix(trimmed, "[") {
+			document.headers = append(document.headers, index)
+			continue
+		}
+		if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			index = valueEnd(document.lines, index)
+		}
+	}
+	return document
+}
+
+// update records the edits turning the local table at path into the merged one
+func (d *tomlDocument) update(path []string, local map[string]interface{}, merged map[string]interface{}) error {
+	keys := slices.Collect(maps.Keys(local))
+	keys = append(keys, slices.Collect(maps.Keys(merged))...)
+	slices.Sort(keys)
+	keys = slices.Compact(keys)
+
+	for _, key := range keys {
+		localValue, inLocal := local[key]
+		mergedValue, inMerged := merged[key]
+		if sameValue(localValue, inLocal, mergedValue, inMerged) {
+			continue
+		}
+		keyPath := append(slices.Clone(path), key)
+		localTable, localIsTable := localValue.(map[string]interface{})
+		mergedTable, mergedIsTable := mergedValue.(map[string]interface{})
+		header := inLocal && localIsTable && d.isHeaderTable(keyPath)
+
+		var err error
+		switch {
+		case header && mergedIsTable:
+			err = d.update(keyPath, localTable, mergedTable)
+		case header:
+			if err = d.deleteTable(keyPath, localTable); err == nil && inMerged {
+				err = d.setKey(path, key, mergedValue)
+			}
+		case inLocal && !inMerged:
+			err = d.deleteKey(path, key)
+		case !inLocal && len(path) == 0 && mergedIsTable:
+			err = d.appendTable(key, mergedTable)
+		default:
+			err = d.setKey(path, key, mergedValue)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isHeaderTable tells if the table at path is declared with a [header], rather than inline
+func (d *tomlDocument) isHeaderTable(path []string) bool {
+	line := d.tree.GetPositionPath(path).Line - 1
+	return slices.Contains(d.headers, line)
+}
+
+// tableRange returns the lines of a header table, after its header, up to the next header
+func (d *tomlDocument) tableRange(path []string) (int, int) {
+	start := 0
+	if len(path) > 0 {
+		start = d.tree.GetPositionPath(path).Line
+	}
+	for _, header := range d.headers {
+		if header >= start {
+			return start, header
+		}
+	}
+	return start, len(d.lines)
+}
+
+// keySpans lists the keys defined in the lines of a table
+func (d *tomlDocument) keySpans(path []string) []keySpan {
+	start, end := d.tableRange(path)
+	spans := []keySpan{}
+	for index := start; index < end; index++ {
+		trimmed := strings.TrimSpace(d.lines[index])
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		last := valueEnd(d.lines, index)
+		spans = append(spans, keySpan{key: lineKey(trimmed), first: index, last: last})
+		index = last
+	}
+	return spans
+}
+
+func (d *tomlDocument) findKey(path []string, key string) (keySpan, error) {
+	for _, span := range d.keySpans(path) {
+		if span.key == key {
+			return span, nil
+		}
+	}
+	return keySpan{}, fmt.Errorf("key '%s' not found in table '%s'", key, strings.Join(path, "."))
+}
+
+// setKey replaces the value of a key, or adds the key after the last one of its table
+func (d *tomlDocument) setKey(path []string, key string, value interface{}) error {
+	rendered, err := inlineValue(value)
+	if err != nil {
+		return err
+	}
+	if span, err := d.findKey(path, key); err == nil {
+		line := indentation(d.lines[span.first]) + tomlKey(key) + " = " + rendered
+		d.edits = append(d.edits, lineEdit{start: span.first, end: span.last + 1, replacement: []string{line}})
+		return nil
+	}
+
+	start, _ := d.tableRange(path)
+	at, indent := start, ""
+	if spans := d.keySpans(path); len(spans) > 0 {
+		last := spans[len(spans)-1]
+		at, indent = last.last+1, indentation(d.lines[last.first])
+	}
+	d.edits = append(d.edits, lineEdit{start: at, end: at, replacement: []string{indent + tomlKey(key) + " = " + rendered}})
+	return nil
+}
+
+func (d *tomlDocument) deleteKey(path []string, key string) error {
+	span, err := d.findKey(path, key)
+	if err != nil {
+		return err
+	}
+	d.edits = append(d.edits, lineEdit{start: span.first, end: span.last + 1})
+	return nil
+}
+
+// deleteTable removes a header table with its keys, and its sub-tables declared with their own header
+func (d *tomlDocument) deleteTable(path []string, table map[string]interface{}) error {
+	start, end := d.tableRange(path)
+	d.edits = append(d.edits, lineEdit{start: start - 1, end: end})
+	for _, key := range slices.Sorted(maps.Keys(table)) {
+		keyPath := append(slices.Clone(path), key)
+		if subTable, ok := table[key].(map[string]interface{}); ok && d.isHeaderTable(keyPath) {
+			if err := d.deleteTable(keyPath, subTable); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// appendTable adds a top-level table at the end of the document
+func (d *tomlDocument) appendTable(key string, table map[string]interface{}) error {
+	tree, err := toml.TreeFromMap(map[string]interface{}{key: table})
+	if err != nil {
+		return err
+	}
+	content, err := tree.Marshal()
+	if err != nil {
+		return err
+	}
+	lines := strings.Split(strings.Trim(string(content), "\n"), "\n")
+	d.edits = append(d.edits, lineEdit{start: len(d.lines), end: len(d.lines), replacement: append([]string{""}, lines...)})
+	return nil
+}
+
+// bytes applies the edits, from the end of the document so the line numbers of the others stay valid
+func (d *tomlDocument) bytes() []byte {
+	if len(d.edits) == 0 {
+		return []byte(strings.Join(d.lines, "\n") + "\n")
+	}
+	order := make([]int, len(d.edits))
+	for index := range order {
+		order[index] = index
+	}
+	slices.SortFunc(order, func(a, b int) int {
+		editA, editB := d.edits[a], d.edits[b]
+		if editA.start != editB.start {
+			return editB.start - editA.start
+		}
+		// At the same line, replacements go first, and insertions keep their order
+		if (editA.end > editA.start) != (editB.end > editB.start) {
+			if editA.end > editA.start {
+				return -1
+			}
+			return 1
+		}
+		return b - a
+	})
+
+	lines := slices.Clone(d.lines)
+	for _, index := range order {
+		edit := d.edits[index]
+		lines = slices.Replace(lines, edit.start, edit.end, edit.replacement...)
+	}
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
+
+// valueEnd returns the last line of the key starting at line start, values like arrays spanning several lines
+func valueEnd(lines []string, start int) int {
+	depth := 0
+	multiline := ""
+	for index := start; index < len(lines); index++ {
+		line := lines[index]
+		for position := 0; position < len(line); position++ {
+			rest := line[position:]
+			if multiline != "" {
+				if strings.HasPrefix(rest, multiline) {
+					position += 2
+					multiline = ""
+				} else if multiline == `"""` && rest[0] == '\\' {
+					position++
+				}
+				continue
+			}
+			switch {
+			case strings.HasPrefix(rest, `"""`), strings.HasPrefix(rest, "'''"):
+				multiline = rest[:3]
+				position += 2
+			case rest[0] == '"':
+				position += quotedLength(rest, '"')
+			case rest[0] == '\'':
+				position += quotedLength(rest, '\'')
+			case rest[0] == '[' || rest[0] == '{':
+				depth++
+			case rest[0] == ']' || rest[0] == '}':
+				depth--
+			case rest[0] == '#':
+				position = len(line)
+			}
+		}
+		if depth <= 0 && multiline == "" {
+			return index
+		}
+	}
+	return len(lines) - 1
+}
+
+// quotedLength returns the index of the closing quote of a string starting with quote
+func quotedLength(text string, quote byte) int {
+	for position := 1; position < len(text); position++ {
+		if quote == '"' && text[position] == '\\' {
+			position++
+			continue
+		}
+		if text[position] == quote {
+			return position
+		}
+	}
+	return len(text) - 1
+}
+
+// lineKey reads the key of a line like `key = value` or `"my key" = value`
+func lineKey(line string) string {
+	switch line[0] {
+	case '"':
+		if key, err := strconv.Unquote(line[:quotedLength(line, '"')+1]); err == nil {
+			return key
+		}
+	case '\'':
+		return line[1:quotedLength(line, '\'')]
+	}
+	key, _, _ := strings.Cut(line, "=")
+	return strings.TrimSpace(key)
+}
+
+// tomlKey writes a key as it appears in a TOML file, quoted when it is not a bare key
+func tomlKey(key string) string {
+	if bareKeyRegexp.MatchString(key) {
+		return key
+	}
+	return strconv.Quote(key)
+}
+
+// inlineValue writes a value on a single line, tables being written as inline tables
+func inlineValue(value interface{}) (string, error) {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		entries := []string{}
+		for _, key := range slices.Sorted(maps.Keys(typed)) {
+			rendered, err := inlineValue(typed[key])
+			if err != nil {
+				return "", err
+			}
+			entries = append(entries, tomlKey(key)+" = "+rendered)
+		}
+		return "{ " + strings.Join(entries, ", ") + " }", nil
+	case []interface{}:
+		items := []string{}
+		for _, item := range typed {
+			rendered, err := inlineValue(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, rendered)
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	}
+	tree, err := toml.TreeFromMap(map[string]interface{}{"value": value})
+	if err != nil {
+		return "", err
+	}
+	content, err := tree.Marshal()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(string(content), "value = "), "\n"), nil
+}
+
+func indentation(line string) string {
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
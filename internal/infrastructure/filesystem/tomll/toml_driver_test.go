@@ -27,10 +27,10 @@ func TestDriverLoad(t *testing.T) {
 func TestDriverSave(t *testing.T) {
 
 	createStorage := &RepositoryToml{
-		Aliases: map[string]string{
+		Aliases: map[string]interface{}{
 			"gs": "git status",
 		},
-		Exports: map[string]string{
+		Exports: map[string]interface{}{
 			"PATH": "/usr/local/bin",
 		},
 	}
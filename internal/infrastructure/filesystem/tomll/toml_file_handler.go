@@ -21,7 +21,7 @@ func (h *TomlFileHandler) LoadRepositoryFile(path string) (*common.RepositoryDto
 	if err != nil {
 		return nil, err
 	}
-	return toRepositoryDto(repoToml), nil
+	return toRepositoryDto(repoToml)
 }
 
 func (h *TomlFileHandler) SaveRepositoryFile(path string, data *common.RepositoryDto) error {
@@ -45,3 +45,19 @@ func (h *TomlFileHandler) SaveUserPreferenceFile(path string, data *common.UserP
 	userPrefToml := toUserPreferenceToml(data)
 	return SaveToml(path, userPrefToml)
 }
+
+func (h *TomlFileHandler) LoadLockFile(path string) (*common.LockDto, error) {
+	lockToml, err := LoadToml[LockToml](path)
+	if err != nil {
+		return nil, err
+	}
+	return toLockDto(lockToml), nil
+}
+
+func (h *TomlFileHandler) SaveLockFile(path string, data *common.LockDto) error {
+	return SaveToml(path, toLockToml(data))
+}
+
+func (h *TomlFileHandler) MergeRepositoryFile(base []byte, remote []byte, local []byte, resolve common.KeyResolver) ([]byte, error) {
+	return MergeThreeWay(base, remote, local, resolve)
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -56,9 +57,9 @@ name_origin = "test-repo"`
 	}
 
 	// Verify exports
-	expectedExports := map[string]string{
-		"API_KEY": "test_key",
-		"DEBUG":   "true",
+	expectedExports := common.ExportsMap{
+		"API_KEY": {Value: "test_key"},
+		"DEBUG":   {Value: "true"},
 	}
 	if !reflect.DeepEqual(dto.Exports, expectedExports) {
 		t.Errorf("Expected exports %v, but got %v", expectedExports, dto.Exports)
@@ -83,9 +84,9 @@ func TestTomlFileHandler_SaveRepositoryFile(t *testing.T) {
 			"deploy": "docker deploy",
 			"clean":  "make clean",
 		},
-		Exports: map[string]string{
-			"ENV":     "production",
-			"VERSION": "1.0.0",
+		Exports: common.ExportsMap{
+			"ENV":     {Value: "production"},
+			"VERSION": {Value: "1.0.0"},
 		},
 		Metadata: common.MetadataDto{
 			UrlOrigin:  "https://github.com/example/project.git",
@@ -115,6 +116,218 @@ func TestTomlFileHandler_SaveRepositoryFile(t *testing.T) {
 	}
 }
 
+func TestTomlFileHandler_RepositoryFile_ExportDefinitions(t *testing.T) {
+	handler := &TomlFileHandler{}
+	tempDir := t.TempDir()
+	repoFile := filepath.Join(tempDir, "exports_repo.toml")
+
+	repoContent := `[exports]
+EDITOR = "vim"
+TOKEN = { value = "a$b", literal = true }
+PATH = { prepend = ["$HOME/bin", "$HOME/.local/bin"], append = ["/opt/bin"] }`
+
+	err := os.WriteFile(repoFile, []byte(repoContent), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	dto, err := handler.LoadRepositoryFile(repoFile)
+	if err != nil {
+		t.Fatalf("LoadRepositoryFile failed: %v", err)
+	}
+
+	expectedExports := common.ExportsMap{
+		"EDITOR": {Value: "vim"},
+		"TOKEN":  {Value: "a$b", Literal: true},
+		"PATH":   {Prepend: []string{"$HOME/bin", "$HOME/.local/bin"}, Append: []string{"/opt/bin"}},
+	}
+	if !reflect.DeepEqual(dto.Exports, expectedExports) {
+		t.Errorf("Expected exports %v, but got %v", expectedExports, dto.Exports)
+	}
+
+	// Saving keeps every definition
+	err = handler.SaveRepositoryFile(repoFile, dto)
+	if err != nil {
+		t.Fatalf("SaveRepositoryFile failed: %v", err)
+	}
+	loadedDto, err := handler.LoadRepositoryFile(repoFile)
+	if err != nil {
+		t.Fatalf("Failed to load saved file: %v", err)
+	}
+	if !reflect.DeepEqual(expectedExports, loadedDto.Exports) {
+		t.Errorf("Expected exports %v after saving, but got %v", expectedExports, loadedDto.Exports)
+	}
+}
+
+func TestTomlFileHandler_RepositoryFile_Conditions(t *testing.T) {
+	handler := &TomlFileHandler{}
+	tempDir := t.TempDir()
+	repoFile := filepath.Join(tempDir, "conditions_repo.toml")
+
+	repoContent := `[aliases]
+ll = "ls -la"
+copy = { value = "pbcopy", os = "darwin" }
+k = { value = "kubectl", if_command = "kubectl", hostname = "build-*" }
+
+[exports]
+EDITOR = "vim"
+PATH = { prepend = ["/opt/homebrew/bin"], os = "darwin", shell = "zsh" }`
+
+	err := os.WriteFile(repoFile, []byte(repoContent), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	dto, err := handler.LoadRepositoryFile(repoFile)
+	if err != nil {
+		t.Fatalf("LoadRepositoryFile failed: %v", err)
+	}
+
+	expectedAliases := common.AliasesMap{"ll": "ls -la", "copy": "pbcopy", "k": "kubectl"}
+	expectedAliasConditions := map[string]common.ConditionDto{
+		"copy": {OS: "darwin"},
+		"k":    {IfCommand: "kubectl", Hostname: "build-*"},
+	}
+	expectedExportConditions := map[string]common.ConditionDto{
+		"PATH": {OS: "darwin", Shell: "zsh"},
+	}
+	if !reflect.DeepEqual(dto.Aliases, expectedAliases) {
+		t.Errorf("Expected aliases %v, but got %v", expectedAliases, dto.Aliases)
+	}
+	if !reflect.DeepEqual(dto.AliasConditions, expectedAliasConditions) {
+		t.Errorf("Expected alias conditions %v, but got %v", expectedAliasConditions, dto.AliasConditions)
+	}
+	if !reflect.DeepEqual(dto.ExportConditions, expectedExportConditions) {
+		t.Errorf("Expected export conditions %v, but got %v", expectedExportConditions, dto.ExportConditions)
+	}
+
+	// Saving keeps every condition
+	err = handler.SaveRepositoryFile(repoFile, dto)
+	if err != nil {
+		t.Fatalf("SaveRepositoryFile failed: %v", err)
+	}
+	loadedDto, err := handler.LoadRepositoryFile(repoFile)
+	if err != nil {
+		t.Fatalf("Failed to load saved file: %v", err)
+	}
+	if !reflect.DeepEqual(dto, loadedDto) {
+		t.Errorf("Expected %v after saving, but got %v", dto, loadedDto)
+	}
+}
+
+func TestTomlFileHandler_RepositoryFile_Manifest(t *testing.T) {
+	handler := &TomlFileHandler{}
+	tempDir := t.TempDir()
+	repoFile := filepath.Join(tempDir, "manifest_repo.toml")
+
+	repoContent := `[aliases]
+ll = "ls -la"
+
+[manifest]
+description = "Aliases of the platform team"
+version = "1.2.0"
+authors = ["Jane Doe <jane@example.com>"]
+min_duh_version = "0.5.0"
+dependencies = ["https://github.com/example/base-duh.git"]`
+
+	err := os.WriteFile(repoFile, []byte(repoContent), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	dto, err := handler.LoadRepositoryFile(repoFile)
+	if err != nil {
+		t.Fatalf("LoadRepositoryFile failed: %v", err)
+	}
+
+	expectedManifest := common.ManifestDto{
+		Description:   "Aliases of the platform team",
+		Version:       "1.2.0",
+		Authors:       []string{"Jane Doe <jane@example.com>"},
+		MinDuhVersion: "0.5.0",
+		Dependencies:  []string{"https://github.com/example/base-duh.git"},
+	}
+	if !reflect.DeepEqual(dto.Manifest, expectedManifest) {
+		t.Errorf("Expected manifest %v, but got %v", expectedManifest, dto.Manifest)
+	}
+
+	// Saving keeps the manifest
+	err = handler.SaveRepositoryFile(repoFile, dto)
+	if err != nil {
+		t.Fatalf("SaveRepositoryFile failed: %v", err)
+	}
+	loadedDto, err := handler.LoadRepositoryFile(repoFile)
+	if err != nil {
+		t.Fatalf("Failed to load saved file: %v", err)
+	}
+	if !reflect.DeepEqual(expectedManifest, loadedDto.Manifest) {
+		t.Errorf("Expected manifest %v after saving, but got %v", expectedManifest, loadedDto.Manifest)
+	}
+
+	// Packages without a manifest do not get an empty section
+	dto.Manifest = common.ManifestDto{}
+	err = handler.SaveRepositoryFile(repoFile, dto)
+	if err != nil {
+		t.Fatalf("SaveRepositoryFile failed: %v", err)
+	}
+	content, err := os.ReadFile(repoFile)
+	if err != nil {
+		t.Fatalf("Failed to read saved file: %v", err)
+	}
+	if strings.Contains(string(content), "[manifest]") {
+		t.Errorf("Expected no manifest section, but got:\n%s", content)
+	}
+}
+
+func TestTomlFileHandler_RepositoryFile_LazyFunctions(t *testing.T) {
+	handler := &TomlFileHandler{}
+	tempDir := t.TempDir()
+	repoFile := filepath.Join(tempDir, "lazy_repo.toml")
+
+	err := os.WriteFile(repoFile, []byte("[aliases]\nll = \"ls -la\"\n\n[functions]\nlazy = true"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	dto, err := handler.LoadRepositoryFile(repoFile)
+	if err != nil {
+		t.Fatalf("LoadRepositoryFile failed: %v", err)
+	}
+	if !dto.LazyFunctions {
+		t.Error("Expected lazy functions to be loaded")
+	}
+
+	// Packages loading their functions at startup do not get an empty section
+	dto.LazyFunctions = false
+	err = handler.SaveRepositoryFile(repoFile, dto)
+	if err != nil {
+		t.Fatalf("SaveRepositoryFile failed: %v", err)
+	}
+	content, err := os.ReadFile(repoFile)
+	if err != nil {
+		t.Fatalf("Failed to read saved file: %v", err)
+	}
+	if strings.Contains(string(content), "[functions]") {
+		t.Errorf("Expected no functions section, but got:\n%s", content)
+	}
+}
+
+func TestTomlFileHandler_LoadRepositoryFile_InvalidExport(t *testing.T) {
+	handler := &TomlFileHandler{}
+	tempDir := t.TempDir()
+	repoFile := filepath.Join(tempDir, "invalid_exports_repo.toml")
+
+	err := os.WriteFile(repoFile, []byte("[exports]\nPATH = { prepend = \"$HOME/bin\" }"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	_, err = handler.LoadRepositoryFile(repoFile)
+	if err == nil {
+		t.Error("Expected an error for an invalid export definition")
+	}
+}
+
 func TestTomlFileHandler_LoadUserPreferenceFile(t *testing.T) {
 	handler := &TomlFileHandler{}
 	tempDir := t.TempDir()
@@ -197,6 +410,49 @@ func TestTomlFileHandler_SaveUserPreferenceFile(t *testing.T) {
 	}
 }
 
+func TestTomlFileHandler_UserPreferenceFile_TopLevelCode(t *testing.T) {
+	handler := &TomlFileHandler{}
+	userPrefFile := filepath.Join(t.TempDir(), "top_level_code_user_pref.toml")
+
+	content := "[repositories]\nactivated_repos = [\"local\"]\ndefault_repo_name = \"local\"\n\n[functions]\ntop_level_code = \"strip\"\n"
+	if err := os.WriteFile(userPrefFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	dto, err := handler.LoadUserPreferenceFile(userPrefFile)
+	if err != nil {
+		t.Fatalf("LoadUserPreferenceFile failed: %v", err)
+	}
+	if dto.TopLevelCode != "strip" {
+		t.Errorf("Expected top_level_code 'strip', but got '%s'", dto.TopLevelCode)
+	}
+
+	// A typo must not prevent the preferences from loading, and is kept as written until the user fixes it
+	invalid := strings.Replace(content, "strip", "strp", 1)
+	if err := os.WriteFile(userPrefFile, []byte(invalid), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	dto, err = handler.LoadUserPreferenceFile(userPrefFile)
+	if err != nil {
+		t.Fatalf("LoadUserPreferenceFile failed for an invalid top_level_code: %v", err)
+	}
+	if dto.TopLevelCode != "strp" {
+		t.Errorf("Expected top_level_code 'strp', but got '%s'", dto.TopLevelCode)
+	}
+
+	// Without a policy, no empty section is written
+	dto.TopLevelCode = ""
+	if err := handler.SaveUserPreferenceFile(userPrefFile, dto); err != nil {
+		t.Fatalf("SaveUserPreferenceFile failed: %v", err)
+	}
+	saved, err := os.ReadFile(userPrefFile)
+	if err != nil {
+		t.Fatalf("Failed to read saved file: %v", err)
+	}
+	if strings.Contains(string(saved), "[functions]") {
+		t.Errorf("Expected no functions section, but got:\n%s", saved)
+	}
+}
+
 func TestTomlFileHandler_LoadRepositoryFile_FileNotFound(t *testing.T) {
 	handler := &TomlFileHandler{}
 
@@ -0,0 +1,122 @@
+package shelll
+
+import (
+	"duh/internal/domain/entity"
+	"fmt"
+	"strings"
+)
+
+type FishRenderer struct{}
+
+func NewFishRenderer() *FishRenderer {
+	return &FishRenderer{}
+}
+
+func (f *FishRenderer) Name() string {
+	return Fish
+}
+
+func (f *FishRenderer) Alias(name, value string) string {
+	return fmt.Sprintf("alias %s \"%s\"", name, escapeFishString(value))
+}
+
+func (f *FishRenderer) Export(name string, export entity.Export) string {
+	if !export.IsPathList() {
+		return fmt.Sprintf("set -gx %s %s", name, quoteFishValue(export))
+	}
+
+	// --path exports the list joined with colons, whatever the variable name
+	lines := []string{}
+	if export.Value != "" {
+		lines = append(lines, fmt.Sprintf("set -gx --path %s %s", name, quoteFishValue(export)))
+	}
+	for i := len(export.Prepend) - 1; i >= 0; i-- {
+		entry := escapeFishExpandable(export.Prepend[i])
+		lines = append(lines, fmt.Sprintf(`contains -- "%[2]s" $%[1]s; or set -gx --path --prepend %[1]s "%[2]s"`, name, entry))
+	}
+	for _, entry := range export.Append {
+		entry = escapeFishExpandable(entry)
+		lines = append(lines, fmt.Sprintf(`contains -- "%[2]s" $%[1]s; or set -gx --path --append %[1]s "%[2]s"`, name, entry))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (f *FishRenderer) ReloadAlias() string {
+	return "alias duh_reload 'duh inject --quiet --shell fish | source'"
+}
+
+// Function scripts are written for POSIX shells, fish can't source them
+func (f *FishRenderer) Script(script entity.Script) string {
+	return fmt.Sprintf("# duh: script '%s' skipped, fish can't load POSIX shell functions", script.Name)
+}
+
+func (f *FishRenderer) LazyScript(script entity.Script) string {
+	return f.Script(script)
+}
+
+func (f *FishRenderer) GitConfig(entries []entity.GitConfigEntry) string {
+	lines := []string{"set -q GIT_CONFIG_COUNT; or set -gx GIT_CONFIG_COUNT 0"}
+	for _, entry := range entries {
+		lines = append(lines,
+			"set -gx GIT_CONFIG_KEY_$GIT_CONFIG_COUNT "+singleQuoteFish(entry.Key),
+			"set -gx GIT_CONFIG_VALUE_$GIT_CONFIG_COUNT "+singleQuoteFish(entry.Value),
+			"set -gx GIT_CONFIG_COUNT (math $GIT_CONFIG_COUNT + 1)",
+		)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (f *FishRenderer) Comment(text string) string {
+	return "# " + text
+}
+
+// Completion renders nothing: fish does not load the function scripts, so the functions to complete never exist
+func (f *FishRenderer) Completion(function entity.Function) string {
+	return ""
+}
+
+func (f *FishRenderer) Warning(text string) string {
+	return "echo " + singleQuoteFish("duh: "+text) + " >&2"
+}
+
+// quoteFishValue quotes the value of an export, literal values are single-quoted so nothing is expanded
+func quoteFishValue(export entity.Export) string {
+	if export.Literal {
+		return singleQuoteFish(export.Value)
+	}
+	return "\"" + escapeFishExpandable(export.Value) + "\""
+}
+
+// singleQuoteFish quotes a value so fish reads it as is
+func singleQuoteFish(input string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	return "'" + replacer.Replace(input) + "'"
+}
+
+// escapeFishExpandable escapes a value put inside double quotes, keeping only $VARIABLE and ${VARIABLE} expansion,
+// so a value never runs a command. Fish has no ${VARIABLE}, it becomes $VARIABLE followed by an empty string
+// ending the name.
+func escapeFishExpandable(input string) string {
+	var escaped strings.Builder
+	for _, part := range splitParameters(input) {
+		switch {
+		case part.parameter == "":
+			escaped.WriteString(escapeFishString(part.text))
+		case strings.HasPrefix(part.text, "${"):
+			escaped.WriteString("$" + part.parameter + `""`)
+		default:
+			escaped.WriteString(part.text)
+		}
+	}
+	return escaped.String()
+}
+
+// escapeFishString escapes the characters interpreted by fish inside double quotes
+func escapeFishString(input string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`, // backslash must be first
+		`$`, `\$`, // variable expansion
+		`"`, `\"`, // double quote
+	)
+	return replacer.Replace(input)
+}
@@ -0,0 +1,262 @@
+package shelll
+
+import (
+	"duh/internal/domain/entity"
+	"duh/internal/domain/utils"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// posixRenderer holds the syntax shared by bash, zsh and sh
+type posixRenderer struct {
+	shell string
+}
+
+func (p *posixRenderer) Name() string {
+	return p.shell
+}
+
+func (p *posixRenderer) Alias(name, value string) string {
+	return fmt.Sprintf("alias %s=\"%s\"", name, utils.EscapeShellString(value))
+}
+
+func (p *posixRenderer) Export(name string, export entity.Export) string {
+	if !export.IsPathList() {
+		return fmt.Sprintf("export %s=%s", name, quotePosixValue(export))
+	}
+
+	// Entries are only added when missing, so re-injecting with duh_reload keeps the list clean
+	lines := []string{}
+	if export.Value != "" {
+		lines = append(lines, fmt.Sprintf("%s=%s", name, quotePosixValue(export)))
+	}
+	for i := len(export.Prepend) - 1; i >= 0; i-- {
+		entry := escapePosixExpandable(export.Prepend[i])
+		lines = append(lines, addMissingEntry(name, entry, fmt.Sprintf(`"%[2]s${%[1]s:+:${%[1]s}}"`, name, entry)))
+	}
+	for _, entry := range export.Append {
+		entry = escapePosixExpandable(entry)
+		lines = append(lines, addMissingEntry(name, entry, fmt.Sprintf(`"${%[1]s:+${%[1]s}:}%[2]s"`, name, entry)))
+	}
+	lines = append(lines, "export "+name)
+	return strings.Join(lines, "\n")
+}
+
+// addMissingEntry sets the list variable name to value unless it already holds entry.
+// The entry is double quoted in the case pattern, so glob characters in it, or in what it expands to,
+// are matched literally: /opt/*/bin is only found as is, not as /opt/go/bin.
+func addMissingEntry(name string, entry string, value string) string {
+	return fmt.Sprintf(`case ":${%[1]s}:" in *":%[2]s:"*) ;; *) %[1]s=%[3]s ;; esac`, name, entry, value)
+}
+
+func (p *posixRenderer) ReloadAlias() string {
+	return fmt.Sprintf("alias duh_reload='eval \"$(duh inject --quiet --shell %s)\"'", p.shell)
+}
+
+func (p *posixRenderer) Script(script entity.Script) string {
+	return script.DataToInject
+}
+
+// LazyScript defines each function as a stub sourcing the script, which replaces the stubs with the real functions.
+// The stubs of the script are removed first, so a script not defining one of them can't make it call itself forever.
+// The function is called whatever the status of the script, which is the status of its last top-level command.
+func (p *posixRenderer) LazyScript(script entity.Script) string {
+	names := []string{}
+	for _, function := range script.Functions {
+		names = append(names, function.Name)
+	}
+	path := singleQuotePosix(script.PathToFile)
+	lines := []string{}
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf(`%s() { unset -f %s; . %s; %s "$@"; }`, name, strings.Join(names, " "), path, name))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Completion defines a completion function for bash and zsh, sh has no programmable completion.
+// Arguments without values are left to the default completion of the shell, usually files.
+func (p *posixRenderer) Completion(function entity.Function) string {
+	if !function.HasCompletion() {
+		return ""
+	}
+	switch p.shell {
+	case Bash:
+		return bashCompletion(function)
+	case Zsh:
+		return zshCompletion(function)
+	}
+	return ""
+}
+
+// bashCompletion uses complete -W for a function taking a single argument, and a function
+// completing each position otherwise
+func bashCompletion(function entity.Function) string {
+	if len(function.Args) == 1 {
+		return fmt.Sprintf("complete -o default -W %s %s", singleQuotePosix(strings.Join(function.Args[0].Choices(), " ")), function.Name)
+	}
+	completer := "_duh_complete_" + function.Name
+	lines := []string{completer + "() {", "    case $COMP_CWORD in"}
+	for index, arg := range function.Args {
+		choices := arg.Choices()
+		if len(choices) == 0 {
+			continue
+		}
+		reply := fmt.Sprintf(`COMPREPLY=($(compgen -W %s -- "${COMP_WORDS[COMP_CWORD]}"))`, singleQuotePosix(strings.Join(choices, " ")))
+		if arg.Repeated() {
+			// The positions of the arguments without values have no branch, * would catch them too
+			lines = append(lines, fmt.Sprintf("    *) (( COMP_CWORD >= %d )) && %s ;;", index+1, reply))
+		} else {
+			lines = append(lines, fmt.Sprintf("    %d) %s ;;", index+1, reply))
+		}
+	}
+	lines = append(lines, "    esac", "}", fmt.Sprintf("complete -o default -F %s %s", completer, function.Name))
+	return strings.Join(lines, "\n")
+}
+
+// zshCompletion describes the arguments with _arguments. compdef only exists once compinit ran,
+// so the completion is only registered when it did.
+func zshCompletion(function entity.Function) string {
+	specs := []string{}
+	for index, arg := range function.Args {
+		message := arg.Description
+		if message == "" {
+			message = arg.Label()
+		}
+		message = strings.ReplaceAll(message, ":", `\:`)
+		action := "_default"
+		if choices := arg.Choices(); len(choices) > 0 {
+			escaped := []string{}
+			for _, choice := range choices {
+				escaped = append(escaped, strings.NewReplacer(" ", `\ `, ":", `\:`, "(", `\(`, ")", `\)`).Replace(choice))
+			}
+			action = "(" + strings.Join(escaped, " ") + ")"
+		}
+		var spec string
+		switch {
+		case arg.Repeated():
+			spec = fmt.Sprintf("*:%s:%s", message, action)
+		case arg.Optional():
+			spec = fmt.Sprintf("%d::%s:%s", index+1, message, action)
+		default:
+			spec = fmt.Sprintf("%d:%s:%s", index+1, message, action)
+		}
+		specs = append(specs, singleQuotePosix(spec))
+	}
+	completer := "_duh_complete_" + function.Name
+	return strings.Join([]string{
+		completer + "() {",
+		"    _arguments " + strings.Join(specs, " "),
+		"}",
+		fmt.Sprintf("if (( $+functions[compdef] )); then compdef %s %s; fi", completer, function.Name),
+	}, "\n")
+}
+
+func (p *posixRenderer) GitConfig(entries []entity.GitConfigEntry) string {
+	lines := []string{"GIT_CONFIG_COUNT=${GIT_CONFIG_COUNT:-0}"}
+	for _, entry := range entries {
+		lines = append(lines,
+			fmt.Sprintf(`export "GIT_CONFIG_KEY_${GIT_CONFIG_COUNT}="%s`, singleQuotePosix(entry.Key)),
+			fmt.Sprintf(`export "GIT_CONFIG_VALUE_${GIT_CONFIG_COUNT}="%s`, singleQuotePosix(entry.Value)),
+			"GIT_CONFIG_COUNT=$((GIT_CONFIG_COUNT + 1))",
+		)
+	}
+	return strings.Join(append(lines, "export GIT_CONFIG_COUNT"), "\n")
+}
+
+func (p *posixRenderer) Comment(text string) string {
+	return "# " + text
+}
+
+func (p *posixRenderer) Warning(text string) string {
+	return "echo 'duh: " + strings.ReplaceAll(text, "'", `'\''`) + "' >&2"
+}
+
+// quotePosixValue quotes the value of an export, literal values are single-quoted so nothing is expanded
+func quotePosixValue(export entity.Export) string {
+	if export.Literal {
+		return singleQuotePosix(export.Value)
+	}
+	return "\"" + escapePosixExpandable(export.Value) + "\""
+}
+
+// singleQuotePosix quotes a value so the shell reads it as is
+func singleQuotePosix(input string) string {
+	return "'" + strings.ReplaceAll(input, "'", `'\''`) + "'"
+}
+
+// escapePosixExpandable escapes a value put inside double quotes, keeping only $VARIABLE and ${VARIABLE} expansion,
+// so a value never runs a command
+func escapePosixExpandable(input string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`, // backslash must be first
+		`"`, `\"`, // double quote
+		"`", "\\`", // legacy command substitution
+	)
+	var escaped strings.Builder
+	for _, part := range splitParameters(input) {
+		if part.parameter != "" {
+			escaped.WriteString(part.text)
+			continue
+		}
+		escaped.WriteString(strings.ReplaceAll(replacer.Replace(part.text), "$", `\$`))
+	}
+	return escaped.String()
+}
+
+// expandablePart is a piece of an expandable value: a $VARIABLE or ${VARIABLE} reference, or text between them
+type expandablePart struct {
+	text string
+	// Name of the variable referenced by text, empty for plain text
+	parameter string
+}
+
+var parameterPattern = regexp.MustCompile(`\$(?:([A-Za-z_][A-Za-z0-9_]*)|\{([A-Za-z_][A-Za-z0-9_]*)\})`)
+
+// splitParameters splits a value into its variable references and the text around them,
+// every other $ like the one of $(command) being plain text
+func splitParameters(input string) []expandablePart {
+	parts := []expandablePart{}
+	last := 0
+	for _, match := range parameterPattern.FindAllStringSubmatchIndex(input, -1) {
+		if match[0] > last {
+			parts = append(parts, expandablePart{text: input[last:match[0]]})
+		}
+		name := ""
+		if match[2] >= 0 {
+			name = input[match[2]:match[3]]
+		} else {
+			name = input[match[4]:match[5]]
+		}
+		parts = append(parts, expandablePart{text: input[match[0]:match[1]], parameter: name})
+		last = match[1]
+	}
+	if last < len(input) {
+		parts = append(parts, expandablePart{text: input[last:]})
+	}
+	return parts
+}
+
+type BashRenderer struct {
+	posixRenderer
+}
+
+func NewBashRenderer() *BashRenderer {
+	return &BashRenderer{posixRenderer{shell: Bash}}
+}
+
+type ZshRenderer struct {
+	posixRenderer
+}
+
+func NewZshRenderer() *ZshRenderer {
+	return &ZshRenderer{posixRenderer{shell: Zsh}}
+}
+
+type ShRenderer struct {
+	posixRenderer
+}
+
+func NewShRenderer() *ShRenderer {
+	return &ShRenderer{posixRenderer{shell: Sh}}
+}
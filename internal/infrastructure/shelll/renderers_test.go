@@ -0,0 +1,257 @@
+package shelll
+
+import (
+	"duh/internal/domain/entity"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PosixRenderers(t *testing.T) {
+	for _, renderer := range []*posixRenderer{
+		&NewBashRenderer().posixRenderer,
+		&NewZshRenderer().posixRenderer,
+		&NewShRenderer().posixRenderer,
+	} {
+		assert.Equal(t, `alias ll="ls -la"`, renderer.Alias("ll", "ls -la"))
+		assert.Equal(t, "alias hi=\"echo \\\"\\$USER\\\" \\`date\\`\"", renderer.Alias("hi", "echo \"$USER\" `date`"))
+		assert.Equal(t, `export EDITOR="vim"`, renderer.Export("EDITOR", entity.Export{Value: "vim"}))
+		assert.Contains(t, renderer.ReloadAlias(), "--shell "+renderer.Name())
+		assert.Equal(t, "f() { :; }", renderer.Script(entity.Script{Name: "f", DataToInject: "f() { :; }"}))
+	}
+}
+
+func Test_FishRenderer(t *testing.T) {
+	renderer := NewFishRenderer()
+
+	assert.Equal(t, `alias ll "ls -la"`, renderer.Alias("ll", "ls -la"))
+	assert.Equal(t, `alias hi "echo \"\$USER\""`, renderer.Alias("hi", "echo \"$USER\""))
+	assert.Equal(t, `set -gx EDITOR "vim"`, renderer.Export("EDITOR", entity.Export{Value: "vim"}))
+	assert.Equal(t, "alias duh_reload 'duh inject --quiet --shell fish | source'", renderer.ReloadAlias())
+
+	scriptOutput := renderer.Script(entity.Script{Name: "deploy", DataToInject: "deploy() { :; }"})
+	assert.NotContains(t, scriptOutput, "deploy()")
+	assert.Contains(t, scriptOutput, "# duh: script 'deploy' skipped")
+}
+
+func Test_PosixRenderer_Exports(t *testing.T) {
+	renderer := NewBashRenderer()
+
+	assert.Equal(t, `export GOBIN="$HOME/go/bin"`, renderer.Export("GOBIN", entity.Export{Value: "$HOME/go/bin"}))
+	assert.Equal(t, "export MSG=\"say \\\"hi\\\" \\`now\\`\"", renderer.Export("MSG", entity.Export{Value: "say \"hi\" `now`"}))
+	assert.Equal(t, `export TOKEN='a$b'\''c'`, renderer.Export("TOKEN", entity.Export{Value: "a$b'c", Literal: true}))
+
+	pathExport := renderer.Export("PATH", entity.Export{Prepend: []string{"$HOME/bin"}, Append: []string{"/opt/bin"}})
+	assert.Contains(t, pathExport, `PATH="$HOME/bin${PATH:+:${PATH}}"`)
+	assert.Contains(t, pathExport, `PATH="${PATH:+${PATH}:}/opt/bin"`)
+	assert.True(t, strings.HasSuffix(pathExport, "export PATH"))
+}
+
+func Test_PosixRenderer_ExpandableRunsNoCommand(t *testing.T) {
+	renderer := NewShRenderer()
+	export := renderer.Export("DUH_TEST_VALUE", entity.Export{Value: "${HOME}/bin:$(echo ran):$1"})
+	assert.Equal(t, `export DUH_TEST_VALUE="${HOME}/bin:\$(echo ran):\$1"`, export)
+
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh is not available")
+	}
+	t.Setenv("HOME", "/home/duh")
+	output, err := exec.Command(sh, "-c", export+"\necho \"$DUH_TEST_VALUE\"").Output()
+	assert.NoError(t, err)
+	assert.Equal(t, "/home/duh/bin:$(echo ran):$1\n", string(output))
+}
+
+func Test_PosixRenderer_PathListIsDeduplicated(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh is not available")
+	}
+	renderer := NewShRenderer()
+	export := renderer.Export("DUH_TEST_LIST", entity.Export{
+		Prepend: []string{"/first", "/second"},
+		Append:  []string{"/last", "/b"},
+	})
+
+	// Injecting twice, like duh_reload does, must not duplicate entries
+	script := export + "\n" + export + "\necho \"$DUH_TEST_LIST\""
+	cmd := exec.Command(sh, "-c", script)
+	cmd.Env = append(os.Environ(), "DUH_TEST_LIST=/a:/b")
+	output, err := cmd.Output()
+	assert.NoError(t, err)
+	assert.Equal(t, "/first:/second:/a:/b:/last\n", string(output))
+
+	// An empty list does not get a leading colon
+	cmd = exec.Command(sh, "-c", export+"\necho \"$DUH_TEST_LIST\"")
+	cmd.Env = os.Environ()
+	output, err = cmd.Output()
+	assert.NoError(t, err)
+	assert.Equal(t, "/first:/second:/last:/b\n", string(output))
+}
+
+func Test_PosixRenderer_PathListEntryIsLiteral(t *testing.T) {
+	export := NewShRenderer().Export("DUH_TEST_LIST", entity.Export{
+		Prepend: []string{"/opt/*/bin"},
+		Append:  []string{"$DUH_TEST_GLOB"},
+	})
+	for _, shell := range []string{"sh", "bash"} {
+		path, err := exec.LookPath(shell)
+		if err != nil {
+			continue
+		}
+		// Glob characters, written or expanded, must not match the entries already in the list
+		cmd := exec.Command(path, "-c", export+"\necho \"$DUH_TEST_LIST\"")
+		cmd.Env = append(os.Environ(), "DUH_TEST_LIST=/opt/go/bin:/usr/b", "DUH_TEST_GLOB=/usr/[ab]")
+		output, err := cmd.Output()
+		assert.NoError(t, err, shell)
+		assert.Equal(t, "/opt/*/bin:/opt/go/bin:/usr/b:/usr/[ab]\n", string(output), shell)
+	}
+}
+
+func Test_FishRenderer_Exports(t *testing.T) {
+	renderer := NewFishRenderer()
+
+	assert.Equal(t, `set -gx GOBIN "$HOME/go/bin"`, renderer.Export("GOBIN", entity.Export{Value: "$HOME/go/bin"}))
+	assert.Equal(t, `set -gx TOKEN 'a$b\'c'`, renderer.Export("TOKEN", entity.Export{Value: "a$b'c", Literal: true}))
+	// Only variables are expanded, never a command
+	assert.Equal(t, `set -gx FOO "\$(curl example.com)"`, renderer.Export("FOO", entity.Export{Value: "$(curl example.com)"}))
+	assert.Equal(t, `set -gx GOBIN "$HOME""/go/bin"`, renderer.Export("GOBIN", entity.Export{Value: "${HOME}/go/bin"}))
+
+	pathExport := renderer.Export("PATH", entity.Export{Prepend: []string{"/first", "$HOME/bin"}, Append: []string{"/opt/bin"}})
+	assert.Equal(t, strings.Join([]string{
+		`contains -- "$HOME/bin" $PATH; or set -gx --path --prepend PATH "$HOME/bin"`,
+		`contains -- "/first" $PATH; or set -gx --path --prepend PATH "/first"`,
+		`contains -- "/opt/bin" $PATH; or set -gx --path --append PATH "/opt/bin"`,
+	}, "\n"), pathExport)
+}
+
+func Test_PosixRenderer_LazyScript(t *testing.T) {
+	renderer := NewShRenderer()
+	script := entity.Script{
+		Name:       "greet",
+		PathToFile: "/pkgs/team/functions/it's greet.sh",
+		Functions:  []entity.Function{{Name: "hello"}, {Name: "bye"}},
+	}
+	assert.Equal(t, strings.Join([]string{
+		`hello() { unset -f hello bye; . '/pkgs/team/functions/it'\''s greet.sh'; hello "$@"; }`,
+		`bye() { unset -f hello bye; . '/pkgs/team/functions/it'\''s greet.sh'; bye "$@"; }`,
+	}, "\n"), renderer.LazyScript(script))
+
+	assert.Contains(t, NewFishRenderer().LazyScript(script), "# duh: script 'greet' skipped")
+}
+
+func Test_Renderers_Warning(t *testing.T) {
+	assert.Equal(t, `echo 'duh: script '\''up'\'' runs code' >&2`, NewBashRenderer().Warning("script 'up' runs code"))
+	assert.Equal(t, `echo 'duh: script \'up\' runs code' >&2`, NewFishRenderer().Warning("script 'up' runs code"))
+}
+
+func Test_PosixRenderer_LazyScriptIsSourcedOnFirstCall(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh is not available")
+	}
+	scriptPath := t.TempDir() + "/greet.sh"
+	content := "echo loaded\nhello() { echo \"hello $1\"; }\nbye() { echo \"bye $1\"; }\n"
+	assert.NoError(t, os.WriteFile(scriptPath, []byte(content), 0644))
+	stubs := NewShRenderer().LazyScript(entity.Script{
+		Name:       "greet",
+		PathToFile: scriptPath,
+		Functions:  []entity.Function{{Name: "hello"}, {Name: "bye"}},
+	})
+
+	// The script is only sourced once, by the first function called
+	output, err := exec.Command(sh, "-c", stubs+"\necho started\nhello world\nbye world\nhello again").Output()
+	assert.NoError(t, err)
+	assert.Equal(t, "started\nloaded\nhello world\nbye world\nhello again\n", string(output))
+
+	// A script not defining a function fails instead of calling the stub again
+	assert.NoError(t, os.WriteFile(scriptPath, []byte("hello() { :; }\n"), 0644))
+	err = exec.Command(sh, "-c", stubs+"\nbye").Run()
+	assert.Error(t, err)
+
+	// A script ending with a failing command still defines its functions
+	assert.NoError(t, os.WriteFile(scriptPath, []byte(content+"[ -n \"$DUH_UNSET\" ] && echo set\n"), 0644))
+	output, err = exec.Command(sh, "-c", stubs+"\nhello world").Output()
+	assert.NoError(t, err)
+	assert.Equal(t, "loaded\nhello world\n", string(output))
+}
+
+var deployFunction = entity.Function{Name: "deploy", Args: []entity.FunctionArg{
+	{Name: "<staging|production>", Description: "Environment to deploy"},
+	{Name: "[ref]", Description: "Git ref, HEAD by default"},
+	{Name: "[--dry-run|--force]..."},
+}}
+
+func Test_Renderers_Completion(t *testing.T) {
+	mkcd := entity.Function{Name: "mkcd", Args: []entity.FunctionArg{{Name: "dir"}}}
+	for _, renderer := range []interface{ Completion(entity.Function) string }{
+		NewBashRenderer(), NewZshRenderer(), NewShRenderer(), NewFishRenderer(),
+	} {
+		// Nothing to complete without values
+		assert.Empty(t, renderer.Completion(mkcd))
+	}
+
+	single := entity.Function{Name: "deploy", Args: []entity.FunctionArg{{Name: "<staging|production>"}}}
+	assert.Equal(t, "complete -o default -W 'staging production' deploy", NewBashRenderer().Completion(single))
+	assert.Equal(t, `_duh_complete_deploy() {
+    case $COMP_CWORD in
+    1) COMPREPLY=($(compgen -W 'staging production' -- "${COMP_WORDS[COMP_CWORD]}")) ;;
+    *) (( COMP_CWORD >= 3 )) && COMPREPLY=($(compgen -W '--dry-run --force' -- "${COMP_WORDS[COMP_CWORD]}")) ;;
+    esac
+}
+complete -o default -F _duh_complete_deploy deploy`, NewBashRenderer().Completion(deployFunction))
+
+	assert.Equal(t, `_duh_complete_deploy() {
+    _arguments '1:Environment to deploy:(staging production)' '2::Git ref, HEAD by default:_default' '*:--dry-run|--force:(--dry-run --force)'
+}
+if (( $+functions[compdef] )); then compdef _duh_complete_deploy deploy; fi`, NewZshRenderer().Completion(deployFunction))
+
+	assert.Empty(t, NewShRenderer().Completion(deployFunction))
+	// Fish does not load the scripts, there is no function to complete
+	assert.Empty(t, NewFishRenderer().Completion(deployFunction))
+}
+
+func Test_BashRenderer_CompletionIsEvaluated(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash is not available")
+	}
+	script := NewBashRenderer().Completion(deployFunction) + `
+complete -p deploy
+COMP_WORDS=(deploy st); COMP_CWORD=1; _duh_complete_deploy; echo "${COMPREPLY[@]}"
+COMPREPLY=(); COMP_WORDS=(deploy staging ''); COMP_CWORD=2; _duh_complete_deploy; echo "refs: ${COMPREPLY[@]}"
+COMP_WORDS=(deploy staging main --); COMP_CWORD=3; _duh_complete_deploy; echo "${COMPREPLY[@]}"
+COMP_WORDS=(deploy staging main --force --d); COMP_CWORD=4; _duh_complete_deploy; echo "${COMPREPLY[@]}"`
+	output, err := exec.Command(bash, "-c", script).Output()
+	assert.NoError(t, err)
+	// The ref is left to the default completion
+	assert.Equal(t, "complete -o default -F _duh_complete_deploy deploy\nstaging\nrefs: \n--dry-run --force\n--dry-run\n", string(output))
+}
+
+func Test_Renderers_GitConfig(t *testing.T) {
+	entries := []entity.GitConfigEntry{{Key: "alias.st", Value: "status -sb"}, {Key: "user.name", Value: "O'Brien"}}
+	assert.Equal(t, strings.Join([]string{
+		"set -q GIT_CONFIG_COUNT; or set -gx GIT_CONFIG_COUNT 0",
+		"set -gx GIT_CONFIG_KEY_$GIT_CONFIG_COUNT 'alias.st'",
+		"set -gx GIT_CONFIG_VALUE_$GIT_CONFIG_COUNT 'status -sb'",
+		"set -gx GIT_CONFIG_COUNT (math $GIT_CONFIG_COUNT + 1)",
+		"set -gx GIT_CONFIG_KEY_$GIT_CONFIG_COUNT 'user.name'",
+		`set -gx GIT_CONFIG_VALUE_$GIT_CONFIG_COUNT 'O\'Brien'`,
+		"set -gx GIT_CONFIG_COUNT (math $GIT_CONFIG_COUNT + 1)",
+	}, "\n"), NewFishRenderer().GitConfig(entries))
+
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh is not available")
+	}
+	// The entries are numbered after the ones already in the environment
+	cmd := exec.Command(sh, "-c", NewShRenderer().GitConfig(entries)+`
+echo "$GIT_CONFIG_COUNT $GIT_CONFIG_KEY_0 $GIT_CONFIG_KEY_1=$GIT_CONFIG_VALUE_1 $GIT_CONFIG_KEY_2=$GIT_CONFIG_VALUE_2"`)
+	cmd.Env = append(os.Environ(), "GIT_CONFIG_COUNT=1", "GIT_CONFIG_KEY_0=core.pager", "GIT_CONFIG_VALUE_0=less")
+	output, err := cmd.Output()
+	assert.NoError(t, err)
+	assert.Equal(t, "3 core.pager alias.st=status -sb user.name=O'Brien\n", string(output))
+}
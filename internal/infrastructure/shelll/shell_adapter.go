@@ -0,0 +1,65 @@
+package shelll
+
+import (
+	"duh/internal/domain/port"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	Bash = "bash"
+	Zsh  = "zsh"
+	Fish = "fish"
+	Sh   = "sh"
+)
+
+// Shells that are not supported by name but understand POSIX sh syntax
+var posixCompatibleShells = []string{"dash", "ash", "ksh", "mksh", "busybox"}
+
+type ShellAdapter struct {
+	renderers map[string]port.ShellRenderer
+}
+
+func NewShellAdapter() *ShellAdapter {
+	return &ShellAdapter{
+		renderers: map[string]port.ShellRenderer{
+			Bash: NewBashRenderer(),
+			Zsh:  NewZshRenderer(),
+			Fish: NewFishRenderer(),
+			Sh:   NewShRenderer(),
+		},
+	}
+}
+
+func (s *ShellAdapter) GetRenderer(shellName string) (port.ShellRenderer, error) {
+	if shellName == "" {
+		shellName = DetectShell(os.Getenv("SHELL"))
+	}
+	renderer, ok := s.renderers[strings.ToLower(shellName)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported shell '%s'. Available shells: %s", shellName, strings.Join(s.SupportedShells(), ", "))
+	}
+	return renderer, nil
+}
+
+func (s *ShellAdapter) SupportedShells() []string {
+	return []string{Bash, Zsh, Fish, Sh}
+}
+
+// DetectShell returns the shell name matching the given $SHELL value
+// Falls back to bash, which was the only syntax duh used to emit
+func DetectShell(shellEnv string) string {
+	name := strings.ToLower(filepath.Base(strings.TrimSpace(shellEnv)))
+	switch name {
+	case Bash, Zsh, Fish, Sh:
+		return name
+	}
+	for _, compatible := range posixCompatibleShells {
+		if name == compatible {
+			return Sh
+		}
+	}
+	return Bash
+}
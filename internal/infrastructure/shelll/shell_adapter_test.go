@@ -0,0 +1,58 @@
+package shelll
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DetectShell(t *testing.T) {
+	tests := []struct {
+		shellEnv string
+		expected string
+	}{
+		{"/bin/bash", Bash},
+		{"/usr/bin/zsh", Zsh},
+		{"/opt/homebrew/bin/fish", Fish},
+		{"/bin/sh", Sh},
+		{"/bin/dash", Sh},
+		{"/bin/ksh", Sh},
+		{"", Bash},
+		{"/usr/bin/nushell", Bash},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, DetectShell(tt.shellEnv), "for SHELL=%q", tt.shellEnv)
+	}
+}
+
+func Test_GetRenderer(t *testing.T) {
+	adapter := NewShellAdapter()
+
+	for _, shell := range adapter.SupportedShells() {
+		renderer, err := adapter.GetRenderer(shell)
+		assert.NoError(t, err)
+		assert.Equal(t, shell, renderer.Name())
+	}
+
+	renderer, err := adapter.GetRenderer("FISH")
+	assert.NoError(t, err)
+	assert.Equal(t, Fish, renderer.Name())
+}
+
+func Test_GetRenderer_DetectsFromEnv(t *testing.T) {
+	t.Setenv("SHELL", "/usr/bin/fish")
+	adapter := NewShellAdapter()
+
+	renderer, err := adapter.GetRenderer("")
+	assert.NoError(t, err)
+	assert.Equal(t, Fish, renderer.Name())
+}
+
+func Test_GetRenderer_UnsupportedShell(t *testing.T) {
+	adapter := NewShellAdapter()
+
+	_, err := adapter.GetRenderer("powershell")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported shell 'powershell'")
+}
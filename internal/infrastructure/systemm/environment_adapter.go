@@ -0,0 +1,30 @@
+package systemm
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+type EnvironmentAdapter struct{}
+
+func NewEnvironmentAdapter() *EnvironmentAdapter {
+	return &EnvironmentAdapter{}
+}
+
+func (e *EnvironmentAdapter) OS() string {
+	return runtime.GOOS
+}
+
+func (e *EnvironmentAdapter) Hostname() (string, error) {
+	return os.Hostname()
+}
+
+func (e *EnvironmentAdapter) HasCommand(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+func (e *EnvironmentAdapter) Getenv(name string) string {
+	return os.Getenv(name)
+}
@@ -33,6 +33,7 @@ func BuildAliasCommand(aliasHandler *handler.AliasHandler) *cobra.Command {
 		Args:  cobra.NoArgs,
 		Run:   aliasHandler.ListAliases,
 	}
+	addOutputFlag(listAliasCmd)
 
 	aliasCmd.AddCommand(setAliasCmd)
 	aliasCmd.AddCommand(unsetAliasCmd)
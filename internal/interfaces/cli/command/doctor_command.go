@@ -0,0 +1,33 @@
+package command
+
+import (
+	"duh/internal/interfaces/cli/handler"
+
+	"github.com/spf13/cobra"
+)
+
+func BuildDoctorCommand(doctorHandler *handler.DoctorHandler) *cobra.Command {
+	doctorCmd := &cobra.Command{
+		Use:   "doctor [subcommand]",
+		Short: "Check the health of your duh setup",
+	}
+
+	conflictsCmd := &cobra.Command{
+		Use:   "conflicts",
+		Short: "Report names defined by more than one enabled package",
+		Long: `Report every alias, export, function and git alias defined by more than one enabled package,
+and every alias sharing its name with a function, with the definition that wins.
+
+Packages are injected in their activation order, so the last enabled package wins.
+An alias always shadows a function with the same name.
+
+The command exits with a non-zero code when conflicts are found, so it can be used as a CI check.`,
+		Args:          cobra.NoArgs,
+		RunE:          doctorHandler.Conflicts,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	doctorCmd.AddCommand(conflictsCmd)
+	return doctorCmd
+}
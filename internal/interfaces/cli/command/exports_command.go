@@ -16,9 +16,20 @@ func BuildExportsCommand(exportsHandler *handler.ExportsHandler) *cobra.Command
 	setExportCmd := &cobra.Command{
 		Use:   "set [export_name] [value]",
 		Short: "Set an export for an environment variable",
-		Args:  cobra.ExactArgs(2),
-		Run:   exportsHandler.SetExport,
+		Long: `Set an export for an environment variable.
+
+By default, variables in the value are expanded by the shell: duh exports set GOBIN '$HOME/go/bin'
+Use --literal to inject the value as is, without any expansion.
+
+Use --prepend or --append to add an entry to a colon-separated list like PATH.
+The entry is only added if it is not already in the list: duh exports set PATH '$HOME/bin' --prepend`,
+		Args: cobra.ExactArgs(2),
+		Run:  exportsHandler.SetExport,
 	}
+	setExportCmd.Flags().Bool("literal", false, "Inject the value as is, without expanding variables")
+	setExportCmd.Flags().Bool("prepend", false, "Add the value to the start of a colon-separated list like PATH")
+	setExportCmd.Flags().Bool("append", false, "Add the value to the end of a colon-separated list like PATH")
+	setExportCmd.MarkFlagsMutuallyExclusive("literal", "prepend", "append")
 
 	unsetExportCmd := &cobra.Command{
 		Use:   "unset [export_name]",
@@ -33,6 +44,7 @@ func BuildExportsCommand(exportsHandler *handler.ExportsHandler) *cobra.Command
 		Args:  cobra.NoArgs,
 		Run:   exportsHandler.ListExports,
 	}
+	addOutputFlag(listExportCmd)
 
 	exportsCmd.AddCommand(setExportCmd)
 	exportsCmd.AddCommand(unsetExportCmd)
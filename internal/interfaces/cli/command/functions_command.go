@@ -21,6 +21,7 @@ func BuildFunctionsCommand(functionsHandler *handler.FunctionsHandler) *cobra.Co
 	}
 	listFunctionsCmd.Flags().BoolP("all", "a", false, "List all functions (not just activated ones)")
 	listFunctionsCmd.Flags().Bool("core", false, "List internal core functions")
+	addOutputFlag(listFunctionsCmd)
 
 	functionDetailsCmd := &cobra.Command{
 		Use:   "info [functionName]",
@@ -28,6 +29,7 @@ func BuildFunctionsCommand(functionsHandler *handler.FunctionsHandler) *cobra.Co
 		Args:  cobra.ExactArgs(1),
 		Run:   functionsHandler.GetFunctionInfo,
 	}
+	addOutputFlag(functionDetailsCmd)
 
 	addFunction := &cobra.Command{
 		Use:   "add [functionName]",
@@ -36,9 +38,32 @@ func BuildFunctionsCommand(functionsHandler *handler.FunctionsHandler) *cobra.Co
 		Run:   functionsHandler.CreateFunctionScript,
 	}
 
+	lintFunctionsCmd := &cobra.Command{
+		Use:   "lint",
+		Short: "Check function scripts for common shell mistakes",
+		Long: `Run static checks over the function scripts of every package:
+  - exit inside a function, which closes the shell of the user (error)
+  - bash-only syntax in a script declared POSIX by its shebang or "# duh: shell=sh" (error)
+  - unquoted variable expansions and command substitutions (warning)
+  - code outside functions, run in every shell at startup (warning)
+  - functions replacing a common command, or hidden by an alias of another package (warning)
+  - functions without a documentation comment (info)
+
+Each finding is printed as file:line:column: severity: message [rule].
+The command fails when an error is found, or a warning with --strict, so it can be used as a CI gate.`,
+		Args:          cobra.NoArgs,
+		RunE:          functionsHandler.LintFunctions,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	lintFunctionsCmd.Flags().StringP("package", "p", "", "Only lint the scripts of this package")
+	lintFunctionsCmd.Flags().Bool("strict", false, "Fail on warnings too")
+	addOutputFlag(lintFunctionsCmd)
+
 	functionsCmd.AddCommand(listFunctionsCmd)
 	functionsCmd.AddCommand(functionDetailsCmd)
 	functionsCmd.AddCommand(addFunction)
+	functionsCmd.AddCommand(lintFunctionsCmd)
 
 	return functionsCmd
 }
@@ -0,0 +1,29 @@
+package command
+
+import (
+	"duh/internal/interfaces/cli/handler"
+
+	"github.com/spf13/cobra"
+)
+
+func BuildImportCommand(importHandler *handler.ImportHandler) *cobra.Command {
+	importCmd := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Import aliases, exports and functions from an existing dotfile",
+		Long: `Read a shell configuration file like ~/.bashrc, ~/.zshrc or ~/.bash_aliases, and add to a package:
+  - its alias statements as aliases
+  - its export statements as exports, PATH="$HOME/bin:$PATH" becoming an entry prepended to PATH
+  - each function declaration as a script in functions/<name>.sh, with the comments above it
+
+Definitions the package already has with another value are skipped,
+and every statement duh cannot classify is reported with its line, so it can be moved by hand.
+The dotfile itself is left untouched.`,
+		Args:          cobra.ExactArgs(1),
+		RunE:          importHandler.ImportDotfile,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	importCmd.Flags().StringP("package", "p", "", "Package to import into (default: the default package)")
+	importCmd.Flags().Bool("dry-run", false, "Show what would be imported without writing anything")
+	return importCmd
+}
@@ -10,11 +10,24 @@ func BuildInjectCommand(injectHandler *handler.InjectHandler) *cobra.Command {
 	injectCmd := &cobra.Command{
 		Use:   "inject",
 		Short: "Inject configuration into your shell environment",
-		Long:  "Generate shell commands to set up aliases and environment variables. Use --quiet for silent output suitable for sourcing.",
-		Run:   injectHandler.HandleInject,
+		Long: `Generate shell commands to set up aliases and environment variables. Use --quiet for silent output suitable for sourcing.
+
+The output syntax matches the shell given with --shell (bash, zsh, fish, sh).
+When omitted, the shell is detected from the $SHELL environment variable.
+
+Packages are injected in their activation order, with keys sorted inside each package.
+When several packages define the same alias or export, the last enabled package wins.
+Use --explain to see which package each line comes from.
+
+The injection is cached in the duh data directory, and rebuilt only when a package
+or the user preferences change. Use --no-cache to force a rebuild.`,
+		Run: injectHandler.HandleInject,
 	}
 
 	injectCmd.Flags().Bool("quiet", false, "Silent output suitable for eval/sourcing")
+	injectCmd.Flags().Bool("no-cache", false, "Rebuild the injection instead of using the cached one")
+	injectCmd.Flags().Bool("explain", false, "Annotate each line with the package it comes from")
+	injectCmd.Flags().StringP("shell", "s", "", "Shell syntax to generate: bash, zsh, fish or sh (default: detected from $SHELL)")
 
 	return injectCmd
 }
@@ -0,0 +1,46 @@
+package command
+
+import (
+	"duh/internal/interfaces/cli/handler"
+
+	"github.com/spf13/cobra"
+)
+
+func BuildLockCommand(lockHandler *handler.LockHandler) *cobra.Command {
+	return &cobra.Command{
+		Use:   "lock",
+		Short: "Record the exact state of every package in the lockfile",
+		Long: `Write duh_lock.toml next to user_preferences.toml, recording the url, commit,
+enabled state and pin of every package.
+
+Commit the lockfile to your dotfiles, or share it with your team,
+then run 'duh sync --locked' on another machine to reproduce the same environment.`,
+		Args:          cobra.NoArgs,
+		RunE:          lockHandler.Lock,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+}
+
+func BuildSyncCommand(lockHandler *handler.LockHandler) *cobra.Command {
+	syncCmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Install and enable the packages recorded in the lockfile",
+		Long: `Bring the packages in line with duh_lock.toml:
+  - packages missing on this machine are cloned
+  - pins are restored
+  - the packages enabled in the lockfile are enabled, in the same order, and only them
+
+With --locked, every package is also reset to the exact commit recorded in the lockfile.
+Packages with local changes are left alone, unless --force is used to discard the changes.
+
+The command exits with a non-zero code when a package could not be synced, so it can be used in CI.`,
+		Args:          cobra.NoArgs,
+		RunE:          lockHandler.Sync,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	syncCmd.Flags().Bool("locked", false, "Reset every package to the commit recorded in the lockfile")
+	syncCmd.Flags().Bool("force", false, "Discard local changes of packages reset to their locked commit (destructive)")
+	return syncCmd
+}
@@ -1,12 +1,13 @@
 package command
 
 import (
+	"duh/internal/domain/entity"
 	"duh/internal/interfaces/cli/handler"
 
 	"github.com/spf13/cobra"
 )
 
-func BuildPackageCommand(packageHandler *handler.PackageHandler) *cobra.Command {
+func BuildPackageCommand(packageHandler *handler.PackageHandler, injectHandler *handler.InjectHandler) *cobra.Command {
 	packageCmd := &cobra.Command{
 		Use:     "package [subcommand]",
 		Aliases: []string{"pkg", "packages", "repo", "repos", "repository"},
@@ -19,6 +20,7 @@ func BuildPackageCommand(packageHandler *handler.PackageHandler) *cobra.Command
 		Args:  cobra.NoArgs,
 		Run:   packageHandler.ListPackages,
 	}
+	addOutputFlag(listPackageCmd)
 
 	enablePackageCmd := &cobra.Command{
 		Use:   "enable [package_name]",
@@ -72,12 +74,125 @@ func BuildPackageCommand(packageHandler *handler.PackageHandler) *cobra.Command
 	}
 
 	addPackageCmd := &cobra.Command{
-		Use:   "add [url] [name (optional)]",
+		Use:   "add [url[#ref]] [name (optional)]",
 		Short: "Add a new package",
-		Args:  cobra.RangeArgs(1, 2),
-		Run:   packageHandler.AddPackage,
+		Long: `Clone a package from its git url and enable it.
+Append #<ref> to the url to pin the package to a tag, branch or commit, e.g.
+  duh package add https://github.com/team/duh-pkg#release/1.2
+@<ref> also pins the package when the ref has no / or :, e.g.
+  duh package add https://github.com/team/duh-pkg@v1.2.0
+
+The dependencies listed in the [manifest] section of the package db.toml are
+cloned and enabled first, unless a package cloned from the same url is already installed.
+`,
+		Args: cobra.RangeArgs(1, 2),
+		Run:  packageHandler.AddPackage,
+	}
+
+	pinPackageCmd := &cobra.Command{
+		Use:   "pin [name] [ref]",
+		Short: "Pin a package to a tag, branch or commit",
+		Long: `Check out a tag, branch or commit of a package, and keep it there.
+
+duh package update leaves packages pinned to a tag or commit where they are,
+and moves packages pinned to a branch to the newest commit of this branch.
+Pins are stored in user_preferences.toml.
+`,
+		Args: cobra.ExactArgs(2),
+		Run:  packageHandler.PinPackage,
+	}
+
+	unpinPackageCmd := &cobra.Command{
+		Use:   "unpin [name]",
+		Short: "Remove the pin of a package, so it follows its remote again",
+		Args:  cobra.ExactArgs(1),
+		Run:   packageHandler.UnpinPackage,
 	}
 
+	lazyPackageCmd := &cobra.Command{
+		Use:   "lazy [name] [on|off]",
+		Short: "Load the functions of a package on their first call instead of at shell startup",
+		Long: `Inject a small stub per function of the package instead of the whole script.
+On its first call, the stub sources the script and runs the function.
+
+A script can override the setting of its package with a "# duh: lazy" or
+"# duh: lazy=false" directive. The setting is stored in the db.toml of the package.
+`,
+		Args:      cobra.ExactArgs(2),
+		ValidArgs: []string{"on", "off"},
+		Run:       packageHandler.SetLazyFunctions,
+	}
+
+	infoPackageCmd := &cobra.Command{
+		Use:   "info [name]",
+		Short: "Show the manifest and status of a package",
+		Args:  cobra.ExactArgs(1),
+		Run:   packageHandler.PackageInfo,
+	}
+	addOutputFlag(infoPackageCmd)
+
+	statusPackageCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the git state of every package",
+		Long: `Show, for every package, whether it is local-only or has a remote, the checked out branch,
+the commits not pushed (ahead) and not pulled yet (behind), the files with uncommitted changes,
+and when its remote was last fetched.
+
+Remotes are fetched first, use --no-fetch to work offline from the last fetch.
+Use --output json or --output yaml for a machine-readable output.`,
+		Args:          cobra.NoArgs,
+		RunE:          packageHandler.PackagesStatus,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	addOutputFlag(statusPackageCmd)
+	statusPackageCmd.Flags().Bool("json", false, "Print the status as JSON, same as --output json")
+	statusPackageCmd.Flags().Bool("no-fetch", false, "Do not fetch the remotes, use the last fetched state")
+
+	logPackageCmd := &cobra.Command{
+		Use:   "log [name]",
+		Short: "Show the commits of a package, with the entries each one changed",
+		Long: `Show the commit history of a package, newest first, with the aliases, exports,
+functions and git aliases each commit added, removed or changed.`,
+		Args: cobra.ExactArgs(1),
+		Run:  packageHandler.PackageLog,
+	}
+	logPackageCmd.Flags().IntP("max-count", "n", 20, "Number of commits to show")
+
+	rollbackPackageCmd := &cobra.Command{
+		Use:   "rollback [name] [commit (optional)]",
+		Short: "Restore a package as it was at a previous commit",
+		Long: `Restore the files of a package as they were at a commit, the previous one by default,
+and show how the injection changes.
+
+The history is not rewritten: the rollback is a new commit on top of it,
+so it can be pushed and the package still updated from its remote.
+The package must be on a branch, without local changes. A pinned package is
+rolled back by pinning it to an older ref instead, see duh package pin.`,
+		Args: cobra.RangeArgs(1, 2),
+		Run:  packageHandler.RollbackPackage,
+	}
+
+	exportPackageCmd := &cobra.Command{
+		Use:   "export [name...]",
+		Short: "Render packages as a standalone script, for machines without duh",
+		Long: `Render the aliases, exports and functions of one or more packages into a single script,
+to be sourced on machines where duh cannot be installed, like servers reached over SSH.
+
+The content is rendered exactly as duh inject renders these packages, in the given order,
+with the functions of duh core they may rely on. The git configuration of the packages is set
+through the GIT_CONFIG_* environment variables (git 2.31 or later) instead of an include in ~/.gitconfig.
+Conditions are evaluated on this machine.
+
+Example:
+  duh package export base work --format bash > duh.sh
+  scp duh.sh server: && ssh server 'echo ". ~/duh.sh" >> ~/.bashrc'`,
+		Args: cobra.MinimumNArgs(1),
+		Run:  injectHandler.ExportPackages,
+	}
+	exportPackageCmd.Flags().String("format", "", "Shell syntax to generate: sh, bash, zsh or fish (default: detected from $SHELL)")
+	exportPackageCmd.Flags().StringP("file", "f", "", "Write the script to a file instead of the standard output")
+
 	createPackageCmd := &cobra.Command{
 		Use:   "create [name]",
 		Short: "Create a new empty package",
@@ -94,8 +209,19 @@ By default, updates are safe and won't proceed if local changes exist.
 Strategies:
   --commit  Commit local changes before updating (safer)
   --force   Discard local changes and force update (destructive)
+  --rebase  Replay local commits and changes on top of the remote, merging db.toml key by key.
+            Only keys changed differently on both sides are conflicts, you choose which side to keep.
+
+If no strategy flag is provided, the update will fail if local changes exist.
+
+Pinned packages stay on their pin, see duh package pin.
+Local changes of pinned packages are never committed, only --force discards them.
+  --upgrade Move pins on a release tag like v1.2.0 to the newest release with the same major version
+
+Packages are updated in parallel, --jobs sets how many at the same time.
 
-If neither flag is provided, the update will fail if local changes exist.`,
+  --dry-run Fetch packages without pulling, and list the aliases, exports, functions
+            and git aliases the update would add, remove or change`,
 		Args: cobra.NoArgs,
 		Run:  packageHandler.UpdatePackages,
 	}
@@ -151,6 +277,10 @@ duh export EDITOR nano
 	// Add flags to update command
 	updatePackageCmd.Flags().Bool("force", false, "Force update by discarding local changes (destructive)")
 	updatePackageCmd.Flags().Bool("commit", false, "Commit local changes before updating (safer)")
+	updatePackageCmd.Flags().Bool("rebase", false, "Replay local changes on top of the remote, merging db.toml key by key")
+	updatePackageCmd.Flags().Bool("upgrade", false, "Move pins to the newest release tag with the same major version")
+	updatePackageCmd.Flags().Bool("dry-run", false, "Show the changes an update would bring without pulling")
+	updatePackageCmd.Flags().IntP("jobs", "j", entity.DefaultUpdateJobs, "Number of packages updated at the same time")
 
 	packageCmd.AddCommand(listPackageCmd)
 	packageCmd.AddCommand(enablePackageCmd)
@@ -160,6 +290,14 @@ duh export EDITOR nano
 	packageCmd.AddCommand(getDefaultPackageCmd)
 	packageCmd.AddCommand(renamePackageCmd)
 	packageCmd.AddCommand(addPackageCmd)
+	packageCmd.AddCommand(infoPackageCmd)
+	packageCmd.AddCommand(statusPackageCmd)
+	packageCmd.AddCommand(logPackageCmd)
+	packageCmd.AddCommand(rollbackPackageCmd)
+	packageCmd.AddCommand(exportPackageCmd)
+	packageCmd.AddCommand(pinPackageCmd)
+	packageCmd.AddCommand(unpinPackageCmd)
+	packageCmd.AddCommand(lazyPackageCmd)
 	packageCmd.AddCommand(createPackageCmd)
 	packageCmd.AddCommand(updatePackageCmd)
 	packageCmd.AddCommand(editPackageCmd)
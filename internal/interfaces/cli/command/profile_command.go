@@ -0,0 +1,60 @@
+package command
+
+import (
+	"duh/internal/interfaces/cli/handler"
+
+	"github.com/spf13/cobra"
+)
+
+func BuildProfileCommand(profileHandler *handler.ProfileHandler) *cobra.Command {
+	profileCmd := &cobra.Command{
+		Use:   "profile [subcommand]",
+		Short: "Use different packages on each machine, duh.",
+		Long: `A profile is a named set of enabled packages with its own default package.
+Package commands (enable, disable, set-default...) change the active profile only.
+
+The active profile is selected, in this order:
+  1. the DUH_PROFILE environment variable
+  2. the profile chosen with 'duh profile use' on this machine
+  3. the first profile, by name, with a hostname pattern matching this machine
+  4. the default profile`,
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List profiles, the active one is marked with *",
+		Args:  cobra.NoArgs,
+		Run:   profileHandler.ListProfiles,
+	}
+
+	currentCmd := &cobra.Command{
+		Use:   "current",
+		Short: "Print the active profile and how it was selected",
+		Args:  cobra.NoArgs,
+		Run:   profileHandler.CurrentProfile,
+	}
+
+	createCmd := &cobra.Command{
+		Use:   "create [profile_name]",
+		Short: "Create a profile, starting with the packages of the active profile",
+		Args:  cobra.ExactArgs(1),
+		Run:   profileHandler.CreateProfile,
+	}
+	createCmd.Flags().StringSlice("hostname", []string{}, "Hostname pattern selecting the profile automatically, supports * and ? (repeatable)")
+
+	useCmd := &cobra.Command{
+		Use:   "use [profile_name]",
+		Short: "Use a profile on this machine",
+		Long: `Use a profile on this machine.
+The choice is stored for the current hostname, so other machines syncing the same duh directory are not affected.`,
+		Args: cobra.ExactArgs(1),
+		Run:  profileHandler.UseProfile,
+	}
+
+	profileCmd.AddCommand(listCmd)
+	profileCmd.AddCommand(currentCmd)
+	profileCmd.AddCommand(createCmd)
+	profileCmd.AddCommand(useCmd)
+
+	return profileCmd
+}
@@ -14,6 +14,10 @@ func BuildRootCli(
 	injectHandler *handler.InjectHandler,
 	packageHandler *handler.PackageHandler,
 	selfHandler *handler.SelfHandler,
+	doctorHandler *handler.DoctorHandler,
+	profileHandler *handler.ProfileHandler,
+	lockHandler *handler.LockHandler,
+	importHandler *handler.ImportHandler,
 ) *cobra.Command {
 	rootCmd := &cobra.Command{
 		Use:   "duh",
@@ -25,14 +29,23 @@ func BuildRootCli(
 			DisableDefaultCmd: true,
 		},
 	}
-
 	// Add all subcommands using new handlers
 	rootCmd.AddCommand(BuildInjectCommand(injectHandler))
 	rootCmd.AddCommand(BuildAliasCommand(aliasHandler))
 	rootCmd.AddCommand(BuildExportsCommand(exportsHandler))
-	rootCmd.AddCommand(BuildPackageCommand(packageHandler))
+	rootCmd.AddCommand(BuildPackageCommand(packageHandler, injectHandler))
 	rootCmd.AddCommand(BuildFunctionsCommand(functionsHandler))
 	rootCmd.AddCommand(BuildSelfCommand(selfHandler))
+	rootCmd.AddCommand(BuildDoctorCommand(doctorHandler))
+	rootCmd.AddCommand(BuildProfileCommand(profileHandler))
+	rootCmd.AddCommand(BuildLockCommand(lockHandler))
+	rootCmd.AddCommand(BuildSyncCommand(lockHandler))
+	rootCmd.AddCommand(BuildImportCommand(importHandler))
 
 	return rootCmd
 }
+
+// addOutputFlag registers --output on the list and info commands, the only ones printing structured output
+func addOutputFlag(cmd *cobra.Command) {
+	cmd.Flags().StringP("output", "o", "table", "Output format: table, json or yaml")
+}
@@ -40,12 +40,23 @@ func (a *AliasHandler) UnsetAlias(cmd *cobra.Command, args []string) {
 }
 
 func (a *AliasHandler) ListAliases(cmd *cobra.Command, args []string) {
+	format, err := outputFormat(cmd)
+	if err != nil {
+		std.Errf("Error listing aliases: %v\n", err)
+		return
+	}
 	entries, err := a.aliasUsecase.ListAliases()
 	if err != nil {
 		std.Errf("%s: %v\n", "Error listing aliases", err)
 		return
 	}
-	for key, value := range entries {
-		fmt.Printf("%s='%s'\n", key, value)
+	if format != OutputTable {
+		if err := printStructured(cmd, format, aliasViewsOf(entries)); err != nil {
+			std.Errf("Error listing aliases: %v\n", err)
+		}
+		return
+	}
+	for _, entry := range entries {
+		fmt.Printf("%s='%s'\n", entry.Name, entry.Value)
 	}
 }
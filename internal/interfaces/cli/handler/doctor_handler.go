@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"duh/internal/application/usecase"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type DoctorHandler struct {
+	doctorUsecase *usecase.DoctorUsecase
+}
+
+func NewDoctorHandler(doctorUsecase *usecase.DoctorUsecase) *DoctorHandler {
+	return &DoctorHandler{
+		doctorUsecase: doctorUsecase,
+	}
+}
+
+// Conflicts returns an error when conflicts are found, so duh exits with a non-zero code
+func (d *DoctorHandler) Conflicts(cmd *cobra.Command, args []string) error {
+	conflicts, err := d.doctorUsecase.GetConflicts()
+	if err != nil {
+		return fmt.Errorf("error checking conflicts: %w", err)
+	}
+
+	if len(conflicts) == 0 {
+		cmd.Println("✅ No conflicts between enabled packages")
+		return nil
+	}
+
+	for _, conflict := range conflicts {
+		cmd.Printf("⚠️  %s '%s' defined by %s → '%s' wins\n",
+			conflict.Kind,
+			conflict.Name,
+			strings.Join(conflict.Packages, ", "),
+			conflict.Winner,
+		)
+	}
+	return fmt.Errorf("%d conflict(s) found between enabled packages", len(conflicts))
+}
@@ -21,10 +21,23 @@ func NewExportsHandler(exportsUsecase *usecase.ExportsUsecase) *ExportsHandler {
 func (e *ExportsHandler) SetExport(cmd *cobra.Command, args []string) {
 	exportName := args[0]
 	value := args[1]
-	err := e.exportsUsecase.SetExport(exportName, value)
-	if err != nil {
+	literal, _ := cmd.Flags().GetBool("literal")
+	prepend, _ := cmd.Flags().GetBool("prepend")
+	appendEntry, _ := cmd.Flags().GetBool("append")
+
+	err := e.exportsUsecase.SetExport(exportName, value, usecase.SetExportOptions{
+		Literal: literal,
+		Prepend: prepend,
+		Append:  appendEntry,
+	})
+	switch {
+	case err != nil:
 		std.Errf("Error setting export: %v\n", err)
-	} else {
+	case prepend:
+		fmt.Printf("Export '%s' prepended with '%s'\n", exportName, value)
+	case appendEntry:
+		fmt.Printf("Export '%s' appended with '%s'\n", exportName, value)
+	default:
 		fmt.Printf("Export '%s' set for value '%s'\n", exportName, value)
 	}
 }
@@ -40,11 +53,22 @@ func (e *ExportsHandler) UnsetExport(cmd *cobra.Command, args []string) {
 }
 
 func (e *ExportsHandler) ListExports(cmd *cobra.Command, args []string) {
+	format, err := outputFormat(cmd)
+	if err != nil {
+		std.Errf("Error listing exports: %v\n", err)
+		return
+	}
 	exports, err := e.exportsUsecase.ListExports()
 	if err != nil {
 		std.Errf("Error listing exports: %v\n", err)
 		return
 	}
+	if format != OutputTable {
+		if err := printStructured(cmd, format, exportViewsOf(exports)); err != nil {
+			std.Errf("Error listing exports: %v\n", err)
+		}
+		return
+	}
 
 	if len(exports) == 0 {
 		cmd.Println("No exports found")
@@ -52,7 +76,7 @@ func (e *ExportsHandler) ListExports(cmd *cobra.Command, args []string) {
 	}
 
 	cmd.Println("Current exports:")
-	for name, value := range exports {
-		fmt.Printf("  %s=%s\n", name, value)
+	for _, export := range exports {
+		fmt.Printf("  %s=%s\n", export.Name, export.Export)
 	}
 }
@@ -5,28 +5,37 @@ import (
 	"duh/internal/domain/entity"
 	"duh/internal/interfaces/cli/std"
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
 
 type FunctionsHandler struct {
 	functionsUsecase *usecase.FunctionsUsecase
+	lintUsecase      *usecase.LintUsecase
 }
 
-func NewFunctionsHandler(functionsUsecase *usecase.FunctionsUsecase) *FunctionsHandler {
+func NewFunctionsHandler(functionsUsecase *usecase.FunctionsUsecase, lintUsecase *usecase.LintUsecase) *FunctionsHandler {
 	return &FunctionsHandler{
 		functionsUsecase: functionsUsecase,
+		lintUsecase:      lintUsecase,
 	}
 }
 
 func (f *FunctionsHandler) ListFunctions(cmd *cobra.Command, args []string) {
 	showAll, _ := cmd.Flags().GetBool("all")
 	var scripts []entity.Script
-	var err error
+
+	format, err := outputFormat(cmd)
+	if err != nil {
+		std.Errf("Error while listing functions: %v\n", err)
+		return
+	}
 
 	showCore, _ := cmd.Flags().GetBool("core")
 	if showCore {
-		f.showInternalFunctions()
+		f.showInternalFunctions(cmd, format)
 		return
 	}
 
@@ -40,6 +49,13 @@ func (f *FunctionsHandler) ListFunctions(cmd *cobra.Command, args []string) {
 		std.Errf("Error while listing functions: %v\n", err)
 		return
 	}
+	if warning := f.functionsUsecase.TopLevelCodeWarning(); warning != "" {
+		cmd.PrintErrf("⚠️  %s\n", warning)
+	}
+	if format != OutputTable {
+		printScripts(cmd, format, scripts)
+		return
+	}
 	for _, script := range scripts {
 		if len(script.Warnings) > 0 {
 			fmt.Printf("Warnings:\n")
@@ -53,6 +69,11 @@ func (f *FunctionsHandler) ListFunctions(cmd *cobra.Command, args []string) {
 
 func (f *FunctionsHandler) GetFunctionInfo(cmd *cobra.Command, args []string) {
 	functionName := args[0]
+	format, err := outputFormat(cmd)
+	if err != nil {
+		std.Errf("Error retrieving function details: %v\n", err)
+		return
+	}
 	script, err := f.functionsUsecase.GetScriptByFunctionName(functionName)
 	if err != nil {
 		std.Errf("Error retrieving function details: %v\n", err)
@@ -66,16 +87,130 @@ func (f *FunctionsHandler) GetFunctionInfo(cmd *cobra.Command, args []string) {
 		if fun.Name != functionName {
 			continue
 		}
+		missing := f.functionsUsecase.MissingRequirements(fun)
+		if format != OutputTable {
+			view := functionViewOf(*script, fun)
+			view.MissingRequirements = missing
+			if err := printStructured(cmd, format, view); err != nil {
+				std.Errf("Error retrieving function details: %v\n", err)
+			}
+			return
+		}
+		displayFunctionManual(*script, fun, missing)
+	}
+}
 
-		fmt.Printf("%s()\n", fun.Name)
-		if len(fun.Documentation) > 0 {
-			for _, docLine := range fun.Documentation {
-				fmt.Printf("  %s\n", docLine)
+// displayFunctionManual prints the documentation of a function like a man page
+func displayFunctionManual(script entity.Script, fun entity.Function, missing []string) {
+	fmt.Printf("NAME\n")
+	if summary := fun.Summary(); summary != "" {
+		fmt.Printf("    %s() - %s\n", fun.Name, summary)
+	} else {
+		fmt.Printf("    %s()\n", fun.Name)
+	}
+	fmt.Printf("\nSYNOPSIS\n    %s\n", fun.Usage())
+
+	if strings.Contains(fun.Description, "\n") {
+		fmt.Printf("\nDESCRIPTION\n")
+		for _, line := range strings.Split(fun.Description, "\n") {
+			fmt.Printf("    %s\n", line)
+		}
+	}
+
+	if len(fun.Args) > 0 {
+		width := 0
+		for _, arg := range fun.Args {
+			width = max(width, len(arg.Name))
+		}
+		fmt.Printf("\nARGUMENTS\n")
+		for _, arg := range fun.Args {
+			fmt.Printf("    %-*s  %s\n", width, arg.Name, arg.Description)
+		}
+	}
+
+	if len(fun.Examples) > 0 {
+		fmt.Printf("\nEXAMPLES\n")
+		for index, example := range fun.Examples {
+			if index > 0 {
+				fmt.Printf("\n")
+			}
+			for _, line := range strings.Split(example, "\n") {
+				fmt.Printf("    %s\n", line)
 			}
 		}
-		fmt.Printf("Script: %s\n", script.PathToFile)
-		fmt.Printf("\n")
 	}
+
+	if len(fun.Requires) > 0 {
+		width := 0
+		for _, command := range fun.Requires {
+			width = max(width, len(command))
+		}
+		fmt.Printf("\nREQUIRES\n")
+		for _, command := range fun.Requires {
+			if slices.Contains(missing, command) {
+				fmt.Printf("    %-*s  ❌ not found in PATH\n", width, command)
+			} else {
+				fmt.Printf("    %-*s  ✅\n", width, command)
+			}
+		}
+	}
+
+	fmt.Printf("\nSOURCE\n")
+	if script.Package == "" {
+		fmt.Printf("    %s\n", script.PathToFile)
+	} else {
+		fmt.Printf("    %s (package '%s')\n", script.PathToFile, script.Package)
+	}
+	fmt.Printf("\n")
+}
+
+// LintFunctions prints the findings of the scripts, and fails when one of them is an error,
+// or a warning with --strict, so it can be used as a CI gate
+func (f *FunctionsHandler) LintFunctions(cmd *cobra.Command, args []string) error {
+	packageName, _ := cmd.Flags().GetString("package")
+	strict, _ := cmd.Flags().GetBool("strict")
+	format, err := outputFormat(cmd)
+	if err != nil {
+		return err
+	}
+
+	findings, err := f.lintUsecase.LintScripts(packageName)
+	if err != nil {
+		return fmt.Errorf("error linting functions: %w", err)
+	}
+
+	counts := map[string]int{}
+	for _, finding := range findings {
+		counts[finding.Severity]++
+	}
+	if format != OutputTable {
+		views := []lintFindingView{}
+		for _, finding := range findings {
+			views = append(views, lintFindingViewOf(finding))
+		}
+		if err := printStructured(cmd, format, views); err != nil {
+			return err
+		}
+	} else {
+		for _, finding := range findings {
+			cmd.Println(finding.String())
+		}
+		if len(findings) == 0 {
+			cmd.Println("✅ No issue found")
+		} else {
+			cmd.Printf("\n%d errors, %d warnings, %d infos\n",
+				counts[entity.SeverityError], counts[entity.SeverityWarning], counts[entity.SeverityInfo])
+		}
+	}
+
+	failing := counts[entity.SeverityError]
+	if strict {
+		failing += counts[entity.SeverityWarning]
+	}
+	if failing > 0 {
+		return fmt.Errorf("lint failed with %d findings to fix", failing)
+	}
+	return nil
 }
 
 // /!\ Do not test this it will open an editor
@@ -92,12 +227,16 @@ func (f *FunctionsHandler) CreateFunctionScript(cmd *cobra.Command, args []strin
 	}
 }
 
-func (f *FunctionsHandler) showInternalFunctions() {
+func (f *FunctionsHandler) showInternalFunctions(cmd *cobra.Command, format string) {
 	scripts, err := f.functionsUsecase.GetInternalFunctions()
 	if err != nil {
 		std.Errf("Error while listing internal functions: %v\n", err)
 		return
 	}
+	if format != OutputTable {
+		printScripts(cmd, format, scripts)
+		return
+	}
 	for _, script := range scripts {
 		displayFunctionDetails(script)
 	}
@@ -106,9 +245,19 @@ func (f *FunctionsHandler) showInternalFunctions() {
 func displayFunctionDetails(script entity.Script) {
 	for _, fun := range script.Functions {
 		fmt.Printf("- %s()\n", fun.Name)
-		if len(fun.Documentation) > 0 {
-			fmt.Printf("  %s\n", fun.Documentation[0])
+		if summary := fun.Summary(); summary != "" {
+			fmt.Printf("  %s\n", summary)
 		}
 		fmt.Printf("\n")
 	}
 }
+
+func printScripts(cmd *cobra.Command, format string, scripts []entity.Script) {
+	views := []scriptView{}
+	for _, script := range scripts {
+		views = append(views, scriptViewOf(script))
+	}
+	if err := printStructured(cmd, format, views); err != nil {
+		std.Errf("Error while listing functions: %v\n", err)
+	}
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"duh/internal/application/usecase"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/spf13/cobra"
+)
+
+type ImportHandler struct {
+	importUsecase *usecase.ImportUsecase
+}
+
+func NewImportHandler(importUsecase *usecase.ImportUsecase) *ImportHandler {
+	return &ImportHandler{
+		importUsecase: importUsecase,
+	}
+}
+
+func (i *ImportHandler) ImportDotfile(cmd *cobra.Command, args []string) error {
+	packageName, _ := cmd.Flags().GetString("package")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	result, err := i.importUsecase.ImportDotfile(args[0], packageName, dryRun)
+	if err != nil {
+		return fmt.Errorf("error importing %s: %w", args[0], err)
+	}
+
+	if result.IsEmpty() {
+		cmd.Printf("Nothing to import from %s into package '%s'\n", args[0], result.Package)
+	} else {
+		if dryRun {
+			cmd.Printf("Would import into package '%s':\n", result.Package)
+		} else {
+			cmd.Printf("📥 Imported into package '%s':\n", result.Package)
+		}
+		for _, name := range slices.Sorted(maps.Keys(result.Aliases)) {
+			cmd.Printf("  + alias %s='%s'\n", name, result.Aliases[name])
+		}
+		for _, name := range slices.Sorted(maps.Keys(result.Exports)) {
+			cmd.Printf("  + export %s=%s\n", name, result.Exports[name])
+		}
+		for _, script := range result.Scripts {
+			if script.PathToFile != "" {
+				cmd.Printf("  + function %s() in %s\n", script.Name, script.PathToFile)
+			} else {
+				cmd.Printf("  + function %s() in functions/%s.sh\n", script.Name, script.Name)
+			}
+		}
+	}
+
+	if len(result.Skipped) > 0 {
+		cmd.Printf("\nSkipped, already defined differently in the package:\n")
+		for _, skipped := range result.Skipped {
+			cmd.Printf("  - %s\n", skipped)
+		}
+	}
+	if len(result.Unclassified) > 0 {
+		cmd.Printf("\n⚠️  %d statements are not an alias, an export or a function, move them by hand if needed:\n", len(result.Unclassified))
+		for _, statement := range result.Unclassified {
+			cmd.Printf("  line %d: %s\n", statement.Line, statement.Details)
+		}
+	}
+	if dryRun {
+		cmd.Printf("\nDry run, nothing was written.\n")
+	}
+	return nil
+}
@@ -21,11 +21,18 @@ func NewInjectHandler(injectUsecase *usecase.InjectUsecase) *InjectHandler {
 
 func (i *InjectHandler) HandleInject(cmd *cobra.Command, args []string) {
 	quiet, _ := cmd.Flags().GetBool("quiet")
+	shell, _ := cmd.Flags().GetString("shell")
+	explain, _ := cmd.Flags().GetBool("explain")
+	noCache, _ := cmd.Flags().GetBool("no-cache")
 
-	injection, err := i.injectUsecase.GetInjectionString()
+	injection, err := i.injectUsecase.GetInjectionString(usecase.InjectOptions{
+		Shell:   shell,
+		Explain: explain,
+		NoCache: noCache,
+	})
 	if err != nil {
 		if !quiet {
-			std.Errf("Error setting alias: %v\n", err)
+			std.Errf("Error generating injection: %v\n", err)
 		}
 		return
 	}
@@ -38,3 +45,25 @@ func (i *InjectHandler) HandleInject(cmd *cobra.Command, args []string) {
 		fmt.Printf("%s\n", injection)
 	}
 }
+
+// ExportPackages prints the packages as a standalone script, or writes it to --file
+func (i *InjectHandler) ExportPackages(cmd *cobra.Command, args []string) {
+	format, _ := cmd.Flags().GetString("format")
+	file, _ := cmd.Flags().GetString("file")
+
+	script, err := i.injectUsecase.ExportPackages(args, format)
+	if err != nil {
+		std.Errf("Error exporting packages: %v\n", err)
+		return
+	}
+
+	if file == "" {
+		fmt.Fprint(cmd.OutOrStdout(), script)
+		return
+	}
+	if err := os.WriteFile(file, []byte(script), 0644); err != nil {
+		std.Errf("Error writing %s: %v\n", file, err)
+		return
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Exported %d package(s) to %s\n", len(args), file)
+}
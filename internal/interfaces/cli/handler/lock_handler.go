@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"duh/internal/application/usecase"
+	"duh/internal/domain/entity"
+	"duh/internal/domain/errorss"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+type LockHandler struct {
+	lockUsecase *usecase.LockUsecase
+}
+
+func NewLockHandler(lockUsecase *usecase.LockUsecase) *LockHandler {
+	return &LockHandler{
+		lockUsecase: lockUsecase,
+	}
+}
+
+func (l *LockHandler) Lock(cmd *cobra.Command, args []string) error {
+	lock, err := l.lockUsecase.Lock()
+	if err != nil {
+		return fmt.Errorf("error writing the lockfile: %w", err)
+	}
+
+	cmd.Printf("🔒 Locked %d packages:\n", len(lock.Packages))
+	for _, pkg := range lock.Packages {
+		status := "✗"
+		if pkg.Enabled {
+			status = "✓"
+		}
+		origin := "created locally"
+		if pkg.Url != "" {
+			origin = pkg.Url
+		}
+		if pkg.Commit != "" {
+			origin += " @ " + entity.ShortCommit(pkg.Commit)
+		}
+		if pkg.Pin != "" {
+			origin += " (pinned to " + pkg.Pin + ")"
+		}
+		cmd.Printf("  %s %s: %s\n", status, pkg.Name, origin)
+	}
+	return nil
+}
+
+// Sync returns an error when a package could not be synced, so duh exits with a non-zero code
+func (l *LockHandler) Sync(cmd *cobra.Command, args []string) error {
+	locked, _ := cmd.Flags().GetBool("locked")
+	force, _ := cmd.Flags().GetBool("force")
+
+	results, err := l.lockUsecase.Sync(locked, force)
+	var notFoundErr *errorss.NotFoundError
+	if errors.As(err, &notFoundErr) {
+		return fmt.Errorf("no lockfile found, run 'duh lock' first")
+	}
+	if err != nil {
+		return fmt.Errorf("error syncing packages: %w", err)
+	}
+
+	for _, name := range results.Cloned {
+		cmd.Printf("⬇️  Package '%s' cloned\n", name)
+	}
+	for _, name := range results.Reset {
+		cmd.Printf("↩️  Package '%s' reset to its locked commit\n", name)
+	}
+	if len(results.LocalChangesDetected) > 0 {
+		cmd.Println("⚠️  Packages with local changes (not reset):")
+		for _, name := range results.LocalChangesDetected {
+			cmd.Printf("  • %s\n", name)
+		}
+		cmd.Println("\nUse --force to discard them.")
+	}
+	if len(results.OtherErrors) > 0 {
+		cmd.Println("❌ Packages with errors:")
+		for _, err := range results.OtherErrors {
+			cmd.Printf("  • %v\n", err)
+		}
+	}
+
+	issues := len(results.LocalChangesDetected) + len(results.OtherErrors)
+	if issues > 0 {
+		return fmt.Errorf("%d packages could not be synced", issues)
+	}
+	cmd.Println("✅ Packages synced with the lockfile")
+	return nil
+}
@@ -0,0 +1,329 @@
+package handler
+
+import (
+	"duh/internal/domain/entity"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Formats of the --output flag
+const (
+	OutputTable = "table"
+	OutputJson  = "json"
+	OutputYaml  = "yaml"
+)
+
+// OutputFormats lists the values accepted by --output
+var OutputFormats = []string{OutputTable, OutputJson, OutputYaml}
+
+// outputFormat reads the --output flag, table when the command does not have it
+func outputFormat(cmd *cobra.Command) (string, error) {
+	format, err := cmd.Flags().GetString("output")
+	if err != nil || format == "" {
+		return OutputTable, nil
+	}
+	if !slices.Contains(OutputFormats, format) {
+		return "", fmt.Errorf("unsupported output format '%s', expected one of: table, json, yaml", format)
+	}
+	return format, nil
+}
+
+// printStructured prints data as JSON or YAML on the standard output of the command
+func printStructured(cmd *cobra.Command, format string, data any) error {
+	var encoded []byte
+	var err error
+	if format == OutputYaml {
+		encoded, err = yaml.Marshal(data)
+	} else {
+		encoded, err = json.MarshalIndent(data, "", "  ")
+		encoded = append(encoded, '\n')
+	}
+	if err != nil {
+		return err
+	}
+	_, err = cmd.OutOrStdout().Write(encoded)
+	return err
+}
+
+// sourceView is where an item is defined
+type sourceView struct {
+	Package string `json:"package" yaml:"package"`
+	File    string `json:"file" yaml:"file"`
+}
+
+type aliasView struct {
+	Name   string     `json:"name" yaml:"name"`
+	Value  string     `json:"value" yaml:"value"`
+	Source sourceView `json:"source" yaml:"source"`
+}
+
+type exportView struct {
+	Name    string   `json:"name" yaml:"name"`
+	Value   string   `json:"value,omitempty" yaml:"value,omitempty"`
+	Literal bool     `json:"literal,omitempty" yaml:"literal,omitempty"`
+	Prepend []string `json:"prepend,omitempty" yaml:"prepend,omitempty"`
+	Append  []string `json:"append,omitempty" yaml:"append,omitempty"`
+	// Every package contributing to the export, an export extending a list like PATH can have several
+	Sources []sourceView `json:"sources" yaml:"sources"`
+}
+
+type packageView struct {
+	Name          string            `json:"name" yaml:"name"`
+	Enabled       bool              `json:"enabled" yaml:"enabled"`
+	Default       bool              `json:"default" yaml:"default"`
+	Pin           string            `json:"pin,omitempty" yaml:"pin,omitempty"`
+	LazyFunctions bool              `json:"lazy_functions" yaml:"lazy_functions"`
+	File          string            `json:"file" yaml:"file"`
+	Aliases       map[string]string `json:"aliases" yaml:"aliases"`
+	Exports       []exportView      `json:"exports" yaml:"exports"`
+	// Read from the package gitconfig file, not from File
+	GitAliases []aliasView  `json:"git_aliases" yaml:"git_aliases"`
+	Manifest   manifestView `json:"manifest" yaml:"manifest"`
+	// Dependencies of the manifest, with the name of the package installed for each, empty when missing
+	Dependencies []dependencyView `json:"dependencies" yaml:"dependencies"`
+}
+
+type dependencyView struct {
+	Url         string `json:"url" yaml:"url"`
+	InstalledAs string `json:"installed_as" yaml:"installed_as"`
+}
+
+type manifestView struct {
+	Description   string   `json:"description,omitempty" yaml:"description,omitempty"`
+	Version       string   `json:"version,omitempty" yaml:"version,omitempty"`
+	Authors       []string `json:"authors,omitempty" yaml:"authors,omitempty"`
+	MinDuhVersion string   `json:"min_duh_version,omitempty" yaml:"min_duh_version,omitempty"`
+	Dependencies  []string `json:"dependencies,omitempty" yaml:"dependencies,omitempty"`
+	// Why the manifest is invalid, empty when it is valid
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+type functionView struct {
+	Name          string     `json:"name" yaml:"name"`
+	Usage         string     `json:"usage" yaml:"usage"`
+	Description   string     `json:"description" yaml:"description"`
+	Args          []argView  `json:"args" yaml:"args"`
+	Examples      []string   `json:"examples" yaml:"examples"`
+	Requires      []string   `json:"requires" yaml:"requires"`
+	Documentation []string   `json:"documentation" yaml:"documentation"`
+	Source        sourceView `json:"source" yaml:"source"`
+	// Required commands not found in the PATH, only checked by duh functions info
+	MissingRequirements []string `json:"missing_requirements,omitempty" yaml:"missing_requirements,omitempty"`
+}
+
+type argView struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description" yaml:"description"`
+}
+
+type warningView struct {
+	Line    int    `json:"line" yaml:"line"`
+	Details string `json:"details" yaml:"details"`
+}
+
+type scriptView struct {
+	Name      string         `json:"name" yaml:"name"`
+	Source    sourceView     `json:"source" yaml:"source"`
+	Functions []functionView `json:"functions" yaml:"functions"`
+	Warnings  []warningView  `json:"warnings" yaml:"warnings"`
+}
+
+// packageStatusView is the structured output of duh package status, one object per package
+type packageStatusView struct {
+	Name        string     `json:"name" yaml:"name"`
+	Enabled     bool       `json:"enabled" yaml:"enabled"`
+	Pin         string     `json:"pin,omitempty" yaml:"pin,omitempty"`
+	Git         bool       `json:"git" yaml:"git"`
+	LocalOnly   bool       `json:"local_only" yaml:"local_only"`
+	Remote      string     `json:"remote,omitempty" yaml:"remote,omitempty"`
+	Branch      string     `json:"branch,omitempty" yaml:"branch,omitempty"`
+	Commit      string     `json:"commit,omitempty" yaml:"commit,omitempty"`
+	Ahead       int        `json:"ahead" yaml:"ahead"`
+	Behind      int        `json:"behind" yaml:"behind"`
+	Modified    []string   `json:"modified" yaml:"modified"`
+	LastFetched *time.Time `json:"last_fetched,omitempty" yaml:"last_fetched,omitempty"`
+	FetchError  string     `json:"fetch_error,omitempty" yaml:"fetch_error,omitempty"`
+	// Why the status could not be read, the other fields except name, enabled and pin are then empty
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+type lintFindingView struct {
+	Package  string `json:"package" yaml:"package"`
+	File     string `json:"file" yaml:"file"`
+	Line     int    `json:"line" yaml:"line"`
+	Column   int    `json:"column" yaml:"column"`
+	Severity string `json:"severity" yaml:"severity"`
+	Rule     string `json:"rule" yaml:"rule"`
+	Message  string `json:"message" yaml:"message"`
+}
+
+func aliasViewsOf(aliases []entity.MergedAlias) []aliasView {
+	views := []aliasView{}
+	for _, alias := range aliases {
+		views = append(views, aliasView{Name: alias.Name, Value: alias.Value, Source: sourceViewOf(alias.Source)})
+	}
+	return views
+}
+
+func exportViewsOf(exports []entity.MergedExport) []exportView {
+	views := []exportView{}
+	for _, export := range exports {
+		sources := []sourceView{}
+		for _, source := range export.Sources {
+			sources = append(sources, sourceViewOf(source))
+		}
+		views = append(views, exportViewOf(export.Name, export.Export, sources))
+	}
+	return views
+}
+
+func exportViewOf(name string, export entity.Export, sources []sourceView) exportView {
+	return exportView{
+		Name:    name,
+		Value:   export.Value,
+		Literal: export.Literal,
+		Prepend: export.Prepend,
+		Append:  export.Append,
+		Sources: sources,
+	}
+}
+
+func sourceViewOf(source entity.Source) sourceView {
+	return sourceView{Package: source.Package, File: source.File}
+}
+
+func packageViewOf(info entity.PackageInfo) packageView {
+	pkg := info.Package
+	exports := []exportView{}
+	for _, name := range slices.Sorted(maps.Keys(pkg.Exports)) {
+		exports = append(exports, exportViewOf(name, pkg.Exports[name], []sourceView{sourceViewOf(pkg.Source())}))
+	}
+	gitAliases := []aliasView{}
+	for _, name := range slices.Sorted(maps.Keys(pkg.GitAliases)) {
+		gitAliases = append(gitAliases, aliasView{Name: name, Value: pkg.GitAliases[name], Source: sourceViewOf(pkg.GitAliasSource())})
+	}
+	dependencies := []dependencyView{}
+	for _, dependency := range info.Dependencies {
+		dependencies = append(dependencies, dependencyView{Url: dependency.Url, InstalledAs: dependency.InstalledAs})
+	}
+	return packageView{
+		Name:          pkg.Name,
+		Enabled:       info.Enabled,
+		Default:       info.Default,
+		Pin:           info.Pin,
+		LazyFunctions: pkg.LazyFunctions,
+		File:          pkg.DbFilePath,
+		Aliases:       nonNilMap(pkg.Aliases),
+		Exports:       exports,
+		GitAliases:    gitAliases,
+		Manifest: manifestView{
+			Description:   pkg.Manifest.Description,
+			Version:       pkg.Manifest.Version,
+			Authors:       pkg.Manifest.Authors,
+			MinDuhVersion: pkg.Manifest.MinDuhVersion,
+			Dependencies:  pkg.Manifest.Dependencies,
+			Error:         errorMessage(info.ManifestError),
+		},
+		Dependencies: dependencies,
+	}
+}
+
+func scriptViewOf(script entity.Script) scriptView {
+	view := scriptView{
+		Name:      script.Name,
+		Source:    scriptSource(script),
+		Functions: []functionView{},
+		Warnings:  []warningView{},
+	}
+	for _, function := range script.Functions {
+		view.Functions = append(view.Functions, functionViewOf(script, function))
+	}
+	for _, warning := range script.Warnings {
+		view.Warnings = append(view.Warnings, warningView{Line: warning.Line, Details: warning.Details})
+	}
+	return view
+}
+
+func functionViewOf(script entity.Script, function entity.Function) functionView {
+	args := []argView{}
+	for _, arg := range function.Args {
+		args = append(args, argView{Name: arg.Name, Description: arg.Description})
+	}
+	return functionView{
+		Name:          function.Name,
+		Usage:         function.Usage(),
+		Description:   function.Description,
+		Args:          args,
+		Examples:      nonNilSlice(function.Examples),
+		Requires:      nonNilSlice(function.Requires),
+		Documentation: nonNilSlice(function.Documentation),
+		Source:        scriptSource(script),
+	}
+}
+
+// scriptSource is empty for the scripts embedded in duh, which belong to no package
+func scriptSource(script entity.Script) sourceView {
+	return sourceView{Package: script.Package, File: script.PathToFile}
+}
+
+func packageStatusViewOf(status entity.PackageStatus) packageStatusView {
+	view := packageStatusView{
+		Name:       status.Name,
+		Enabled:    status.Enabled,
+		Pin:        status.Pin,
+		Git:        status.Git,
+		LocalOnly:  status.Error == nil && status.LocalOnly(),
+		Remote:     status.Remote,
+		Branch:     status.Branch,
+		Commit:     status.Commit,
+		Ahead:      status.Ahead,
+		Behind:     status.Behind,
+		Modified:   nonNilSlice(status.Modified),
+		FetchError: errorMessage(status.FetchError),
+		Error:      errorMessage(status.Error),
+	}
+	if !status.LastFetched.IsZero() {
+		view.LastFetched = &status.LastFetched
+	}
+	return view
+}
+
+func lintFindingViewOf(finding entity.LintFinding) lintFindingView {
+	return lintFindingView{
+		Package:  finding.Package,
+		File:     finding.File,
+		Line:     finding.Line,
+		Column:   finding.Column,
+		Severity: finding.Severity,
+		Rule:     finding.Rule,
+		Message:  finding.Message,
+	}
+}
+
+// errorMessage is the message of err, empty when it is nil
+func errorMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func nonNilSlice(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+func nonNilMap(values map[string]string) map[string]string {
+	if values == nil {
+		return map[string]string{}
+	}
+	return values
+}
@@ -1,8 +1,12 @@
 package handler
 
 import (
+	"bufio"
 	"duh/internal/application/usecase"
 	"duh/internal/domain/entity"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -18,6 +22,26 @@ func NewPackageHandler(packageUsecase *usecase.PackageUsecase) *PackageHandler {
 }
 
 func (p *PackageHandler) ListPackages(cmd *cobra.Command, args []string) {
+	format, err := outputFormat(cmd)
+	if err != nil {
+		cmd.PrintErrf("Error listing packages: %v\n", err)
+		return
+	}
+	if format != OutputTable {
+		infos, err := p.packageUsecase.ListPackagesInfo()
+		if err == nil {
+			views := []packageView{}
+			for _, info := range infos {
+				views = append(views, packageViewOf(info))
+			}
+			err = printStructured(cmd, format, views)
+		}
+		if err != nil {
+			cmd.PrintErrf("Error listing packages: %v\n", err)
+		}
+		return
+	}
+
 	packages, err := p.packageUsecase.ListPackages()
 	if err != nil {
 		cmd.PrintErrf("Error listing packages: %v\n", err)
@@ -121,7 +145,10 @@ func (p *PackageHandler) AddPackage(cmd *cobra.Command, args []string) {
 	if len(args) == 2 {
 		name = &args[1]
 	}
-	err := p.packageUsecase.AddPackage(url, name)
+	dependencies, err := p.packageUsecase.AddPackage(url, name)
+	for _, dependency := range dependencies {
+		cmd.Printf("Dependency '%s' added and enabled\n", dependency)
+	}
 	if err != nil {
 		cmd.PrintErrf("Error adding package: %v\n", err)
 		return
@@ -129,6 +156,67 @@ func (p *PackageHandler) AddPackage(cmd *cobra.Command, args []string) {
 	cmd.Printf("Package '%s' added and enabled\n", url)
 }
 
+func (p *PackageHandler) PackageInfo(cmd *cobra.Command, args []string) {
+	format, err := outputFormat(cmd)
+	if err != nil {
+		cmd.PrintErrf("Error getting package info: %v\n", err)
+		return
+	}
+	info, err := p.packageUsecase.GetPackageInfo(args[0])
+	if err != nil {
+		cmd.PrintErrf("Error getting package info: %v\n", err)
+		return
+	}
+	if format != OutputTable {
+		if err := printStructured(cmd, format, packageViewOf(*info)); err != nil {
+			cmd.PrintErrf("Error getting package info: %v\n", err)
+		}
+		return
+	}
+
+	manifest := info.Package.Manifest
+	status := "disabled"
+	if info.Enabled {
+		status = "enabled"
+	}
+	if info.Default {
+		status += ", default"
+	}
+	cmd.Printf("Name:            %s\n", info.Package.Name)
+	cmd.Printf("Status:          %s\n", status)
+	cmd.Printf("Pinned to:       %s\n", valueOrDash(info.Pin))
+	cmd.Printf("Lazy functions:  %t\n", info.Package.LazyFunctions)
+	cmd.Printf("Version:         %s\n", valueOrDash(manifest.Version))
+	cmd.Printf("Description:     %s\n", valueOrDash(manifest.Description))
+	cmd.Printf("Authors:         %s\n", valueOrDash(strings.Join(manifest.Authors, ", ")))
+	cmd.Printf("Min duh version: %s\n", valueOrDash(manifest.MinDuhVersion))
+	if info.ManifestError != nil {
+		cmd.Printf("⚠️  %v\n", info.ManifestError)
+	}
+	cmd.Printf("Content:         %d aliases, %d exports, %d git aliases\n",
+		len(info.Package.Aliases), len(info.Package.Exports), len(info.Package.GitAliases))
+
+	if len(info.Dependencies) == 0 {
+		cmd.Println("Dependencies:    none")
+		return
+	}
+	cmd.Println("Dependencies:")
+	for _, dependency := range info.Dependencies {
+		if dependency.InstalledAs == "" {
+			cmd.Printf("  ✗ %s (missing)\n", dependency.Url)
+		} else {
+			cmd.Printf("  ✓ %s (installed as '%s')\n", dependency.Url, dependency.InstalledAs)
+		}
+	}
+}
+
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
 func (p *PackageHandler) CreatePackage(cmd *cobra.Command, args []string) {
 	packageName := args[0]
 	err := p.packageUsecase.CreatePackage(packageName)
@@ -143,22 +231,75 @@ func (p *PackageHandler) UpdatePackages(cmd *cobra.Command, args []string) {
 	// Determine strategy based on flags
 	forceFlag, _ := cmd.Flags().GetBool("force")
 	commitFlag, _ := cmd.Flags().GetBool("commit")
+	upgradeFlag, _ := cmd.Flags().GetBool("upgrade")
+
+	rebaseFlag, _ := cmd.Flags().GetBool("rebase")
 
 	strategy := entity.UpdateSafe // default strategy
 	if forceFlag && commitFlag {
 		cmd.PrintErrf("Cannot use both --force and --commit flags together\n")
 		return
+	} else if rebaseFlag && (forceFlag || commitFlag) {
+		cmd.PrintErrf("Cannot use --rebase with --force or --commit\n")
+		return
 	} else if forceFlag {
 		strategy = entity.UpdateForce
 	} else if commitFlag {
 		strategy = entity.UpdateKeep
+	} else if rebaseFlag {
+		strategy = entity.UpdateRebase
+	}
+
+	if dryRunFlag, _ := cmd.Flags().GetBool("dry-run"); dryRunFlag {
+		if upgradeFlag || forceFlag || commitFlag || rebaseFlag {
+			cmd.PrintErrf("Cannot use --dry-run with --force, --commit, --rebase or --upgrade\n")
+			return
+		}
+		p.previewUpdates(cmd)
+		return
 	}
 
-	results, err := p.packageUsecase.UpdatePackages(strategy)
+	jobs, _ := cmd.Flags().GetInt("jobs")
+	results, err := p.packageUsecase.UpdatePackages(entity.UpdateOptions{
+		Strategy:   strategy,
+		Upgrade:    upgradeFlag,
+		Jobs:       jobs,
+		OnProgress: func(progress entity.UpdateProgress) { printUpdateProgress(cmd, progress) },
+		Resolve:    conflictPrompt(cmd),
+	})
 	if err != nil {
 		cmd.PrintErrf("Error updating packages: %v\n", err)
 		return
 	}
+	cmd.Println()
+
+	if len(results.UpgradedPins) > 0 {
+		cmd.Println("⬆️  Pins upgraded:")
+		for _, upgrade := range results.UpgradedPins {
+			cmd.Printf("  • %s: %s → %s\n", upgrade.Package, upgrade.From, upgrade.To)
+		}
+	}
+
+	if len(results.Updated) > 0 {
+		cmd.Println("🔄 Updated packages:")
+		for _, update := range results.Updated {
+			cmd.Printf("  • %s: updated from %s to %s\n", update.Package, update.From, update.To)
+		}
+	}
+
+	if len(results.UpToDate) > 0 {
+		cmd.Println("✓ Already up to date:")
+		for _, pkg := range results.UpToDate {
+			cmd.Printf("  • %s\n", pkg)
+		}
+	}
+
+	if len(results.Skipped) > 0 {
+		cmd.Println("⏭️  Skipped (no git remote):")
+		for _, pkg := range results.Skipped {
+			cmd.Printf("  • %s\n", pkg)
+		}
+	}
 
 	// Report results
 	if len(results.LocalChangesDetected) > 0 {
@@ -166,7 +307,7 @@ func (p *PackageHandler) UpdatePackages(cmd *cobra.Command, args []string) {
 		for _, pkg := range results.LocalChangesDetected {
 			cmd.Printf("  • %s\n", pkg)
 		}
-		cmd.Println("\nUse --commit to auto-commit changes or --force to discard them.")
+		cmd.Println("\nUse --commit to auto-commit changes, --rebase to replay them on the remote or --force to discard them.")
 	}
 
 	if len(results.OtherErrors) > 0 {
@@ -184,6 +325,316 @@ func (p *PackageHandler) UpdatePackages(cmd *cobra.Command, args []string) {
 	}
 }
 
+func (p *PackageHandler) previewUpdates(cmd *cobra.Command) {
+	preview, err := p.packageUsecase.PreviewUpdates()
+	if err != nil {
+		cmd.PrintErrf("Error previewing updates: %v\n", err)
+		return
+	}
+
+	for _, pkg := range preview.Packages {
+		cmd.Printf("📦 %s: %s → %s\n", pkg.Package, entity.ShortCommit(pkg.From), entity.ShortCommit(pkg.To))
+		if len(pkg.Changes) == 0 {
+			cmd.Println("  No change to aliases, exports, functions or git aliases")
+		}
+		for _, change := range pkg.Changes {
+			cmd.Printf("  %s %s\n", changeSymbol(change.Action), change)
+		}
+	}
+
+	if len(preview.UpToDate) > 0 {
+		cmd.Println("✓ Already up to date:")
+		for _, pkg := range preview.UpToDate {
+			cmd.Printf("  • %s\n", pkg)
+		}
+	}
+
+	if len(preview.Skipped) > 0 {
+		cmd.Println("⏭️  Skipped (no git remote):")
+		for _, pkg := range preview.Skipped {
+			cmd.Printf("  • %s\n", pkg)
+		}
+	}
+
+	if len(preview.Errors) > 0 {
+		cmd.Println("❌ Packages with errors:")
+		for _, err := range preview.Errors {
+			cmd.Printf("  • %v\n", err)
+		}
+	}
+
+	cmd.Println("\nDry run, nothing was pulled. Run duh package update to apply these changes.")
+}
+
+func changeSymbol(action string) string {
+	switch action {
+	case entity.ChangeAdded:
+		return "+"
+	case entity.ChangeRemoved:
+		return "-"
+	default:
+		return "~"
+	}
+}
+
+// conflictPrompt asks on the command input which side of each merge conflict to keep
+func conflictPrompt(cmd *cobra.Command) func(entity.MergeConflict) (string, error) {
+	reader := bufio.NewReader(cmd.InOrStdin())
+	return func(conflict entity.MergeConflict) (string, error) {
+		cmd.Printf("⚠️  %s: '%s' changed both locally and on the remote in %s\n", conflict.Package, conflict.Key, conflict.File)
+		cmd.Printf("  local:  %s\n", valueOrRemoved(conflict.Local))
+		cmd.Printf("  remote: %s\n", valueOrRemoved(conflict.Remote))
+		for {
+			cmd.Print("Keep [l]ocal or [r]emote? ")
+			answer, err := reader.ReadString('\n')
+			switch strings.ToLower(strings.TrimSpace(answer)) {
+			case "l", "local":
+				return entity.KeepLocal, nil
+			case "r", "remote":
+				return entity.KeepRemote, nil
+			}
+			if err != nil {
+				cmd.Println()
+				return "", fmt.Errorf("no choice made for '%s'", conflict.Key)
+			}
+		}
+	}
+}
+
+func valueOrRemoved(value string) string {
+	if value == "" {
+		return "(removed)"
+	}
+	return value
+}
+
+// printUpdateProgress prints a line when a package starts or finishes its update
+func printUpdateProgress(cmd *cobra.Command, progress entity.UpdateProgress) {
+	switch progress.Status {
+	case "":
+		cmd.Printf("⏳ %s: updating...\n", progress.Package)
+	case entity.UpdateStatusUpdated:
+		cmd.Printf("🔄 %s: updated %s\n", progress.Package, progress.Details)
+	case entity.UpdateStatusUpToDate:
+		cmd.Printf("✓ %s: already up to date\n", progress.Package)
+	case entity.UpdateStatusSkipped:
+		cmd.Printf("⏭️  %s: skipped, no git remote\n", progress.Package)
+	case entity.UpdateStatusLocalChanges:
+		cmd.Printf("⚠️  %s: local changes, not updated\n", progress.Package)
+	case entity.UpdateStatusFailed:
+		cmd.Printf("❌ %s: failed\n", progress.Package)
+	}
+}
+
+func (p *PackageHandler) PackagesStatus(cmd *cobra.Command, args []string) error {
+	noFetch, _ := cmd.Flags().GetBool("no-fetch")
+	format, err := outputFormat(cmd)
+	if err != nil {
+		return fmt.Errorf("error getting packages status: %w", err)
+	}
+	if asJson, _ := cmd.Flags().GetBool("json"); asJson {
+		format = OutputJson
+	}
+	statuses, err := p.packageUsecase.GetPackagesStatus(!noFetch)
+	if err != nil {
+		return fmt.Errorf("error getting packages status: %w", err)
+	}
+
+	if format != OutputTable {
+		output := []packageStatusView{}
+		for _, status := range statuses {
+			output = append(output, packageStatusViewOf(status))
+		}
+		if err := printStructured(cmd, format, output); err != nil {
+			return fmt.Errorf("error encoding packages status: %w", err)
+		}
+		return unreadableStatuses(statuses)
+	}
+
+	if len(statuses) == 0 {
+		cmd.Println("No packages found")
+		return nil
+	}
+	for _, status := range statuses {
+		printPackageStatus(cmd, status)
+	}
+	return unreadableStatuses(statuses)
+}
+
+// unreadableStatuses fails the command when the status of a package could not be read, once all are printed
+func unreadableStatuses(statuses []entity.PackageStatus) error {
+	unreadable := 0
+	for _, status := range statuses {
+		if status.Error != nil {
+			unreadable++
+		}
+	}
+	if unreadable > 0 {
+		return fmt.Errorf("the status of %d packages could not be read", unreadable)
+	}
+	return nil
+}
+
+func printPackageStatus(cmd *cobra.Command, status entity.PackageStatus) {
+	marker := "✗"
+	if status.Enabled {
+		marker = "✓"
+	}
+	if status.Error != nil {
+		cmd.Printf("%s %s\n", marker, status.Name)
+		cmd.Printf("    ❌ status failed: %v\n", status.Error)
+		return
+	}
+	if !status.Git {
+		cmd.Printf("%s %s (not a git repository)\n", marker, status.Name)
+		return
+	}
+
+	details := []string{}
+	if status.LocalOnly() {
+		details = append(details, "local only")
+	}
+	switch {
+	case status.Commit == "":
+		details = append(details, "no commit yet")
+	case status.Branch == "":
+		details = append(details, "detached at "+entity.ShortCommit(status.Commit))
+	default:
+		details = append(details, fmt.Sprintf("%s @ %s", status.Branch, entity.ShortCommit(status.Commit)))
+	}
+	if status.Pin != "" {
+		details = append(details, "pinned to "+status.Pin)
+	}
+	cmd.Printf("%s %s (%s)\n", marker, status.Name, strings.Join(details, ", "))
+
+	if !status.LocalOnly() {
+		cmd.Printf("    remote:       %s\n", status.Remote)
+		cmd.Printf("    commits:      %d ahead, %d behind\n", status.Ahead, status.Behind)
+	}
+	if len(status.Modified) > 0 {
+		cmd.Printf("    modified:     %s\n", strings.Join(status.Modified, ", "))
+	}
+	if !status.LocalOnly() {
+		cmd.Printf("    last fetched: %s\n", fetchedAgo(status.LastFetched, time.Now()))
+	}
+	if status.FetchError != nil {
+		cmd.Printf("    ⚠️  fetch failed: %v\n", status.FetchError)
+	}
+}
+
+// fetchedAgo tells how long ago a remote was fetched, in the largest suitable unit
+func fetchedAgo(fetched time.Time, now time.Time) string {
+	if fetched.IsZero() {
+		return "never"
+	}
+	elapsed := now.Sub(fetched)
+	switch {
+	case elapsed < time.Minute:
+		return "just now"
+	case elapsed < time.Hour:
+		return ago(int(elapsed/time.Minute), "minute")
+	case elapsed < 24*time.Hour:
+		return ago(int(elapsed/time.Hour), "hour")
+	default:
+		return ago(int(elapsed/(24*time.Hour)), "day")
+	}
+}
+
+func ago(count int, unit string) string {
+	if count == 1 {
+		return fmt.Sprintf("1 %s ago", unit)
+	}
+	return fmt.Sprintf("%d %ss ago", count, unit)
+}
+
+func (p *PackageHandler) PackageLog(cmd *cobra.Command, args []string) {
+	limit, _ := cmd.Flags().GetInt("max-count")
+	history, err := p.packageUsecase.GetPackageHistory(args[0], limit)
+	if err != nil {
+		cmd.PrintErrf("Error getting package history: %v\n", err)
+		return
+	}
+	if len(history) == 0 {
+		cmd.Printf("Package '%s' has no git history\n", args[0])
+		return
+	}
+
+	for _, entry := range history {
+		commit := entry.Commit
+		cmd.Printf("%s  %s  %s  %s\n", entity.ShortCommit(commit.Hash), commit.Date.Format(time.DateOnly), commit.Author, commit.Subject)
+		for _, change := range entry.Changes {
+			cmd.Printf("    %s %s\n", changeSymbol(change.Action), change)
+		}
+	}
+}
+
+func (p *PackageHandler) RollbackPackage(cmd *cobra.Command, args []string) {
+	ref := ""
+	if len(args) > 1 {
+		ref = args[1]
+	}
+	// The injection diff is rendered for the shell of the user
+	rollback, err := p.packageUsecase.RollbackPackage(args[0], ref, "")
+	if err != nil {
+		cmd.PrintErrf("Error rolling back package: %v\n", err)
+		return
+	}
+
+	cmd.Printf("⏪ %s: restored the files of %s (was at %s)\n", rollback.Package, entity.ShortCommit(rollback.To), entity.ShortCommit(rollback.From))
+	if len(rollback.InjectionDiff) == 0 {
+		cmd.Println("  No change to the injection of the package")
+	}
+	for _, line := range rollback.InjectionDiff {
+		cmd.Printf("  %s\n", line)
+	}
+	cmd.Println("\nThe rollback is a new commit, push it with duh package push to share it.")
+}
+
+func (p *PackageHandler) PinPackage(cmd *cobra.Command, args []string) {
+	packageName := args[0]
+	ref := args[1]
+	err := p.packageUsecase.PinPackage(packageName, ref)
+	if err != nil {
+		cmd.PrintErrf("Error pinning package: %v\n", err)
+		return
+	}
+	cmd.Printf("Package '%s' pinned to '%s'\n", packageName, ref)
+}
+
+func (p *PackageHandler) UnpinPackage(cmd *cobra.Command, args []string) {
+	packageName := args[0]
+	err := p.packageUsecase.UnpinPackage(packageName)
+	if err != nil {
+		cmd.PrintErrf("Error unpinning package: %v\n", err)
+		return
+	}
+	cmd.Printf("Package '%s' unpinned, it follows its remote again\n", packageName)
+}
+
+func (p *PackageHandler) SetLazyFunctions(cmd *cobra.Command, args []string) {
+	packageName := args[0]
+	var lazy bool
+	switch args[1] {
+	case "on":
+		lazy = true
+	case "off":
+		lazy = false
+	default:
+		cmd.PrintErrf("Error: expected 'on' or 'off', got '%s'\n", args[1])
+		return
+	}
+	err := p.packageUsecase.SetLazyFunctions(packageName, lazy)
+	if err != nil {
+		cmd.PrintErrf("Error changing lazy loading: %v\n", err)
+		return
+	}
+	if lazy {
+		cmd.Printf("Functions of package '%s' are now loaded on their first call\n", packageName)
+	} else {
+		cmd.Printf("Functions of package '%s' are now loaded at shell startup\n", packageName)
+	}
+}
+
 func (p *PackageHandler) EditPackage(cmd *cobra.Command, args []string) {
 	packageName := args[0]
 	err := p.packageUsecase.EditPackage(packageName)
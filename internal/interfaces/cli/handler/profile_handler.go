@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"duh/internal/application/usecase"
+	"duh/internal/domain/entity"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type ProfileHandler struct {
+	profileUsecase *usecase.ProfileUsecase
+}
+
+func NewProfileHandler(profileUsecase *usecase.ProfileUsecase) *ProfileHandler {
+	return &ProfileHandler{
+		profileUsecase: profileUsecase,
+	}
+}
+
+func (p *ProfileHandler) ListProfiles(cmd *cobra.Command, args []string) {
+	profiles, active, err := p.profileUsecase.ListProfiles()
+	if err != nil {
+		cmd.PrintErrf("Error listing profiles: %v\n", err)
+		return
+	}
+
+	printProfileWarning(cmd, active)
+	for _, profile := range profiles {
+		marker := " "
+		if profile.Name == active.Name {
+			marker = "*"
+		}
+		cmd.Printf("%s %s: %s (default package: %s)\n",
+			marker,
+			profile.Name,
+			strings.Join(profile.EnabledPackages, ", "),
+			profile.DefaultPackage,
+		)
+		if len(profile.Hostnames) > 0 {
+			cmd.Printf("    hostnames: %s\n", strings.Join(profile.Hostnames, ", "))
+		}
+	}
+}
+
+func (p *ProfileHandler) CurrentProfile(cmd *cobra.Command, args []string) {
+	active, err := p.profileUsecase.GetActiveProfile()
+	if err != nil {
+		cmd.PrintErrf("Error getting active profile: %v\n", err)
+		return
+	}
+	printProfileWarning(cmd, active)
+	cmd.Printf("%s (%s)\n", active.Name, active.Reason)
+}
+
+// printProfileWarning tells why the profile asked for is not the active one
+func printProfileWarning(cmd *cobra.Command, active entity.ActiveProfile) {
+	if active.Warning != "" {
+		cmd.PrintErrf("⚠️  %s\n", active.Warning)
+	}
+}
+
+func (p *ProfileHandler) CreateProfile(cmd *cobra.Command, args []string) {
+	profileName := args[0]
+	hostnames, _ := cmd.Flags().GetStringSlice("hostname")
+	err := p.profileUsecase.CreateProfile(profileName, hostnames)
+	if err != nil {
+		cmd.PrintErrf("Error creating profile: %v\n", err)
+		return
+	}
+	cmd.Printf("Profile '%s' created\n", profileName)
+}
+
+func (p *ProfileHandler) UseProfile(cmd *cobra.Command, args []string) {
+	profileName := args[0]
+	active, err := p.profileUsecase.UseProfile(profileName)
+	if err != nil {
+		cmd.PrintErrf("Error using profile: %v\n", err)
+		return
+	}
+	cmd.Printf("Profile '%s' is now used on this machine\n", profileName)
+	if active.Name != profileName && active.Reason == entity.ProfileSelectedByEnv {
+		cmd.Printf("⚠️  %s is set, so profile '%s' stays active in this shell\n", entity.ProfileEnvVar, active.Name)
+	}
+}
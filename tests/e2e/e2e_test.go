@@ -5,6 +5,7 @@ package e2e
 import (
 	"bytes"
 	"duh/cmd/cli/context"
+	"encoding/json"
 
 	"os"
 	"path/filepath"
@@ -27,6 +28,13 @@ func init() {
 
 // executeCommand is a helper to run CLI commands and capture output
 func executeCommand(args []string) (string, error) {
+	stdoutOutput, stderrOutput, err := executeCommandOutputs(args)
+	// Combine outputs for backward compatibility
+	return stdoutOutput + stderrOutput, err
+}
+
+// executeCommandOutputs runs a CLI command and captures its stdout and stderr apart
+func executeCommandOutputs(args []string) (string, string, error) {
 	// Capture real stdout/stderr for commands that write directly to os.Stdout
 	oldStdout := os.Stdout
 	oldStderr := os.Stderr
@@ -73,9 +81,7 @@ func executeCommand(args []string) (string, error) {
 	os.Stdout = oldStdout
 	os.Stderr = oldStderr
 
-	// Combine outputs for backward compatibility
-	combined := stdoutOutput + stderrOutput
-	return combined, err
+	return stdoutOutput, stderrOutput, err
 }
 
 // Test_E2E_Complete tests the full duh CLI workflow end-to-end
@@ -126,6 +132,34 @@ func Test_E2E_Complete(t *testing.T) {
 		assert.Contains(t, output, "BROWSER=firefox")
 	})
 
+	t.Run("exports with path lists and literal values", func(t *testing.T) {
+		output, err := executeCommand([]string{"exports", "set", "PATH", "$HOME/bin", "--prepend"})
+		assert.NoError(t, err)
+		assert.Contains(t, output, "Export 'PATH' prepended with '$HOME/bin'")
+
+		output, err = executeCommand([]string{"exports", "set", "PATH", "/opt/bin", "--append"})
+		assert.NoError(t, err)
+		assert.Contains(t, output, "Export 'PATH' appended with '/opt/bin'")
+
+		output, err = executeCommand([]string{"exports", "set", "TOKEN", "a$b", "--literal"})
+		assert.NoError(t, err)
+
+		output, err = executeCommand([]string{"exports", "list"})
+		assert.NoError(t, err)
+		assert.Contains(t, output, "PATH=[prepend: $HOME/bin] [append: /opt/bin]")
+		assert.Contains(t, output, "TOKEN=a$b (literal)")
+
+		output, err = executeCommand([]string{"inject"})
+		assert.NoError(t, err)
+		assert.Contains(t, output, `PATH="$HOME/bin${PATH:+:${PATH}}"`)
+		assert.Contains(t, output, `export TOKEN='a$b'`)
+
+		output, err = executeCommand([]string{"exports", "unset", "PATH"})
+		assert.NoError(t, err)
+		output, err = executeCommand([]string{"exports", "unset", "TOKEN"})
+		assert.NoError(t, err)
+	})
+
 	t.Run("inject command generates correct output", func(t *testing.T) {
 		output, err := executeCommand([]string{"inject"})
 		assert.NoError(t, err)
@@ -137,6 +171,28 @@ func Test_E2E_Complete(t *testing.T) {
 		assert.Contains(t, output, "export BROWSER=\"firefox\"")
 	})
 
+	t.Run("inject command renders the requested shell", func(t *testing.T) {
+		output, err := executeCommand([]string{"inject", "--shell", "fish"})
+		assert.NoError(t, err)
+		assert.Contains(t, output, "alias ll \"ls -la\"")
+		assert.Contains(t, output, "set -gx EDITOR \"vim\"")
+		assert.Contains(t, output, "alias duh_reload 'duh inject --quiet --shell fish | source'")
+
+		output, err = executeCommand([]string{"inject", "--shell", "zsh"})
+		assert.NoError(t, err)
+		assert.Contains(t, output, "alias ll=\"ls -la\"")
+		assert.Contains(t, output, "--shell zsh")
+
+		output, err = executeCommand([]string{"inject", "--shell", "bash", "--explain"})
+		assert.NoError(t, err)
+		assert.Contains(t, output, "# package 'local'")
+		assert.Contains(t, output, "alias ll=\"ls -la\" # from 'local'")
+
+		output, err = executeCommand([]string{"inject", "--shell", "powershell"})
+		assert.NoError(t, err)
+		assert.Contains(t, output, "unsupported shell 'powershell'")
+	})
+
 	t.Run("remove aliases", func(t *testing.T) {
 		// Remove an alias
 		output, err := executeCommand([]string{"alias", "unset", "ll"})
@@ -172,6 +228,12 @@ func Test_E2E_Complete(t *testing.T) {
 		assert.NotContains(t, output, "export EDITOR=")
 		assert.Contains(t, output, "alias gs=\"git status\"")
 		assert.Contains(t, output, "export BROWSER=\"firefox\"")
+
+		// Rebuilding without the cache gives the same injection
+		cached := output
+		output, err = executeCommand([]string{"inject", "--no-cache"})
+		assert.NoError(t, err)
+		assert.Equal(t, cached, output)
 	})
 
 	t.Run("package management", func(t *testing.T) {
@@ -221,6 +283,158 @@ func Test_E2E_Complete(t *testing.T) {
 		executeCommand([]string{"package", "delete", "emptypackage"})
 	})
 
+	t.Run("doctor conflicts", func(t *testing.T) {
+		output, err := executeCommand([]string{"doctor", "conflicts"})
+		assert.NoError(t, err)
+		assert.Contains(t, output, "No conflicts between enabled packages")
+
+		// Define the same alias in a second enabled package
+		_, err = executeCommand([]string{"package", "create", "conflicting"})
+		assert.NoError(t, err)
+		_, err = executeCommand([]string{"package", "default", "set", "conflicting"})
+		assert.NoError(t, err)
+		_, err = executeCommand([]string{"alias", "set", "gs", "git status -sb"})
+		assert.NoError(t, err)
+
+		output, err = executeCommand([]string{"doctor", "conflicts"})
+		assert.Error(t, err)
+		assert.Contains(t, output, "alias 'gs' defined by local, conflicting → 'conflicting' wins")
+
+		// Clean up
+		executeCommand([]string{"package", "default", "set", "local"})
+		executeCommand([]string{"package", "delete", "conflicting"})
+
+		output, err = executeCommand([]string{"doctor", "conflicts"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("profiles", func(t *testing.T) {
+		output, err := executeCommand([]string{"profile", "current"})
+		assert.NoError(t, err)
+		assert.Contains(t, output, "default (no other profile selected)")
+
+		output, err = executeCommand([]string{"profile", "create", "work", "--hostname", "corp-*"})
+		assert.NoError(t, err)
+		assert.Contains(t, output, "Profile 'work' created")
+
+		output, err = executeCommand([]string{"profile", "use", "work"})
+		assert.NoError(t, err)
+		assert.Contains(t, output, "Profile 'work' is now used on this machine")
+
+		// Packages enabled in the work profile are not enabled in the default profile
+		_, err = executeCommand([]string{"package", "create", "workpkg"})
+		assert.NoError(t, err)
+		output, err = executeCommand([]string{"package", "list"})
+		assert.NoError(t, err)
+		assert.Contains(t, output, "✓ workpkg")
+
+		output, err = executeCommand([]string{"profile", "list"})
+		assert.NoError(t, err)
+		assert.Contains(t, output, "* work: local, workpkg (default package: local)")
+		assert.Contains(t, output, "hostnames: corp-*")
+
+		t.Setenv("DUH_PROFILE", "default")
+		output, err = executeCommand([]string{"profile", "current"})
+		assert.NoError(t, err)
+		assert.Contains(t, output, "default (selected by DUH_PROFILE)")
+		output, err = executeCommand([]string{"package", "list"})
+		assert.NoError(t, err)
+		assert.Contains(t, output, "✗ workpkg")
+
+		// An unknown profile falls back to the default one instead of failing
+		t.Setenv("DUH_PROFILE", "unknown")
+		output, err = executeCommand([]string{"profile", "current"})
+		assert.NoError(t, err)
+		assert.Contains(t, output, "profile 'unknown' from DUH_PROFILE does not exist, using the default profile")
+		assert.Contains(t, output, "default (no other profile selected)")
+		output, err = executeCommand([]string{"inject", "--no-cache"})
+		assert.NoError(t, err)
+		assert.Contains(t, output, "echo 'duh: profile '\\''unknown'\\'' from DUH_PROFILE does not exist")
+
+		// Clean up
+		_, err = executeCommand([]string{"profile", "use", "default"})
+		assert.NoError(t, err)
+		executeCommand([]string{"package", "delete", "workpkg"})
+	})
+
+	t.Run("package info", func(t *testing.T) {
+		output, err := executeCommand([]string{"package", "info", "local"})
+		assert.NoError(t, err)
+		assert.Contains(t, output, "Name:            local")
+		assert.Contains(t, output, "Status:          enabled, default")
+		assert.Contains(t, output, "Dependencies:    none")
+
+		output, _ = executeCommand([]string{"package", "info", "unknown"})
+		assert.Contains(t, output, "resource not found: package with ID unknown")
+	})
+
+	t.Run("structured output", func(t *testing.T) {
+		_, err := executeCommand([]string{"alias", "set", "jq2", "jq ."})
+		require.NoError(t, err)
+		defer executeCommand([]string{"alias", "unset", "jq2"})
+
+		output, err := executeCommand([]string{"alias", "list", "--output", "json"})
+		assert.NoError(t, err)
+		var aliases []struct {
+			Name   string `json:"name"`
+			Value  string `json:"value"`
+			Source struct {
+				Package string `json:"package"`
+				File    string `json:"file"`
+			} `json:"source"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(output), &aliases))
+		found := false
+		for _, alias := range aliases {
+			if alias.Name == "jq2" {
+				found = true
+				assert.Equal(t, "jq .", alias.Value)
+				assert.Equal(t, "local", alias.Source.Package)
+				assert.Equal(t, filepath.Join(duhPath, "packages", "local", "db.toml"), alias.Source.File)
+			}
+		}
+		assert.True(t, found)
+
+		output, err = executeCommand([]string{"package", "info", "local", "-o", "yaml"})
+		assert.NoError(t, err)
+		assert.Contains(t, output, "name: local")
+		assert.Contains(t, output, "jq2: jq .")
+		assert.Contains(t, output, "git_aliases: []")
+
+		output, _ = executeCommand([]string{"alias", "list", "--output", "xml"})
+		assert.Contains(t, output, "unsupported output format 'xml'")
+
+		// Commands without structured output reject the flag instead of ignoring it
+		_, err = executeCommand([]string{"alias", "set", "jq3", "jq .", "-o", "json"})
+		assert.ErrorContains(t, err, "unknown shorthand flag: 'o'")
+	})
+
+	t.Run("lock and sync", func(t *testing.T) {
+		output, err := executeCommand([]string{"sync", "--locked"})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "no lockfile found, run 'duh lock' first")
+
+		_, err = executeCommand([]string{"package", "create", "lockedpkg"})
+		assert.NoError(t, err)
+		output, err = executeCommand([]string{"lock"})
+		assert.NoError(t, err)
+		assert.Contains(t, output, "✓ local: created locally")
+		assert.Contains(t, output, "✓ lockedpkg: created locally")
+
+		// Syncing enables the packages recorded as enabled again
+		_, err = executeCommand([]string{"package", "disable", "lockedpkg"})
+		assert.NoError(t, err)
+		output, err = executeCommand([]string{"sync", "--locked"})
+		assert.NoError(t, err)
+		assert.Contains(t, output, "✅ Packages synced with the lockfile")
+		output, err = executeCommand([]string{"package", "list"})
+		assert.NoError(t, err)
+		assert.Contains(t, output, "✓ lockedpkg")
+
+		// Clean up
+		executeCommand([]string{"package", "delete", "lockedpkg"})
+	})
+
 	t.Run("function management", func(t *testing.T) {
 		// Test listing functions (should show available functions)
 		_, err := executeCommand([]string{"function", "list"})
@@ -290,6 +504,49 @@ func Test_E2E_Complete(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Contains(t, output, "not found")
 	})
+
+	t.Run("failing lint keeps structured output parseable", func(t *testing.T) {
+		scriptPath := filepath.Join(duhPath, "packages", "local", "functions", "broken.sh")
+		require.NoError(t, os.MkdirAll(filepath.Dir(scriptPath), 0755))
+		require.NoError(t, os.WriteFile(scriptPath, []byte("# Leaves the shell\nbroken() {\n  exit 1\n}\n"), 0644))
+		defer os.Remove(scriptPath)
+
+		stdout, _, err := executeCommandOutputs([]string{"functions", "lint", "-o", "json"})
+		assert.Error(t, err)
+		var findings []struct {
+			Rule     string `json:"rule"`
+			Severity string `json:"severity"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(stdout), &findings), stdout)
+		assert.NotEmpty(t, findings)
+	})
+
+	t.Run("unreadable package status is reported with the package", func(t *testing.T) {
+		brokenPath := filepath.Join(duhPath, "packages", "broken")
+		require.NoError(t, os.MkdirAll(brokenPath, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(brokenPath, "db.toml"), []byte("[aliases]\n"), 0644))
+		// A repository whose git configuration cannot be parsed
+		require.NoError(t, os.MkdirAll(filepath.Join(brokenPath, ".git"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(brokenPath, ".git", "HEAD"), []byte("ref: refs/heads/main\n"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(brokenPath, ".git", "config"), []byte("[core\n"), 0644))
+		defer os.RemoveAll(brokenPath)
+
+		stdout, stderr, err := executeCommandOutputs([]string{"package", "status", "--no-fetch", "-o", "json"})
+		assert.Error(t, err)
+		assert.EqualError(t, err, "the status of 1 packages could not be read", stderr)
+		var statuses []struct {
+			Name  string `json:"name"`
+			Error string `json:"error"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(stdout), &statuses), stdout)
+		errors := map[string]string{}
+		for _, status := range statuses {
+			errors[status.Name] = status.Error
+		}
+		assert.NotEmpty(t, errors["broken"])
+		assert.Contains(t, errors, "local")
+		assert.Empty(t, errors["local"])
+	})
 }
 
 // Test_E2E_Help tests help commands
@@ -3,18 +3,26 @@ package integration
 import (
 	"duh/internal/domain/constants"
 	"duh/internal/domain/entity"
+	"duh/internal/domain/service"
 	"duh/internal/infrastructure/filesystem/common"
 	"duh/internal/infrastructure/filesystem/file_db"
+	"duh/internal/infrastructure/filesystem/fs_user_repository"
 	"duh/internal/infrastructure/filesystem/tomll"
+	"duh/internal/infrastructure/systemm"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/stretchr/testify/assert"
 )
 
+// Selects the active profile of the test user preferences, like the CLI does
+var profileResolver = service.NewProfileService(systemm.NewEnvironmentAdapter())
+
 func setup(t *testing.T) *file_db.FileDbRepository {
 	tempdir := filepath.Join(t.TempDir(), "filedbrepo_test")
 	// defer os.RemoveAll(tempdir)
@@ -23,7 +31,7 @@ func setup(t *testing.T) *file_db.FileDbRepository {
 	hasChanged, err := initService.Check()
 	assert.NoError(t, err)
 	assert.Truef(t, hasChanged, "initialization should have made changes")
-	return file_db.NewFileDbAdapter(pathProvider, common.NewCustomPathProvider("gitconfig.ini"), &tomll.TomlFileHandler{})
+	return file_db.NewFileDbAdapter(pathProvider, common.NewCustomPathProvider("gitconfig.ini"), &tomll.TomlFileHandler{}, profileResolver)
 }
 
 func Test_GetEnabledPackages(t *testing.T) {
@@ -36,6 +44,26 @@ func Test_GetEnabledPackages(t *testing.T) {
 	assert.Equal(t, "local", enabledRepos[0].Name)
 }
 
+func Test_GetEnabledPackages_ActivationOrder(t *testing.T) {
+	fileDbRepository := setup(t)
+	_, err := fileDbRepository.DirectoryService.CreatePackage("aaa")
+	assert.NoError(t, err)
+	_, err = fileDbRepository.DirectoryService.CreatePackage("zzz")
+	assert.NoError(t, err)
+
+	// Enabled after "local", so they must come after it regardless of their names
+	assert.NoError(t, fileDbRepository.EnablePackage("zzz"))
+	assert.NoError(t, fileDbRepository.EnablePackage("aaa"))
+
+	enabledRepos, err := fileDbRepository.GetEnabledPackages()
+	assert.NoError(t, err)
+	names := []string{}
+	for _, repo := range enabledRepos {
+		names = append(names, repo.Name)
+	}
+	assert.Equal(t, []string{"local", "zzz", "aaa"}, names)
+}
+
 func Test_GetDefaultPackage(t *testing.T) {
 	fileDbRepository := setup(t)
 	defaultRepo, err := fileDbRepository.GetDefaultPackage()
@@ -81,7 +109,7 @@ func Test_UpsertPackage(t *testing.T) {
 	repo := entity.Package{
 		Name:    "newrepo",
 		Aliases: map[string]string{"nr": "newr"},
-		Exports: map[string]string{"export1": "value1"},
+		Exports: map[string]entity.Export{"export1": {Value: "value1"}},
 	}
 	err := fileDbRepository.UpsertPackage(repo)
 	assert.NoError(t, err)
@@ -93,7 +121,7 @@ func Test_UpsertPackage(t *testing.T) {
 	repoOverride := entity.Package{
 		Name:    "newrepo",
 		Aliases: map[string]string{"nr": "newr2"},
-		Exports: map[string]string{"export1": "value2"},
+		Exports: map[string]entity.Export{"export1": {Value: "value2"}},
 	}
 	err = fileDbRepository.UpsertPackage(repoOverride)
 	assert.NoError(t, err)
@@ -104,6 +132,253 @@ func Test_UpsertPackage(t *testing.T) {
 	assert.Equal(t, repoOverride.Exports, repoP.Exports)
 }
 
+func Test_UpsertPackage_KeepsConditions(t *testing.T) {
+	fileDbRepository := setup(t)
+	repo := entity.Package{
+		Name:             "conditional",
+		Aliases:          map[string]string{"copy": "pbcopy", "ll": "ls -la"},
+		AliasConditions:  map[string]entity.Condition{"copy": {OS: "darwin"}},
+		Exports:          map[string]entity.Export{"PATH": {Prepend: []string{"/opt/homebrew/bin"}}},
+		ExportConditions: map[string]entity.Condition{"PATH": {OS: "darwin", Shell: "zsh"}},
+	}
+	err := fileDbRepository.UpsertPackage(repo)
+	assert.NoError(t, err)
+
+	repoP, err := fileDbRepository.GetRepositoryByName("conditional")
+	assert.NoError(t, err)
+	assert.Equal(t, repo.Aliases, repoP.Aliases)
+	assert.Equal(t, repo.AliasConditions, repoP.AliasConditions)
+	assert.Equal(t, repo.Exports, repoP.Exports)
+	assert.Equal(t, repo.ExportConditions, repoP.ExportConditions)
+}
+
+func Test_UpsertPackage_KeepsManifest(t *testing.T) {
+	fileDbRepository := setup(t)
+	repo := entity.Package{
+		Name:    "described",
+		Aliases: map[string]string{"ll": "ls -la"},
+		Manifest: entity.Manifest{
+			Description:  "Aliases of the platform team",
+			Version:      "1.2.0",
+			Authors:      []string{"Jane Doe <jane@example.com>"},
+			Dependencies: []string{"https://github.com/example/base-duh.git"},
+		},
+	}
+	err := fileDbRepository.UpsertPackage(repo)
+	assert.NoError(t, err)
+
+	// Changing an alias keeps the manifest
+	repo.Aliases["gs"] = "git status"
+	err = fileDbRepository.UpsertPackage(repo)
+	assert.NoError(t, err)
+
+	repoP, err := fileDbRepository.GetRepositoryByName("described")
+	assert.NoError(t, err)
+	assert.Equal(t, repo.Aliases, repoP.Aliases)
+	assert.Equal(t, repo.Manifest, repoP.Manifest)
+}
+
+func Test_FindPackageByUrl(t *testing.T) {
+	fileDbRepository := setup(t)
+	repoPath, err := fileDbRepository.CreatePackage("cloned")
+	assert.NoError(t, err)
+	gitRepo, err := git.PlainInit(repoPath, false)
+	assert.NoError(t, err)
+	_, err = gitRepo.CreateRemote(&config.RemoteConfig{
+		Name: git.DefaultRemoteName,
+		URLs: []string{"https://github.com/example/base-duh.git"},
+	})
+	assert.NoError(t, err)
+
+	name, found, err := fileDbRepository.FindPackageByUrl("https://github.com/example/base-duh")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "cloned", name)
+
+	_, found, err = fileDbRepository.FindPackageByUrl("https://github.com/example/other-duh")
+	assert.NoError(t, err)
+	assert.False(t, found)
+}
+
+// createPackageRemote creates a package repository whose tag v1.0.0 defines the alias "v" as "1",
+// and whose default branch defines it as "2"
+func createPackageRemote(t *testing.T) string {
+	remotePath := filepath.Join(t.TempDir(), "remote")
+	repo, err := git.PlainInit(remotePath, false)
+	assert.NoError(t, err)
+	worktree, err := repo.Worktree()
+	assert.NoError(t, err)
+	for _, release := range []string{"1", "2"} {
+		content := "[aliases]\nv = \"" + release + "\"\n"
+		assert.NoError(t, os.WriteFile(filepath.Join(remotePath, "db.toml"), []byte(content), 0644))
+		_, err = worktree.Add("db.toml")
+		assert.NoError(t, err)
+		hash, err := worktree.Commit("release "+release, &git.CommitOptions{
+			Author: &object.Signature{Name: "Test User", Email: "test@example.com"},
+		})
+		assert.NoError(t, err)
+		if release == "1" {
+			_, err = repo.CreateTag("v1.0.0", hash, nil)
+			assert.NoError(t, err)
+		}
+	}
+	return remotePath
+}
+
+func Test_FetchIncomingUpdate(t *testing.T) {
+	fileDbRepository := setup(t)
+	name := "shared"
+	_, err := fileDbRepository.AddPackage(createPackageRemote(t), &name)
+	assert.NoError(t, err)
+
+	update, err := fileDbRepository.FetchIncomingUpdate("shared")
+	assert.NoError(t, err)
+	assert.Equal(t, update.From, update.To)
+
+	// Move the package back to its first release, the second one becomes incoming
+	err = fileDbRepository.CheckoutPackageCommit("shared", "v1.0.0", false)
+	assert.NoError(t, err)
+	update, err = fileDbRepository.FetchIncomingUpdate("shared")
+	assert.NoError(t, err)
+	assert.NotEqual(t, update.From, update.To)
+	assert.Equal(t, map[string]string{"v": "1"}, update.Current.Aliases)
+	assert.Equal(t, map[string]string{"v": "2"}, update.Incoming.Aliases)
+
+	// Nothing was pulled
+	repo, err := fileDbRepository.GetRepositoryByName("shared")
+	assert.NoError(t, err)
+	assert.Equal(t, "1", repo.Aliases["v"])
+
+	// The default package is not a git repository
+	update, err = fileDbRepository.FetchIncomingUpdate("local")
+	assert.NoError(t, err)
+	assert.Nil(t, update)
+}
+
+func Test_UpdatePackages_Rebase(t *testing.T) {
+	fileDbRepository := setup(t)
+	remotePath := createPackageRemote(t)
+	name := "shared"
+	_, err := fileDbRepository.AddPackage(remotePath, &name)
+	assert.NoError(t, err)
+
+	// The remote changes v and adds r, while the package gets local aliases l and v
+	content := "[aliases]\nv = \"3\"\nr = \"remote\"\n"
+	assert.NoError(t, os.WriteFile(filepath.Join(remotePath, "db.toml"), []byte(content), 0644))
+	remote, err := git.PlainOpen(remotePath)
+	assert.NoError(t, err)
+	worktree, err := remote.Worktree()
+	assert.NoError(t, err)
+	_, err = worktree.Add("db.toml")
+	assert.NoError(t, err)
+	_, err = worktree.Commit("release 3", &git.CommitOptions{
+		Author: &object.Signature{Name: "Test User", Email: "test@example.com"},
+	})
+	assert.NoError(t, err)
+	repo, err := fileDbRepository.GetRepositoryByName("shared")
+	assert.NoError(t, err)
+	repo.Aliases["l"] = "local"
+	repo.Aliases["v"] = "mine"
+	assert.NoError(t, fileDbRepository.UpsertPackage(*repo))
+
+	conflicts := []entity.MergeConflict{}
+	results, err := fileDbRepository.UpdatePackages(entity.UpdateOptions{
+		Strategy: entity.UpdateRebase,
+		Resolve: func(conflict entity.MergeConflict) (string, error) {
+			conflicts = append(conflicts, conflict)
+			return entity.KeepLocal, nil
+		},
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, results.OtherErrors)
+	assert.Equal(t, []entity.MergeConflict{
+		{Package: "shared", File: "db.toml", Key: "aliases.v", Local: `"mine"`, Remote: `"3"`},
+	}, conflicts)
+	repo, err = fileDbRepository.GetRepositoryByName("shared")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"v": "mine", "l": "local", "r": "remote"}, repo.Aliases)
+}
+
+func Test_UpdatePackages_KeepCommitsWithIdentity(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	tempdir := filepath.Join(t.TempDir(), "filedbrepo_test")
+	pathProvider := common.NewCustomPathProvider(tempdir)
+	fileHandler := &tomll.TomlFileHandler{}
+	_, err := file_db.NewInitDbService(pathProvider, fileHandler).Check()
+	assert.NoError(t, err)
+	fileDbRepository := file_db.NewFileDbAdapter(pathProvider, common.NewCustomPathProvider("gitconfig.ini"), fileHandler, profileResolver)
+	name := "shared"
+	_, err = fileDbRepository.AddPackage(createPackageRemote(t), &name)
+	assert.NoError(t, err)
+	repoPath, err := fileDbRepository.DirectoryService.GetRepositoryPath(name)
+	assert.NoError(t, err)
+
+	// The package is committed to with its own identity
+	userPrefRepo := fs_user_repository.NewFsUserRepository(fileHandler, pathProvider, profileResolver)
+	userPrefs, err := userPrefRepo.GetUserPreference()
+	assert.NoError(t, err)
+	userPrefs.Identities = map[string]common.IdentityDto{
+		"shared": {Name: "Work User", Email: "work@example.com"},
+	}
+	assert.NoError(t, userPrefRepo.SaveUserPreference(userPrefs))
+
+	repo, err := fileDbRepository.GetRepositoryByName("shared")
+	assert.NoError(t, err)
+	repo.Aliases["l"] = "ls -la"
+	assert.NoError(t, fileDbRepository.UpsertPackage(*repo))
+
+	results, err := fileDbRepository.UpdatePackages(entity.UpdateOptions{Strategy: entity.UpdateKeep})
+	assert.NoError(t, err)
+	assert.Empty(t, results.OtherErrors)
+
+	gitRepo, err := git.PlainOpen(repoPath)
+	assert.NoError(t, err)
+	head, err := gitRepo.Head()
+	assert.NoError(t, err)
+	commit, err := gitRepo.CommitObject(head.Hash())
+	assert.NoError(t, err)
+	assert.Equal(t, "Work User", commit.Author.Name)
+	assert.Equal(t, "work@example.com", commit.Author.Email)
+	assert.Equal(t, "Add alias `l`\n\n- alias `l` added: ls -la", commit.Message)
+}
+
+func Test_PinPackage(t *testing.T) {
+	fileDbRepository := setup(t)
+	name := "pinned"
+	_, err := fileDbRepository.AddPackage(createPackageRemote(t), &name)
+	assert.NoError(t, err)
+
+	err = fileDbRepository.PinPackage("pinned", "v1.0.0")
+	assert.NoError(t, err)
+	repo, err := fileDbRepository.GetRepositoryByName("pinned")
+	assert.NoError(t, err)
+	assert.Equal(t, "1", repo.Aliases["v"])
+
+	// Updates keep the package on its pin
+	results, err := fileDbRepository.UpdatePackages(entity.UpdateOptions{Strategy: entity.UpdateSafe})
+	assert.NoError(t, err)
+	assert.Empty(t, results.OtherErrors)
+	repo, err = fileDbRepository.GetRepositoryByName("pinned")
+	assert.NoError(t, err)
+	assert.Equal(t, "1", repo.Aliases["v"])
+
+	// The pin follows the package when it is renamed
+	err = fileDbRepository.RenamePackage("pinned", "renamed")
+	assert.NoError(t, err)
+	pins, err := fileDbRepository.GetPins()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"renamed": "v1.0.0"}, pins)
+
+	err = fileDbRepository.UnpinPackage("renamed")
+	assert.NoError(t, err)
+	repo, err = fileDbRepository.GetRepositoryByName("renamed")
+	assert.NoError(t, err)
+	assert.Equal(t, "2", repo.Aliases["v"])
+	pins, err = fileDbRepository.GetPins()
+	assert.NoError(t, err)
+	assert.Empty(t, pins)
+}
+
 func Test_ChangeDefaultPackage(t *testing.T) {
 	fileDbRepository := setup(t)
 	repoName := "newdefaultrepo"
@@ -172,7 +447,7 @@ func Test_UpdatePackages(t *testing.T) {
 	fileDbRepository := setup(t)
 
 	// Test with no repositories having git remotes
-	results, err := fileDbRepository.UpdatePackages(entity.UpdateSafe)
+	results, err := fileDbRepository.UpdatePackages(entity.UpdateOptions{Strategy: entity.UpdateSafe})
 	assert.NoError(t, err)
 	assert.Empty(t, results.LocalChangesDetected)
 	assert.Empty(t, results.OtherErrors)
@@ -187,19 +462,19 @@ func Test_UpdatePackages_WithGitRepositories(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Test safe strategy - should succeed when no local changes
-	results, err := fileDbRepository.UpdatePackages(entity.UpdateSafe)
+	results, err := fileDbRepository.UpdatePackages(entity.UpdateOptions{Strategy: entity.UpdateSafe})
 	assert.NoError(t, err)
 	assert.Empty(t, results.LocalChangesDetected)
 	assert.Empty(t, results.OtherErrors)
 
 	// Test keep strategy
-	results, err = fileDbRepository.UpdatePackages(entity.UpdateKeep)
+	results, err = fileDbRepository.UpdatePackages(entity.UpdateOptions{Strategy: entity.UpdateKeep})
 	assert.NoError(t, err)
 	assert.Empty(t, results.LocalChangesDetected)
 	assert.Empty(t, results.OtherErrors)
 
 	// Test force strategy
-	results, err = fileDbRepository.UpdatePackages(entity.UpdateForce)
+	results, err = fileDbRepository.UpdatePackages(entity.UpdateOptions{Strategy: entity.UpdateForce})
 	assert.NoError(t, err)
 	assert.Empty(t, results.LocalChangesDetected)
 	assert.Empty(t, results.OtherErrors)
@@ -224,7 +499,7 @@ func Test_UpdatePackages_InvalidStrategy(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Test with invalid strategy - should return error
-	results, err := fileDbRepository.UpdatePackages("invalid")
+	results, err := fileDbRepository.UpdatePackages(entity.UpdateOptions{Strategy: "invalid"})
 	assert.NoError(t, err) // The function itself doesn't error, but individual repos might
 	assert.Empty(t, results.LocalChangesDetected)
 	// Should have an error for the repository with invalid strategy
@@ -270,7 +545,7 @@ func Test_BonusInjection(t *testing.T) {
 	// Create a custom file db repository with custom git config path
 	pathProvider := common.NewCustomPathProvider(tempBaseDir)
 	gitConfigPathProvider := common.NewCustomPathProvider(tempGitConfigPath)
-	fileDbRepository := file_db.NewFileDbAdapter(pathProvider, gitConfigPathProvider, &tomll.TomlFileHandler{})
+	fileDbRepository := file_db.NewFileDbAdapter(pathProvider, gitConfigPathProvider, &tomll.TomlFileHandler{}, profileResolver)
 
 	// Initialize the repository structure
 	initService := file_db.NewInitDbService(pathProvider, &tomll.TomlFileHandler{})
@@ -322,7 +597,7 @@ func Test_BonusInjection_DuplicateIncludes(t *testing.T) {
 	// Create a custom file db repository with custom git config path
 	pathProvider := common.NewCustomPathProvider(tempBaseDir)
 	gitConfigPathProvider := common.NewCustomPathProvider(tempGitConfigPath)
-	fileDbRepository := file_db.NewFileDbAdapter(pathProvider, gitConfigPathProvider, &tomll.TomlFileHandler{})
+	fileDbRepository := file_db.NewFileDbAdapter(pathProvider, gitConfigPathProvider, &tomll.TomlFileHandler{}, profileResolver)
 
 	// Initialize the repository structure
 	initService := file_db.NewInitDbService(pathProvider, &tomll.TomlFileHandler{})
@@ -379,7 +654,7 @@ func Test_BonusInjection_EmptyRepos(t *testing.T) {
 	// Create a custom file db repository with custom git config path
 	pathProvider := common.NewCustomPathProvider(tempBaseDir)
 	gitConfigPathProvider := common.NewCustomPathProvider(tempGitConfigPath)
-	fileDbRepository := file_db.NewFileDbAdapter(pathProvider, gitConfigPathProvider, &tomll.TomlFileHandler{})
+	fileDbRepository := file_db.NewFileDbAdapter(pathProvider, gitConfigPathProvider, &tomll.TomlFileHandler{}, profileResolver)
 
 	// Initialize the repository structure
 	initService := file_db.NewInitDbService(pathProvider, &tomll.TomlFileHandler{})
@@ -410,7 +685,7 @@ func Test_BonusInjection_GitConfigPathError(t *testing.T) {
 	// Create a custom file db repository with invalid git config path provider
 	pathProvider := common.NewCustomPathProvider(tempBaseDir)
 	gitConfigPathProvider := common.NewCustomPathProvider("/invalid/path/that/does/not/exist")
-	fileDbRepository := file_db.NewFileDbAdapter(pathProvider, gitConfigPathProvider, &tomll.TomlFileHandler{})
+	fileDbRepository := file_db.NewFileDbAdapter(pathProvider, gitConfigPathProvider, &tomll.TomlFileHandler{}, profileResolver)
 
 	// Initialize the repository structure
 	initService := file_db.NewInitDbService(pathProvider, &tomll.TomlFileHandler{})
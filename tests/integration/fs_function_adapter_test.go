@@ -103,7 +103,7 @@ echo "Global execution in repo2"`
 	// Set up dependencies - use actual implementations
 	pathProvider := common.NewCustomPathProvider(tempDir)
 	fileHandler := &tomll.TomlFileHandler{}
-	userPrefRepo := fs_user_repository.NewFsUserRepository(fileHandler, pathProvider)
+	userPrefRepo := fs_user_repository.NewFsUserRepository(fileHandler, pathProvider, profileResolver)
 
 	// Create repository under test
 	repo := fs_function_adapter.NewFSFunctionsRepository(pathProvider, userPrefRepo)
@@ -177,7 +177,7 @@ default_repo_name = ""`
 
 	pathProvider := common.NewCustomPathProvider(tempDir)
 	fileHandler := &tomll.TomlFileHandler{}
-	userPrefRepo := fs_user_repository.NewFsUserRepository(fileHandler, pathProvider)
+	userPrefRepo := fs_user_repository.NewFsUserRepository(fileHandler, pathProvider, profileResolver)
 
 	repo := fs_function_adapter.NewFSFunctionsRepository(pathProvider, userPrefRepo)
 
@@ -207,7 +207,7 @@ default_repo_name = "nonexistent1"`
 
 	pathProvider := common.NewCustomPathProvider(tempDir)
 	fileHandler := &tomll.TomlFileHandler{}
-	userPrefRepo := fs_user_repository.NewFsUserRepository(fileHandler, pathProvider)
+	userPrefRepo := fs_user_repository.NewFsUserRepository(fileHandler, pathProvider, profileResolver)
 
 	repo := fs_function_adapter.NewFSFunctionsRepository(pathProvider, userPrefRepo)
 